@@ -0,0 +1,66 @@
+// Command cerberus-store-plugin is the reference store plugin. It serves the
+// filesystem store of cerberus over the storeplugin protocol, and is meant as
+// a starting point for the plugins of other custody backends.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/Layr-Labs/cerberus/internal/store/filesystem"
+	"github.com/Layr-Labs/cerberus/internal/store/plugin"
+	"github.com/Layr-Labs/cerberus/pkg/storeplugin"
+)
+
+const pluginName = "cerberus-filesystem"
+
+var (
+	addressFlag = &cli.StringFlag{
+		Name:    "address",
+		Usage:   "Address to listen on, unix:///path/to/socket or a loopback host:port",
+		EnvVars: []string{storeplugin.AddressEnv},
+	}
+
+	keystoreDirFlag = &cli.StringFlag{
+		Name:    "keystore-dir",
+		Usage:   "Directory where the keystore files are stored",
+		Value:   "./data/keystore",
+		EnvVars: []string{"KEYSTORE_DIR"},
+	}
+)
+
+func main() {
+	app := cli.NewApp()
+
+	app.Name = "cerberus-store-plugin"
+	app.Usage = "Reference cerberus store plugin serving keystore files"
+	app.Flags = []cli.Flag{
+		addressFlag,
+		keystoreDirFlag,
+	}
+	app.Action = run
+
+	if err := app.Run(os.Args); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(c *cli.Context) error {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil)).With("plugin", pluginName)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	keystore := filesystem.NewStore(c.String(keystoreDirFlag.Name), logger)
+	srv := plugin.NewServer(pluginName, keystore, storeplugin.CapabilityPasswordEncryption)
+
+	logger.Info("Serving store plugin", "keystoreDir", c.String(keystoreDirFlag.Name))
+	return storeplugin.Serve(ctx, c.String(addressFlag.Name), srv)
+}
@@ -0,0 +1,599 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/apikey"
+	"github.com/Layr-Labs/cerberus/internal/common"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+	"github.com/Layr-Labs/cerberus/internal/metrics"
+	"github.com/Layr-Labs/cerberus/internal/migration"
+	"github.com/Layr-Labs/cerberus/internal/server"
+	"github.com/Layr-Labs/cerberus/internal/services/admin"
+	"github.com/Layr-Labs/cerberus/internal/store"
+
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	unusedDaysFlag = &cli.IntFlag{
+		Name:  "days",
+		Usage: "Number of days without signatures",
+		Value: 30,
+	}
+
+	publicKeyG1Flag = &cli.StringFlag{
+		Name:     "public-key-g1",
+		Usage:    "G1 public key of the key",
+		Required: true,
+	}
+
+	notBeforeFlag = &cli.StringFlag{
+		Name:  "not-before",
+		Usage: "RFC3339 time before which the key can't sign (empty for no lower bound)",
+	}
+
+	notAfterFlag = &cli.StringFlag{
+		Name:  "not-after",
+		Usage: "RFC3339 time from which the key can't sign (empty for no expiry)",
+	}
+
+	optionalPublicKeyG1Flag = &cli.StringFlag{
+		Name:  "public-key-g1",
+		Usage: "G1 public key of the key (all keys if empty)",
+	}
+
+	newKeystorePasswordFlag = &cli.StringFlag{
+		Name:     "keystore-password",
+		Usage:    "Password to encrypt the keys with",
+		Required: true,
+		EnvVars:  []string{"KEYSTORE_PASSWORD"},
+	}
+
+	fromStoreFlag = &cli.StringFlag{
+		Name:     "from",
+		Usage:    "Named store or storage type to copy the keys from",
+		Required: true,
+	}
+
+	toStoreFlag = &cli.StringFlag{
+		Name:     "to",
+		Usage:    "Named store or storage type to copy the keys to",
+		Required: true,
+	}
+
+	migrationPasswordFlag = &cli.StringFlag{
+		Name:    "keystore-password",
+		Usage:   "Password of the keys in the source store and in the target store",
+		EnvVars: []string{"KEYSTORE_PASSWORD"},
+	}
+
+	deleteSourceFlag = &cli.BoolFlag{
+		Name:  "delete-source",
+		Usage: "Delete the keys from the source store once their copy is verified",
+	}
+
+	keyVersionFlag = &cli.StringFlag{
+		Name:     "version",
+		Usage:    "ID of the version of the key in its store",
+		Required: true,
+	}
+
+	pinVersionFlag = &cli.StringFlag{
+		Name:  "version",
+		Usage: "ID of the version of the key to sign with (empty to sign with the current version)",
+	}
+
+	apiKeyNameFlag = &cli.StringFlag{
+		Name:     "name",
+		Usage:    "Name of the API key, unique among the API keys of the key",
+		Required: true,
+	}
+
+	apiKeyScopesFlag = &cli.StringFlag{
+		Name: "scopes",
+		Usage: "Comma separated scopes the API key is restricted to, among " +
+			strings.Join(model.Scopes, ", ") + " (every method if empty)",
+	}
+
+	apiKeyExpiresAtFlag = &cli.StringFlag{
+		Name:  "expires-at",
+		Usage: "RFC3339 time from which the API key is rejected (empty for no expiry)",
+	}
+
+	keysCommand = &cli.Command{
+		Name:  "keys",
+		Usage: "Manage the keys of the signer",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "unused",
+				Usage:  "List the keys that haven't signed for a number of days",
+				Flags:  []cli.Flag{unusedDaysFlag},
+				Action: listUnusedKeys,
+			},
+			{
+				Name:   "set-validity",
+				Usage:  "Set the time window in which a key can sign",
+				Flags:  []cli.Flag{publicKeyG1Flag, notBeforeFlag, notAfterFlag},
+				Action: setKeyValidity,
+			},
+			{
+				Name:  "encrypt-plaintext",
+				Usage: "Encrypt the keys stored in plaintext in a cloud secret manager",
+				Description: "Rewrites the keys stored as raw private keys as EIP-2335 keystores " +
+					"encrypted with the password. Signing requests for these keys must then " +
+					"provide the same password. Keys already stored as keystores are skipped.",
+				Flags:  []cli.Flag{optionalPublicKeyG1Flag, newKeystorePasswordFlag},
+				Action: encryptPlaintextKeys,
+			},
+			{
+				Name:  "migrate",
+				Usage: "Copy the keys from one store to another",
+				Description: "Copies the keys of the source store to the target store, " +
+					"re-encrypting them as the target store requires, and reads each " +
+					"copy back to verify its public key. The stores are named stores " +
+					"or storage types configured with the global flags. Moving a key " +
+					"to a named store records it in the key metadata, so that signing " +
+					"requests use the new store. Keys already in the target store are " +
+					"verified and skipped, so an interrupted migration can be run again.",
+				Flags: []cli.Flag{
+					fromStoreFlag,
+					toStoreFlag,
+					optionalPublicKeyG1Flag,
+					migrationPasswordFlag,
+					deleteSourceFlag,
+				},
+				Action: migrateKeys,
+			},
+			{
+				Name:   "versions",
+				Usage:  "List the versions of a key kept by a cloud secret manager",
+				Flags:  []cli.Flag{publicKeyG1Flag},
+				Action: listKeyVersions,
+			},
+			{
+				Name:  "pin-version",
+				Usage: "Make a key sign with a version of the key rather than the current one",
+				Description: "Records the version in the key metadata, signing requests then " +
+					"read that version of the key. Every version holds the same private " +
+					"key, so pinning keeps a key usable when a newer version can't be " +
+					"decrypted. Without --version the key is unpinned.",
+				Flags:  []cli.Flag{publicKeyG1Flag, pinVersionFlag},
+				Action: pinKeyVersion,
+			},
+			{
+				Name:  "rollback",
+				Usage: "Make an earlier version of a key current again",
+				Description: "Moves the current version of the key back to the version, or " +
+					"adds a copy of it as a new version when the store always reads the " +
+					"latest version, and records the current version in the key metadata.",
+				Flags:  []cli.Flag{publicKeyG1Flag, keyVersionFlag},
+				Action: rollbackKey,
+			},
+			{
+				Name:  "rewrap",
+				Usage: "Write a key as a new version envelope encrypted with the master key",
+				Description: "Wraps the version the key signs with in a new data key " +
+					"encrypted by the current master key, such as after rotating the " +
+					"master key, and records the new version in the key metadata.",
+				Flags:  []cli.Flag{publicKeyG1Flag},
+				Action: rewrapKey,
+			},
+			{
+				Name:  "create-api-key",
+				Usage: "Add an API key to a key",
+				Description: "Prints the new API key, which isn't stored and can't be shown " +
+					"again. Signing requests are accepted with any unexpired API key of " +
+					"the key allowed the method, so clients can move to the new API key " +
+					"before the old one is revoked.",
+				Flags: []cli.Flag{
+					publicKeyG1Flag,
+					apiKeyNameFlag,
+					apiKeyScopesFlag,
+					apiKeyExpiresAtFlag,
+				},
+				Action: createAPIKey,
+			},
+			{
+				Name:   "list-api-keys",
+				Usage:  "List the API keys of a key",
+				Flags:  []cli.Flag{publicKeyG1Flag},
+				Action: listAPIKeys,
+			},
+			{
+				Name:   "revoke-api-key",
+				Usage:  "Revoke an API key of a key",
+				Flags:  []cli.Flag{publicKeyG1Flag, apiKeyNameFlag},
+				Action: revokeAPIKey,
+			},
+		},
+	}
+)
+
+// newAdminService opens the key resources and builds an admin service on top
+// of them for the offline key management commands. Without the API key
+// pepper, the commands creating API keys fail.
+func newAdminService(c *cli.Context) (*admin.Service, *server.KeyResources, error) {
+	cfg, err := newConfiguration(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger := newLogger(c)
+
+	var apiKeys *apikey.Hasher
+	if cfg.APIKeyPepper != "" {
+		apiKeys, err = apikey.NewHasher(cfg.APIKeyPepper)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	resources, err := server.NewKeyResources(cfg, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	adminService := admin.NewService(
+		cfg,
+		logger,
+		metrics.NewNoopRPCMetrics(),
+		resources.KeyMetadataRepo,
+		resources.KeyUsageRepo,
+		resources.APIKeyRepo,
+		apiKeys,
+	)
+	return adminService, resources, nil
+}
+
+func listUnusedKeys(c *cli.Context) error {
+	days := c.Int(unusedDaysFlag.Name)
+	if days <= 0 {
+		return fmt.Errorf("--%s must be positive", unusedDaysFlag.Name)
+	}
+
+	adminService, resources, err := newAdminService(c)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+
+	keys, err := adminService.ListUnusedKeys(c.Context, time.Duration(days)*24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PUBLIC KEY G1\tCREATED AT\tLAST USED AT\tSIGNATURES")
+	for _, key := range keys {
+		lastUsedAt := "never"
+		if key.LastUsedAt != nil {
+			lastUsedAt = key.LastUsedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%d\n",
+			key.PublicKeyG1,
+			key.CreatedAt.Format(time.RFC3339),
+			lastUsedAt,
+			key.SignatureCount,
+		)
+	}
+	return w.Flush()
+}
+
+func setKeyValidity(c *cli.Context) error {
+	notBefore, err := parseOptionalTime(c.String(notBeforeFlag.Name))
+	if err != nil {
+		return fmt.Errorf("invalid --%s: %w", notBeforeFlag.Name, err)
+	}
+	notAfter, err := parseOptionalTime(c.String(notAfterFlag.Name))
+	if err != nil {
+		return fmt.Errorf("invalid --%s: %w", notAfterFlag.Name, err)
+	}
+
+	adminService, resources, err := newAdminService(c)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+
+	publicKeyG1 := c.String(publicKeyG1Flag.Name)
+	if err := adminService.SetKeyValidity(c.Context, publicKeyG1, notBefore, notAfter); err != nil {
+		return err
+	}
+	fmt.Printf("Updated the validity window of %s\n", publicKeyG1)
+	return nil
+}
+
+func encryptPlaintextKeys(c *cli.Context) error {
+	adminService, resources, err := newAdminService(c)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+
+	migrator, ok := store.As[store.KeystoreMigrator](resources.KeyStore)
+	if !ok {
+		return fmt.Errorf("the configured store doesn't store plaintext keys")
+	}
+
+	pubKeys := []string{c.String(optionalPublicKeyG1Flag.Name)}
+	if pubKeys[0] == "" {
+		pubKeys, err = resources.KeyStore.ListKeys(c.Context)
+		if err != nil {
+			return err
+		}
+	}
+
+	password := c.String(newKeystorePasswordFlag.Name)
+	encrypted := 0
+	for _, pubKey := range pubKeys {
+		migrated, err := migrator.MigrateToKeystore(c.Context, pubKey, password)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", pubKey, err)
+		}
+		if !migrated {
+			continue
+		}
+		fmt.Printf("Encrypted %s\n", pubKey)
+		encrypted++
+
+		// The plaintext version may be destroyed, the metadata must point to
+		// the encrypted one
+		_, err = adminService.RecordKeyVersion(c.Context, resources.KeyStore, pubKey)
+		if err != nil && !errors.Is(err, repository.ErrKeyNotFound) &&
+			!errors.Is(err, store.ErrVersioningUnsupported) {
+			return fmt.Errorf("failed to record the encrypted version of %s: %w", pubKey, err)
+		}
+	}
+	fmt.Printf("Encrypted %d of %d keys\n", encrypted, len(pubKeys))
+	return nil
+}
+
+func migrateKeys(c *cli.Context) error {
+	from := c.String(fromStoreFlag.Name)
+	to := c.String(toStoreFlag.Name)
+	if from == to {
+		return fmt.Errorf(
+			"--%s and --%s must be different stores",
+			fromStoreFlag.Name,
+			toStoreFlag.Name,
+		)
+	}
+
+	adminService, resources, err := newAdminService(c)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+
+	fromStore, _, err := resources.OpenStore(from)
+	if err != nil {
+		return fmt.Errorf("failed to open store %s: %w", from, err)
+	}
+	toStore, toStoreName, err := resources.OpenStore(to)
+	if err != nil {
+		return fmt.Errorf("failed to open store %s: %w", to, err)
+	}
+
+	var pubKeys []string
+	if pubKey := c.String(optionalPublicKeyG1Flag.Name); pubKey != "" {
+		pubKeys = []string{pubKey}
+	}
+
+	report, err := adminService.MigrateKeys(c.Context, fromStore, toStore, migration.Options{
+		PublicKeys:   pubKeys,
+		Password:     c.String(migrationPasswordFlag.Name),
+		StoreName:    toStoreName,
+		DeleteSource: c.Bool(deleteSourceFlag.Name),
+	})
+	if err != nil {
+		return err
+	}
+
+	printKeys("Migrated keys", report.Migrated)
+	printKeys("Keys already in target store", report.AlreadyMigrated)
+	for _, failure := range report.Failed {
+		fmt.Printf("Failed to migrate %s: %v\n", failure.PublicKeyG1, failure.Error)
+	}
+
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("failed to migrate %d keys", len(report.Failed))
+	}
+	return nil
+}
+
+func listKeyVersions(c *cli.Context) error {
+	adminService, resources, err := newAdminService(c)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+
+	publicKeyG1 := c.String(publicKeyG1Flag.Name)
+	versions, err := adminService.ListKeyVersions(c.Context, resources.KeyStore, publicKeyG1)
+	if err != nil {
+		return err
+	}
+	metadata, err := resources.KeyMetadataRepo.Get(c.Context, common.Trim0x(publicKeyG1))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tCREATED AT\tCURRENT\tSIGNS")
+	for _, version := range versions {
+		signs := version.Current
+		if metadata.KeyVersionPinned {
+			signs = version.ID == metadata.KeyVersion
+		}
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%t\t%t\n",
+			version.ID,
+			version.CreatedAt.Format(time.RFC3339),
+			version.Current,
+			signs,
+		)
+	}
+	return w.Flush()
+}
+
+func pinKeyVersion(c *cli.Context) error {
+	adminService, resources, err := newAdminService(c)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+
+	publicKeyG1 := c.String(publicKeyG1Flag.Name)
+	versionID := c.String(pinVersionFlag.Name)
+	err = adminService.PinKeyVersion(c.Context, resources.KeyStore, publicKeyG1, versionID)
+	if err != nil {
+		return err
+	}
+	if versionID == "" {
+		fmt.Printf("Unpinned %s, it signs with its current version\n", publicKeyG1)
+	} else {
+		fmt.Printf("Pinned %s to version %s\n", publicKeyG1, versionID)
+	}
+	return nil
+}
+
+func rollbackKey(c *cli.Context) error {
+	adminService, resources, err := newAdminService(c)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+
+	publicKeyG1 := c.String(publicKeyG1Flag.Name)
+	versionID := c.String(keyVersionFlag.Name)
+	current, err := adminService.RollbackKey(c.Context, resources.KeyStore, publicKeyG1, versionID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(
+		"Rolled back %s to version %s, current version is %s\n",
+		publicKeyG1,
+		versionID,
+		current,
+	)
+	return nil
+}
+
+func rewrapKey(c *cli.Context) error {
+	adminService, resources, err := newAdminService(c)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+
+	publicKeyG1 := c.String(publicKeyG1Flag.Name)
+	current, err := adminService.RewrapKey(c.Context, resources.KeyStore, publicKeyG1)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Re-wrapped %s as version %s\n", publicKeyG1, current)
+	return nil
+}
+
+func createAPIKey(c *cli.Context) error {
+	expiresAt, err := parseOptionalTime(c.String(apiKeyExpiresAtFlag.Name))
+	if err != nil {
+		return fmt.Errorf("invalid --%s: %w", apiKeyExpiresAtFlag.Name, err)
+	}
+
+	adminService, resources, err := newAdminService(c)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+
+	publicKeyG1 := c.String(publicKeyG1Flag.Name)
+	apiKey, _, err := adminService.CreateAPIKey(
+		c.Context,
+		publicKeyG1,
+		c.String(apiKeyNameFlag.Name),
+		parseList(c.String(apiKeyScopesFlag.Name)),
+		expiresAt,
+	)
+	if err != nil {
+		return err
+	}
+	fmt.Println(apiKey)
+	return nil
+}
+
+func listAPIKeys(c *cli.Context) error {
+	adminService, resources, err := newAdminService(c)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+
+	apiKeys, err := adminService.ListAPIKeys(c.Context, c.String(publicKeyG1Flag.Name))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSCOPES\tHASH\tCREATED AT\tEXPIRES AT\tLAST USED AT")
+	for _, apiKey := range apiKeys {
+		scopes := "all"
+		if len(apiKey.Scopes) > 0 {
+			scopes = strings.Join(apiKey.Scopes, ",")
+		}
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\t%s\n",
+			apiKey.Name,
+			scopes,
+			apiKey.HashScheme,
+			apiKey.CreatedAt.Format(time.RFC3339),
+			formatOptionalTime(apiKey.ExpiresAt),
+			formatOptionalTime(apiKey.LastUsedAt),
+		)
+	}
+	return w.Flush()
+}
+
+func revokeAPIKey(c *cli.Context) error {
+	adminService, resources, err := newAdminService(c)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+
+	publicKeyG1 := c.String(publicKeyG1Flag.Name)
+	name := c.String(apiKeyNameFlag.Name)
+	if err := adminService.RevokeAPIKey(c.Context, publicKeyG1, name); err != nil {
+		return err
+	}
+	fmt.Printf("Revoked API key %s of %s\n", name, publicKeyG1)
+	return nil
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Layr-Labs/cerberus/internal/reconciler"
+	"github.com/Layr-Labs/cerberus/internal/server"
+
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	repairFlag = &cli.BoolFlag{
+		Name:  "repair",
+		Usage: "Repair the inconsistencies that can be fixed safely",
+		Value: false,
+	}
+
+	keystorePasswordFlag = &cli.StringFlag{
+		Name:    "keystore-password",
+		Usage:   "Password used to read encrypted keys found in the store without metadata",
+		EnvVars: []string{"KEYSTORE_PASSWORD"},
+	}
+
+	reconcileCommand = &cli.Command{
+		Name:  "reconcile",
+		Usage: "Compare the keys in the store with the key metadata in the database",
+		Description: "Reports keys stored without metadata, metadata without a stored key " +
+			"and metadata with mismatching G1/G2 public keys. With --repair, keys " +
+			"without metadata are registered in a locked state and the other " +
+			"inconsistent keys are locked, so that they can't sign. With a replicated store, the keys missing " +
+			"from some replicas are reported as well but never repaired.",
+		Flags:  []cli.Flag{repairFlag, keystorePasswordFlag},
+		Action: reconcile,
+	}
+)
+
+func reconcile(c *cli.Context) error {
+	cfg, err := newConfiguration(c)
+	if err != nil {
+		return err
+	}
+	logger := newLogger(c)
+
+	resources, err := server.NewKeyResources(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer resources.Close()
+
+	report, err := reconciler.New(
+		resources.KeyStore,
+		resources.KeyMetadataRepo,
+		logger,
+	).Run(c.Context, reconciler.Options{
+		Repair:   c.Bool(repairFlag.Name),
+		Password: c.String(keystorePasswordFlag.Name),
+	})
+	if err != nil {
+		return err
+	}
+
+	printKeys("Keys in store without metadata", report.MissingMetadata)
+	printKeys("Key metadata without key in store", report.MissingFromStore)
+	printKeys("Key metadata with mismatching G1/G2 public keys", report.InvalidKeyPairs)
+	if len(report.ReplicaDrift) > 0 {
+		fmt.Printf("Keys missing from replicas: %d\n", len(report.ReplicaDrift))
+		for _, d := range report.ReplicaDrift {
+			fmt.Printf("  %s: %s\n", d.PubKey, strings.Join(d.MissingFrom, ", "))
+		}
+	}
+	for _, action := range report.Repaired {
+		fmt.Printf("Repaired %s: %s\n", action.PublicKeyG1, action.Description)
+	}
+	for _, action := range report.Failed {
+		fmt.Printf("Failed to repair %s: %s\n", action.PublicKeyG1, action.Description)
+	}
+	for _, action := range report.Skipped {
+		fmt.Printf("Skipped %s: %s\n", action.PublicKeyG1, action.Description)
+	}
+
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("failed to repair %d keys", len(report.Failed))
+	}
+	if len(report.Skipped) > 0 {
+		return fmt.Errorf(
+			"skipped %d encrypted keys, run with --%s to register them",
+			len(report.Skipped),
+			keystorePasswordFlag.Name,
+		)
+	}
+	if !report.Consistent() && !c.Bool(repairFlag.Name) {
+		return fmt.Errorf("found inconsistencies, run with --%s to repair them", repairFlag.Name)
+	}
+	return nil
+}
+
+func printKeys(title string, keys []string) {
+	fmt.Printf("%s: %d\n", title, len(keys))
+	for _, key := range keys {
+		fmt.Printf("  %s\n", key)
+	}
+}
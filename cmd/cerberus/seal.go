@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/seal"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+var (
+	sharesFlag = &cli.IntFlag{
+		Name:  "shares",
+		Usage: "Number of shares to split the master key into",
+		Value: 5,
+	}
+
+	thresholdFlag = &cli.IntFlag{
+		Name:  "threshold",
+		Usage: "Number of shares needed to reconstruct the master key",
+		Value: 3,
+	}
+
+	kekOutputFlag = &cli.StringFlag{
+		Name:     "output",
+		Usage:    "File to write the hex encoded master key to",
+		Required: true,
+	}
+
+	unsealServerFlag = &cli.StringFlag{
+		Name:    "address",
+		Usage:   "URL of the unseal server",
+		Value:   "http://127.0.0.1:50053",
+		EnvVars: []string{"CERBERUS_UNSEAL_URL"},
+	}
+
+	unsealCACertFlag = &cli.StringFlag{
+		Name:  "ca-cert",
+		Usage: "CA certificate of the unseal server when it uses TLS",
+	}
+
+	shareFlag = &cli.StringFlag{
+		Name:  "share",
+		Usage: "Hex encoded unseal share (prompted for if empty)",
+	}
+
+	sealCommand = &cli.Command{
+		Name:  "seal",
+		Usage: "Manage the master key wrapping the keys of the cloud stores",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "init",
+				Usage:  "Generate a master key and split it into unseal shares",
+				Flags:  []cli.Flag{sharesFlag, thresholdFlag},
+				Action: initSeal,
+			},
+			{
+				Name:   "generate-kek",
+				Usage:  "Generate a master key and write it to a file",
+				Flags:  []cli.Flag{kekOutputFlag},
+				Action: generateKEK,
+			},
+			{
+				Name:   "status",
+				Usage:  "Show the unseal progress of a running server",
+				Flags:  []cli.Flag{unsealServerFlag, unsealCACertFlag},
+				Action: sealStatus,
+			},
+			{
+				Name:   "unseal",
+				Usage:  "Submit an unseal share to a running server",
+				Flags:  []cli.Flag{unsealServerFlag, unsealCACertFlag, shareFlag},
+				Action: unseal,
+			},
+		},
+	}
+)
+
+func initSeal(c *cli.Context) error {
+	key, err := seal.GenerateKey()
+	if err != nil {
+		return err
+	}
+	defer clear(key)
+
+	shares, err := seal.Split(key, c.Int(sharesFlag.Name), c.Int(thresholdFlag.Name))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Master key fingerprint: %s\n\n", seal.Fingerprint(key))
+	for i, share := range shares {
+		fmt.Printf("Unseal share %d: %s\n", i+1, hex.EncodeToString(share))
+	}
+	fmt.Printf(
+		"\nDistribute the shares to different operators, %d of them are needed to unseal.\n"+
+			"Start the server with --master-key-source shamir --unseal-threshold %d "+
+			"--master-key-fingerprint %s\n",
+		c.Int(thresholdFlag.Name),
+		c.Int(thresholdFlag.Name),
+		seal.Fingerprint(key),
+	)
+	return nil
+}
+
+func generateKEK(c *cli.Context) error {
+	key, err := seal.GenerateKey()
+	if err != nil {
+		return err
+	}
+	defer clear(key)
+
+	f, err := os.OpenFile(c.String(kekOutputFlag.Name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, hex.EncodeToString(key)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote master key with fingerprint %s\n", seal.Fingerprint(key))
+	return nil
+}
+
+func sealStatus(c *cli.Context) error {
+	client, err := newUnsealClient(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Get(unsealURL(c, seal.StatusPath))
+	if err != nil {
+		return err
+	}
+	return printSealStatus(resp)
+}
+
+func unseal(c *cli.Context) error {
+	share := c.String(shareFlag.Name)
+	if share == "" {
+		var err error
+		share, err = readShare()
+		if err != nil {
+			return err
+		}
+	}
+
+	client, err := newUnsealClient(c)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(seal.UnsealRequest{Share: share})
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(
+		unsealURL(c, seal.UnsealPath),
+		"application/json",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return err
+	}
+	return printSealStatus(resp)
+}
+
+// readShare prompts for the share without echoing it when stdin is a terminal
+func readShare() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Unseal share: ")
+		share, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return strings.TrimSpace(string(share)), err
+	}
+
+	share, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && share == "" {
+		return "", fmt.Errorf("failed to read unseal share: %w", err)
+	}
+	return strings.TrimSpace(share), nil
+}
+
+func unsealURL(c *cli.Context, path string) string {
+	return strings.TrimSuffix(c.String(unsealServerFlag.Name), "/") + path
+}
+
+func newUnsealClient(c *cli.Context) (*http.Client, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	caCert := c.String(unsealCACertFlag.Name)
+	if caCert == "" {
+		return client, nil
+	}
+	pem, err := os.ReadFile(caCert)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", caCert)
+	}
+	client.Transport = &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+	}
+	return client, nil
+}
+
+func printSealStatus(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+			return fmt.Errorf("unseal server returned %s", resp.Status)
+		}
+		return fmt.Errorf("unseal server returned %s: %s", resp.Status, body.Error)
+	}
+
+	var status seal.Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return err
+	}
+	if !status.Sealed {
+		fmt.Println("Unsealed")
+		return nil
+	}
+	fmt.Printf("Sealed, %d of %d shares submitted\n", status.Progress, status.Threshold)
+	return nil
+}
@@ -0,0 +1,152 @@
+// Package apikey generates the API keys authenticating the signing requests
+// of the keys and checks them.
+//
+// An API key is "cerb_<lookup id>_<secret>", of hex encoded random bytes. The
+// lookup ID finds the stored API key without scanning the API keys of the
+// key, and only an HMAC-SHA256 of the whole API key, keyed by a server-side
+// pepper, is stored, so that a leaked database can't be brute forced without
+// the pepper. The API keys created before this format are UUIDs stored as an
+// unsalted SHA-256, they keep working until they are rotated.
+package apikey
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Layr-Labs/cerberus/internal/common"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+)
+
+const (
+	// DefaultName is the name of the API key created with a key, which
+	// Admin/GenerateNewApiKey replaces
+	DefaultName = "default"
+
+	// Prefix starts the API keys, so that they are recognizable by secret
+	// scanners
+	Prefix = "cerb_"
+
+	// MinPepperLength is the minimum length of the pepper, the size of the
+	// HMAC-SHA256 key
+	MinPepperLength = 32
+
+	lookupIDBytes = 8
+	secretBytes   = 32
+)
+
+// Hasher generates the API keys and checks them with the pepper
+type Hasher struct {
+	pepper []byte
+}
+
+// NewHasher returns an error if the pepper is shorter than MinPepperLength,
+// an empty pepper would store the API keys as an HMAC without a secret
+func NewHasher(pepper string) (*Hasher, error) {
+	if len(pepper) < MinPepperLength {
+		return nil, fmt.Errorf("API key pepper of at least %d bytes is required", MinPepperLength)
+	}
+	return &Hasher{pepper: []byte(pepper)}, nil
+}
+
+// New returns a new API key and the model storing its hash. The public key of
+// the model has to be set before it is stored.
+func (h *Hasher) New(
+	name string,
+	scopes []string,
+	expiresAt *time.Time,
+) (string, *model.APIKey, error) {
+	if err := ValidateScopes(scopes); err != nil {
+		return "", nil, err
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", nil, err
+	}
+	lookupID, err := randomHex(lookupIDBytes)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomHex(secretBytes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := Prefix + lookupID + "_" + secret
+	return key, &model.APIKey{
+		ID:         id.String(),
+		LookupID:   lookupID,
+		Name:       name,
+		Hash:       h.hash(key),
+		HashScheme: model.HashSchemeHMACSHA256,
+		Scopes:     scopes,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// Verify returns true if key is the API key of the model. The hashes are
+// compared in constant time. The legacy SHA-256 hashes are only verified, New
+// never creates them.
+func (h *Hasher) Verify(apiKey *model.APIKey, key string) bool {
+	var hash string
+	switch apiKey.HashScheme {
+	case model.HashSchemeHMACSHA256:
+		hash = h.hash(key)
+	case model.HashSchemeSHA256:
+		hash = common.CreateSHA256Hash(key)
+	default:
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(apiKey.Hash)) == 1
+}
+
+func (h *Hasher) hash(key string) string {
+	mac := hmac.New(sha256.New, h.pepper)
+	mac.Write([]byte(key))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// LookupID returns the lookup ID of the API key, and false for the API keys
+// of the legacy format
+func LookupID(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, Prefix)
+	if !ok {
+		return "", false
+	}
+	lookupID, _, ok := strings.Cut(rest, "_")
+	if !ok || lookupID == "" {
+		return "", false
+	}
+	return lookupID, true
+}
+
+// ValidateScopes returns an error if a scope is unknown
+func ValidateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !slices.Contains(model.Scopes, scope) {
+			return fmt.Errorf(
+				"unknown scope %q, expected one of %s",
+				scope,
+				strings.Join(model.Scopes, ", "),
+			)
+		}
+	}
+	return nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
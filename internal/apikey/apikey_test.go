@@ -0,0 +1,80 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Layr-Labs/cerberus/internal/common"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+)
+
+const testPepper = "0123456789abcdef0123456789abcdef"
+
+func TestNewHasher(t *testing.T) {
+	_, err := NewHasher("")
+	assert.Error(t, err)
+	_, err = NewHasher(testPepper[:MinPepperLength-1])
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	hasher, err := NewHasher(testPepper)
+	require.NoError(t, err)
+	key, apiKey, err := hasher.New("validator", []string{model.ScopeSignG1}, nil)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(key, Prefix))
+	lookupID, ok := LookupID(key)
+	require.True(t, ok)
+	assert.Equal(t, apiKey.LookupID, lookupID)
+	assert.Len(t, key, len(Prefix)+2*lookupIDBytes+1+2*secretBytes)
+	assert.Equal(t, model.HashSchemeHMACSHA256, apiKey.HashScheme)
+
+	other, otherAPIKey, err := hasher.New("validator", nil, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+	assert.NotEqual(t, apiKey.LookupID, otherAPIKey.LookupID)
+
+	_, _, err = hasher.New("validator", []string{"sign-everything"}, nil)
+	assert.Error(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	hasher, err := NewHasher(testPepper)
+	require.NoError(t, err)
+	key, apiKey, err := hasher.New("validator", nil, nil)
+	require.NoError(t, err)
+
+	assert.True(t, hasher.Verify(apiKey, key))
+	assert.False(t, hasher.Verify(apiKey, key+"0"))
+	otherHasher, err := NewHasher(strings.Repeat("x", MinPepperLength))
+	require.NoError(t, err)
+	assert.False(t, otherHasher.Verify(apiKey, key))
+
+	legacyKey := "01959f4e-8f5c-7a2b-9d3e-1f2a3b4c5d6e"
+	legacy := &model.APIKey{
+		Hash:       common.CreateSHA256Hash(legacyKey),
+		HashScheme: model.HashSchemeSHA256,
+	}
+	assert.True(t, hasher.Verify(legacy, legacyKey))
+	assert.False(t, hasher.Verify(legacy, key))
+
+	unknown := &model.APIKey{Hash: apiKey.Hash, HashScheme: "md5"}
+	assert.False(t, hasher.Verify(unknown, key))
+}
+
+func TestLookupID(t *testing.T) {
+	for key, want := range map[string]string{
+		"cerb_0011223344556677_secret":         "0011223344556677",
+		"cerb__secret":                         "",
+		"cerb_0011223344556677":                "",
+		"01959f4e-8f5c-7a2b-9d3e-1f2a3b4c5d6e": "",
+	} {
+		lookupID, ok := LookupID(key)
+		assert.Equal(t, want != "", ok, key)
+		assert.Equal(t, want, lookupID, key)
+	}
+}
@@ -3,8 +3,6 @@ package common
 import (
 	"crypto/sha256"
 	"encoding/hex"
-
-	"github.com/google/uuid"
 )
 
 func Trim0x(s string) string {
@@ -26,13 +24,3 @@ func CreateSHA256Hash(s string) string {
 	hash.Write([]byte(s))
 	return hex.EncodeToString(hash.Sum(nil))
 }
-
-func GenerateNewAPIKeyAndHash() (string, string, error) {
-	newUUID, err := uuid.NewV7()
-	if err != nil {
-		return "", "", err
-	}
-	apiKey := newUUID.String()
-	apiKeyHash := CreateSHA256Hash(apiKey)
-	return apiKey, apiKeyHash, nil
-}
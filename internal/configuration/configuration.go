@@ -1,23 +1,73 @@
 package configuration
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/Layr-Labs/cerberus/pkg/storeplugin"
+)
 
 type StorageType string
 
 type AWSAuthenticationMode string
 
+type VaultAuthMethod string
+
+type MasterKeySource string
+
+type CloudSecretFormat string
+
+type DatabaseType string
+
 const (
 	FileSystemStorageType          StorageType = "filesystem"
 	AWSSecretManagerStorageType    StorageType = "aws-secrets-manager"
 	GoogleSecretManagerStorageType StorageType = "google-secrets-manager"
+	VaultStorageType               StorageType = "vault"
+	PKCS11StorageType              StorageType = "pkcs11"
+	MemoryStorageType              StorageType = "memory"
+	ReplicatedStorageType          StorageType = "replicated"
+	KubernetesStorageType          StorageType = "kubernetes"
+	PostgresStorageType            StorageType = "postgres"
+	PluginStorageType              StorageType = "plugin"
 
 	EnvironmentAWSAuthenticationMode AWSAuthenticationMode = "environment"
 	SpecifiedAWSAuthenticationMode   AWSAuthenticationMode = "specified"
+
+	TokenVaultAuthMethod      VaultAuthMethod = "token"
+	AppRoleVaultAuthMethod    VaultAuthMethod = "approle"
+	KubernetesVaultAuthMethod VaultAuthMethod = "kubernetes"
+
+	NoneMasterKeySource    MasterKeySource = "none"
+	KEKFileMasterKeySource MasterKeySource = "kek-file"
+	ShamirMasterKeySource  MasterKeySource = "shamir"
+
+	RawCloudSecretFormat      CloudSecretFormat = "raw"
+	KeystoreCloudSecretFormat CloudSecretFormat = "keystore"
+
+	PostgresDatabaseType DatabaseType = "postgres"
+	MemoryDatabaseType   DatabaseType = "memory"
 )
 
+// NamedStore is a store the keys can be routed to by name
+type NamedStore struct {
+	Name        string
+	StorageType StorageType
+}
+
 type Configuration struct {
 	StorageType StorageType
 
+	// Named stores replacing the storage type. The keys are created in the
+	// default store, or the first one, unless another store is requested.
+	// Each store is configured by the parameters of its storage type.
+	Stores       []NamedStore
+	DefaultStore string
+
 	// FileSystem storage parameters
 	KeystoreDir string
 
@@ -28,9 +78,94 @@ type Configuration struct {
 	AWSAccessKeyID        string
 	AWSSecretAccessKey    string
 
+	// AWS secrets naming and settings. The prefix defaults to "cerberus/",
+	// the secrets are replicated to the replica regions.
+	AWSSecretPrefix   string
+	AWSKMSKeyID       string
+	AWSTags           map[string]string
+	AWSReplicaRegions []string
+
 	// Google Secrets Manager storage parameters
 	GCPProjectID string
 
+	// GCP secrets naming and settings. The prefix defaults to "cerberus" and
+	// is also the value of the project label. The secrets are automatically
+	// replicated unless replica locations are set, and encrypted with a KMS
+	// key per replica location, or a single key with automatic replication.
+	GCPSecretPrefix     string
+	GCPKMSKeyNames      []string
+	GCPReplicaLocations []string
+	GCPLabels           map[string]string
+
+	// HashiCorp Vault storage parameters
+	VaultAddress             string
+	VaultNamespace           string
+	VaultAuthMethod          VaultAuthMethod
+	VaultAuthMount           string
+	VaultToken               string
+	VaultAppRoleID           string
+	VaultAppRoleSecretID     string
+	VaultKubernetesRole      string
+	VaultKubernetesTokenPath string
+	VaultKVMount             string
+	VaultPathPrefix          string
+	VaultTransitMount        string
+	VaultTransitKey          string
+
+	// PKCS#11 storage parameters
+	PKCS11ModulePath  string
+	PKCS11TokenLabel  string
+	PKCS11Slot        uint
+	PKCS11PINSource   string
+	PKCS11LabelPrefix string
+
+	// Memory storage parameters
+	MemorySnapshotFile string
+
+	// Kubernetes Secrets storage parameters. The namespace defaults to the
+	// one of the kubeconfig context or of the pod, the kubeconfig to the
+	// in-cluster service account when none is found.
+	KubernetesNamespace    string
+	KubernetesKubeconfig   string
+	KubernetesContext      string
+	KubernetesSecretPrefix string
+	KubernetesLabels       map[string]string
+
+	// Store plugin parameters. The plugin is either running on the address or
+	// launched with the command, whose first element is the executable.
+	PluginAddress      string
+	PluginCommand      []string
+	PluginStartTimeout time.Duration
+
+	// Replicated storage parameters. The replicas are read in order, each one
+	// is configured by the parameters of its storage type. A zero write
+	// quorum means a majority of the replicas.
+	ReplicaStorageTypes []StorageType
+	WriteQuorum         int
+
+	// Resilience of the calls to the stores. Each call has the timeout of its
+	// storage type, or the default timeout, zero meaning none. The reads are
+	// retried up to the retry attempts with jittered exponential delays. The
+	// circuit breaker of a store opens after the threshold of consecutive
+	// failures, zero disabling it.
+	StoreTimeout            time.Duration
+	StoreTimeouts           map[StorageType]time.Duration
+	StoreRetryAttempts      int
+	StoreRetryBaseDelay     time.Duration
+	StoreRetryMaxDelay      time.Duration
+	StoreBreakerThreshold   int
+	StoreBreakerOpenTimeout time.Duration
+
+	// Format of the keys written to the cloud and Kubernetes stores
+	CloudSecretFormat CloudSecretFormat
+
+	// Master key envelope encrypting the keys of the cloud stores
+	MasterKeySource      MasterKeySource
+	MasterKeyFile        string
+	MasterKeyFingerprint string
+	UnsealThreshold      int
+	UnsealAddress        string
+
 	GrpcPort    int
 	MetricsPort int
 	AdminPort   int
@@ -40,39 +175,73 @@ type Configuration struct {
 	TLSCACert    string
 	TLSServerKey string
 
+	// Pepper keying the HMAC of the stored API keys, required by the servers
+	// and the commands creating API keys. Changing it invalidates every API
+	// key created with the previous one.
+	APIKeyPepper string
+
+	// Database holding the key metadata and usage
+	DatabaseType DatabaseType
+
 	// Postgres database parameters
 	PostgresDatabaseURL string
+
+	// Interval between writes of the recorded key usage to the database
+	UsageFlushInterval time.Duration
+
+	// Export the usage metrics per key rather than only per signing method
+	PerKeyUsageMetrics bool
+
+	// Lifetime of newly created keys. Zero means the keys never expire.
+	KeyValidityPeriod time.Duration
+
+	// Interval between checks for expired keys and how long before expiry to warn
+	ExpiryCheckInterval time.Duration
+	ExpiryWarningPeriod time.Duration
+
+	// Store and key metadata reconciliation parameters
+	ReconcileOnStartup bool
+	ReconcileRepair    bool
+
+	// Keystore directory watch parameters. The new key files are registered
+	// in a locked state, decrypted with the watch keystore password.
+	WatchKeystoreDir      bool
+	WatchRegisterKeys     bool
+	WatchKeystorePassword string
 }
 
 func (s *Configuration) Validate() error {
-	if s.StorageType == "" {
-		return fmt.Errorf("storage type is required")
-	}
-
-	switch s.StorageType {
-	case FileSystemStorageType:
-		if s.KeystoreDir == "" {
-			return fmt.Errorf("keystore directory is required")
+	if len(s.Stores) > 0 {
+		if err := s.validateStores(); err != nil {
+			return err
 		}
-	case AWSSecretManagerStorageType:
-		if s.AWSRegion == "" {
-			return fmt.Errorf("AWS region is required")
+	} else {
+		if s.StorageType == "" {
+			return fmt.Errorf("storage type is required")
 		}
-
-		if s.AWSAuthenticationMode == SpecifiedAWSAuthenticationMode {
-			if s.AWSAccessKeyID == "" {
-				return fmt.Errorf("AWS access key ID is required")
-			}
-			if s.AWSSecretAccessKey == "" {
-				return fmt.Errorf("AWS secret access key is required")
-			}
+		if s.DefaultStore != "" {
+			return fmt.Errorf("default store requires named stores")
 		}
-	case GoogleSecretManagerStorageType:
-		if s.GCPProjectID == "" {
-			return fmt.Errorf("GCP project ID is required")
+		if err := s.validateStore(s.StorageType); err != nil {
+			return err
+		}
+	}
+
+	switch s.CloudSecretFormat {
+	case "", RawCloudSecretFormat:
+	case KeystoreCloudSecretFormat:
+		if !s.isCloudStorageType() && !s.hasStorageType(KubernetesStorageType) {
+			return fmt.Errorf(
+				"keystore secret format is only supported by the cloud secret manager " +
+					"and Kubernetes stores",
+			)
 		}
 	default:
-		return fmt.Errorf("unsupported storage type: %s", s.StorageType)
+		return fmt.Errorf("unsupported cloud secret format: %s", s.CloudSecretFormat)
+	}
+
+	if err := s.validateMasterKey(); err != nil {
+		return err
 	}
 
 	if s.GrpcPort == 0 {
@@ -91,9 +260,503 @@ func (s *Configuration) Validate() error {
 		return fmt.Errorf("TLS CA certificate is required when TLS server key is provided")
 	}
 
-	if s.PostgresDatabaseURL == "" {
-		return fmt.Errorf("postgres database URL is required")
+	switch s.DatabaseType {
+	case "", PostgresDatabaseType:
+		if s.PostgresDatabaseURL == "" {
+			return fmt.Errorf("postgres database URL is required")
+		}
+	case MemoryDatabaseType:
+	default:
+		return fmt.Errorf("unsupported database type: %s", s.DatabaseType)
+	}
+
+	if s.UsageFlushInterval <= 0 {
+		return fmt.Errorf("usage flush interval must be positive")
+	}
+
+	if s.KeyValidityPeriod < 0 {
+		return fmt.Errorf("key validity period must not be negative")
+	}
+
+	if s.ExpiryCheckInterval <= 0 {
+		return fmt.Errorf("expiry check interval must be positive")
+	}
+
+	if s.ExpiryWarningPeriod < 0 {
+		return fmt.Errorf("expiry warning period must not be negative")
+	}
+
+	if err := s.validateWatch(); err != nil {
+		return err
+	}
+
+	if err := s.validateStoreResilience(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Configuration) validateStoreResilience() error {
+	if s.StoreTimeout < 0 {
+		return fmt.Errorf("store timeout must not be negative")
+	}
+	for storageType, timeout := range s.StoreTimeouts {
+		if storageType == ReplicatedStorageType {
+			return fmt.Errorf("replicated store has no timeout, set the timeouts of its replicas")
+		}
+		if timeout < 0 {
+			return fmt.Errorf("store timeout of %s must not be negative", storageType)
+		}
+	}
+
+	if s.StoreRetryAttempts < 0 {
+		return fmt.Errorf("store retry attempts must not be negative")
+	}
+	if s.StoreRetryBaseDelay < 0 {
+		return fmt.Errorf("store retry base delay must not be negative")
+	}
+	if s.StoreRetryAttempts > 1 && s.StoreRetryBaseDelay > s.StoreRetryMaxDelay {
+		return fmt.Errorf("store retry base delay must not exceed the max delay")
+	}
+
+	if s.StoreBreakerThreshold < 0 {
+		return fmt.Errorf("store breaker threshold must not be negative")
+	}
+	if s.StoreBreakerThreshold > 0 && s.StoreBreakerOpenTimeout <= 0 {
+		return fmt.Errorf("store breaker open timeout must be positive")
+	}
+
+	return nil
+}
+
+// StoreTimeoutOf returns the timeout of the calls to the store of the storage
+// type
+func (s *Configuration) StoreTimeoutOf(storageType StorageType) time.Duration {
+	if timeout, ok := s.StoreTimeouts[storageType]; ok {
+		return timeout
+	}
+	return s.StoreTimeout
+}
+
+func (s *Configuration) validateWatch() error {
+	if !s.WatchKeystoreDir {
+		if s.WatchRegisterKeys {
+			return fmt.Errorf("registering watched keys requires the keystore directory watch")
+		}
+		return nil
+	}
+
+	if !s.hasFileSystemStore() {
+		return fmt.Errorf("keystore directory watch requires the filesystem store")
+	}
+	if s.WatchRegisterKeys && s.WatchKeystorePassword == "" {
+		return fmt.Errorf("watch keystore password is required to register watched keys")
+	}
+
+	return nil
+}
+
+// hasFileSystemStore returns true if the keys are kept in a keystore
+// directory, directly or as one of the named stores
+func (s *Configuration) hasFileSystemStore() bool {
+	_, ok := s.FileSystemStoreName()
+	return ok
+}
+
+// FileSystemStoreName returns the name of the filesystem named store, or an
+// empty name if the storage type is filesystem
+func (s *Configuration) FileSystemStoreName() (string, bool) {
+	if len(s.Stores) == 0 {
+		return "", s.StorageType == FileSystemStorageType
+	}
+	for _, named := range s.Stores {
+		if named.StorageType == FileSystemStorageType {
+			return named.Name, true
+		}
+	}
+	return "", false
+}
+
+// validateStorageType checks the parameters of a storage type other than
+// replicated
+func (s *Configuration) validateStorageType(storageType StorageType) error {
+	switch storageType {
+	case FileSystemStorageType:
+		if s.KeystoreDir == "" {
+			return fmt.Errorf("keystore directory is required")
+		}
+	case AWSSecretManagerStorageType:
+		if err := s.validateAWS(); err != nil {
+			return err
+		}
+	case GoogleSecretManagerStorageType:
+		if err := s.validateGCP(); err != nil {
+			return err
+		}
+	case VaultStorageType:
+		if err := s.validateVault(); err != nil {
+			return err
+		}
+	case PKCS11StorageType:
+		if s.PKCS11ModulePath == "" {
+			return fmt.Errorf("PKCS#11 module path is required")
+		}
+		if s.PKCS11PINSource == "" {
+			return fmt.Errorf("PKCS#11 PIN source is required")
+		}
+	case MemoryStorageType:
+	case KubernetesStorageType:
+		if err := s.validateKubernetes(); err != nil {
+			return err
+		}
+	case PostgresStorageType:
+		// The keys are kept in the database of the key metadata
+		if s.DatabaseType == MemoryDatabaseType {
+			return fmt.Errorf("postgres store requires the postgres database")
+		}
+	case PluginStorageType:
+		if err := s.validatePlugin(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported storage type: %s", storageType)
+	}
+
+	return nil
+}
+
+// validateStore checks the parameters of a storage type
+func (s *Configuration) validateStore(storageType StorageType) error {
+	if storageType == ReplicatedStorageType {
+		return s.validateReplicas()
+	}
+	return s.validateStorageType(storageType)
+}
+
+func (s *Configuration) validateStores() error {
+	names := make(map[string]struct{}, len(s.Stores))
+	storageTypes := make(map[StorageType]struct{}, len(s.Stores))
+	for _, named := range s.Stores {
+		if named.Name == "" {
+			return fmt.Errorf("store name is required")
+		}
+		if _, ok := names[named.Name]; ok {
+			return fmt.Errorf("duplicate store name: %s", named.Name)
+		}
+		names[named.Name] = struct{}{}
+
+		// The stores of a storage type share its parameters, two of them
+		// would hold the same keys
+		if _, ok := storageTypes[named.StorageType]; ok {
+			return fmt.Errorf("duplicate store storage type: %s", named.StorageType)
+		}
+		storageTypes[named.StorageType] = struct{}{}
+
+		if err := s.validateStore(named.StorageType); err != nil {
+			return fmt.Errorf("invalid store %s: %w", named.Name, err)
+		}
+	}
+
+	if s.DefaultStore != "" {
+		if _, ok := names[s.DefaultStore]; !ok {
+			return fmt.Errorf("default store %s is not a named store", s.DefaultStore)
+		}
+	}
+	return nil
+}
+
+func (s *Configuration) validateReplicas() error {
+	if len(s.ReplicaStorageTypes) < 2 {
+		return fmt.Errorf("at least two replica storage types are required")
+	}
+
+	seen := make(map[StorageType]struct{}, len(s.ReplicaStorageTypes))
+	for _, storageType := range s.ReplicaStorageTypes {
+		if storageType == ReplicatedStorageType {
+			return fmt.Errorf("replicated storage type can't be a replica")
+		}
+		if _, ok := seen[storageType]; ok {
+			return fmt.Errorf("duplicate replica storage type: %s", storageType)
+		}
+		seen[storageType] = struct{}{}
+
+		if err := s.validateStorageType(storageType); err != nil {
+			return fmt.Errorf("invalid %s replica: %w", storageType, err)
+		}
+	}
+
+	if s.WriteQuorum < 0 {
+		return fmt.Errorf("write quorum must not be negative")
+	}
+	if s.WriteQuorum > len(s.ReplicaStorageTypes) {
+		return fmt.Errorf(
+			"write quorum must not exceed the number of replicas (%d)",
+			len(s.ReplicaStorageTypes),
+		)
+	}
+
+	return nil
+}
+
+var (
+	// awsSecretPrefixPattern matches the characters allowed in AWS secret names
+	awsSecretPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9/_+=.@-]*$`)
+
+	// gcpLabelKeyPattern and gcpLabelValuePattern match the GCP label keys
+	// and values, the secret prefix is also a label value
+	gcpLabelKeyPattern   = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+	gcpLabelValuePattern = regexp.MustCompile(`^[a-z0-9_-]{0,63}$`)
+)
+
+func (s *Configuration) validateAWS() error {
+	if s.AWSRegion == "" {
+		return fmt.Errorf("AWS region is required")
+	}
+
+	if s.AWSAuthenticationMode == SpecifiedAWSAuthenticationMode {
+		if s.AWSAccessKeyID == "" {
+			return fmt.Errorf("AWS access key ID is required")
+		}
+		if s.AWSSecretAccessKey == "" {
+			return fmt.Errorf("AWS secret access key is required")
+		}
+	}
+
+	if !awsSecretPrefixPattern.MatchString(s.AWSSecretPrefix) {
+		return fmt.Errorf("invalid AWS secret prefix: %s", s.AWSSecretPrefix)
+	}
+
+	for key := range s.AWSTags {
+		if key == "" {
+			return fmt.Errorf("AWS tag key is required")
+		}
+		if strings.HasPrefix(strings.ToLower(key), "aws:") {
+			return fmt.Errorf("AWS tag key %s must not use the reserved aws: prefix", key)
+		}
+	}
+
+	regions := make(map[string]struct{}, len(s.AWSReplicaRegions))
+	for _, region := range s.AWSReplicaRegions {
+		if region == "" {
+			return fmt.Errorf("AWS replica region is required")
+		}
+		if region == s.AWSRegion {
+			return fmt.Errorf("AWS replica region %s is the primary region", region)
+		}
+		if _, ok := regions[region]; ok {
+			return fmt.Errorf("duplicate AWS replica region: %s", region)
+		}
+		regions[region] = struct{}{}
+	}
+
+	return nil
+}
+
+func (s *Configuration) validateGCP() error {
+	if s.GCPProjectID == "" {
+		return fmt.Errorf("GCP project ID is required")
+	}
+
+	if s.GCPSecretPrefix != "" && !gcpLabelKeyPattern.MatchString(s.GCPSecretPrefix) {
+		return fmt.Errorf(
+			"invalid GCP secret prefix %s: it must start with a lowercase letter "+
+				"and only contain lowercase letters, digits, _ and -",
+			s.GCPSecretPrefix,
+		)
+	}
+
+	for key, value := range s.GCPLabels {
+		if !gcpLabelKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid GCP label key: %s", key)
+		}
+		if key == "project" {
+			return fmt.Errorf("GCP label key project is reserved")
+		}
+		if !gcpLabelValuePattern.MatchString(value) {
+			return fmt.Errorf("invalid GCP label value for %s: %s", key, value)
+		}
+	}
+
+	if len(s.GCPReplicaLocations) == 0 {
+		if len(s.GCPKMSKeyNames) > 1 {
+			return fmt.Errorf("a single GCP KMS key is allowed without replica locations")
+		}
+		return nil
+	}
+
+	locations := make(map[string]struct{}, len(s.GCPReplicaLocations))
+	for _, location := range s.GCPReplicaLocations {
+		if location == "" {
+			return fmt.Errorf("GCP replica location is required")
+		}
+		if _, ok := locations[location]; ok {
+			return fmt.Errorf("duplicate GCP replica location: %s", location)
+		}
+		locations[location] = struct{}{}
+
+		if len(s.GCPKMSKeyNames) == 0 {
+			continue
+		}
+		matches := 0
+		for _, keyName := range s.GCPKMSKeyNames {
+			if strings.Contains(keyName, "/locations/"+location+"/") {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fmt.Errorf("exactly one GCP KMS key is required in location %s", location)
+		}
+	}
+	if len(s.GCPKMSKeyNames) > len(s.GCPReplicaLocations) {
+		return fmt.Errorf("every GCP KMS key must be in a replica location")
+	}
+
+	return nil
+}
+
+// kubernetesSecretPrefixPattern matches the prefixes giving valid secret
+// names (DNS subdomains) once the public key is appended
+var kubernetesSecretPrefixPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]{0,188})?$`)
+
+func (s *Configuration) validateKubernetes() error {
+	if s.KubernetesSecretPrefix != "" &&
+		!kubernetesSecretPrefixPattern.MatchString(s.KubernetesSecretPrefix) {
+		return fmt.Errorf(
+			"invalid Kubernetes secret prefix %s: it must start with a lowercase letter "+
+				"or digit and only contain lowercase letters, digits, . and -",
+			s.KubernetesSecretPrefix,
+		)
+	}
+
+	for key, value := range s.KubernetesLabels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid Kubernetes label key %s: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf(
+				"invalid Kubernetes label value for %s: %s",
+				key,
+				strings.Join(errs, ", "),
+			)
+		}
 	}
 
 	return nil
 }
+
+func (s *Configuration) validatePlugin() error {
+	if s.PluginAddress == "" && len(s.PluginCommand) == 0 {
+		return fmt.Errorf("plugin address or command is required")
+	}
+	if s.PluginAddress != "" && len(s.PluginCommand) > 0 {
+		return fmt.Errorf("plugin address and command are mutually exclusive")
+	}
+	if s.PluginAddress != "" {
+		if _, _, err := storeplugin.ParseAddress(s.PluginAddress); err != nil {
+			return err
+		}
+	}
+	if s.PluginStartTimeout < 0 {
+		return fmt.Errorf("plugin start timeout must not be negative")
+	}
+	return nil
+}
+
+func (s *Configuration) validateVault() error {
+	if s.VaultAddress == "" {
+		return fmt.Errorf("vault address is required")
+	}
+
+	switch s.VaultAuthMethod {
+	case TokenVaultAuthMethod:
+		if s.VaultToken == "" {
+			return fmt.Errorf("vault token is required")
+		}
+	case AppRoleVaultAuthMethod:
+		if s.VaultAppRoleID == "" {
+			return fmt.Errorf("vault AppRole role ID is required")
+		}
+		if s.VaultAppRoleSecretID == "" {
+			return fmt.Errorf("vault AppRole secret ID is required")
+		}
+	case KubernetesVaultAuthMethod:
+		if s.VaultKubernetesRole == "" {
+			return fmt.Errorf("vault Kubernetes role is required")
+		}
+	default:
+		return fmt.Errorf("unsupported vault auth method: %s", s.VaultAuthMethod)
+	}
+
+	if s.VaultKVMount == "" {
+		return fmt.Errorf("vault KV mount is required")
+	}
+
+	if s.VaultTransitKey != "" && s.VaultTransitMount == "" {
+		return fmt.Errorf("vault transit mount is required when a transit key is set")
+	}
+
+	return nil
+}
+
+func (s *Configuration) validateMasterKey() error {
+	switch s.MasterKeySource {
+	case "", NoneMasterKeySource:
+		return nil
+	case KEKFileMasterKeySource:
+		if s.MasterKeyFile == "" {
+			return fmt.Errorf("master key file is required")
+		}
+	case ShamirMasterKeySource:
+		if s.UnsealThreshold < 2 {
+			return fmt.Errorf("unseal threshold must be at least 2")
+		}
+		if s.MasterKeyFingerprint == "" {
+			return fmt.Errorf("master key fingerprint is required")
+		}
+		if s.UnsealAddress == "" {
+			return fmt.Errorf("unseal address is required")
+		}
+	default:
+		return fmt.Errorf("unsupported master key source: %s", s.MasterKeySource)
+	}
+
+	if !s.isCloudStorageType() && !s.hasStorageType(PostgresStorageType) {
+		return fmt.Errorf(
+			"master key is only supported by the cloud secret manager and postgres stores",
+		)
+	}
+
+	return nil
+}
+
+// isCloudStorageType returns true if the keys are kept in a cloud secret
+// manager, directly, as one of the named stores or as one of the replicas
+func (s *Configuration) isCloudStorageType() bool {
+	return s.hasStorageType(AWSSecretManagerStorageType, GoogleSecretManagerStorageType)
+}
+
+// hasStorageType returns true if the keys are kept in one of the storage
+// types, directly, as one of the named stores or as one of the replicas
+func (s *Configuration) hasStorageType(wanted ...StorageType) bool {
+	storageTypes := []StorageType{s.StorageType}
+	if len(s.Stores) > 0 {
+		storageTypes = storageTypes[:0]
+		for _, named := range s.Stores {
+			storageTypes = append(storageTypes, named.StorageType)
+		}
+	}
+	for _, storageType := range storageTypes {
+		if storageType == ReplicatedStorageType {
+			storageTypes = append(storageTypes, s.ReplicaStorageTypes...)
+		}
+	}
+	for _, storageType := range storageTypes {
+		for _, w := range wanted {
+			if storageType == w {
+				return true
+			}
+		}
+	}
+	return false
+}
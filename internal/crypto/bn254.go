@@ -1,7 +1,6 @@
 package crypto
 
 import (
-	"encoding/hex"
 	"math/big"
 
 	"github.com/consensys/gnark-crypto/ecc/bn254"
@@ -93,7 +92,7 @@ func NewKeyPair(sk *PrivateKey) *KeyPair {
 
 // NewKeyPairFromString creates a new keypair from a decimal string
 func NewKeyPairFromString(sk string) (*KeyPair, error) {
-	ele, err := new(fr.Element).SetString(sk)
+	ele, err := PrivateKeyFromDecimalString(sk)
 	if err != nil {
 		return nil, err
 	}
@@ -102,12 +101,7 @@ func NewKeyPairFromString(sk string) (*KeyPair, error) {
 
 // NewKeyPairFromHexString creates a new keypair from a hex string
 func NewKeyPairFromHexString(sk string) (*KeyPair, error) {
-	skBytes, err := hex.DecodeString(sk)
-	if err != nil {
-		return nil, err
-	}
-	skInt := new(big.Int).SetBytes(skBytes)
-	ele, err := new(fr.Element).SetString(skInt.String())
+	ele, err := PrivateKeyFromHexString(sk)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,121 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// ErrInvalidPrivateKey is returned when a private key isn't a canonical
+// non-zero BN254 scalar
+var ErrInvalidPrivateKey = errors.New("invalid private key")
+
+// PrivateKeyFromBytes parses a big-endian BN254 scalar. Unlike
+// fr.Element.SetBytes it doesn't reduce the value modulo the field order, so
+// two different inputs can never give the same key.
+func PrivateKeyFromBytes(b []byte) (*PrivateKey, error) {
+	if len(b) == 0 {
+		return nil, fmt.Errorf("%w: empty value", ErrInvalidPrivateKey)
+	}
+	if len(b) > fr.Bytes {
+		return nil, fmt.Errorf(
+			"%w: %d bytes, must be at most %d bytes",
+			ErrInvalidPrivateKey,
+			len(b),
+			fr.Bytes,
+		)
+	}
+	return privateKeyFromBigInt(new(big.Int).SetBytes(b))
+}
+
+// PrivateKeyFromHexString parses a big-endian BN254 scalar from a hex string
+// with an optional 0x or 0X prefix
+func PrivateKeyFromHexString(sk string) (*PrivateKey, error) {
+	sk, _ = trimHexPrefix(sk)
+	skBytes, err := hex.DecodeString(sk)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a hex string: %v", ErrInvalidPrivateKey, err)
+	}
+	return PrivateKeyFromBytes(skBytes)
+}
+
+// PrivateKeyFromDecimalString parses a BN254 scalar from a decimal string
+func PrivateKeyFromDecimalString(sk string) (*PrivateKey, error) {
+	if strings.HasPrefix(sk, "-") {
+		return nil, fmt.Errorf("%w: negative value", ErrInvalidPrivateKey)
+	}
+	if !isDecimalString(sk) {
+		return nil, fmt.Errorf("%w: not a decimal string", ErrInvalidPrivateKey)
+	}
+	skInt, ok := new(big.Int).SetString(sk, 10)
+	if !ok {
+		return nil, fmt.Errorf("%w: not a decimal string", ErrInvalidPrivateKey)
+	}
+	return privateKeyFromBigInt(skInt)
+}
+
+// PrivateKeyFromString parses a BN254 scalar from a hex string or a decimal
+// string. Strings with a 0x prefix are hex, and strings of only digits are
+// decimal, except those of 64 digits: they are as likely to be the 32 byte hex
+// encoding of a key, so they are refused rather than guessed.
+func PrivateKeyFromString(sk string) (*PrivateKey, error) {
+	if _, prefixed := trimHexPrefix(sk); prefixed || !isDecimalString(sk) {
+		return PrivateKeyFromHexString(sk)
+	}
+	if len(strings.TrimLeft(sk, "+")) == 2*fr.Bytes {
+		return nil, fmt.Errorf(
+			"%w: %d digits could be hex or decimal, prefix hex with 0x",
+			ErrInvalidPrivateKey,
+			2*fr.Bytes,
+		)
+	}
+	return PrivateKeyFromDecimalString(sk)
+}
+
+// trimHexPrefix removes a 0x or 0X prefix, and reports whether there was one
+func trimHexPrefix(s string) (string, bool) {
+	if len(s) >= 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		return s[2:], true
+	}
+	return s, false
+}
+
+// isDecimalString reports whether the string is a decimal number with an
+// optional sign
+func isDecimalString(s string) bool {
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		s = s[1:]
+	}
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// PrivateKeyBytes returns the canonical 32 byte big-endian encoding of the key
+func PrivateKeyBytes(sk *PrivateKey) []byte {
+	b := sk.Bytes()
+	return b[:]
+}
+
+func privateKeyFromBigInt(skInt *big.Int) (*PrivateKey, error) {
+	if skInt.Sign() == 0 {
+		return nil, fmt.Errorf("%w: zero value", ErrInvalidPrivateKey)
+	}
+	if skInt.Cmp(fr.Modulus()) >= 0 {
+		return nil, fmt.Errorf(
+			"%w: value is not below the BN254 scalar field order",
+			ErrInvalidPrivateKey,
+		)
+	}
+	return new(fr.Element).SetBigInt(skInt), nil
+}
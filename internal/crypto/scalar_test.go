@@ -0,0 +1,125 @@
+package crypto
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// BN254 scalar field order
+	frModulusHex = "30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f0000001"
+	frModulusDec = "21888242871839275222246405745257275088548364400416034343698204186575808495617"
+)
+
+func TestPrivateKeyFromString(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string // decimal value of the key
+		wantErr bool
+	}{
+		{name: "decimal", input: "12345", want: "12345"},
+		{name: "decimal with plus sign", input: "+12345", want: "12345"},
+		{
+			name:  "hex",
+			input: "040ad69253b921aca71dd714cccc3095576fbe1a21f86c9b10cb5b119b1c6899",
+			want:  "1828400783668354888414048188282769214701810628628956872636287381381672888473",
+		},
+		{name: "hex with 0x prefix", input: "0x0f", want: "15"},
+		{name: "hex with 0X prefix", input: "0X0F", want: "15"},
+		{name: "uppercase hex", input: "ABCDEF", want: "11259375"},
+		{name: "hex of only digits", input: "0x12", want: "18"},
+		{name: "64 digits hex", input: "0x" + zeros(62) + "12", want: "18"},
+		{name: "short hex", input: "abcdef", want: "11259375"},
+		{
+			name:  "largest decimal",
+			input: "21888242871839275222246405745257275088548364400416034343698204186575808495616",
+			want:  "21888242871839275222246405745257275088548364400416034343698204186575808495616",
+		},
+		{
+			name:  "largest hex",
+			input: "30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f0000000",
+			want:  "21888242871839275222246405745257275088548364400416034343698204186575808495616",
+		},
+		{name: "empty", input: "", wantErr: true},
+		{name: "0x only", input: "0x", wantErr: true},
+		{name: "decimal zero", input: "0", wantErr: true},
+		{name: "hex zero", input: "0x00", wantErr: true},
+		{name: "32 zero bytes", input: "0x" + zeros(64), wantErr: true},
+		{name: "negative decimal", input: "-1", wantErr: true},
+		{name: "negative modulus", input: "-" + frModulusDec, wantErr: true},
+		{name: "decimal modulus", input: frModulusDec, wantErr: true},
+		{name: "hex modulus", input: frModulusHex, wantErr: true},
+		{
+			name:    "decimal modulus plus one",
+			input:   "21888242871839275222246405745257275088548364400416034343698204186575808495618",
+			wantErr: true,
+		},
+		{name: "33 bytes", input: "01" + frModulusHex, wantErr: true},
+		{name: "33 bytes with leading zero", input: "0x00" + "01" + zeros(62), wantErr: true},
+		{name: "odd length hex", input: "abc", wantErr: true},
+		{name: "not hex", input: "xyz", wantErr: true},
+		{name: "64 digits", input: zeros(62) + "12", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sk, err := PrivateKeyFromString(tt.input)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidPrivateKey)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, sk.BigInt(new(big.Int)).String())
+		})
+	}
+}
+
+func TestPrivateKeyFromBytes(t *testing.T) {
+	sk, err := PrivateKeyFromBytes([]byte{0x01})
+	require.NoError(t, err)
+	assert.Equal(t, "1", sk.String())
+
+	// Leading zeros are fine as long as the value fits in 32 bytes
+	padded := make([]byte, 32)
+	padded[31] = 0x01
+	sk, err = PrivateKeyFromBytes(padded)
+	require.NoError(t, err)
+	assert.Equal(t, "1", sk.String())
+	assert.Equal(t, padded, PrivateKeyBytes(sk))
+
+	_, err = PrivateKeyFromBytes(nil)
+	assert.ErrorIs(t, err, ErrInvalidPrivateKey)
+
+	_, err = PrivateKeyFromBytes(make([]byte, 32))
+	assert.ErrorIs(t, err, ErrInvalidPrivateKey)
+
+	_, err = PrivateKeyFromBytes(make([]byte, 33))
+	assert.ErrorIs(t, err, ErrInvalidPrivateKey)
+}
+
+func TestNewKeyPairFromHexStringIsStrict(t *testing.T) {
+	// The modulus plus one used to wrap around to the same key as one
+	_, err := NewKeyPairFromHexString(
+		"30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f0000002",
+	)
+	assert.ErrorIs(t, err, ErrInvalidPrivateKey)
+
+	kp, err := NewKeyPairFromHexString("01")
+	require.NoError(t, err)
+	assert.Equal(t, "1", kp.PrivKey.String())
+
+	_, err = NewKeyPairFromString(frModulusDec)
+	assert.ErrorIs(t, err, ErrInvalidPrivateKey)
+}
+
+func zeros(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = '0'
+	}
+	return string(b)
+}
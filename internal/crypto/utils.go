@@ -1,6 +1,8 @@
 package crypto
 
 import (
+	"encoding/hex"
+	"fmt"
 	"math/big"
 
 	"github.com/consensys/gnark-crypto/ecc/bn254"
@@ -69,6 +71,30 @@ func CheckG1AndG2DiscreteLogEquality(
 	)
 }
 
+// CheckPublicKeyPairHex checks that the hex encoded compressed G1 and G2
+// public keys belong to the same private key
+func CheckPublicKeyPairHex(g1Hex string, g2Hex string) (bool, error) {
+	g1Bytes, err := hex.DecodeString(g1Hex)
+	if err != nil {
+		return false, fmt.Errorf("invalid G1 public key hex: %w", err)
+	}
+	g2Bytes, err := hex.DecodeString(g2Hex)
+	if err != nil {
+		return false, fmt.Errorf("invalid G2 public key hex: %w", err)
+	}
+
+	pointG1 := new(bn254.G1Affine)
+	if _, err := pointG1.SetBytes(g1Bytes); err != nil {
+		return false, fmt.Errorf("invalid G1 public key: %w", err)
+	}
+	pointG2 := new(bn254.G2Affine)
+	if _, err := pointG2.SetBytes(g2Bytes); err != nil {
+		return false, fmt.Errorf("invalid G2 public key: %w", err)
+	}
+
+	return CheckG1AndG2DiscreteLogEquality(pointG1, pointG2)
+}
+
 func GetG1Generator() *bn254.G1Affine {
 	g1Gen := new(bn254.G1Affine)
 	_, err := g1Gen.X.SetString("1")
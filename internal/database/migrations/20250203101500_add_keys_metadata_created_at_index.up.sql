@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS keys_metadata_created_at_public_key_g1_idx
+    ON public.keys_metadata (created_at DESC, public_key_g1 DESC);
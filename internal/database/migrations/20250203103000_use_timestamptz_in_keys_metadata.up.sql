@@ -0,0 +1,9 @@
+-- The creation filters and the page tokens compare created_at with times in
+-- UTC, which a TIMESTAMP column without time zone can't tell apart from times
+-- in the session time zone. cerberus has always written both columns itself in
+-- UTC, so the existing values are read as UTC. Rows inserted by other means
+-- relying on DEFAULT NOW() on a server whose TimeZone isn't UTC hold local
+-- times and must be corrected by hand.
+ALTER TABLE public.keys_metadata
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
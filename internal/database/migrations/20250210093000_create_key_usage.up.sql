@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS public.key_usage (
+    public_key_g1 VARCHAR(255) NOT NULL,
+    method VARCHAR(64) NOT NULL,
+    signature_count BIGINT NOT NULL DEFAULT 0,
+    last_used_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (public_key_g1, method)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE public.keys_metadata ADD COLUMN not_before TIMESTAMPTZ;
+ALTER TABLE public.keys_metadata ADD COLUMN not_after TIMESTAMPTZ;
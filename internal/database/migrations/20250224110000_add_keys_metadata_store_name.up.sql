@@ -0,0 +1 @@
+ALTER TABLE public.keys_metadata ADD COLUMN store_name TEXT NOT NULL DEFAULT '';
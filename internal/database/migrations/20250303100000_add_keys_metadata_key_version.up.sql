@@ -0,0 +1,2 @@
+ALTER TABLE public.keys_metadata ADD COLUMN key_version TEXT NOT NULL DEFAULT '';
+ALTER TABLE public.keys_metadata ADD COLUMN key_version_pinned BOOLEAN NOT NULL DEFAULT false;
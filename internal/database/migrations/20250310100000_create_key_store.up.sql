@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS public.key_store (
+    public_key_g1 VARCHAR(255) PRIMARY KEY,
+    payload BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS public.api_keys (
+    id UUID PRIMARY KEY,
+    public_key_g1 VARCHAR(255) NOT NULL
+        REFERENCES public.keys_metadata (public_key_g1) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    hash TEXT NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    UNIQUE (public_key_g1, name)
+);
+
+-- The API key of each key becomes its "default" API key, allowed every method
+INSERT INTO public.api_keys (id, public_key_g1, name, hash, created_at)
+SELECT gen_random_uuid(), public_key_g1, 'default', api_key_hash, updated_at
+FROM public.keys_metadata
+WHERE api_key_hash IS NOT NULL AND api_key_hash <> '';
+
+-- api_key_hash is no longer written, it is kept so that the previous version
+-- can still be rolled back to. A later migration drops it once the api_keys
+-- table is in use.
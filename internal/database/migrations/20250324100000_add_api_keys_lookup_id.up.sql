@@ -0,0 +1,4 @@
+ALTER TABLE public.api_keys ADD COLUMN lookup_id VARCHAR(32) UNIQUE;
+
+-- The existing API keys keep their unsalted SHA-256 hash until they are rotated
+ALTER TABLE public.api_keys ADD COLUMN hash_scheme VARCHAR(32) NOT NULL DEFAULT 'sha256';
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// Scopes of the API keys, each allowing a signing method
+const (
+	ScopeSignGeneric = "sign-generic"
+	ScopeSignG1      = "sign-g1"
+)
+
+// Scopes are the scopes an API key can be restricted to
+var Scopes = []string{ScopeSignGeneric, ScopeSignG1}
+
+// Hash schemes of the API keys
+const (
+	// HashSchemeSHA256 is the unsalted SHA-256 of the legacy API keys
+	HashSchemeSHA256 = "sha256"
+
+	// HashSchemeHMACSHA256 is the HMAC-SHA256 keyed by the server pepper
+	HashSchemeHMACSHA256 = "hmac-sha256"
+)
+
+// APIKey authenticates the signing requests of a key. A key may have several
+// API keys, so that clients can move to a new one before the old one is revoked.
+type APIKey struct {
+	ID          string `db:"id"`
+	PublicKeyG1 string `db:"public_key_g1"`
+	Name        string `db:"name"`
+
+	// LookupID is the part of the API key finding it, empty for the legacy
+	// API keys
+	LookupID   string `db:"lookup_id"`
+	Hash       string `db:"hash"`
+	HashScheme string `db:"hash_scheme"`
+
+	// Scopes restricts the signing methods the API key may call. An empty
+	// list allows every method.
+	Scopes []string `db:"scopes"`
+
+	CreatedAt time.Time `db:"created_at"`
+
+	// ExpiresAt is the time from which the API key is rejected, a nil
+	// ExpiresAt never expires
+	ExpiresAt  *time.Time `db:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+}
+
+// ExpiredAt returns true if the API key is expired at the time
+func (k *APIKey) ExpiredAt(t time.Time) bool {
+	return k.ExpiresAt != nil && !t.Before(*k.ExpiresAt)
+}
+
+// HasScope returns true if the API key may call the method of the scope
+func (k *APIKey) HasScope(scope string) bool {
+	return len(k.Scopes) == 0 || slices.Contains(k.Scopes, scope)
+}
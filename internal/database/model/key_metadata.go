@@ -7,6 +7,36 @@ type KeyMetadata struct {
 	PublicKeyG2 string    `db:"public_key_g2"`
 	CreatedAt   time.Time `db:"created_at"`
 	UpdatedAt   time.Time `db:"updated_at"`
-	ApiKeyHash  string    `db:"api_key_hash"`
-	Locked      bool      `db:"locked"`
+
+	// ApiKeyHash is the API key of the versions preceding the api_keys table,
+	// no longer written and kept until a later migration drops the column
+	ApiKeyHash string `db:"api_key_hash"`
+	Locked     bool   `db:"locked"`
+
+	// NotBefore and NotAfter bound the time window in which the key may sign.
+	// A nil bound means the window is open on that side.
+	NotBefore *time.Time `db:"not_before"`
+	NotAfter  *time.Time `db:"not_after"`
+
+	// StoreName is the name of the store holding the private key. It is empty
+	// for the keys created before stores were named, which are in the default store.
+	StoreName string `db:"store_name"`
+
+	// KeyVersion is the version of the key in its store recorded when the key
+	// was created, re-encrypted, re-wrapped or rolled back. It is empty for
+	// the stores without versions. When KeyVersionPinned is true the key signs
+	// with this version rather than the current one.
+	KeyVersion       string `db:"key_version"`
+	KeyVersionPinned bool   `db:"key_version_pinned"`
+}
+
+// ValidAt returns true if the time is within the validity window of the key
+func (m *KeyMetadata) ValidAt(t time.Time) bool {
+	if m.NotBefore != nil && t.Before(*m.NotBefore) {
+		return false
+	}
+	if m.NotAfter != nil && !t.Before(*m.NotAfter) {
+		return false
+	}
+	return true
 }
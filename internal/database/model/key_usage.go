@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// KeyUsage holds the signature count and last use of a key for one signing method
+type KeyUsage struct {
+	PublicKeyG1    string    `db:"public_key_g1"`
+	Method         string    `db:"method"`
+	SignatureCount int64     `db:"signature_count"`
+	LastUsedAt     time.Time `db:"last_used_at"`
+}
+
+// KeyUsageSummary aggregates the usage of a key over all signing methods
+type KeyUsageSummary struct {
+	PublicKeyG1    string
+	CreatedAt      time.Time
+	LastUsedAt     *time.Time
+	SignatureCount int64
+}
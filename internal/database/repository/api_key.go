@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+)
+
+type APIKeyRepository interface {
+	// Create adds an API key to a key. The names of the API keys of a key are
+	// unique, a taken name returns ErrAPIKeyAlreadyExists.
+	Create(ctx context.Context, apiKey *model.APIKey) error
+
+	// Replace adds an API key to a key, revoking the API key of the key with
+	// the same name in the same step, so that the key is never left without it
+	Replace(ctx context.Context, apiKey *model.APIKey) error
+
+	// GetByLookupID returns the API key with the lookup ID
+	GetByLookupID(ctx context.Context, lookupID string) (*model.APIKey, error)
+
+	// List returns the API keys of a key, oldest first
+	List(ctx context.Context, publicKeyG1 string) ([]*model.APIKey, error)
+
+	// Delete revokes the API key of a key with the name
+	Delete(ctx context.Context, publicKeyG1 string, name string) error
+
+	// UpdateLastUsed records the last use of an API key
+	UpdateLastUsed(ctx context.Context, id string, lastUsedAt time.Time) error
+}
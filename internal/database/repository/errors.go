@@ -3,5 +3,9 @@ package repository
 import "errors"
 
 var (
-	ErrKeyNotFound = errors.New("key not found")
+	ErrKeyNotFound      = errors.New("key not found")
+	ErrInvalidPageToken = errors.New("invalid page token")
+
+	ErrAPIKeyNotFound      = errors.New("api key not found")
+	ErrAPIKeyAlreadyExists = errors.New("api key already exists")
 )
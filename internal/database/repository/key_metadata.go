@@ -2,16 +2,65 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/Layr-Labs/cerberus/internal/database/model"
 )
 
+// MaxPageSize is the largest page a single paginated listing returns
+const MaxPageSize = 1000
+
+// ListOptions controls pagination and filtering of key metadata listings
+type ListOptions struct {
+	// PageSize is the maximum number of keys to return. Zero means no limit.
+	PageSize int
+
+	// PageToken is the opaque cursor returned by a previous call
+	PageToken string
+
+	// Locked filters keys by their lock status when set
+	Locked *bool
+
+	// CreatedAfter and CreatedBefore filter keys by creation time when non-zero
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	// PublicKeyG1Prefix filters keys whose G1 public key starts with the prefix
+	PublicKeyG1Prefix string
+}
+
 type KeyMetadataRepository interface {
 	Create(ctx context.Context, metadata *model.KeyMetadata) error
 	Get(ctx context.Context, publicKeyG1 string) (*model.KeyMetadata, error)
 	Update(ctx context.Context, metadata *model.KeyMetadata) error
-	UpdateAPIKeyHash(ctx context.Context, publicKeyG1 string, apiKeyHash string) error
 	UpdateLockStatus(ctx context.Context, publicKeyG1 string, locked bool) error
+
+	// UpdateValidity sets the time window in which the key may sign.
+	// A nil bound opens the window on that side.
+	UpdateValidity(
+		ctx context.Context,
+		publicKeyG1 string,
+		notBefore *time.Time,
+		notAfter *time.Time,
+	) error
+
+	// UpdateStoreName records the store holding the key after it was moved
+	UpdateStoreName(ctx context.Context, publicKeyG1 string, storeName string) error
+
+	// UpdateKeyVersion records the version of the key in its store and
+	// whether the key signs with that version rather than the current one
+	UpdateKeyVersion(
+		ctx context.Context,
+		publicKeyG1 string,
+		keyVersion string,
+		pinned bool,
+	) error
+
 	Delete(ctx context.Context, publicKeyG1 string) error
 	List(ctx context.Context) ([]*model.KeyMetadata, error)
+
+	// ListPage returns one page of keys matching the options, ordered by
+	// creation time descending, and the token for the next page.
+	// The next page token is empty when there are no more keys.
+	ListPage(ctx context.Context, opts *ListOptions) ([]*model.KeyMetadata, string, error)
 }
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+)
+
+type KeyUsageRepository interface {
+	// RecordUsage adds the signature counts of each entry to the stored counts
+	// and advances the last used time
+	RecordUsage(ctx context.Context, usage []*model.KeyUsage) error
+
+	// Get returns the usage of a key per signing method
+	Get(ctx context.Context, publicKeyG1 string) ([]*model.KeyUsage, error)
+
+	// ListForKeys returns the usage of the given keys per signing method
+	ListForKeys(ctx context.Context, publicKeyG1s []string) ([]*model.KeyUsage, error)
+
+	// ListUnusedSince returns the keys that haven't signed since the given time.
+	// Keys that never signed are included if they were created before it.
+	ListUnusedSince(ctx context.Context, since time.Time) ([]*model.KeyUsageSummary, error)
+}
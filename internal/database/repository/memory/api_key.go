@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+)
+
+type apiKeyRepo struct {
+	db *DB
+}
+
+func NewAPIKeyRepository(db *DB) repository.APIKeyRepository {
+	return &apiKeyRepo{
+		db: db,
+	}
+}
+
+func (r *apiKeyRepo) Create(ctx context.Context, apiKey *model.APIKey) error {
+	if err := validateAPIKey(apiKey); err != nil {
+		return err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, k := range r.db.apiKeys[apiKey.PublicKeyG1] {
+		if k.Name == apiKey.Name {
+			return repository.ErrAPIKeyAlreadyExists
+		}
+	}
+	if apiKey.LookupID != "" && r.find(func(k *model.APIKey) bool {
+		return k.LookupID == apiKey.LookupID
+	}) != nil {
+		return errors.New("api key lookup id already exists")
+	}
+
+	r.db.apiKeys[apiKey.PublicKeyG1] = append(
+		r.db.apiKeys[apiKey.PublicKeyG1],
+		storedAPIKey(apiKey),
+	)
+	return nil
+}
+
+func (r *apiKeyRepo) Replace(ctx context.Context, apiKey *model.APIKey) error {
+	if err := validateAPIKey(apiKey); err != nil {
+		return err
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if apiKey.LookupID != "" && r.find(func(k *model.APIKey) bool {
+		return k.LookupID == apiKey.LookupID && k.Name != apiKey.Name
+	}) != nil {
+		return errors.New("api key lookup id already exists")
+	}
+
+	apiKeys := slices.DeleteFunc(r.db.apiKeys[apiKey.PublicKeyG1], func(k *model.APIKey) bool {
+		return k.Name == apiKey.Name
+	})
+	r.db.apiKeys[apiKey.PublicKeyG1] = append(apiKeys, storedAPIKey(apiKey))
+	return nil
+}
+
+func (r *apiKeyRepo) GetByLookupID(ctx context.Context, lookupID string) (*model.APIKey, error) {
+	if lookupID == "" {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	apiKey := r.find(func(k *model.APIKey) bool { return k.LookupID == lookupID })
+	if apiKey == nil {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+	return copyAPIKey(apiKey), nil
+}
+
+func (r *apiKeyRepo) List(ctx context.Context, publicKeyG1 string) ([]*model.APIKey, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	apiKeys := make([]*model.APIKey, 0, len(r.db.apiKeys[publicKeyG1]))
+	for _, k := range r.db.apiKeys[publicKeyG1] {
+		apiKeys = append(apiKeys, copyAPIKey(k))
+	}
+	return apiKeys, nil
+}
+
+func (r *apiKeyRepo) Delete(ctx context.Context, publicKeyG1 string, name string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	apiKeys := r.db.apiKeys[publicKeyG1]
+	i := slices.IndexFunc(apiKeys, func(k *model.APIKey) bool { return k.Name == name })
+	if i < 0 {
+		return repository.ErrAPIKeyNotFound
+	}
+	r.db.apiKeys[publicKeyG1] = slices.Delete(apiKeys, i, i+1)
+	return nil
+}
+
+func (r *apiKeyRepo) UpdateLastUsed(ctx context.Context, id string, lastUsedAt time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	k := r.find(func(k *model.APIKey) bool { return k.ID == id })
+	if k == nil {
+		return repository.ErrAPIKeyNotFound
+	}
+	if k.LastUsedAt == nil || lastUsedAt.After(*k.LastUsedAt) {
+		k.LastUsedAt = utcOrNil(&lastUsedAt)
+	}
+	return nil
+}
+
+// storedAPIKey sets the creation time of the API key and returns the copy of
+// it to store
+func storedAPIKey(apiKey *model.APIKey) *model.APIKey {
+	apiKey.CreatedAt = time.Now().UTC()
+	stored := copyAPIKey(apiKey)
+	stored.ExpiresAt = utcOrNil(apiKey.ExpiresAt)
+	stored.LastUsedAt = nil
+	return stored
+}
+
+// find returns the stored API key matching, the caller holds the lock
+func (r *apiKeyRepo) find(match func(k *model.APIKey) bool) *model.APIKey {
+	for _, apiKeys := range r.db.apiKeys {
+		for _, k := range apiKeys {
+			if match(k) {
+				return k
+			}
+		}
+	}
+	return nil
+}
+
+func validateAPIKey(apiKey *model.APIKey) error {
+	switch {
+	case apiKey.ID == "":
+		return errors.New("api key id is required")
+	case apiKey.PublicKeyG1 == "":
+		return errors.New("public key g1 is required")
+	case apiKey.Name == "":
+		return errors.New("api key name is required")
+	case apiKey.Hash == "":
+		return errors.New("api key hash is required")
+	case apiKey.HashScheme == "":
+		return errors.New("api key hash scheme is required")
+	}
+	return nil
+}
+
+func copyAPIKey(k *model.APIKey) *model.APIKey {
+	c := *k
+	c.Scopes = slices.Clone(k.Scopes)
+	if k.ExpiresAt != nil {
+		expiresAt := *k.ExpiresAt
+		c.ExpiresAt = &expiresAt
+	}
+	if k.LastUsedAt != nil {
+		lastUsedAt := *k.LastUsedAt
+		c.LastUsedAt = &lastUsedAt
+	}
+	return &c
+}
@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyRepository(t *testing.T) {
+	db := NewDB()
+	metadataRepo := NewKeyMetadataRepository(db)
+	repo := NewAPIKeyRepository(db)
+	ctx := context.Background()
+
+	require.NoError(t, metadataRepo.Create(ctx, &model.KeyMetadata{
+		PublicKeyG1: "test_key_g1",
+		PublicKeyG2: "test_key_g2",
+	}))
+
+	expiresAt := time.Now().Add(time.Hour)
+	require.NoError(t, repo.Create(ctx, &model.APIKey{
+		ID:          "id_1",
+		PublicKeyG1: "test_key_g1",
+		Name:        "first",
+		Hash:        "hash_1",
+		HashScheme:  model.HashSchemeSHA256,
+	}))
+	require.NoError(t, repo.Create(ctx, &model.APIKey{
+		ID:          "id_2",
+		PublicKeyG1: "test_key_g1",
+		Name:        "second",
+		LookupID:    "lookup_2",
+		Hash:        "hash_2",
+		HashScheme:  model.HashSchemeHMACSHA256,
+		Scopes:      []string{model.ScopeSignG1},
+		ExpiresAt:   &expiresAt,
+	}))
+	assert.Error(t, repo.Create(ctx, &model.APIKey{ID: "id_3", PublicKeyG1: "test_key_g1"}))
+
+	// Names are unique per key
+	err := repo.Create(ctx, &model.APIKey{
+		ID:          "id_3",
+		PublicKeyG1: "test_key_g1",
+		Name:        "first",
+		Hash:        "hash_3",
+		HashScheme:  model.HashSchemeSHA256,
+	})
+	assert.ErrorIs(t, err, repository.ErrAPIKeyAlreadyExists)
+
+	apiKeys, err := repo.List(ctx, "test_key_g1")
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 2)
+	assert.Equal(t, "first", apiKeys[0].Name)
+	assert.Equal(t, "second", apiKeys[1].Name)
+	assert.Equal(t, []string{model.ScopeSignG1}, apiKeys[1].Scopes)
+	require.NotNil(t, apiKeys[1].ExpiresAt)
+	assert.True(t, expiresAt.Equal(*apiKeys[1].ExpiresAt))
+
+	// The returned API keys are copies
+	apiKeys[1].Scopes[0] = model.ScopeSignGeneric
+	apiKeys, err = repo.List(ctx, "test_key_g1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{model.ScopeSignG1}, apiKeys[1].Scopes)
+
+	apiKey, err := repo.GetByLookupID(ctx, "lookup_2")
+	require.NoError(t, err)
+	assert.Equal(t, "second", apiKey.Name)
+	assert.Equal(t, model.HashSchemeHMACSHA256, apiKey.HashScheme)
+	_, err = repo.GetByLookupID(ctx, "missing")
+	assert.ErrorIs(t, err, repository.ErrAPIKeyNotFound)
+	assert.Equal(t, "", apiKeys[0].LookupID)
+
+	// Replacing an API key revokes the API key of the same name in the same
+	// step, and adds it if there is none
+	require.NoError(t, repo.Replace(ctx, &model.APIKey{
+		ID:          "id_4",
+		PublicKeyG1: "test_key_g1",
+		Name:        "second",
+		LookupID:    "lookup_4",
+		Hash:        "hash_4",
+		HashScheme:  model.HashSchemeHMACSHA256,
+	}))
+	require.NoError(t, repo.Replace(ctx, &model.APIKey{
+		ID:          "id_5",
+		PublicKeyG1: "test_key_g1",
+		Name:        "third",
+		Hash:        "hash_5",
+		HashScheme:  model.HashSchemeSHA256,
+	}))
+	_, err = repo.GetByLookupID(ctx, "lookup_2")
+	assert.ErrorIs(t, err, repository.ErrAPIKeyNotFound)
+	apiKeys, err = repo.List(ctx, "test_key_g1")
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 3)
+	assert.Equal(t, "first", apiKeys[0].Name)
+	assert.Equal(t, "id_4", apiKeys[1].ID)
+	assert.Equal(t, "second", apiKeys[1].Name)
+	assert.Empty(t, apiKeys[1].Scopes)
+	assert.Nil(t, apiKeys[1].ExpiresAt)
+	assert.Equal(t, "third", apiKeys[2].Name)
+
+	// The last use never moves backwards
+	lastUsedAt := time.Now()
+	require.NoError(t, repo.UpdateLastUsed(ctx, "id_1", lastUsedAt))
+	require.NoError(t, repo.UpdateLastUsed(ctx, "id_1", lastUsedAt.Add(-time.Hour)))
+	apiKeys, err = repo.List(ctx, "test_key_g1")
+	require.NoError(t, err)
+	require.NotNil(t, apiKeys[0].LastUsedAt)
+	assert.True(t, lastUsedAt.Equal(*apiKeys[0].LastUsedAt))
+	assert.ErrorIs(t, repo.UpdateLastUsed(ctx, "missing", lastUsedAt), repository.ErrAPIKeyNotFound)
+
+	require.NoError(t, repo.Delete(ctx, "test_key_g1", "first"))
+	assert.ErrorIs(t, repo.Delete(ctx, "test_key_g1", "first"), repository.ErrAPIKeyNotFound)
+
+	// The API keys are deleted with their key
+	require.NoError(t, metadataRepo.Delete(ctx, "test_key_g1"))
+	apiKeys, err = repo.List(ctx, "test_key_g1")
+	require.NoError(t, err)
+	assert.Empty(t, apiKeys)
+}
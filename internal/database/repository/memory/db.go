@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+)
+
+// DB holds the key metadata, usage and API keys in memory, for development and tests.
+// Everything is lost on restart.
+type DB struct {
+	mu       sync.RWMutex
+	metadata map[string]*model.KeyMetadata
+
+	// usage is keyed by public key and then by signing method
+	usage map[string]map[string]*model.KeyUsage
+
+	// apiKeys is keyed by public key, each list in creation order
+	apiKeys map[string][]*model.APIKey
+}
+
+func NewDB() *DB {
+	return &DB{
+		metadata: make(map[string]*model.KeyMetadata),
+		usage:    make(map[string]map[string]*model.KeyUsage),
+		apiKeys:  make(map[string][]*model.APIKey),
+	}
+}
+
+func copyMetadata(m *model.KeyMetadata) *model.KeyMetadata {
+	c := *m
+	if m.NotBefore != nil {
+		notBefore := *m.NotBefore
+		c.NotBefore = &notBefore
+	}
+	if m.NotAfter != nil {
+		notAfter := *m.NotAfter
+		c.NotAfter = &notAfter
+	}
+	return &c
+}
+
+func utcOrNil(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
@@ -0,0 +1,242 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+)
+
+type keyMetadataRepo struct {
+	db *DB
+}
+
+func NewKeyMetadataRepository(db *DB) repository.KeyMetadataRepository {
+	return &keyMetadataRepo{
+		db: db,
+	}
+}
+
+func (r *keyMetadataRepo) Create(ctx context.Context, metadata *model.KeyMetadata) error {
+	if metadata.PublicKeyG1 == "" {
+		return errors.New("public key g1 is required")
+	}
+	if metadata.PublicKeyG2 == "" {
+		return errors.New("public key g2 is required")
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.metadata[metadata.PublicKeyG1]; ok {
+		return errors.New("key metadata already exists")
+	}
+
+	now := time.Now().UTC()
+	metadata.CreatedAt = now
+	metadata.UpdatedAt = now
+
+	stored := copyMetadata(metadata)
+	stored.Locked = false
+	stored.NotBefore = utcOrNil(metadata.NotBefore)
+	stored.NotAfter = utcOrNil(metadata.NotAfter)
+	r.db.metadata[metadata.PublicKeyG1] = stored
+	return nil
+}
+
+func (r *keyMetadataRepo) Get(ctx context.Context, publicKeyG1 string) (*model.KeyMetadata, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	m, ok := r.db.metadata[publicKeyG1]
+	if !ok {
+		return nil, repository.ErrKeyNotFound
+	}
+	return copyMetadata(m), nil
+}
+
+func (r *keyMetadataRepo) Update(ctx context.Context, metadata *model.KeyMetadata) error {
+	if metadata.PublicKeyG1 == "" {
+		return errors.New("public key g1 is required")
+	}
+
+	notFound := errors.New("key metadata not found")
+	return r.update(metadata.PublicKeyG1, notFound, func(m *model.KeyMetadata) {
+		metadata.UpdatedAt = m.UpdatedAt
+	})
+}
+
+func (r *keyMetadataRepo) UpdateLockStatus(
+	ctx context.Context,
+	publicKeyG1 string,
+	locked bool,
+) error {
+	return r.update(publicKeyG1, nil, func(m *model.KeyMetadata) {
+		m.Locked = locked
+	})
+}
+
+func (r *keyMetadataRepo) UpdateValidity(
+	ctx context.Context,
+	publicKeyG1 string,
+	notBefore *time.Time,
+	notAfter *time.Time,
+) error {
+	if publicKeyG1 == "" {
+		return errors.New("public key g1 is required")
+	}
+
+	return r.update(publicKeyG1, repository.ErrKeyNotFound, func(m *model.KeyMetadata) {
+		m.NotBefore = utcOrNil(notBefore)
+		m.NotAfter = utcOrNil(notAfter)
+	})
+}
+
+func (r *keyMetadataRepo) UpdateStoreName(
+	ctx context.Context,
+	publicKeyG1 string,
+	storeName string,
+) error {
+	if publicKeyG1 == "" {
+		return errors.New("public key g1 is required")
+	}
+
+	return r.update(publicKeyG1, repository.ErrKeyNotFound, func(m *model.KeyMetadata) {
+		m.StoreName = storeName
+	})
+}
+
+func (r *keyMetadataRepo) UpdateKeyVersion(
+	ctx context.Context,
+	publicKeyG1 string,
+	keyVersion string,
+	pinned bool,
+) error {
+	if publicKeyG1 == "" {
+		return errors.New("public key g1 is required")
+	}
+
+	return r.update(publicKeyG1, repository.ErrKeyNotFound, func(m *model.KeyMetadata) {
+		m.KeyVersion = keyVersion
+		m.KeyVersionPinned = pinned
+	})
+}
+
+func (r *keyMetadataRepo) Delete(ctx context.Context, publicKeyG1 string) error {
+	if publicKeyG1 == "" {
+		return errors.New("public key g1 is required")
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.metadata[publicKeyG1]; !ok {
+		return errors.New("key metadata not found")
+	}
+	delete(r.db.metadata, publicKeyG1)
+	delete(r.db.apiKeys, publicKeyG1)
+	return nil
+}
+
+func (r *keyMetadataRepo) List(ctx context.Context) ([]*model.KeyMetadata, error) {
+	metadata, _, err := r.ListPage(ctx, nil)
+	return metadata, err
+}
+
+func (r *keyMetadataRepo) ListPage(
+	ctx context.Context,
+	opts *repository.ListOptions,
+) ([]*model.KeyMetadata, string, error) {
+	if opts == nil {
+		opts = &repository.ListOptions{}
+	}
+
+	pageSize := opts.PageSize
+	if pageSize < 0 {
+		return nil, "", errors.New("page size must not be negative")
+	}
+	if pageSize > repository.MaxPageSize {
+		pageSize = repository.MaxPageSize
+	}
+
+	var cursor *repository.PageCursor
+	if opts.PageToken != "" {
+		var err error
+		cursor, err = repository.DecodePageToken(opts.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	r.db.mu.RLock()
+	var metadata []*model.KeyMetadata
+	for _, m := range r.db.metadata {
+		if opts.Locked != nil && m.Locked != *opts.Locked {
+			continue
+		}
+		if !opts.CreatedAfter.IsZero() && m.CreatedAt.Before(opts.CreatedAfter) {
+			continue
+		}
+		if !opts.CreatedBefore.IsZero() && !m.CreatedAt.Before(opts.CreatedBefore) {
+			continue
+		}
+		if !strings.HasPrefix(m.PublicKeyG1, opts.PublicKeyG1Prefix) {
+			continue
+		}
+		if cursor != nil && !before(m, cursor.CreatedAt, cursor.PublicKeyG1) {
+			continue
+		}
+
+		metadata = append(metadata, copyMetadata(m))
+	}
+	r.db.mu.RUnlock()
+
+	// Same order as postgres: created_at DESC, public_key_g1 DESC
+	sort.Slice(metadata, func(i, j int) bool {
+		return before(metadata[j], metadata[i].CreatedAt, metadata[i].PublicKeyG1)
+	})
+
+	var nextPageToken string
+	if pageSize > 0 && len(metadata) > pageSize {
+		metadata = metadata[:pageSize]
+		last := metadata[pageSize-1]
+		nextPageToken = repository.EncodePageToken(repository.PageCursor{
+			CreatedAt:   last.CreatedAt,
+			PublicKeyG1: last.PublicKeyG1,
+		})
+	}
+	return metadata, nextPageToken, nil
+}
+
+// update applies the change to the key and advances its update time.
+// Returns notFoundErr if the key doesn't exist, or nil to ignore missing keys
+// like the postgres repository does.
+func (r *keyMetadataRepo) update(
+	publicKeyG1 string,
+	notFoundErr error,
+	change func(m *model.KeyMetadata),
+) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	m, ok := r.db.metadata[publicKeyG1]
+	if !ok {
+		return notFoundErr
+	}
+	m.UpdatedAt = time.Now().UTC()
+	change(m)
+	return nil
+}
+
+// before returns true if the key sorts before the cursor position in the
+// (created_at, public_key_g1) order
+func before(m *model.KeyMetadata, createdAt time.Time, publicKeyG1 string) bool {
+	if !m.CreatedAt.Equal(createdAt) {
+		return m.CreatedAt.Before(createdAt)
+	}
+	return m.PublicKeyG1 < publicKeyG1
+}
@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyMetadataRepository_CreateGetDelete(t *testing.T) {
+	repo := NewKeyMetadataRepository(NewDB())
+	ctx := context.Background()
+
+	metadata := &model.KeyMetadata{
+		PublicKeyG1: "test_key_g1",
+		PublicKeyG2: "test_key_g2",
+	}
+	require.NoError(t, repo.Create(ctx, metadata))
+	assert.False(t, metadata.CreatedAt.IsZero())
+
+	// Keys are unique
+	assert.Error(t, repo.Create(ctx, &model.KeyMetadata{
+		PublicKeyG1: "test_key_g1",
+		PublicKeyG2: "test_key_g2",
+	}))
+	assert.Error(t, repo.Create(ctx, &model.KeyMetadata{PublicKeyG1: "test_key_g1"}))
+
+	got, err := repo.Get(ctx, "test_key_g1")
+	require.NoError(t, err)
+	assert.Equal(t, "test_key_g2", got.PublicKeyG2)
+
+	// The returned metadata is a copy
+	got.Locked = true
+	got, err = repo.Get(ctx, "test_key_g1")
+	require.NoError(t, err)
+	assert.False(t, got.Locked)
+
+	require.NoError(t, repo.UpdateLockStatus(ctx, "test_key_g1", true))
+	got, err = repo.Get(ctx, "test_key_g1")
+	require.NoError(t, err)
+	assert.True(t, got.Locked)
+
+	require.NoError(t, repo.Delete(ctx, "test_key_g1"))
+	_, err = repo.Get(ctx, "test_key_g1")
+	assert.ErrorIs(t, err, repository.ErrKeyNotFound)
+	assert.Error(t, repo.Delete(ctx, "test_key_g1"))
+}
+
+func TestKeyMetadataRepository_UpdateValidity(t *testing.T) {
+	repo := NewKeyMetadataRepository(NewDB())
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &model.KeyMetadata{
+		PublicKeyG1: "test_key_g1",
+		PublicKeyG2: "test_key_g2",
+	}))
+
+	notAfter := time.Now().Add(time.Hour)
+	require.NoError(t, repo.UpdateValidity(ctx, "test_key_g1", nil, &notAfter))
+	got, err := repo.Get(ctx, "test_key_g1")
+	require.NoError(t, err)
+	assert.Nil(t, got.NotBefore)
+	require.NotNil(t, got.NotAfter)
+	assert.True(t, notAfter.Equal(*got.NotAfter))
+
+	err = repo.UpdateValidity(ctx, "missing", nil, nil)
+	assert.ErrorIs(t, err, repository.ErrKeyNotFound)
+}
+
+func TestKeyMetadataRepository_UpdateStoreName(t *testing.T) {
+	repo := NewKeyMetadataRepository(NewDB())
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &model.KeyMetadata{
+		PublicKeyG1: "test_key_g1",
+		PublicKeyG2: "test_key_g2",
+		StoreName:   "disk",
+	}))
+
+	require.NoError(t, repo.UpdateStoreName(ctx, "test_key_g1", "hsm"))
+	got, err := repo.Get(ctx, "test_key_g1")
+	require.NoError(t, err)
+	assert.Equal(t, "hsm", got.StoreName)
+
+	err = repo.UpdateStoreName(ctx, "missing", "hsm")
+	assert.ErrorIs(t, err, repository.ErrKeyNotFound)
+}
+
+func TestKeyMetadataRepository_UpdateKeyVersion(t *testing.T) {
+	repo := NewKeyMetadataRepository(NewDB())
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &model.KeyMetadata{
+		PublicKeyG1: "test_key_g1",
+		PublicKeyG2: "test_key_g2",
+		KeyVersion:  "1",
+	}))
+
+	require.NoError(t, repo.UpdateKeyVersion(ctx, "test_key_g1", "2", true))
+	got, err := repo.Get(ctx, "test_key_g1")
+	require.NoError(t, err)
+	assert.Equal(t, "2", got.KeyVersion)
+	assert.True(t, got.KeyVersionPinned)
+
+	err = repo.UpdateKeyVersion(ctx, "missing", "2", false)
+	assert.ErrorIs(t, err, repository.ErrKeyNotFound)
+}
+
+func TestKeyMetadataRepository_ListPage(t *testing.T) {
+	repo := NewKeyMetadataRepository(NewDB())
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, repo.Create(ctx, &model.KeyMetadata{
+			PublicKeyG1: fmt.Sprintf("key_%d", i),
+			PublicKeyG2: fmt.Sprintf("g2_%d", i),
+		}))
+	}
+	require.NoError(t, repo.UpdateLockStatus(ctx, "key_1", true))
+
+	// Pages cover every key once, newest first
+	var listed []string
+	opts := &repository.ListOptions{PageSize: 2}
+	for {
+		page, next, err := repo.ListPage(ctx, opts)
+		require.NoError(t, err)
+		for _, m := range page {
+			listed = append(listed, m.PublicKeyG1)
+		}
+		if next == "" {
+			break
+		}
+		opts.PageToken = next
+	}
+	assert.Equal(t, []string{"key_4", "key_3", "key_2", "key_1", "key_0"}, listed)
+
+	locked := true
+	page, _, err := repo.ListPage(ctx, &repository.ListOptions{Locked: &locked})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "key_1", page[0].PublicKeyG1)
+
+	page, _, err = repo.ListPage(ctx, &repository.ListOptions{PublicKeyG1Prefix: "key_3"})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+
+	_, _, err = repo.ListPage(ctx, &repository.ListOptions{PageToken: "not a token"})
+	assert.ErrorIs(t, err, repository.ErrInvalidPageToken)
+
+	all, err := repo.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 5)
+}
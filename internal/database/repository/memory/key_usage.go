@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+)
+
+type keyUsageRepo struct {
+	db *DB
+}
+
+func NewKeyUsageRepository(db *DB) repository.KeyUsageRepository {
+	return &keyUsageRepo{
+		db: db,
+	}
+}
+
+func (r *keyUsageRepo) RecordUsage(ctx context.Context, usage []*model.KeyUsage) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, u := range usage {
+		byMethod, ok := r.db.usage[u.PublicKeyG1]
+		if !ok {
+			byMethod = make(map[string]*model.KeyUsage)
+			r.db.usage[u.PublicKeyG1] = byMethod
+		}
+
+		stored, ok := byMethod[u.Method]
+		if !ok {
+			byMethod[u.Method] = &model.KeyUsage{
+				PublicKeyG1:    u.PublicKeyG1,
+				Method:         u.Method,
+				SignatureCount: u.SignatureCount,
+				LastUsedAt:     u.LastUsedAt.UTC(),
+			}
+			continue
+		}
+		stored.SignatureCount += u.SignatureCount
+		if u.LastUsedAt.After(stored.LastUsedAt) {
+			stored.LastUsedAt = u.LastUsedAt.UTC()
+		}
+	}
+	return nil
+}
+
+func (r *keyUsageRepo) Get(ctx context.Context, publicKeyG1 string) ([]*model.KeyUsage, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return sortedUsage(r.db.usage[publicKeyG1]), nil
+}
+
+func (r *keyUsageRepo) ListForKeys(
+	ctx context.Context,
+	publicKeyG1s []string,
+) ([]*model.KeyUsage, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var usage []*model.KeyUsage
+	for _, publicKeyG1 := range publicKeyG1s {
+		usage = append(usage, sortedUsage(r.db.usage[publicKeyG1])...)
+	}
+	sort.SliceStable(usage, func(i, j int) bool {
+		return usage[i].PublicKeyG1 < usage[j].PublicKeyG1
+	})
+	return usage, nil
+}
+
+func (r *keyUsageRepo) ListUnusedSince(
+	ctx context.Context,
+	since time.Time,
+) ([]*model.KeyUsageSummary, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var summaries []*model.KeyUsageSummary
+	for publicKeyG1, m := range r.db.metadata {
+		s := &model.KeyUsageSummary{
+			PublicKeyG1: publicKeyG1,
+			CreatedAt:   m.CreatedAt,
+		}
+		for _, u := range r.db.usage[publicKeyG1] {
+			s.SignatureCount += u.SignatureCount
+			if s.LastUsedAt == nil || u.LastUsedAt.After(*s.LastUsedAt) {
+				lastUsedAt := u.LastUsedAt
+				s.LastUsedAt = &lastUsedAt
+			}
+		}
+		if lastActivity(s).Before(since) {
+			summaries = append(summaries, s)
+		}
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return lastActivity(summaries[i]).Before(lastActivity(summaries[j]))
+	})
+	return summaries, nil
+}
+
+// lastActivity is the last use of the key, or its creation if it never signed
+func lastActivity(s *model.KeyUsageSummary) time.Time {
+	if s.LastUsedAt != nil {
+		return *s.LastUsedAt
+	}
+	return s.CreatedAt
+}
+
+func sortedUsage(byMethod map[string]*model.KeyUsage) []*model.KeyUsage {
+	usage := make([]*model.KeyUsage, 0, len(byMethod))
+	for _, u := range byMethod {
+		c := *u
+		usage = append(usage, &c)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].Method < usage[j].Method
+	})
+	return usage
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyUsageRepository(t *testing.T) {
+	db := NewDB()
+	metadataRepo := NewKeyMetadataRepository(db)
+	usageRepo := NewKeyUsageRepository(db)
+	ctx := context.Background()
+
+	for _, key := range []string{"used_key", "unused_key"} {
+		require.NoError(t, metadataRepo.Create(ctx, &model.KeyMetadata{
+			PublicKeyG1: key,
+			PublicKeyG2: key + "_g2",
+		}))
+	}
+
+	firstUse := time.Now().UTC().Add(-time.Minute)
+	secondUse := time.Now().UTC()
+	require.NoError(t, usageRepo.RecordUsage(ctx, []*model.KeyUsage{
+		{PublicKeyG1: "used_key", Method: "SignGeneric", SignatureCount: 2, LastUsedAt: secondUse},
+		{PublicKeyG1: "used_key", Method: "SignG1", SignatureCount: 1, LastUsedAt: firstUse},
+	}))
+
+	// Counts add up and the last used time never moves backwards
+	require.NoError(t, usageRepo.RecordUsage(ctx, []*model.KeyUsage{
+		{PublicKeyG1: "used_key", Method: "SignGeneric", SignatureCount: 3, LastUsedAt: firstUse},
+	}))
+
+	usage, err := usageRepo.Get(ctx, "used_key")
+	require.NoError(t, err)
+	require.Len(t, usage, 2)
+	assert.Equal(t, "SignG1", usage[0].Method)
+	assert.Equal(t, int64(1), usage[0].SignatureCount)
+	assert.Equal(t, "SignGeneric", usage[1].Method)
+	assert.Equal(t, int64(5), usage[1].SignatureCount)
+	assert.Equal(t, secondUse, usage[1].LastUsedAt)
+
+	all, err := usageRepo.ListForKeys(ctx, []string{"used_key", "unused_key"})
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	all, err = usageRepo.ListForKeys(ctx, []string{"unused_key"})
+	require.NoError(t, err)
+	assert.Empty(t, all)
+
+	// Every key is unused since a time in the future
+	unused, err := usageRepo.ListUnusedSince(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, unused, 2)
+
+	// The key that never signed counts from its creation
+	unused, err = usageRepo.ListUnusedSince(ctx, secondUse)
+	require.NoError(t, err)
+	require.Len(t, unused, 1)
+	assert.Equal(t, "unused_key", unused[0].PublicKeyG1)
+	assert.Nil(t, unused[0].LastUsedAt)
+	assert.Zero(t, unused[0].SignatureCount)
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PageCursor identifies the last key of a page. Keys are ordered by
+// (created_at, public_key_g1) descending, so the cursor holds both.
+type PageCursor struct {
+	CreatedAt   time.Time
+	PublicKeyG1 string
+}
+
+// EncodePageToken encodes the cursor into an opaque page token
+func EncodePageToken(cursor PageCursor) string {
+	raw := fmt.Sprintf("%d:%s", cursor.CreatedAt.UnixNano(), cursor.PublicKeyG1)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodePageToken decodes a page token produced by EncodePageToken
+func DecodePageToken(token string) (*PageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+
+	createdAt, publicKeyG1, found := strings.Cut(string(raw), ":")
+	if !found || publicKeyG1 == "" {
+		return nil, ErrInvalidPageToken
+	}
+
+	nanos, err := strconv.ParseInt(createdAt, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+
+	return &PageCursor{
+		CreatedAt:   time.Unix(0, nanos).UTC(),
+		PublicKeyG1: publicKeyG1,
+	}, nil
+}
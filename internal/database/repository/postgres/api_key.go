@@ -0,0 +1,222 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+)
+
+// Codes of the postgres errors
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+type apiKeyRepo struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) repository.APIKeyRepository {
+	return &apiKeyRepo{
+		db: db,
+	}
+}
+
+const (
+	createAPIKeyQuery = `
+        INSERT INTO public.api_keys (
+            id, public_key_g1, name, lookup_id, hash, hash_scheme, scopes, created_at, expires_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    `
+
+	replaceAPIKeyQuery = createAPIKeyQuery + `
+        ON CONFLICT (public_key_g1, name) DO UPDATE
+        SET id = EXCLUDED.id,
+            lookup_id = EXCLUDED.lookup_id,
+            hash = EXCLUDED.hash,
+            hash_scheme = EXCLUDED.hash_scheme,
+            scopes = EXCLUDED.scopes,
+            created_at = EXCLUDED.created_at,
+            expires_at = EXCLUDED.expires_at,
+            last_used_at = NULL
+    `
+
+	getAPIKeyByLookupIDQuery = `
+        SELECT id, public_key_g1, name, lookup_id, hash, hash_scheme, scopes,
+               created_at, expires_at, last_used_at
+        FROM public.api_keys
+        WHERE lookup_id = $1
+    `
+
+	listAPIKeysQuery = `
+        SELECT id, public_key_g1, name, lookup_id, hash, hash_scheme, scopes,
+               created_at, expires_at, last_used_at
+        FROM public.api_keys
+        WHERE public_key_g1 = $1
+        ORDER BY created_at, name
+    `
+
+	deleteAPIKeyQuery = `
+        DELETE FROM public.api_keys
+        WHERE public_key_g1 = $1 AND name = $2
+    `
+
+	updateAPIKeyLastUsedQuery = `
+        UPDATE public.api_keys
+        SET last_used_at = GREATEST(last_used_at, $1)
+        WHERE id = $2
+    `
+)
+
+func (r *apiKeyRepo) Create(ctx context.Context, apiKey *model.APIKey) error {
+	return r.insert(ctx, createAPIKeyQuery, apiKey)
+}
+
+func (r *apiKeyRepo) Replace(ctx context.Context, apiKey *model.APIKey) error {
+	return r.insert(ctx, replaceAPIKeyQuery, apiKey)
+}
+
+// insert runs the query inserting the API key
+func (r *apiKeyRepo) insert(ctx context.Context, query string, apiKey *model.APIKey) error {
+	switch {
+	case apiKey.ID == "":
+		return errors.New("api key id is required")
+	case apiKey.PublicKeyG1 == "":
+		return errors.New("public key g1 is required")
+	case apiKey.Name == "":
+		return errors.New("api key name is required")
+	case apiKey.Hash == "":
+		return errors.New("api key hash is required")
+	case apiKey.HashScheme == "":
+		return errors.New("api key hash scheme is required")
+	}
+
+	apiKey.CreatedAt = time.Now().UTC()
+	scopes := apiKey.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		apiKey.ID,
+		apiKey.PublicKeyG1,
+		apiKey.Name,
+		sql.NullString{String: apiKey.LookupID, Valid: apiKey.LookupID != ""},
+		apiKey.Hash,
+		apiKey.HashScheme,
+		pq.Array(scopes),
+		apiKey.CreatedAt,
+		utcOrNil(apiKey.ExpiresAt),
+	)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case uniqueViolation:
+			return repository.ErrAPIKeyAlreadyExists
+		case foreignKeyViolation:
+			return repository.ErrKeyNotFound
+		}
+	}
+	return err
+}
+
+func (r *apiKeyRepo) GetByLookupID(ctx context.Context, lookupID string) (*model.APIKey, error) {
+	row := conn(ctx, r.db).QueryRowContext(ctx, getAPIKeyByLookupIDQuery, lookupID)
+	apiKey, err := scanAPIKey(row)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return apiKey, nil
+}
+
+func (r *apiKeyRepo) List(ctx context.Context, publicKeyG1 string) ([]*model.APIKey, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, listAPIKeysQuery, publicKeyG1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var apiKeys []*model.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, k)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+func (r *apiKeyRepo) Delete(ctx context.Context, publicKeyG1 string, name string) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, deleteAPIKeyQuery, publicKeyG1, name)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *apiKeyRepo) UpdateLastUsed(ctx context.Context, id string, lastUsedAt time.Time) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, updateAPIKeyLastUsedQuery,
+		lastUsedAt.UTC(),
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// scanAPIKey scans a row of the API key columns, sql.Row and sql.Rows
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (*model.APIKey, error) {
+	k := &model.APIKey{}
+	var lookupID sql.NullString
+	var expiresAt, lastUsedAt sql.NullTime
+	err := row.Scan(
+		&k.ID,
+		&k.PublicKeyG1,
+		&k.Name,
+		&lookupID,
+		&k.Hash,
+		&k.HashScheme,
+		pq.Array(&k.Scopes),
+		&k.CreatedAt,
+		&expiresAt,
+		&lastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	k.LookupID = lookupID.String
+	k.ExpiresAt = timeOrNil(expiresAt)
+	k.LastUsedAt = timeOrNil(lastUsedAt)
+	return k, nil
+}
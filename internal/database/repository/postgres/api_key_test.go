@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithContainer_APIKeyRepository(t *testing.T) {
+	testDB := SetupTestDB(t)
+	ctx := context.Background()
+
+	require.NoError(t, testDB.Repo.Create(ctx, &model.KeyMetadata{
+		PublicKeyG1: "test_key_1",
+		PublicKeyG2: "test_key_2",
+	}))
+
+	expiresAt := time.Now().UTC().Add(time.Hour).Truncate(time.Microsecond)
+	first := &model.APIKey{
+		ID:          uuid.NewString(),
+		PublicKeyG1: "test_key_1",
+		Name:        "first",
+		Hash:        "hash_1",
+		HashScheme:  model.HashSchemeSHA256,
+	}
+	second := &model.APIKey{
+		ID:          uuid.NewString(),
+		PublicKeyG1: "test_key_1",
+		Name:        "second",
+		LookupID:    "lookup_2",
+		Hash:        "hash_2",
+		HashScheme:  model.HashSchemeHMACSHA256,
+		Scopes:      []string{model.ScopeSignG1},
+		ExpiresAt:   &expiresAt,
+	}
+	require.NoError(t, testDB.APIKeyRepo.Create(ctx, first))
+	require.NoError(t, testDB.APIKeyRepo.Create(ctx, second))
+
+	// Names are unique per key and the key must exist
+	err := testDB.APIKeyRepo.Create(ctx, &model.APIKey{
+		ID:          uuid.NewString(),
+		PublicKeyG1: "test_key_1",
+		Name:        "first",
+		Hash:        "hash_3",
+		HashScheme:  model.HashSchemeSHA256,
+	})
+	assert.ErrorIs(t, err, repository.ErrAPIKeyAlreadyExists)
+	err = testDB.APIKeyRepo.Create(ctx, &model.APIKey{
+		ID:          uuid.NewString(),
+		PublicKeyG1: "missing",
+		Name:        "first",
+		Hash:        "hash_3",
+		HashScheme:  model.HashSchemeSHA256,
+	})
+	assert.ErrorIs(t, err, repository.ErrKeyNotFound)
+
+	apiKeys, err := testDB.APIKeyRepo.List(ctx, "test_key_1")
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 2)
+	assert.Equal(t, "first", apiKeys[0].Name)
+	assert.Empty(t, apiKeys[0].Scopes)
+	assert.Nil(t, apiKeys[0].ExpiresAt)
+	assert.Equal(t, "second", apiKeys[1].Name)
+	assert.Equal(t, "hash_2", apiKeys[1].Hash)
+	assert.Equal(t, []string{model.ScopeSignG1}, apiKeys[1].Scopes)
+	require.NotNil(t, apiKeys[1].ExpiresAt)
+	assert.True(t, expiresAt.Equal(*apiKeys[1].ExpiresAt))
+
+	apiKey, err := testDB.APIKeyRepo.GetByLookupID(ctx, "lookup_2")
+	require.NoError(t, err)
+	assert.Equal(t, "second", apiKey.Name)
+	assert.Equal(t, model.HashSchemeHMACSHA256, apiKey.HashScheme)
+	_, err = testDB.APIKeyRepo.GetByLookupID(ctx, "missing")
+	assert.ErrorIs(t, err, repository.ErrAPIKeyNotFound)
+	assert.Equal(t, "", apiKeys[0].LookupID)
+
+	// Replacing an API key revokes the API key of the same name in the same
+	// step, and adds it if there is none
+	require.NoError(t, testDB.APIKeyRepo.Replace(ctx, &model.APIKey{
+		ID:          "id_4",
+		PublicKeyG1: "test_key_1",
+		Name:        "second",
+		LookupID:    "lookup_4",
+		Hash:        "hash_4",
+		HashScheme:  model.HashSchemeHMACSHA256,
+	}))
+	require.NoError(t, testDB.APIKeyRepo.Replace(ctx, &model.APIKey{
+		ID:          "id_5",
+		PublicKeyG1: "test_key_1",
+		Name:        "third",
+		Hash:        "hash_5",
+		HashScheme:  model.HashSchemeSHA256,
+	}))
+	_, err = testDB.APIKeyRepo.GetByLookupID(ctx, "lookup_2")
+	assert.ErrorIs(t, err, repository.ErrAPIKeyNotFound)
+	apiKeys, err = testDB.APIKeyRepo.List(ctx, "test_key_1")
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 3)
+	assert.Equal(t, "first", apiKeys[0].Name)
+	assert.Equal(t, "id_4", apiKeys[1].ID)
+	assert.Equal(t, "second", apiKeys[1].Name)
+	assert.Empty(t, apiKeys[1].Scopes)
+	assert.Nil(t, apiKeys[1].ExpiresAt)
+	assert.Equal(t, "third", apiKeys[2].Name)
+	err = testDB.APIKeyRepo.Replace(ctx, &model.APIKey{
+		ID:          "id_6",
+		PublicKeyG1: "missing_key",
+		Name:        "default",
+		Hash:        "hash_6",
+		HashScheme:  model.HashSchemeSHA256,
+	})
+	assert.ErrorIs(t, err, repository.ErrKeyNotFound)
+
+	// The last use never moves backwards
+	lastUsedAt := time.Now().UTC().Truncate(time.Microsecond)
+	require.NoError(t, testDB.APIKeyRepo.UpdateLastUsed(ctx, first.ID, lastUsedAt))
+	require.NoError(t, testDB.APIKeyRepo.UpdateLastUsed(ctx, first.ID, lastUsedAt.Add(-time.Hour)))
+	apiKeys, err = testDB.APIKeyRepo.List(ctx, "test_key_1")
+	require.NoError(t, err)
+	require.NotNil(t, apiKeys[0].LastUsedAt)
+	assert.True(t, lastUsedAt.Equal(*apiKeys[0].LastUsedAt))
+
+	require.NoError(t, testDB.APIKeyRepo.Delete(ctx, "test_key_1", "first"))
+	err = testDB.APIKeyRepo.Delete(ctx, "test_key_1", "first")
+	assert.ErrorIs(t, err, repository.ErrAPIKeyNotFound)
+
+	// The API keys are deleted with their key
+	require.NoError(t, testDB.Repo.Delete(ctx, "test_key_1"))
+	apiKeys, err = testDB.APIKeyRepo.List(ctx, "test_key_1")
+	require.NoError(t, err)
+	assert.Empty(t, apiKeys)
+}
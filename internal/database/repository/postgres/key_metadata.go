@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Layr-Labs/cerberus/internal/database/model"
@@ -23,12 +25,14 @@ func NewKeyMetadataRepository(db *sql.DB) repository.KeyMetadataRepository {
 const (
 	createKeyMetadataQuery = `
         INSERT INTO public.keys_metadata (
-            public_key_g1, public_key_g2, created_at, updated_at, api_key_hash
-        ) VALUES ($1, $2, $3, $4, $5)
+            public_key_g1, public_key_g2, created_at, updated_at,
+            not_before, not_after, store_name, key_version, key_version_pinned
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
     `
 
 	getKeyMetadataQuery = `
-        SELECT public_key_g1, public_key_g2, created_at, updated_at, api_key_hash, locked
+        SELECT public_key_g1, public_key_g2, created_at, updated_at, locked,
+               not_before, not_after, store_name, key_version, key_version_pinned
         FROM public.keys_metadata
         WHERE public_key_g1 = $1
     `
@@ -39,12 +43,6 @@ const (
         WHERE public_key_g1 = $2
     `
 
-	updateAPIKeyHashQuery = `
-        UPDATE public.keys_metadata
-        SET api_key_hash = $1, updated_at = $2
-        WHERE public_key_g1 = $3
-    `
-
 	updateLockStatusQuery = `
         UPDATE public.keys_metadata
         SET locked = $1, updated_at = $2
@@ -56,13 +54,40 @@ const (
         WHERE public_key_g1 = $1
     `
 
+	updateValidityQuery = `
+        UPDATE public.keys_metadata
+        SET not_before = $1, not_after = $2, updated_at = $3
+        WHERE public_key_g1 = $4
+    `
+
+	updateStoreNameQuery = `
+        UPDATE public.keys_metadata
+        SET store_name = $1, updated_at = $2
+        WHERE public_key_g1 = $3
+    `
+
+	updateKeyVersionQuery = `
+        UPDATE public.keys_metadata
+        SET key_version = $1, key_version_pinned = $2, updated_at = $3
+        WHERE public_key_g1 = $4
+    `
+
 	listAllKeysQuery = `
-        SELECT public_key_g1, public_key_g2, created_at, updated_at, locked
+        SELECT public_key_g1, public_key_g2, created_at, updated_at, locked,
+               not_before, not_after, store_name, key_version, key_version_pinned
         FROM public.keys_metadata
         ORDER BY created_at DESC
     `
+
+	listKeysPageQuery = `
+        SELECT public_key_g1, public_key_g2, created_at, updated_at, locked,
+               not_before, not_after, store_name, key_version, key_version_pinned
+        FROM public.keys_metadata
+    `
 )
 
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 func (r *keyMetadataRepo) Create(ctx context.Context, metadata *model.KeyMetadata) error {
 	if metadata.PublicKeyG1 == "" {
 		return errors.New("public key g1 is required")
@@ -75,25 +100,34 @@ func (r *keyMetadataRepo) Create(ctx context.Context, metadata *model.KeyMetadat
 	metadata.CreatedAt = now
 	metadata.UpdatedAt = now
 
-	_, err := r.db.ExecContext(ctx, createKeyMetadataQuery,
+	_, err := conn(ctx, r.db).ExecContext(ctx, createKeyMetadataQuery,
 		metadata.PublicKeyG1,
 		metadata.PublicKeyG2,
 		metadata.CreatedAt,
 		metadata.UpdatedAt,
-		metadata.ApiKeyHash,
+		utcOrNil(metadata.NotBefore),
+		utcOrNil(metadata.NotAfter),
+		metadata.StoreName,
+		metadata.KeyVersion,
+		metadata.KeyVersionPinned,
 	)
 	return err
 }
 
 func (r *keyMetadataRepo) Get(ctx context.Context, publicKeyG1 string) (*model.KeyMetadata, error) {
 	metadata := &model.KeyMetadata{}
-	err := r.db.QueryRowContext(ctx, getKeyMetadataQuery, publicKeyG1).Scan(
+	var notBefore, notAfter sql.NullTime
+	err := conn(ctx, r.db).QueryRowContext(ctx, getKeyMetadataQuery, publicKeyG1).Scan(
 		&metadata.PublicKeyG1,
 		&metadata.PublicKeyG2,
 		&metadata.CreatedAt,
 		&metadata.UpdatedAt,
-		&metadata.ApiKeyHash,
 		&metadata.Locked,
+		&notBefore,
+		&notAfter,
+		&metadata.StoreName,
+		&metadata.KeyVersion,
+		&metadata.KeyVersionPinned,
 	)
 	if err == sql.ErrNoRows {
 		return nil, repository.ErrKeyNotFound
@@ -101,6 +135,8 @@ func (r *keyMetadataRepo) Get(ctx context.Context, publicKeyG1 string) (*model.K
 	if err != nil {
 		return nil, err
 	}
+	metadata.NotBefore = timeOrNil(notBefore)
+	metadata.NotAfter = timeOrNil(notAfter)
 	return metadata, nil
 }
 
@@ -110,7 +146,7 @@ func (r *keyMetadataRepo) Update(ctx context.Context, metadata *model.KeyMetadat
 	}
 
 	metadata.UpdatedAt = time.Now().UTC()
-	result, err := r.db.ExecContext(ctx, updateKeyMetadataQuery,
+	result, err := conn(ctx, r.db).ExecContext(ctx, updateKeyMetadataQuery,
 		metadata.UpdatedAt,
 		metadata.PublicKeyG1,
 	)
@@ -128,35 +164,12 @@ func (r *keyMetadataRepo) Update(ctx context.Context, metadata *model.KeyMetadat
 	return nil
 }
 
-func (r *keyMetadataRepo) UpdateAPIKeyHash(
-	ctx context.Context,
-	publicKeyG1 string,
-	apiKeyHash string,
-) error {
-	if publicKeyG1 == "" {
-		return errors.New("public key g1 is required")
-	}
-
-	if apiKeyHash == "" {
-		return errors.New("api key hash is required")
-	}
-
-	updatedAt := time.Now().UTC()
-
-	_, err := r.db.ExecContext(ctx, updateAPIKeyHashQuery,
-		apiKeyHash,
-		updatedAt,
-		publicKeyG1,
-	)
-	return err
-}
-
 func (r *keyMetadataRepo) Delete(ctx context.Context, publicKeyG1 string) error {
 	if publicKeyG1 == "" {
 		return errors.New("public key g1 is required")
 	}
 
-	result, err := r.db.ExecContext(ctx, deleteKeyMetadataQuery, publicKeyG1)
+	result, err := conn(ctx, r.db).ExecContext(ctx, deleteKeyMetadataQuery, publicKeyG1)
 	if err != nil {
 		return err
 	}
@@ -172,7 +185,7 @@ func (r *keyMetadataRepo) Delete(ctx context.Context, publicKeyG1 string) error
 }
 
 func (r *keyMetadataRepo) List(ctx context.Context) ([]*model.KeyMetadata, error) {
-	rows, err := r.db.QueryContext(ctx, listAllKeysQuery)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, listAllKeysQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -181,16 +194,24 @@ func (r *keyMetadataRepo) List(ctx context.Context) ([]*model.KeyMetadata, error
 	var metadata []*model.KeyMetadata
 	for rows.Next() {
 		m := &model.KeyMetadata{}
+		var notBefore, notAfter sql.NullTime
 		err := rows.Scan(
 			&m.PublicKeyG1,
 			&m.PublicKeyG2,
 			&m.CreatedAt,
 			&m.UpdatedAt,
 			&m.Locked,
+			&notBefore,
+			&notAfter,
+			&m.StoreName,
+			&m.KeyVersion,
+			&m.KeyVersionPinned,
 		)
 		if err != nil {
 			return nil, err
 		}
+		m.NotBefore = timeOrNil(notBefore)
+		m.NotAfter = timeOrNil(notAfter)
 		metadata = append(metadata, m)
 	}
 
@@ -207,10 +228,216 @@ func (r *keyMetadataRepo) UpdateLockStatus(
 ) error {
 	updatedAt := time.Now().UTC()
 
-	_, err := r.db.ExecContext(ctx, updateLockStatusQuery,
+	_, err := conn(ctx, r.db).ExecContext(ctx, updateLockStatusQuery,
 		locked,
 		updatedAt,
 		publicKeyG1,
 	)
 	return err
 }
+
+func (r *keyMetadataRepo) UpdateValidity(
+	ctx context.Context,
+	publicKeyG1 string,
+	notBefore *time.Time,
+	notAfter *time.Time,
+) error {
+	if publicKeyG1 == "" {
+		return errors.New("public key g1 is required")
+	}
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, updateValidityQuery,
+		utcOrNil(notBefore),
+		utcOrNil(notAfter),
+		time.Now().UTC(),
+		publicKeyG1,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrKeyNotFound
+	}
+	return nil
+}
+
+func (r *keyMetadataRepo) UpdateStoreName(
+	ctx context.Context,
+	publicKeyG1 string,
+	storeName string,
+) error {
+	if publicKeyG1 == "" {
+		return errors.New("public key g1 is required")
+	}
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, updateStoreNameQuery,
+		storeName,
+		time.Now().UTC(),
+		publicKeyG1,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrKeyNotFound
+	}
+	return nil
+}
+
+func (r *keyMetadataRepo) UpdateKeyVersion(
+	ctx context.Context,
+	publicKeyG1 string,
+	keyVersion string,
+	pinned bool,
+) error {
+	if publicKeyG1 == "" {
+		return errors.New("public key g1 is required")
+	}
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, updateKeyVersionQuery,
+		keyVersion,
+		pinned,
+		time.Now().UTC(),
+		publicKeyG1,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrKeyNotFound
+	}
+	return nil
+}
+
+func (r *keyMetadataRepo) ListPage(
+	ctx context.Context,
+	opts *repository.ListOptions,
+) ([]*model.KeyMetadata, string, error) {
+	if opts == nil {
+		opts = &repository.ListOptions{}
+	}
+
+	pageSize := opts.PageSize
+	if pageSize < 0 {
+		return nil, "", errors.New("page size must not be negative")
+	}
+	if pageSize > repository.MaxPageSize {
+		pageSize = repository.MaxPageSize
+	}
+
+	var conditions []string
+	var args []interface{}
+	addCondition := func(format string, values ...interface{}) {
+		placeholders := make([]interface{}, len(values))
+		for i, v := range values {
+			args = append(args, v)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		conditions = append(conditions, fmt.Sprintf(format, placeholders...))
+	}
+
+	if opts.Locked != nil {
+		addCondition("locked = %s", *opts.Locked)
+	}
+	if !opts.CreatedAfter.IsZero() {
+		addCondition("created_at >= %s", opts.CreatedAfter.UTC())
+	}
+	if !opts.CreatedBefore.IsZero() {
+		addCondition("created_at < %s", opts.CreatedBefore.UTC())
+	}
+	if opts.PublicKeyG1Prefix != "" {
+		addCondition("public_key_g1 LIKE %s", likeEscaper.Replace(opts.PublicKeyG1Prefix)+"%")
+	}
+	if opts.PageToken != "" {
+		cursor, err := repository.DecodePageToken(opts.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		addCondition("(created_at, public_key_g1) < (%s, %s)", cursor.CreatedAt, cursor.PublicKeyG1)
+	}
+
+	query := listKeysPageQuery
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, public_key_g1 DESC"
+	if pageSize > 0 {
+		// Fetch one extra row to know whether there is a next page
+		args = append(args, pageSize+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var metadata []*model.KeyMetadata
+	for rows.Next() {
+		m := &model.KeyMetadata{}
+		var notBefore, notAfter sql.NullTime
+		err := rows.Scan(
+			&m.PublicKeyG1,
+			&m.PublicKeyG2,
+			&m.CreatedAt,
+			&m.UpdatedAt,
+			&m.Locked,
+			&notBefore,
+			&notAfter,
+			&m.StoreName,
+			&m.KeyVersion,
+			&m.KeyVersionPinned,
+		)
+		if err != nil {
+			return nil, "", err
+		}
+		m.NotBefore = timeOrNil(notBefore)
+		m.NotAfter = timeOrNil(notAfter)
+		metadata = append(metadata, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var nextPageToken string
+	if pageSize > 0 && len(metadata) > pageSize {
+		metadata = metadata[:pageSize]
+		last := metadata[pageSize-1]
+		nextPageToken = repository.EncodePageToken(repository.PageCursor{
+			CreatedAt:   last.CreatedAt,
+			PublicKeyG1: last.PublicKeyG1,
+		})
+	}
+	return metadata, nextPageToken, nil
+}
+
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+func timeOrNil(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
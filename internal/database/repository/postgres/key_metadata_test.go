@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -266,27 +267,165 @@ func TestKeyMetadataRepository_List(t *testing.T) {
 	})
 }
 
-func TestKeyMetadataRepository_UpdateAPIKeyHash(t *testing.T) {
+func TestKeyMetadataRepository_ListPage(t *testing.T) {
 	testDB := SetupTestDB(t)
-	// No need to defer db.Close() as it's handled by t.Cleanup
+	ctx := context.Background()
 
-	// Create initial test data
+	// Create test data
+	testKeys := []*model.KeyMetadata{
+		{PublicKeyG1: "aa01", PublicKeyG2: "g2_1"},
+		{PublicKeyG1: "aa02", PublicKeyG2: "g2_2"},
+		{PublicKeyG1: "bb03", PublicKeyG2: "g2_3"},
+		{PublicKeyG1: "bb04", PublicKeyG2: "g2_4"},
+		{PublicKeyG1: "cc05", PublicKeyG2: "g2_5"},
+	}
+	for _, key := range testKeys {
+		err := testDB.Repo.Create(ctx, key)
+		require.NoError(t, err)
+	}
+	err := testDB.Repo.UpdateLockStatus(ctx, "bb03", true)
+	require.NoError(t, err)
+
+	t.Run("paginate through all keys", func(t *testing.T) {
+		var seen []string
+		pageToken := ""
+		for {
+			page, nextPageToken, err := testDB.Repo.ListPage(ctx, &repository.ListOptions{
+				PageSize:  2,
+				PageToken: pageToken,
+			})
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(page), 2)
+			for _, key := range page {
+				seen = append(seen, key.PublicKeyG1)
+			}
+			if nextPageToken == "" {
+				break
+			}
+			pageToken = nextPageToken
+		}
+		assert.Equal(t, []string{"cc05", "bb04", "bb03", "aa02", "aa01"}, seen)
+	})
+
+	t.Run("filter by lock status", func(t *testing.T) {
+		locked := true
+		page, nextPageToken, err := testDB.Repo.ListPage(ctx, &repository.ListOptions{
+			Locked: &locked,
+		})
+		require.NoError(t, err)
+		assert.Empty(t, nextPageToken)
+		require.Len(t, page, 1)
+		assert.Equal(t, "bb03", page[0].PublicKeyG1)
+	})
+
+	t.Run("filter by public key prefix", func(t *testing.T) {
+		page, _, err := testDB.Repo.ListPage(ctx, &repository.ListOptions{
+			PublicKeyG1Prefix: "aa",
+		})
+		require.NoError(t, err)
+		require.Len(t, page, 2)
+		assert.Equal(t, "aa02", page[0].PublicKeyG1)
+		assert.Equal(t, "aa01", page[1].PublicKeyG1)
+	})
+
+	t.Run("filter by creation time", func(t *testing.T) {
+		page, _, err := testDB.Repo.ListPage(ctx, &repository.ListOptions{
+			CreatedAfter: time.Now().UTC().Add(time.Hour),
+		})
+		require.NoError(t, err)
+		assert.Empty(t, page)
+	})
+
+	t.Run("invalid page token", func(t *testing.T) {
+		_, _, err := testDB.Repo.ListPage(ctx, &repository.ListOptions{
+			PageToken: "not-a-token",
+		})
+		assert.ErrorIs(t, err, repository.ErrInvalidPageToken)
+	})
+}
+
+func TestKeyMetadataRepository_UpdateValidity(t *testing.T) {
+	testDB := SetupTestDB(t)
+	ctx := context.Background()
+
+	notAfter := time.Now().UTC().Add(24 * time.Hour).Truncate(time.Microsecond)
 	initialKey := &model.KeyMetadata{
 		PublicKeyG1: "test_key_1",
 		PublicKeyG2: "test_key_2",
-		ApiKeyHash:  "test_api_key_hash",
+		NotAfter:    &notAfter,
 	}
-	err := testDB.Repo.Create(context.Background(), initialKey)
+	require.NoError(t, testDB.Repo.Create(ctx, initialKey))
+
+	result, err := testDB.Repo.Get(ctx, initialKey.PublicKeyG1)
 	require.NoError(t, err)
+	assert.Nil(t, result.NotBefore)
+	require.NotNil(t, result.NotAfter)
+	assert.True(t, notAfter.Equal(*result.NotAfter))
 
-	apiKeyHash := "test_api_key_hash_2"
+	notBefore := time.Now().UTC().Add(time.Hour).Truncate(time.Microsecond)
+	err = testDB.Repo.UpdateValidity(ctx, initialKey.PublicKeyG1, &notBefore, nil)
+	require.NoError(t, err)
 
-	err = testDB.Repo.UpdateAPIKeyHash(context.Background(), initialKey.PublicKeyG1, apiKeyHash)
+	result, err = testDB.Repo.Get(ctx, initialKey.PublicKeyG1)
 	require.NoError(t, err)
+	require.NotNil(t, result.NotBefore)
+	assert.True(t, notBefore.Equal(*result.NotBefore))
+	assert.Nil(t, result.NotAfter)
 
-	// Verify the update
-	result, err := testDB.Repo.Get(context.Background(), initialKey.PublicKeyG1)
-	assert.NoError(t, err)
-	assert.Equal(t, apiKeyHash, result.ApiKeyHash)
-	assert.WithinDuration(t, time.Now(), result.UpdatedAt, 2*time.Second)
+	err = testDB.Repo.UpdateValidity(ctx, "non_existent_key", nil, nil)
+	assert.ErrorIs(t, err, repository.ErrKeyNotFound)
+}
+
+func TestKeyMetadataRepository_UpdateStoreName(t *testing.T) {
+	testDB := SetupTestDB(t)
+	ctx := context.Background()
+
+	initialKey := &model.KeyMetadata{
+		PublicKeyG1: "test_key_1",
+		PublicKeyG2: "test_key_2",
+		StoreName:   "disk",
+	}
+	require.NoError(t, testDB.Repo.Create(ctx, initialKey))
+
+	result, err := testDB.Repo.Get(ctx, initialKey.PublicKeyG1)
+	require.NoError(t, err)
+	assert.Equal(t, "disk", result.StoreName)
+
+	err = testDB.Repo.UpdateStoreName(ctx, initialKey.PublicKeyG1, "hsm")
+	require.NoError(t, err)
+
+	result, err = testDB.Repo.Get(ctx, initialKey.PublicKeyG1)
+	require.NoError(t, err)
+	assert.Equal(t, "hsm", result.StoreName)
+
+	err = testDB.Repo.UpdateStoreName(ctx, "non_existent_key", "hsm")
+	assert.ErrorIs(t, err, repository.ErrKeyNotFound)
+}
+
+func TestKeyMetadataRepository_UpdateKeyVersion(t *testing.T) {
+	testDB := SetupTestDB(t)
+	ctx := context.Background()
+
+	initialKey := &model.KeyMetadata{
+		PublicKeyG1: "test_key_1",
+		PublicKeyG2: "test_key_2",
+		KeyVersion:  "1",
+	}
+	require.NoError(t, testDB.Repo.Create(ctx, initialKey))
+
+	result, err := testDB.Repo.Get(ctx, initialKey.PublicKeyG1)
+	require.NoError(t, err)
+	assert.Equal(t, "1", result.KeyVersion)
+	assert.False(t, result.KeyVersionPinned)
+
+	err = testDB.Repo.UpdateKeyVersion(ctx, initialKey.PublicKeyG1, "2", true)
+	require.NoError(t, err)
+
+	result, err = testDB.Repo.Get(ctx, initialKey.PublicKeyG1)
+	require.NoError(t, err)
+	assert.Equal(t, "2", result.KeyVersion)
+	assert.True(t, result.KeyVersionPinned)
+
+	err = testDB.Repo.UpdateKeyVersion(ctx, "non_existent_key", "2", false)
+	assert.ErrorIs(t, err, repository.ErrKeyNotFound)
 }
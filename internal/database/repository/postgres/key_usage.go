@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+)
+
+type keyUsageRepo struct {
+	db *sql.DB
+}
+
+func NewKeyUsageRepository(db *sql.DB) repository.KeyUsageRepository {
+	return &keyUsageRepo{
+		db: db,
+	}
+}
+
+const (
+	recordKeyUsageQuery = `
+        INSERT INTO public.key_usage (
+            public_key_g1, method, signature_count, last_used_at
+        ) VALUES ($1, $2, $3, $4)
+        ON CONFLICT (public_key_g1, method) DO UPDATE
+        SET signature_count = key_usage.signature_count + EXCLUDED.signature_count,
+            last_used_at = GREATEST(key_usage.last_used_at, EXCLUDED.last_used_at)
+    `
+
+	getKeyUsageQuery = `
+        SELECT public_key_g1, method, signature_count, last_used_at
+        FROM public.key_usage
+        WHERE public_key_g1 = $1
+        ORDER BY method
+    `
+
+	listKeysUsageQuery = `
+        SELECT public_key_g1, method, signature_count, last_used_at
+        FROM public.key_usage
+        WHERE public_key_g1 = ANY($1)
+        ORDER BY public_key_g1, method
+    `
+
+	listUnusedKeysQuery = `
+        SELECT km.public_key_g1, km.created_at, u.last_used_at, COALESCE(u.signature_count, 0)
+        FROM public.keys_metadata km
+        LEFT JOIN (
+            SELECT public_key_g1,
+                   MAX(last_used_at) AS last_used_at,
+                   SUM(signature_count) AS signature_count
+            FROM public.key_usage
+            GROUP BY public_key_g1
+        ) u ON u.public_key_g1 = km.public_key_g1
+        WHERE COALESCE(u.last_used_at, km.created_at) < $1
+        ORDER BY COALESCE(u.last_used_at, km.created_at)
+    `
+)
+
+func (r *keyUsageRepo) RecordUsage(ctx context.Context, usage []*model.KeyUsage) error {
+	if len(usage) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, recordKeyUsageQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	// Concurrent flushes of other signers lock the rows in the same order, so
+	// they wait for each other rather than deadlock
+	sorted := make([]*model.KeyUsage, len(usage))
+	copy(sorted, usage)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].PublicKeyG1 != sorted[j].PublicKeyG1 {
+			return sorted[i].PublicKeyG1 < sorted[j].PublicKeyG1
+		}
+		return sorted[i].Method < sorted[j].Method
+	})
+
+	for _, u := range sorted {
+		_, err := stmt.ExecContext(ctx,
+			u.PublicKeyG1,
+			u.Method,
+			u.SignatureCount,
+			u.LastUsedAt.UTC(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *keyUsageRepo) Get(ctx context.Context, publicKeyG1 string) ([]*model.KeyUsage, error) {
+	return r.query(ctx, getKeyUsageQuery, publicKeyG1)
+}
+
+func (r *keyUsageRepo) ListForKeys(
+	ctx context.Context,
+	publicKeyG1s []string,
+) ([]*model.KeyUsage, error) {
+	if len(publicKeyG1s) == 0 {
+		return nil, nil
+	}
+	return r.query(ctx, listKeysUsageQuery, pq.Array(publicKeyG1s))
+}
+
+func (r *keyUsageRepo) ListUnusedSince(
+	ctx context.Context,
+	since time.Time,
+) ([]*model.KeyUsageSummary, error) {
+	rows, err := r.db.QueryContext(ctx, listUnusedKeysQuery, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []*model.KeyUsageSummary
+	for rows.Next() {
+		s := &model.KeyUsageSummary{}
+		var lastUsedAt sql.NullTime
+		err := rows.Scan(
+			&s.PublicKeyG1,
+			&s.CreatedAt,
+			&lastUsedAt,
+			&s.SignatureCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if lastUsedAt.Valid {
+			s.LastUsedAt = &lastUsedAt.Time
+		}
+		summaries = append(summaries, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+func (r *keyUsageRepo) query(
+	ctx context.Context,
+	query string,
+	args ...interface{},
+) ([]*model.KeyUsage, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usage []*model.KeyUsage
+	for rows.Next() {
+		u := &model.KeyUsage{}
+		err := rows.Scan(
+			&u.PublicKeyG1,
+			&u.Method,
+			&u.SignatureCount,
+			&u.LastUsedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyUsageRepository_RecordUsage(t *testing.T) {
+	testDB := SetupTestDB(t)
+	ctx := context.Background()
+
+	firstUse := time.Now().UTC().Add(-time.Minute).Truncate(time.Microsecond)
+	secondUse := time.Now().UTC().Truncate(time.Microsecond)
+
+	err := testDB.UsageRepo.RecordUsage(ctx, []*model.KeyUsage{
+		{
+			PublicKeyG1:    "test_key_1",
+			Method:         "SignGeneric",
+			SignatureCount: 2,
+			LastUsedAt:     secondUse,
+		},
+		{
+			PublicKeyG1:    "test_key_1",
+			Method:         "SignG1",
+			SignatureCount: 1,
+			LastUsedAt:     firstUse,
+		},
+	})
+	require.NoError(t, err)
+
+	// Counts add up and the last used time never moves backwards
+	err = testDB.UsageRepo.RecordUsage(ctx, []*model.KeyUsage{
+		{
+			PublicKeyG1:    "test_key_1",
+			Method:         "SignGeneric",
+			SignatureCount: 3,
+			LastUsedAt:     firstUse,
+		},
+	})
+	require.NoError(t, err)
+
+	usage, err := testDB.UsageRepo.Get(ctx, "test_key_1")
+	require.NoError(t, err)
+	require.Len(t, usage, 2)
+	assert.Equal(t, "SignG1", usage[0].Method)
+	assert.Equal(t, int64(1), usage[0].SignatureCount)
+	assert.Equal(t, "SignGeneric", usage[1].Method)
+	assert.Equal(t, int64(5), usage[1].SignatureCount)
+	assert.WithinDuration(t, secondUse, usage[1].LastUsedAt, time.Millisecond)
+
+	all, err := testDB.UsageRepo.ListForKeys(ctx, []string{"test_key_1", "test_key_2"})
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	all, err = testDB.UsageRepo.ListForKeys(ctx, []string{"test_key_2"})
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
+
+func TestKeyUsageRepository_ListUnusedSince(t *testing.T) {
+	testDB := SetupTestDB(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"used_key", "unused_key"} {
+		err := testDB.Repo.Create(ctx, &model.KeyMetadata{
+			PublicKeyG1: key,
+			PublicKeyG2: key + "_g2",
+		})
+		require.NoError(t, err)
+	}
+
+	err := testDB.UsageRepo.RecordUsage(ctx, []*model.KeyUsage{
+		{
+			PublicKeyG1:    "used_key",
+			Method:         "SignGeneric",
+			SignatureCount: 1,
+			LastUsedAt:     time.Now().UTC().Add(time.Minute),
+		},
+	})
+	require.NoError(t, err)
+
+	unused, err := testDB.UsageRepo.ListUnusedSince(ctx, time.Now().UTC().Add(time.Second))
+	require.NoError(t, err)
+	require.Len(t, unused, 1)
+	assert.Equal(t, "unused_key", unused[0].PublicKeyG1)
+	assert.Nil(t, unused[0].LastUsedAt)
+	assert.Zero(t, unused[0].SignatureCount)
+
+	unused, err = testDB.UsageRepo.ListUnusedSince(ctx, time.Now().UTC().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, unused)
+}
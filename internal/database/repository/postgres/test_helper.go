@@ -81,10 +81,44 @@ func CreateTestContainer(t *testing.T) (*TestContainer, error) {
         CREATE TABLE IF NOT EXISTS public.keys_metadata (
             public_key_g1 VARCHAR(255) PRIMARY KEY,
             public_key_g2 VARCHAR(255) NOT NULL,
-            created_at TIMESTAMP NOT NULL DEFAULT NOW(),
-            updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
             api_key_hash text,
-            locked boolean DEFAULT false
+            locked boolean DEFAULT false,
+            not_before TIMESTAMPTZ,
+            not_after TIMESTAMPTZ,
+            store_name TEXT NOT NULL DEFAULT '',
+            key_version TEXT NOT NULL DEFAULT '',
+            key_version_pinned BOOLEAN NOT NULL DEFAULT false
+        );
+
+        CREATE TABLE IF NOT EXISTS public.key_usage (
+            public_key_g1 VARCHAR(255) NOT NULL,
+            method VARCHAR(64) NOT NULL,
+            signature_count BIGINT NOT NULL DEFAULT 0,
+            last_used_at TIMESTAMPTZ NOT NULL,
+            PRIMARY KEY (public_key_g1, method)
+        );
+
+        CREATE TABLE IF NOT EXISTS public.key_store (
+            public_key_g1 VARCHAR(255) PRIMARY KEY,
+            payload BYTEA NOT NULL,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+        );
+
+        CREATE TABLE IF NOT EXISTS public.api_keys (
+            id UUID PRIMARY KEY,
+            public_key_g1 VARCHAR(255) NOT NULL
+                REFERENCES public.keys_metadata (public_key_g1) ON DELETE CASCADE,
+            name VARCHAR(255) NOT NULL,
+            lookup_id VARCHAR(32) UNIQUE,
+            hash TEXT NOT NULL,
+            hash_scheme VARCHAR(32) NOT NULL DEFAULT 'sha256',
+            scopes TEXT[] NOT NULL DEFAULT '{}',
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            expires_at TIMESTAMPTZ,
+            last_used_at TIMESTAMPTZ,
+            UNIQUE (public_key_g1, name)
         );
     `)
 	if err != nil {
@@ -98,8 +132,10 @@ func CreateTestContainer(t *testing.T) (*TestContainer, error) {
 }
 
 type testDB struct {
-	db   *sql.DB
-	Repo *keyMetadataRepo
+	db         *sql.DB
+	Repo       *keyMetadataRepo
+	UsageRepo  *keyUsageRepo
+	APIKeyRepo *apiKeyRepo
 }
 
 // Modified test setup function
@@ -118,7 +154,9 @@ func SetupTestDB(t *testing.T) *testDB {
 	})
 
 	return &testDB{
-		db:   container.DB,
-		Repo: &keyMetadataRepo{db: container.DB},
+		db:         container.DB,
+		Repo:       &keyMetadataRepo{db: container.DB},
+		UsageRepo:  &keyUsageRepo{db: container.DB},
+		APIKeyRepo: &apiKeyRepo{db: container.DB},
 	}
 }
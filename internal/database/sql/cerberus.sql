@@ -3,8 +3,45 @@ CREATE SCHEMA IF NOT EXISTS public;
 CREATE TABLE IF NOT EXISTS public.keys_metadata (
     public_key_g1 VARCHAR(255) PRIMARY KEY,
     public_key_g2 VARCHAR(255) NOT NULL,
-    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
-    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     api_key_hash text,
-    locked boolean DEFAULT false
+    locked boolean DEFAULT false,
+    not_before TIMESTAMPTZ,
+    not_after TIMESTAMPTZ,
+    store_name TEXT NOT NULL DEFAULT '',
+    key_version TEXT NOT NULL DEFAULT '',
+    key_version_pinned BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS keys_metadata_created_at_public_key_g1_idx
+    ON public.keys_metadata (created_at DESC, public_key_g1 DESC);
+
+CREATE TABLE IF NOT EXISTS public.key_usage (
+    public_key_g1 VARCHAR(255) NOT NULL,
+    method VARCHAR(64) NOT NULL,
+    signature_count BIGINT NOT NULL DEFAULT 0,
+    last_used_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (public_key_g1, method)
+);
+
+CREATE TABLE IF NOT EXISTS public.key_store (
+    public_key_g1 VARCHAR(255) PRIMARY KEY,
+    payload BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS public.api_keys (
+    id UUID PRIMARY KEY,
+    public_key_g1 VARCHAR(255) NOT NULL
+        REFERENCES public.keys_metadata (public_key_g1) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    lookup_id VARCHAR(32) UNIQUE,
+    hash TEXT NOT NULL,
+    hash_scheme VARCHAR(32) NOT NULL DEFAULT 'sha256',
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    UNIQUE (public_key_g1, name)
 );
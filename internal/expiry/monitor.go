@@ -0,0 +1,147 @@
+package expiry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+	"github.com/Layr-Labs/cerberus/internal/metrics"
+)
+
+const checkTimeout = 30 * time.Second
+
+// Result is the outcome of a single expiry check
+type Result struct {
+	// Locked are the keys locked by the check because they expired
+	Locked []string
+
+	// ExpiringSoon are the unlocked keys expiring within the warning period
+	ExpiringSoon []string
+}
+
+// Monitor periodically locks the keys whose validity window has ended and
+// warns about the keys about to expire
+type Monitor struct {
+	keyMetadataRepo repository.KeyMetadataRepository
+	metrics         metrics.KeyExpiryRecorder
+	checkInterval   time.Duration
+	warningPeriod   time.Duration
+	logger          *slog.Logger
+
+	// tracked are the keys with a not_after gauge set by the last check
+	tracked map[string]struct{}
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewMonitor(
+	keyMetadataRepo repository.KeyMetadataRepository,
+	metrics metrics.KeyExpiryRecorder,
+	checkInterval time.Duration,
+	warningPeriod time.Duration,
+	logger *slog.Logger,
+) *Monitor {
+	return &Monitor{
+		keyMetadataRepo: keyMetadataRepo,
+		metrics:         metrics,
+		checkInterval:   checkInterval,
+		warningPeriod:   warningPeriod,
+		logger:          logger.With("component", "key-expiry"),
+		tracked:         make(map[string]struct{}),
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+// Start checks the keys right away and then periodically until Stop is called
+func (m *Monitor) Start() {
+	go func() {
+		defer close(m.done)
+		m.checkWithTimeout()
+
+		ticker := time.NewTicker(m.checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.checkWithTimeout()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic checks
+func (m *Monitor) Stop() {
+	close(m.stop)
+	<-m.done
+}
+
+// Check locks the unlocked keys that expired before now and reports the
+// keys expiring within the warning period
+func (m *Monitor) Check(ctx context.Context, now time.Time) (*Result, error) {
+	keys, err := m.keyMetadataRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	result := &Result{}
+	tracked := make(map[string]struct{})
+	for _, key := range keys {
+		if key.Locked || key.NotAfter == nil {
+			continue
+		}
+
+		if !now.Before(*key.NotAfter) {
+			if err := m.keyMetadataRepo.UpdateLockStatus(ctx, key.PublicKeyG1, true); err != nil {
+				m.logger.Error(
+					fmt.Sprintf("Failed to lock expired key %s: %v", key.PublicKeyG1, err),
+				)
+				continue
+			}
+			m.logger.Warn(fmt.Sprintf(
+				"Locked key %s which expired at %s",
+				key.PublicKeyG1,
+				key.NotAfter.Format(time.RFC3339),
+			))
+			m.metrics.RecordExpiredKeyLocked()
+			result.Locked = append(result.Locked, key.PublicKeyG1)
+			continue
+		}
+
+		m.metrics.SetNotAfter(key.PublicKeyG1, float64(key.NotAfter.Unix()))
+		tracked[key.PublicKeyG1] = struct{}{}
+
+		if key.NotAfter.Sub(now) <= m.warningPeriod {
+			m.logger.Warn(fmt.Sprintf(
+				"Key %s expires at %s",
+				key.PublicKeyG1,
+				key.NotAfter.Format(time.RFC3339),
+			))
+			result.ExpiringSoon = append(result.ExpiringSoon, key.PublicKeyG1)
+		}
+	}
+
+	// Drop the gauges of the keys that were locked, deleted or lost their expiry
+	for publicKeyG1 := range m.tracked {
+		if _, ok := tracked[publicKeyG1]; !ok {
+			m.metrics.DeleteNotAfter(publicKeyG1)
+		}
+	}
+	m.tracked = tracked
+	m.metrics.SetKeysExpiringSoon(len(result.ExpiringSoon))
+
+	return result, nil
+}
+
+func (m *Monitor) checkWithTimeout() {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	if _, err := m.Check(ctx, time.Now().UTC()); err != nil {
+		m.logger.Error(fmt.Sprintf("Failed to check key expiry: %v", err))
+	}
+}
@@ -0,0 +1,106 @@
+package expiry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+	"github.com/Layr-Labs/cerberus/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeKeyMetadataRepo struct {
+	repository.KeyMetadataRepository
+	keys    []*model.KeyMetadata
+	lockErr error
+}
+
+func (r *fakeKeyMetadataRepo) List(_ context.Context) ([]*model.KeyMetadata, error) {
+	return r.keys, nil
+}
+
+func (r *fakeKeyMetadataRepo) UpdateLockStatus(
+	_ context.Context,
+	publicKeyG1 string,
+	locked bool,
+) error {
+	if r.lockErr != nil {
+		return r.lockErr
+	}
+	for _, k := range r.keys {
+		if k.PublicKeyG1 == publicKeyG1 {
+			k.Locked = locked
+			return nil
+		}
+	}
+	return repository.ErrKeyNotFound
+}
+
+func TestMonitorCheck(t *testing.T) {
+	now := time.Date(2025, 2, 17, 12, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Minute)
+	expiresNow := now
+	expiresSoon := now.Add(24 * time.Hour)
+	expiresLater := now.Add(30 * 24 * time.Hour)
+
+	repo := &fakeKeyMetadataRepo{
+		keys: []*model.KeyMetadata{
+			{PublicKeyG1: "no_expiry"},
+			{PublicKeyG1: "expired", NotAfter: &expired},
+			{PublicKeyG1: "expires_now", NotAfter: &expiresNow},
+			{PublicKeyG1: "expired_locked", NotAfter: &expired, Locked: true},
+			{PublicKeyG1: "expires_soon", NotAfter: &expiresSoon},
+			{PublicKeyG1: "expires_later", NotAfter: &expiresLater},
+		},
+	}
+	monitor := NewMonitor(
+		repo,
+		metrics.NewNoopKeyExpiryMetrics(),
+		time.Hour,
+		7*24*time.Hour,
+		testutils.GetTestLogger(),
+	)
+
+	result, err := monitor.Check(context.Background(), now)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"expired", "expires_now"}, result.Locked)
+	assert.Equal(t, []string{"expires_soon"}, result.ExpiringSoon)
+
+	assert.False(t, repo.keys[0].Locked)
+	assert.True(t, repo.keys[1].Locked)
+	assert.True(t, repo.keys[2].Locked)
+	assert.False(t, repo.keys[4].Locked)
+
+	// Locked keys aren't locked again
+	result, err = monitor.Check(context.Background(), now)
+	require.NoError(t, err)
+	assert.Empty(t, result.Locked)
+}
+
+func TestMonitorCheckLockFailure(t *testing.T) {
+	now := time.Now().UTC()
+	expired := now.Add(-time.Minute)
+
+	repo := &fakeKeyMetadataRepo{
+		keys:    []*model.KeyMetadata{{PublicKeyG1: "expired", NotAfter: &expired}},
+		lockErr: errors.New("database unavailable"),
+	}
+	monitor := NewMonitor(
+		repo,
+		metrics.NewNoopKeyExpiryMetrics(),
+		time.Hour,
+		time.Hour,
+		testutils.GetTestLogger(),
+	)
+
+	result, err := monitor.Check(context.Background(), now)
+	require.NoError(t, err)
+	assert.Empty(t, result.Locked)
+	assert.False(t, repo.keys[0].Locked)
+}
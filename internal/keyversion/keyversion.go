@@ -0,0 +1,177 @@
+package keyversion
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/Layr-Labs/cerberus/internal/common"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+// Manager manages the versions of the keys kept by the stores with version
+// history and records the version each key signs with in its metadata
+type Manager struct {
+	keys            store.Store
+	keyMetadataRepo repository.KeyMetadataRepository
+	logger          *slog.Logger
+}
+
+func New(
+	keys store.Store,
+	keyMetadataRepo repository.KeyMetadataRepository,
+	logger *slog.Logger,
+) *Manager {
+	return &Manager{
+		keys:            keys,
+		keyMetadataRepo: keyMetadataRepo,
+		logger:          logger.With("component", "key-version"),
+	}
+}
+
+// List returns the versions of the key, oldest first
+func (m *Manager) List(ctx context.Context, publicKeyG1 string) ([]store.KeyVersion, error) {
+	versioner, metadata, err := m.versioner(ctx, publicKeyG1)
+	if err != nil {
+		return nil, err
+	}
+	return versioner.ListVersions(ctx, metadata.PublicKeyG1)
+}
+
+// Pin makes the key sign with the version rather than the current one. An
+// empty version unpins the key, which then signs with the current version.
+func (m *Manager) Pin(ctx context.Context, publicKeyG1 string, versionID string) error {
+	versioner, metadata, err := m.versioner(ctx, publicKeyG1)
+	if err != nil {
+		return err
+	}
+
+	versions, err := versioner.ListVersions(ctx, metadata.PublicKeyG1)
+	if err != nil {
+		return err
+	}
+	pinned := versionID != ""
+	found := false
+	for _, version := range versions {
+		if version.ID == versionID || (!pinned && version.Current) {
+			versionID = version.ID
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", store.ErrVersionNotFound, versionID)
+	}
+
+	err = m.keyMetadataRepo.UpdateKeyVersion(ctx, metadata.PublicKeyG1, versionID, pinned)
+	if err != nil {
+		return err
+	}
+	m.logger.Info(
+		"Updated key version",
+		"pubKey", metadata.PublicKeyG1,
+		"version", versionID,
+		"pinned", pinned,
+	)
+	return nil
+}
+
+// Rollback makes an earlier version of the key current again and returns
+// the ID of the current version. A pinned key is pinned to it.
+func (m *Manager) Rollback(
+	ctx context.Context,
+	publicKeyG1 string,
+	versionID string,
+) (string, error) {
+	versioner, metadata, err := m.versioner(ctx, publicKeyG1)
+	if err != nil {
+		return "", err
+	}
+
+	current, err := versioner.RollbackKey(ctx, metadata.PublicKeyG1, versionID)
+	if err != nil {
+		return "", err
+	}
+	return current, m.record(ctx, metadata, current)
+}
+
+// Rewrap writes the version the key signs with as a new version envelope
+// encrypted with the current master key, and returns the ID of the new
+// version. A pinned key is pinned to it.
+func (m *Manager) Rewrap(ctx context.Context, publicKeyG1 string) (string, error) {
+	versioner, metadata, err := m.versioner(ctx, publicKeyG1)
+	if err != nil {
+		return "", err
+	}
+
+	var versionID string
+	if metadata.KeyVersionPinned {
+		versionID = metadata.KeyVersion
+	}
+	current, err := versioner.RewrapKey(ctx, metadata.PublicKeyG1, versionID)
+	if err != nil {
+		return "", err
+	}
+	return current, m.record(ctx, metadata, current)
+}
+
+// Record records the current version of the key after it was written again
+// outside of the manager, such as re-encrypted with a password. A pinned key
+// is pinned to it.
+func (m *Manager) Record(ctx context.Context, publicKeyG1 string) (string, error) {
+	versioner, metadata, err := m.versioner(ctx, publicKeyG1)
+	if err != nil {
+		return "", err
+	}
+
+	current, err := versioner.CurrentVersion(ctx, metadata.PublicKeyG1)
+	if err != nil {
+		return "", err
+	}
+	return current, m.record(ctx, metadata, current)
+}
+
+func (m *Manager) record(ctx context.Context, metadata *model.KeyMetadata, versionID string) error {
+	err := m.keyMetadataRepo.UpdateKeyVersion(
+		ctx,
+		metadata.PublicKeyG1,
+		versionID,
+		metadata.KeyVersionPinned,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record key version %s: %w", versionID, err)
+	}
+	m.logger.Info(
+		"Recorded key version",
+		"pubKey", metadata.PublicKeyG1,
+		"previous", metadata.KeyVersion,
+		"version", versionID,
+		"pinned", metadata.KeyVersionPinned,
+	)
+	return nil
+}
+
+// versioner returns the store holding the key, recorded in its metadata, and
+// the metadata of the key
+func (m *Manager) versioner(
+	ctx context.Context,
+	publicKeyG1 string,
+) (store.Versioner, *model.KeyMetadata, error) {
+	publicKeyG1 = common.Trim0x(publicKeyG1)
+	metadata, err := m.keyMetadataRepo.Get(ctx, publicKeyG1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyStore, storeName, err := store.Route(m.keys, metadata.StoreName)
+	if err != nil {
+		return nil, nil, err
+	}
+	versioner, ok := store.As[store.Versioner](keyStore)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", store.ErrVersioningUnsupported, storeName)
+	}
+	return versioner, metadata, nil
+}
@@ -0,0 +1,158 @@
+package keyversion
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+	memoryrepo "github.com/Layr-Labs/cerberus/internal/database/repository/memory"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPubKey = "a3111a2232584734d526d62cbb7c9a0d4ce1984a92b7ecb85bde8878fea5d1b0"
+
+// fakeVersionedStore keeps a list of versions per key, the last one is
+// current. Rolling back and re-wrapping add a version like Secret Manager.
+type fakeVersionedStore struct {
+	store.Store
+	versions []string
+	rewraps  []string
+}
+
+func (s *fakeVersionedStore) CurrentVersion(_ context.Context, _ string) (string, error) {
+	return s.versions[len(s.versions)-1], nil
+}
+
+func (s *fakeVersionedStore) ListVersions(_ context.Context, _ string) ([]store.KeyVersion, error) {
+	var versions []store.KeyVersion
+	for i, id := range s.versions {
+		versions = append(versions, store.KeyVersion{ID: id, Current: i == len(s.versions)-1})
+	}
+	return versions, nil
+}
+
+func (s *fakeVersionedStore) RetrieveKeyVersion(
+	_ context.Context,
+	_ string,
+	_ string,
+	_ string,
+) (*crypto.KeyPair, error) {
+	return nil, store.ErrKeyNotFound
+}
+
+func (s *fakeVersionedStore) RewrapKey(
+	_ context.Context,
+	_ string,
+	versionID string,
+) (string, error) {
+	s.rewraps = append(s.rewraps, versionID)
+	return s.add(), nil
+}
+
+func (s *fakeVersionedStore) RollbackKey(
+	_ context.Context,
+	_ string,
+	versionID string,
+) (string, error) {
+	for _, id := range s.versions {
+		if id == versionID {
+			return s.add(), nil
+		}
+	}
+	return "", store.ErrVersionNotFound
+}
+
+func (s *fakeVersionedStore) add() string {
+	id := fmt.Sprint(len(s.versions) + 1)
+	s.versions = append(s.versions, id)
+	return id
+}
+
+func newManager(t *testing.T, keys store.Store) (*Manager, repository.KeyMetadataRepository) {
+	repo := memoryrepo.NewKeyMetadataRepository(memoryrepo.NewDB())
+	require.NoError(t, repo.Create(context.Background(), &model.KeyMetadata{
+		PublicKeyG1: testPubKey,
+		PublicKeyG2: "g2",
+		KeyVersion:  "1",
+	}))
+	return New(keys, repo, testutils.GetTestLogger()), repo
+}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	keys := &fakeVersionedStore{versions: []string{"1", "2"}}
+	manager, repo := newManager(t, keys)
+
+	versions, err := manager.List(ctx, "0x"+testPubKey)
+	require.NoError(t, err)
+	assert.Len(t, versions, 2)
+
+	// Pinning records the version, unpinning records the current version
+	require.NoError(t, manager.Pin(ctx, testPubKey, "1"))
+	assertKeyVersion(t, repo, "1", true)
+	assert.ErrorIs(t, manager.Pin(ctx, testPubKey, "5"), store.ErrVersionNotFound)
+	require.NoError(t, manager.Pin(ctx, testPubKey, ""))
+	assertKeyVersion(t, repo, "2", false)
+
+	// A pinned key stays pinned to the version written by the rollback and
+	// its pinned version is the one re-wrapped
+	require.NoError(t, manager.Pin(ctx, testPubKey, "1"))
+	current, err := manager.Rollback(ctx, testPubKey, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "3", current)
+	assertKeyVersion(t, repo, "3", true)
+
+	current, err = manager.Rewrap(ctx, testPubKey)
+	require.NoError(t, err)
+	assert.Equal(t, "4", current)
+	assert.Equal(t, []string{"3"}, keys.rewraps)
+	assertKeyVersion(t, repo, "4", true)
+
+	// An unpinned key re-wraps its current version
+	require.NoError(t, manager.Pin(ctx, testPubKey, ""))
+	_, err = manager.Rewrap(ctx, testPubKey)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3", ""}, keys.rewraps)
+
+	keys.add()
+	current, err = manager.Record(ctx, testPubKey)
+	require.NoError(t, err)
+	assert.Equal(t, "6", current)
+	assertKeyVersion(t, repo, "6", false)
+}
+
+func TestManagerWithoutVersions(t *testing.T) {
+	ctx := context.Background()
+	keys, err := memory.NewStore("", testutils.GetTestLogger())
+	require.NoError(t, err)
+	manager, _ := newManager(t, keys)
+
+	_, err = manager.List(ctx, testPubKey)
+	assert.ErrorIs(t, err, store.ErrVersioningUnsupported)
+	_, err = manager.Rewrap(ctx, testPubKey)
+	assert.ErrorIs(t, err, store.ErrVersioningUnsupported)
+
+	_, err = manager.List(ctx, "unknown")
+	assert.ErrorIs(t, err, repository.ErrKeyNotFound)
+}
+
+func assertKeyVersion(
+	t *testing.T,
+	repo repository.KeyMetadataRepository,
+	keyVersion string,
+	pinned bool,
+) {
+	t.Helper()
+	metadata, err := repo.Get(context.Background(), testPubKey)
+	require.NoError(t, err)
+	assert.Equal(t, keyVersion, metadata.KeyVersion)
+	assert.Equal(t, pinned, metadata.KeyVersionPinned)
+}
@@ -0,0 +1,135 @@
+package keywatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+	"github.com/Layr-Labs/cerberus/internal/metrics"
+	"github.com/Layr-Labs/cerberus/internal/store/filesystem"
+)
+
+// Source reports the key file changes, such as filesystem.Watcher
+type Source interface {
+	Run(ctx context.Context, handle func(filesystem.Event)) error
+}
+
+// Evictor drops a key from the signing cache
+type Evictor interface {
+	EvictKey(pubKeyHex string)
+}
+
+// Registrar creates the metadata of a key found in the store, such as
+// reconciler.Reconciler
+type Registrar interface {
+	RegisterKey(ctx context.Context, pubKeyHex string, password string) error
+}
+
+// Monitor acts on the key files changed outside of cerberus: the changed and
+// removed keys are evicted from the signing cache and the new keys are
+// optionally registered in a locked state
+type Monitor struct {
+	source          Source
+	evictor         Evictor
+	registrar       Registrar
+	keyMetadataRepo repository.KeyMetadataRepository
+	password        string
+	metrics         metrics.KeystoreWatchRecorder
+	logger          *slog.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewMonitor creates a Monitor. The new keys are only registered when
+// registrar is not nil, using password to decrypt them.
+func NewMonitor(
+	source Source,
+	evictor Evictor,
+	registrar Registrar,
+	keyMetadataRepo repository.KeyMetadataRepository,
+	password string,
+	metrics metrics.KeystoreWatchRecorder,
+	logger *slog.Logger,
+) *Monitor {
+	return &Monitor{
+		source:          source,
+		evictor:         evictor,
+		registrar:       registrar,
+		keyMetadataRepo: keyMetadataRepo,
+		password:        password,
+		metrics:         metrics,
+		logger:          logger.With("component", "keystore-watch"),
+		done:            make(chan struct{}),
+	}
+}
+
+// Start watches the key files until Stop is called
+func (m *Monitor) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	go func() {
+		defer close(m.done)
+		if err := m.source.Run(ctx, func(event filesystem.Event) {
+			m.Handle(ctx, event)
+		}); err != nil {
+			m.logger.Error("Keystore watch stopped", "error", err)
+			m.metrics.RecordError()
+		}
+	}()
+}
+
+// Stop stops watching the key files
+func (m *Monitor) Stop() {
+	m.cancel()
+	<-m.done
+}
+
+// Handle acts on a single key file change
+func (m *Monitor) Handle(ctx context.Context, event filesystem.Event) {
+	m.logger.Info(
+		fmt.Sprintf("Key file %s", event.Type),
+		"public_key_g1", event.PubKey,
+	)
+	m.metrics.RecordEvent(string(event.Type))
+
+	switch event.Type {
+	case filesystem.KeyChanged, filesystem.KeyRemoved:
+		m.evictor.EvictKey(event.PubKey)
+	case filesystem.KeyCreated:
+		if m.registrar == nil {
+			return
+		}
+		if err := m.register(ctx, event.PubKey); err != nil {
+			m.logger.Error(
+				"Failed to register new key file",
+				"public_key_g1", event.PubKey,
+				"error", err,
+			)
+			m.metrics.RecordError()
+		}
+	}
+}
+
+func (m *Monitor) register(ctx context.Context, pubKeyHex string) error {
+	_, err := m.keyMetadataRepo.Get(ctx, pubKeyHex)
+	if err == nil {
+		// The key was restored or registered already
+		return nil
+	}
+	if !errors.Is(err, repository.ErrKeyNotFound) {
+		return fmt.Errorf("failed to get key metadata: %w", err)
+	}
+
+	if err := m.registrar.RegisterKey(ctx, pubKeyHex, m.password); err != nil {
+		return err
+	}
+	m.logger.Warn(
+		"Registered new key file in locked state, it can't sign until it is reviewed and unlocked",
+		"public_key_g1", pubKeyHex,
+	)
+	m.metrics.RecordKeyRegistered()
+	return nil
+}
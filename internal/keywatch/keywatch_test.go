@@ -0,0 +1,123 @@
+package keywatch
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository/memory"
+	"github.com/Layr-Labs/cerberus/internal/metrics"
+	"github.com/Layr-Labs/cerberus/internal/store/filesystem"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	events []filesystem.Event
+}
+
+func (s *fakeSource) Run(ctx context.Context, handle func(filesystem.Event)) error {
+	for _, event := range s.events {
+		handle(event)
+	}
+	<-ctx.Done()
+	return nil
+}
+
+type fakeEvictor struct {
+	evicted []string
+}
+
+func (e *fakeEvictor) EvictKey(pubKeyHex string) {
+	e.evicted = append(e.evicted, pubKeyHex)
+}
+
+type fakeRegistrar struct {
+	registered []string
+	password   string
+	err        error
+}
+
+func (r *fakeRegistrar) RegisterKey(_ context.Context, pubKeyHex string, password string) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.registered = append(r.registered, pubKeyHex)
+	r.password = password
+	return nil
+}
+
+func TestMonitor(t *testing.T) {
+	repo := memory.NewKeyMetadataRepository(memory.NewDB())
+	require.NoError(t, repo.Create(context.Background(), &model.KeyMetadata{
+		PublicKeyG1: "restored",
+		PublicKeyG2: "restored_g2",
+	}))
+
+	source := &fakeSource{events: []filesystem.Event{
+		{Type: filesystem.KeyCreated, PubKey: "new"},
+		{Type: filesystem.KeyCreated, PubKey: "restored"},
+		{Type: filesystem.KeyChanged, PubKey: "changed"},
+		{Type: filesystem.KeyRemoved, PubKey: "removed"},
+	}}
+	evictor := &fakeEvictor{}
+	registrar := &fakeRegistrar{}
+	monitor := NewMonitor(
+		source,
+		evictor,
+		registrar,
+		repo,
+		"p4ssw0rd",
+		metrics.NewNoopKeystoreWatchMetrics(),
+		testutils.GetTestLogger(),
+	)
+
+	monitor.Start()
+	monitor.Stop()
+
+	assert.Equal(t, []string{"changed", "removed"}, evictor.evicted)
+	assert.Equal(t, []string{"new"}, registrar.registered)
+	assert.Equal(t, "p4ssw0rd", registrar.password)
+}
+
+func TestMonitorWithoutRegistration(t *testing.T) {
+	evictor := &fakeEvictor{}
+	monitor := NewMonitor(
+		&fakeSource{},
+		evictor,
+		nil,
+		memory.NewKeyMetadataRepository(memory.NewDB()),
+		"",
+		metrics.NewNoopKeystoreWatchMetrics(),
+		testutils.GetTestLogger(),
+	)
+
+	// Without a registrar the new keys are only reported
+	monitor.Handle(context.Background(), filesystem.Event{
+		Type:   filesystem.KeyCreated,
+		PubKey: "new",
+	})
+	assert.Empty(t, evictor.evicted)
+}
+
+func TestMonitorRegistrationFailure(t *testing.T) {
+	registrar := &fakeRegistrar{err: errors.New("wrong password")}
+	monitor := NewMonitor(
+		&fakeSource{},
+		&fakeEvictor{},
+		registrar,
+		memory.NewKeyMetadataRepository(memory.NewDB()),
+		"",
+		metrics.NewNoopKeystoreWatchMetrics(),
+		testutils.GetTestLogger(),
+	)
+
+	monitor.Handle(context.Background(), filesystem.Event{
+		Type:   filesystem.KeyCreated,
+		PubKey: "new",
+	})
+	assert.Empty(t, registrar.registered)
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	SubsystemKeyExpiry = "key_expiry"
+
+	MetricNotAfterTimestampSeconds = "not_after_timestamp_seconds"
+	MetricKeysExpiringSoon         = "keys_expiring_soon"
+	MetricExpiredKeysLockedTotal   = "expired_keys_locked_total"
+)
+
+type KeyExpiryRecorder interface {
+	SetNotAfter(publicKeyG1 string, notAfterUnix float64)
+	DeleteNotAfter(publicKeyG1 string)
+	SetKeysExpiringSoon(count int)
+	RecordExpiredKeyLocked()
+}
+
+type KeyExpiryMetrics struct {
+	NotAfterTimestampSeconds *prometheus.GaugeVec
+	KeysExpiringSoon         prometheus.Gauge
+	ExpiredKeysLockedTotal   prometheus.Counter
+}
+
+func NewKeyExpiryMetrics(ns string, registry *prometheus.Registry) *KeyExpiryMetrics {
+	m := &KeyExpiryMetrics{
+		NotAfterTimestampSeconds: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: ns,
+			Subsystem: SubsystemKeyExpiry,
+			Name:      MetricNotAfterTimestampSeconds,
+			Help:      "Unix timestamp after which the key can no longer sign",
+		}, []string{PublicKeyLabelName}),
+		KeysExpiringSoon: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: ns,
+			Subsystem: SubsystemKeyExpiry,
+			Name:      MetricKeysExpiringSoon,
+			Help:      "Number of unlocked keys expiring within the warning period",
+		}),
+		ExpiredKeysLockedTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: ns,
+			Subsystem: SubsystemKeyExpiry,
+			Name:      MetricExpiredKeysLockedTotal,
+			Help:      "Total number of keys locked because they expired",
+		}),
+	}
+	registry.MustRegister(m.NotAfterTimestampSeconds)
+	registry.MustRegister(m.KeysExpiringSoon)
+	registry.MustRegister(m.ExpiredKeysLockedTotal)
+	return m
+}
+
+func (m *KeyExpiryMetrics) SetNotAfter(publicKeyG1 string, notAfterUnix float64) {
+	m.NotAfterTimestampSeconds.WithLabelValues(publicKeyG1).Set(notAfterUnix)
+}
+
+func (m *KeyExpiryMetrics) DeleteNotAfter(publicKeyG1 string) {
+	m.NotAfterTimestampSeconds.DeleteLabelValues(publicKeyG1)
+}
+
+func (m *KeyExpiryMetrics) SetKeysExpiringSoon(count int) {
+	m.KeysExpiringSoon.Set(float64(count))
+}
+
+func (m *KeyExpiryMetrics) RecordExpiredKeyLocked() {
+	m.ExpiredKeysLockedTotal.Inc()
+}
+
+type NoopKeyExpiryMetrics struct{}
+
+func NewNoopKeyExpiryMetrics() *NoopKeyExpiryMetrics {
+	return &NoopKeyExpiryMetrics{}
+}
+
+func (NoopKeyExpiryMetrics) SetNotAfter(publicKeyG1 string, notAfterUnix float64) {}
+
+func (NoopKeyExpiryMetrics) DeleteNotAfter(publicKeyG1 string) {}
+
+func (NoopKeyExpiryMetrics) SetKeysExpiringSoon(count int) {}
+
+func (NoopKeyExpiryMetrics) RecordExpiredKeyLocked() {}
+
+var _ KeyExpiryRecorder = (*NoopKeyExpiryMetrics)(nil)
@@ -0,0 +1,78 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	SubsystemKeyUsage = "key_usage"
+
+	MetricSignaturesTotal          = "signatures_total"
+	MetricLastUsedTimestampSeconds = "last_used_timestamp_seconds"
+
+	PublicKeyLabelName = "public_key_g1"
+)
+
+type KeyUsageRecorder interface {
+	RecordSignature(publicKeyG1 string, method string)
+}
+
+// KeyUsageMetrics counts the signatures per signing method. The per key
+// series are only exported with perKey, as there is one per key and signing
+// method and they are never removed; the database keeps the usage of each key.
+type KeyUsageMetrics struct {
+	SignaturesTotal          *prometheus.CounterVec
+	LastUsedTimestampSeconds *prometheus.GaugeVec
+
+	perKey bool
+}
+
+func NewKeyUsageMetrics(ns string, registry *prometheus.Registry, perKey bool) *KeyUsageMetrics {
+	signaturesLabels := []string{MethodLabelName}
+	var lastUsedLabels []string
+	signaturesHelp := "Total number of signatures per signing method"
+	lastUsedHelp := "Unix timestamp of the last signature"
+	if perKey {
+		signaturesLabels = []string{PublicKeyLabelName, MethodLabelName}
+		lastUsedLabels = []string{PublicKeyLabelName}
+		signaturesHelp = "Total number of signatures per key and signing method"
+		lastUsedHelp = "Unix timestamp of the last signature per key"
+	}
+
+	m := &KeyUsageMetrics{
+		SignaturesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Subsystem: SubsystemKeyUsage,
+			Name:      MetricSignaturesTotal,
+			Help:      signaturesHelp,
+		}, signaturesLabels),
+		LastUsedTimestampSeconds: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: ns,
+			Subsystem: SubsystemKeyUsage,
+			Name:      MetricLastUsedTimestampSeconds,
+			Help:      lastUsedHelp,
+		}, lastUsedLabels),
+		perKey: perKey,
+	}
+	registry.MustRegister(m.SignaturesTotal)
+	registry.MustRegister(m.LastUsedTimestampSeconds)
+	return m
+}
+
+func (m *KeyUsageMetrics) RecordSignature(publicKeyG1 string, method string) {
+	if !m.perKey {
+		m.SignaturesTotal.WithLabelValues(method).Inc()
+		m.LastUsedTimestampSeconds.WithLabelValues().SetToCurrentTime()
+		return
+	}
+	m.SignaturesTotal.WithLabelValues(publicKeyG1, method).Inc()
+	m.LastUsedTimestampSeconds.WithLabelValues(publicKeyG1).SetToCurrentTime()
+}
+
+type NoopKeyUsageMetrics struct{}
+
+func NewNoopKeyUsageMetrics() *NoopKeyUsageMetrics {
+	return &NoopKeyUsageMetrics{}
+}
+
+func (NoopKeyUsageMetrics) RecordSignature(publicKeyG1 string, method string) {}
+
+var _ KeyUsageRecorder = (*NoopKeyUsageMetrics)(nil)
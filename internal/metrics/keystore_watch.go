@@ -0,0 +1,78 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	SubsystemKeystoreWatch = "keystore_watch"
+
+	MetricEventsTotal         = "events_total"
+	MetricRegisteredKeysTotal = "registered_keys_total"
+	MetricErrorsTotal         = "errors_total"
+
+	EventLabelName = "event"
+)
+
+type KeystoreWatchRecorder interface {
+	RecordEvent(event string)
+	RecordKeyRegistered()
+	RecordError()
+}
+
+type KeystoreWatchMetrics struct {
+	EventsTotal         *prometheus.CounterVec
+	RegisteredKeysTotal prometheus.Counter
+	ErrorsTotal         prometheus.Counter
+}
+
+func NewKeystoreWatchMetrics(ns string, registry *prometheus.Registry) *KeystoreWatchMetrics {
+	m := &KeystoreWatchMetrics{
+		EventsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Subsystem: SubsystemKeystoreWatch,
+			Name:      MetricEventsTotal,
+			Help:      "Total number of key files created, changed or removed in the keystore dir",
+		}, []string{EventLabelName}),
+		RegisteredKeysTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: ns,
+			Subsystem: SubsystemKeystoreWatch,
+			Name:      MetricRegisteredKeysTotal,
+			Help:      "Total number of new key files registered in a locked state",
+		}),
+		ErrorsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: ns,
+			Subsystem: SubsystemKeystoreWatch,
+			Name:      MetricErrorsTotal,
+			Help:      "Total number of key file changes which failed to be handled",
+		}),
+	}
+	registry.MustRegister(m.EventsTotal)
+	registry.MustRegister(m.RegisteredKeysTotal)
+	registry.MustRegister(m.ErrorsTotal)
+	return m
+}
+
+func (m *KeystoreWatchMetrics) RecordEvent(event string) {
+	m.EventsTotal.WithLabelValues(event).Inc()
+}
+
+func (m *KeystoreWatchMetrics) RecordKeyRegistered() {
+	m.RegisteredKeysTotal.Inc()
+}
+
+func (m *KeystoreWatchMetrics) RecordError() {
+	m.ErrorsTotal.Inc()
+}
+
+type NoopKeystoreWatchMetrics struct{}
+
+func NewNoopKeystoreWatchMetrics() *NoopKeystoreWatchMetrics {
+	return &NoopKeystoreWatchMetrics{}
+}
+
+func (NoopKeystoreWatchMetrics) RecordEvent(event string) {}
+
+func (NoopKeystoreWatchMetrics) RecordKeyRegistered() {}
+
+func (NoopKeystoreWatchMetrics) RecordError() {}
+
+var _ KeystoreWatchRecorder = (*NoopKeystoreWatchMetrics)(nil)
@@ -0,0 +1,102 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	SubsystemStore = "store"
+
+	MetricOperationsTotal          = "operations_total"
+	MetricOperationDurationSeconds = "operation_duration_seconds"
+	MetricRetriesTotal             = "retries_total"
+	MetricCircuitOpen              = "circuit_open"
+
+	BackendLabelName   = "backend"
+	OperationLabelName = "operation"
+	ResultLabelName    = "result"
+)
+
+type StoreRecorder interface {
+	// RecordOperation starts timing a store operation, the returned function
+	// records its result
+	RecordOperation(backend string, operation string) func(result string)
+	RecordRetry(backend string, operation string)
+	SetCircuitOpen(backend string, open bool)
+}
+
+type StoreMetrics struct {
+	OperationsTotal          *prometheus.CounterVec
+	OperationDurationSeconds *prometheus.SummaryVec
+	RetriesTotal             *prometheus.CounterVec
+	CircuitOpen              *prometheus.GaugeVec
+}
+
+func NewStoreMetrics(ns string, registry *prometheus.Registry) *StoreMetrics {
+	m := &StoreMetrics{
+		OperationsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Subsystem: SubsystemStore,
+			Name:      MetricOperationsTotal,
+			Help:      "Total number of key store operations with their result",
+		}, []string{BackendLabelName, OperationLabelName, ResultLabelName}),
+		OperationDurationSeconds: prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Namespace:  ns,
+			Subsystem:  SubsystemStore,
+			Name:       MetricOperationDurationSeconds,
+			Help:       "Duration of key store operations in seconds, retries included.",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.95: 0.01, 0.99: 0.001},
+		}, []string{BackendLabelName, OperationLabelName}),
+		RetriesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Subsystem: SubsystemStore,
+			Name:      MetricRetriesTotal,
+			Help:      "Total number of key store operations retried after a failure",
+		}, []string{BackendLabelName, OperationLabelName}),
+		CircuitOpen: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: ns,
+			Subsystem: SubsystemStore,
+			Name:      MetricCircuitOpen,
+			Help:      "Whether the circuit breaker of the key store is open, failing fast",
+		}, []string{BackendLabelName}),
+	}
+	registry.MustRegister(m.OperationsTotal)
+	registry.MustRegister(m.OperationDurationSeconds)
+	registry.MustRegister(m.RetriesTotal)
+	registry.MustRegister(m.CircuitOpen)
+	return m
+}
+
+func (m *StoreMetrics) RecordOperation(backend string, operation string) func(result string) {
+	timer := prometheus.NewTimer(m.OperationDurationSeconds.WithLabelValues(backend, operation))
+	return func(result string) {
+		m.OperationsTotal.WithLabelValues(backend, operation, result).Inc()
+		timer.ObserveDuration()
+	}
+}
+
+func (m *StoreMetrics) RecordRetry(backend string, operation string) {
+	m.RetriesTotal.WithLabelValues(backend, operation).Inc()
+}
+
+func (m *StoreMetrics) SetCircuitOpen(backend string, open bool) {
+	value := 0.0
+	if open {
+		value = 1
+	}
+	m.CircuitOpen.WithLabelValues(backend).Set(value)
+}
+
+type NoopStoreMetrics struct{}
+
+func NewNoopStoreMetrics() *NoopStoreMetrics {
+	return &NoopStoreMetrics{}
+}
+
+func (NoopStoreMetrics) RecordOperation(backend string, operation string) func(result string) {
+	return func(result string) {}
+}
+
+func (NoopStoreMetrics) RecordRetry(backend string, operation string) {}
+
+func (NoopStoreMetrics) SetCircuitOpen(backend string, open bool) {}
+
+var _ StoreRecorder = (*NoopStoreMetrics)(nil)
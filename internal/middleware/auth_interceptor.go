@@ -3,10 +3,13 @@ package middleware
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"strings"
+	"time"
 
 	v1 "github.com/Layr-Labs/cerberus-api/pkg/api/v1"
-	"github.com/Layr-Labs/cerberus/internal/common"
+	"github.com/Layr-Labs/cerberus/internal/apikey"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
 	"github.com/Layr-Labs/cerberus/internal/database/repository"
 
 	"google.golang.org/grpc"
@@ -15,11 +18,22 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-// AuthInterceptor creates a selective authentication interceptor
+// lastUseResolution is the precision of the recorded last use of the API
+// keys, which saves a write per signing request
+const lastUseResolution = time.Minute
+
+var errInvalidToken = errors.New("invalid token")
+
+// AuthInterceptor creates a selective authentication interceptor. Requests to
+// the protected service must carry an unexpired API key of the key they sign
+// with, allowed the method.
 func AuthInterceptor(
 	protectedServiceName string,
-	keyMetadataRepo repository.KeyMetadataRepository,
+	apiKeyRepo repository.APIKeyRepository,
+	apiKeys *apikey.Hasher,
+	logger *slog.Logger,
 ) grpc.UnaryServerInterceptor {
+	logger = logger.With("component", "auth")
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		// Check if the current service should be protected
 		if !strings.HasPrefix(info.FullMethod, "/"+protectedServiceName) {
@@ -39,48 +53,111 @@ func AuthInterceptor(
 			return nil, status.Error(codes.Unauthenticated, "missing authorization header")
 		}
 
-		// Validate the token (implement your own validation logic)
-		valid, err := validateToken(ctx, authHeader[0], req, keyMetadataRepo)
+		apiKey, scope, err := validateToken(ctx, authHeader[0], req, apiKeyRepo, apiKeys)
 		if err != nil {
 			return nil, status.Error(codes.Unauthenticated, err.Error())
 		}
-
-		if !valid {
-			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		if !apiKey.HasScope(scope) {
+			return nil, status.Errorf(
+				codes.PermissionDenied,
+				"api key %s is not allowed the %s scope",
+				apiKey.Name,
+				scope,
+			)
 		}
+		recordUse(ctx, apiKey, apiKeyRepo, logger)
 
 		// If authentication successful, proceed with the handler
 		return handler(ctx, req)
 	}
 }
 
-// Example token validation function - replace with your own implementation
+// validateToken returns the unexpired API key of the key of the request
+// matching the token, and the scope the request needs
 func validateToken(
 	ctx context.Context,
 	token string,
 	req interface{},
-	keyMetadataRepo repository.KeyMetadataRepository,
-) (bool, error) {
-	var pubKeyG1 string
+	apiKeyRepo repository.APIKeyRepository,
+	apiKeys *apikey.Hasher,
+) (*model.APIKey, string, error) {
+	var pubKeyG1, scope string
 	switch r := req.(type) {
 	case *v1.SignGenericRequest:
-		pubKeyG1 = r.GetPublicKeyG1()
+		pubKeyG1, scope = r.GetPublicKeyG1(), model.ScopeSignGeneric
 	case *v1.SignG1Request:
-		pubKeyG1 = r.GetPublicKeyG1()
+		pubKeyG1, scope = r.GetPublicKeyG1(), model.ScopeSignG1
 	default:
-		return false, errors.New("invalid request type")
+		return nil, "", errors.New("invalid request type")
 	}
 
-	keyMetadata, err := keyMetadataRepo.Get(ctx, pubKeyG1)
+	candidates, err := candidateAPIKeys(ctx, token, pubKeyG1, apiKeyRepo)
 	if err != nil {
-		return false, err
+		return nil, "", err
 	}
 
-	requestAPIKeyHash := common.CreateSHA256Hash(token)
+	now := time.Now()
+	for _, apiKey := range candidates {
+		if !apiKeys.Verify(apiKey, token) {
+			continue
+		}
+		if apiKey.ExpiredAt(now) {
+			return nil, "", errors.New("api key expired")
+		}
+		return apiKey, scope, nil
+	}
+	return nil, "", errInvalidToken
+}
+
+// candidateAPIKeys returns the API key of the key with the lookup ID of the
+// token, or the legacy API keys of the key for a token without lookup ID
+func candidateAPIKeys(
+	ctx context.Context,
+	token string,
+	pubKeyG1 string,
+	apiKeyRepo repository.APIKeyRepository,
+) ([]*model.APIKey, error) {
+	if lookupID, ok := apikey.LookupID(token); ok {
+		apiKey, err := apiKeyRepo.GetByLookupID(ctx, lookupID)
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if apiKey.PublicKeyG1 != pubKeyG1 {
+			return nil, nil
+		}
+		return []*model.APIKey{apiKey}, nil
+	}
 
-	if keyMetadata.ApiKeyHash != requestAPIKeyHash {
-		return false, errors.New("invalid token")
+	apiKeys, err := apiKeyRepo.List(ctx, pubKeyG1)
+	if err != nil {
+		return nil, err
 	}
+	var legacy []*model.APIKey
+	for _, apiKey := range apiKeys {
+		if apiKey.HashScheme == model.HashSchemeSHA256 {
+			legacy = append(legacy, apiKey)
+		}
+	}
+	return legacy, nil
+}
 
-	return true, nil
+// recordUse records the use of the API key, unless its recorded last use is
+// more recent than lastUseResolution. Failing to record it doesn't fail the
+// request.
+func recordUse(
+	ctx context.Context,
+	apiKey *model.APIKey,
+	apiKeyRepo repository.APIKeyRepository,
+	logger *slog.Logger,
+) {
+	now := time.Now().UTC()
+	if apiKey.LastUsedAt != nil && now.Sub(*apiKey.LastUsedAt) < lastUseResolution {
+		return
+	}
+	if err := apiKeyRepo.UpdateLastUsed(ctx, apiKey.ID, now); err != nil {
+		logger.Warn("Failed to record API key use", "api_key", apiKey.Name, "error", err)
+	}
 }
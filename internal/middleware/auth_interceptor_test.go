@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/Layr-Labs/cerberus-api/pkg/api/v1"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Layr-Labs/cerberus/internal/apikey"
+	"github.com/Layr-Labs/cerberus/internal/common"
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+	memoryrepo "github.com/Layr-Labs/cerberus/internal/database/repository/memory"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testPublicKeyG1 = "a311"
+	testPepper      = "0123456789abcdef0123456789abcdef"
+)
+
+func newHasher(t *testing.T, pepper string) *apikey.Hasher {
+	hasher, err := apikey.NewHasher(pepper)
+	require.NoError(t, err)
+	return hasher
+}
+
+func createAPIKey(
+	t *testing.T,
+	repo repository.APIKeyRepository,
+	publicKeyG1 string,
+	name string,
+	scopes []string,
+	expiresAt *time.Time,
+) string {
+	key, apiKey, err := newHasher(t, testPepper).New(name, scopes, expiresAt)
+	require.NoError(t, err)
+	apiKey.PublicKeyG1 = publicKeyG1
+	require.NoError(t, repo.Create(context.Background(), apiKey))
+	return key
+}
+
+// createLegacyAPIKey stores an API key of the format preceding the lookup IDs
+func createLegacyAPIKey(t *testing.T, repo repository.APIKeyRepository, name string) string {
+	key := uuid.NewString()
+	require.NoError(t, repo.Create(context.Background(), &model.APIKey{
+		ID:          uuid.NewString(),
+		PublicKeyG1: testPublicKeyG1,
+		Name:        name,
+		Hash:        common.CreateSHA256Hash(key),
+		HashScheme:  model.HashSchemeSHA256,
+	}))
+	return key
+}
+
+// intercept runs a request with the token through the interceptor
+func intercept(
+	interceptor grpc.UnaryServerInterceptor,
+	method string,
+	req interface{},
+	token string,
+) error {
+	ctx := context.Background()
+	if token != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", token))
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}
+	_, err := interceptor(ctx, req, info, handler)
+	return err
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	repo := memoryrepo.NewAPIKeyRepository(memoryrepo.NewDB())
+	interceptor := AuthInterceptor(
+		"signer.v1.Signer",
+		repo,
+		newHasher(t, testPepper),
+		testutils.GetTestLogger(),
+	)
+
+	expired := time.Now().Add(-time.Minute)
+	defaultKey := createAPIKey(t, repo, testPublicKeyG1, apikey.DefaultName, nil, nil)
+	rotatedKey := createAPIKey(t, repo, testPublicKeyG1, "rotated", nil, nil)
+	g1Key := createAPIKey(t, repo, testPublicKeyG1, "g1", []string{model.ScopeSignG1}, nil)
+	expiredKey := createAPIKey(t, repo, testPublicKeyG1, "expired", nil, &expired)
+	legacyKey := createLegacyAPIKey(t, repo, "legacy")
+	otherKey := createAPIKey(t, repo, "b422", apikey.DefaultName, nil, nil)
+
+	// The secret of a key with the lookup ID of another one
+	lookupID, _ := apikey.LookupID(defaultKey)
+	otherLookupID, _ := apikey.LookupID(otherKey)
+	forgedKey := strings.Replace(otherKey, otherLookupID, lookupID, 1)
+
+	call := func(method string, req interface{}, token string) error {
+		return intercept(interceptor, method, req, token)
+	}
+	signGeneric := &v1.SignGenericRequest{PublicKeyG1: testPublicKeyG1}
+	signG1 := &v1.SignG1Request{PublicKeyG1: testPublicKeyG1}
+
+	tests := []struct {
+		name     string
+		method   string
+		req      interface{}
+		token    string
+		wantCode codes.Code
+	}{
+		{"DefaultKey", "/signer.v1.Signer/SignGeneric", signGeneric, defaultKey, codes.OK},
+		{"RotatedKey", "/signer.v1.Signer/SignGeneric", signGeneric, rotatedKey, codes.OK},
+		{"LegacyKey", "/signer.v1.Signer/SignGeneric", signGeneric, legacyKey, codes.OK},
+		{"ScopedKey", "/signer.v1.Signer/SignG1", signG1, g1Key, codes.OK},
+		{"OutOfScope", "/signer.v1.Signer/SignGeneric", signGeneric, g1Key, codes.PermissionDenied},
+		{"Expired", "/signer.v1.Signer/SignG1", signG1, expiredKey, codes.Unauthenticated},
+		{"WrongKey", "/signer.v1.Signer/SignG1", signG1, "wrong", codes.Unauthenticated},
+		{"ForgedKey", "/signer.v1.Signer/SignG1", signG1, forgedKey, codes.Unauthenticated},
+		{"KeyOfOtherKey", "/signer.v1.Signer/SignG1", signG1, otherKey, codes.Unauthenticated},
+		{
+			"LegacyHashOfKey",
+			"/signer.v1.Signer/SignG1",
+			signG1,
+			common.CreateSHA256Hash(defaultKey),
+			codes.Unauthenticated,
+		},
+		{"MissingKey", "/signer.v1.Signer/SignG1", signG1, "", codes.Unauthenticated},
+		{
+			"OtherKey",
+			"/signer.v1.Signer/SignG1",
+			&v1.SignG1Request{PublicKeyG1: "b422"},
+			defaultKey,
+			codes.Unauthenticated,
+		},
+		{"Unprotected", "/keymanager.v1.KeyManager/ListKeys", &v1.ListKeysRequest{}, "", codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := call(tt.method, tt.req, tt.token)
+			assert.Equal(t, tt.wantCode, status.Code(err), err)
+		})
+	}
+
+	// The API keys don't verify with another pepper
+	otherInterceptor := AuthInterceptor(
+		"signer.v1.Signer",
+		repo,
+		newHasher(t, strings.Repeat("x", len(testPepper))),
+		testutils.GetTestLogger(),
+	)
+	err := intercept(otherInterceptor, "/signer.v1.Signer/SignGeneric", signGeneric, defaultKey)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// The use of the API keys is recorded
+	apiKeys, err := repo.List(context.Background(), testPublicKeyG1)
+	require.NoError(t, err)
+	for _, apiKey := range apiKeys {
+		if apiKey.Name == "expired" {
+			assert.Nil(t, apiKey.LastUsedAt)
+		} else {
+			assert.NotNil(t, apiKey.LastUsedAt, apiKey.Name)
+		}
+	}
+
+	// Revoked API keys are rejected
+	require.NoError(t, repo.Delete(context.Background(), testPublicKeyG1, apikey.DefaultName))
+	err = call("/signer.v1.Signer/SignGeneric", signGeneric, defaultKey)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
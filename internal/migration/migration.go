@@ -0,0 +1,195 @@
+package migration
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+
+	"github.com/Layr-Labs/cerberus/internal/common"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+// Options controls a migration run
+type Options struct {
+	// PublicKeys lists the keys to migrate, every key of the source store is
+	// migrated if it is empty
+	PublicKeys []string
+
+	// Password decrypts the keys read from the source store and encrypts the
+	// keys written to the target store, if the stores encrypt them
+	Password string
+
+	// StoreName is recorded as the store of the migrated keys in their
+	// metadata. The metadata is left unchanged if it is empty.
+	StoreName string
+
+	// DeleteSource deletes the keys from the source store once their copy in
+	// the target store is verified
+	DeleteSource bool
+}
+
+// Failure describes a key which could not be migrated
+type Failure struct {
+	PublicKeyG1 string
+	Error       error
+}
+
+// Report is the outcome of a migration run
+type Report struct {
+	// Migrated lists the keys copied to the target store
+	Migrated []string
+
+	// AlreadyMigrated lists the keys which were already in the target store,
+	// such as the keys copied by an earlier interrupted run
+	AlreadyMigrated []string
+
+	// Failed lists the keys which could not be migrated, they are left in
+	// the source store
+	Failed []Failure
+}
+
+// Migrator copies the keys of a store to another store
+type Migrator struct {
+	from            store.Store
+	to              store.Store
+	keyMetadataRepo repository.KeyMetadataRepository
+	logger          *slog.Logger
+}
+
+func New(
+	from store.Store,
+	to store.Store,
+	keyMetadataRepo repository.KeyMetadataRepository,
+	logger *slog.Logger,
+) *Migrator {
+	return &Migrator{
+		from:            from,
+		to:              to,
+		keyMetadataRepo: keyMetadataRepo,
+		logger:          logger.With("component", "migration"),
+	}
+}
+
+// Run copies the keys to the target store. Each copy is verified by reading
+// it back from the target store before the metadata is updated and the source
+// is deleted, a key which fails any step is reported and the others go on.
+func (m *Migrator) Run(ctx context.Context, opts Options) (*Report, error) {
+	pubKeys := opts.PublicKeys
+	if len(pubKeys) == 0 {
+		var err error
+		pubKeys, err = m.from.ListKeys(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys from source store: %w", err)
+		}
+	}
+
+	report := &Report{}
+	for _, pubKey := range pubKeys {
+		pubKey = common.Trim0x(pubKey)
+		copied, err := m.migrateKey(ctx, pubKey, opts)
+		if err != nil {
+			m.logger.Error("Failed to migrate key", "pubKey", pubKey, "error", err)
+			report.Failed = append(report.Failed, Failure{PublicKeyG1: pubKey, Error: err})
+			continue
+		}
+		if copied {
+			report.Migrated = append(report.Migrated, pubKey)
+		} else {
+			report.AlreadyMigrated = append(report.AlreadyMigrated, pubKey)
+		}
+	}
+
+	m.logger.Info(
+		"Migration completed",
+		"migrated", len(report.Migrated),
+		"already_migrated", len(report.AlreadyMigrated),
+		"failed", len(report.Failed),
+	)
+	return report, nil
+}
+
+// migrateKey copies the key to the target store and returns false if the
+// target store already held it
+func (m *Migrator) migrateKey(ctx context.Context, pubKey string, opts Options) (bool, error) {
+	keyPair, err := m.from.RetrieveKey(ctx, pubKey, opts.Password)
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve key from source store: %w", err)
+	}
+	privKey := keyPair.PrivKey.Bytes()
+	defer clear(privKey[:])
+
+	copied := true
+	_, err = m.to.StoreKey(ctx, &keystore.KeyPair{
+		PrivateKey: privKey[:],
+		Password:   opts.Password,
+	})
+	if errors.Is(err, store.ErrKeyAlreadyExists) {
+		copied = false
+	} else if err != nil {
+		return false, fmt.Errorf("failed to store key in target store: %w", err)
+	}
+
+	if err := m.verify(ctx, pubKey, opts.Password); err != nil {
+		// Only a copy made by this run is removed, a key already in the
+		// target store may be in use
+		if copied {
+			if rollbackErr := m.to.DeleteKey(ctx, pubKey); rollbackErr != nil {
+				return false, errors.Join(err, fmt.Errorf(
+					"failed to roll back target store: %w",
+					rollbackErr,
+				))
+			}
+		}
+		return false, err
+	}
+
+	if opts.StoreName != "" {
+		if err := m.updateMetadata(ctx, pubKey, opts.StoreName); err != nil {
+			return false, fmt.Errorf("failed to update key metadata: %w", err)
+		}
+	}
+
+	if opts.DeleteSource {
+		if err := m.from.DeleteKey(ctx, pubKey); err != nil {
+			return false, fmt.Errorf("failed to delete key from source store: %w", err)
+		}
+	}
+	return copied, nil
+}
+
+// updateMetadata records the target store of the key and its version there,
+// the versions of the source store don't exist in the target store
+func (m *Migrator) updateMetadata(ctx context.Context, pubKey string, storeName string) error {
+	err := m.keyMetadataRepo.UpdateStoreName(ctx, pubKey, storeName)
+	if errors.Is(err, repository.ErrKeyNotFound) {
+		m.logger.Warn("Migrated key has no metadata", "pubKey", pubKey)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	keyVersion, err := store.CurrentVersion(ctx, m.to, pubKey)
+	if err != nil {
+		return err
+	}
+	return m.keyMetadataRepo.UpdateKeyVersion(ctx, pubKey, keyVersion, false)
+}
+
+// verify reads the key back from the target store and checks its public key
+func (m *Migrator) verify(ctx context.Context, pubKey string, password string) error {
+	keyPair, err := m.to.RetrieveKey(ctx, pubKey, password)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve key from target store: %w", err)
+	}
+	pubKeyBytes := keyPair.PubKey.Bytes()
+	if hex.EncodeToString(pubKeyBytes[:]) != pubKey {
+		return errors.New("key in target store doesn't match its public key")
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	memoryrepo "github.com/Layr-Labs/cerberus/internal/database/repository/memory"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/filesystem"
+	"github.com/Layr-Labs/cerberus/internal/store/memory"
+)
+
+const testPassword = "p@$$w0rd"
+
+// corruptStore returns another key than the requested one, as a broken
+// backend would
+type corruptStore struct {
+	store.Store
+	other *crypto.KeyPair
+}
+
+func (s *corruptStore) RetrieveKey(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (*crypto.KeyPair, error) {
+	if _, err := s.Store.RetrieveKey(ctx, pubKey, password); err != nil {
+		return nil, err
+	}
+	return s.other, nil
+}
+
+func storeKeys(t *testing.T, s store.Store, n int) []string {
+	pubKeys := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+		require.NoError(t, err)
+		pubKey, err := s.StoreKey(context.Background(), keyPair)
+		require.NoError(t, err)
+		pubKeys = append(pubKeys, pubKey)
+	}
+	return pubKeys
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	logger := testutils.GetTestLogger()
+	from := filesystem.NewStore(t.TempDir(), logger)
+	to, err := memory.NewStore("", logger)
+	require.NoError(t, err)
+	repo := memoryrepo.NewKeyMetadataRepository(memoryrepo.NewDB())
+
+	pubKeys := storeKeys(t, from, 3)
+	for _, pubKey := range pubKeys {
+		require.NoError(t, repo.Create(ctx, &model.KeyMetadata{
+			PublicKeyG1:      pubKey,
+			PublicKeyG2:      "g2",
+			StoreName:        "disk",
+			KeyVersion:       "3",
+			KeyVersionPinned: true,
+		}))
+	}
+
+	report, err := New(from, to, repo, logger).Run(ctx, Options{
+		Password:     testPassword,
+		StoreName:    "hsm",
+		DeleteSource: true,
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, pubKeys, report.Migrated)
+	assert.Empty(t, report.Failed)
+
+	for _, pubKey := range pubKeys {
+		_, err := to.RetrieveKey(ctx, pubKey, testPassword)
+		assert.NoError(t, err)
+		_, err = from.RetrieveKey(ctx, pubKey, testPassword)
+		assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+		metadata, err := repo.Get(ctx, pubKey)
+		require.NoError(t, err)
+		assert.Equal(t, "hsm", metadata.StoreName)
+
+		// The version of the source store is forgotten
+		assert.Empty(t, metadata.KeyVersion)
+		assert.False(t, metadata.KeyVersionPinned)
+	}
+}
+
+func TestMigrateKeepsSource(t *testing.T) {
+	ctx := context.Background()
+	logger := testutils.GetTestLogger()
+	from, err := memory.NewStore("", logger)
+	require.NoError(t, err)
+	to, err := memory.NewStore("", logger)
+	require.NoError(t, err)
+	repo := memoryrepo.NewKeyMetadataRepository(memoryrepo.NewDB())
+
+	pubKeys := storeKeys(t, from, 2)
+
+	// The first key was copied by an earlier run
+	keyPair, err := from.RetrieveKey(ctx, pubKeys[0], testPassword)
+	require.NoError(t, err)
+	privKey := keyPair.PrivKey.Bytes()
+	_, err = to.StoreKey(ctx, &keystore.KeyPair{PrivateKey: privKey[:], Password: testPassword})
+	require.NoError(t, err)
+
+	report, err := New(from, to, repo, logger).Run(ctx, Options{Password: testPassword})
+	require.NoError(t, err)
+	assert.Equal(t, []string{pubKeys[1]}, report.Migrated)
+	assert.Equal(t, []string{pubKeys[0]}, report.AlreadyMigrated)
+	assert.Empty(t, report.Failed)
+
+	keys, err := from.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, pubKeys, keys)
+}
+
+func TestMigrateFailures(t *testing.T) {
+	ctx := context.Background()
+	logger := testutils.GetTestLogger()
+	from, err := memory.NewStore("", logger)
+	require.NoError(t, err)
+	backend, err := memory.NewStore("", logger)
+	require.NoError(t, err)
+	repo := memoryrepo.NewKeyMetadataRepository(memoryrepo.NewDB())
+
+	other, err := crypto.NewKeyPairFromHexString(
+		"040ad69253b921aca71dd714cccc3095576fbe1a21f86c9b10cb5b119b1c6899",
+	)
+	require.NoError(t, err)
+	to := &corruptStore{Store: backend, other: other}
+
+	pubKeys := storeKeys(t, from, 1)
+	report, err := New(from, to, repo, logger).Run(ctx, Options{
+		PublicKeys:   append(pubKeys, "0x"+pubKeys[0][:10]),
+		Password:     testPassword,
+		DeleteSource: true,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, report.Migrated)
+	require.Len(t, report.Failed, 2)
+
+	// The copy which doesn't match is removed and the source is kept
+	keys, err := backend.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	_, err = from.RetrieveKey(ctx, pubKeys[0], testPassword)
+	assert.NoError(t, err)
+
+	// A missing key fails on its own
+	assert.True(t, errors.Is(report.Failed[1].Error, store.ErrKeyNotFound))
+}
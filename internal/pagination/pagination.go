@@ -0,0 +1,96 @@
+// Package pagination carries key listing options over gRPC metadata.
+//
+// The cerberus-api list requests have no pagination fields yet, so clients
+// pass page size, page token and filters as request headers and receive the
+// next page token as a response header. Requests without any of these headers
+// keep the previous behaviour and return every key.
+package pagination
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/common"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	PageSizeHeader              = "x-page-size"
+	PageTokenHeader             = "x-page-token"
+	LockedFilterHeader          = "x-filter-locked"
+	CreatedAfterFilterHeader    = "x-filter-created-after"
+	CreatedBeforeFilterHeader   = "x-filter-created-before"
+	PublicKeyPrefixFilterHeader = "x-filter-public-key-g1-prefix"
+
+	NextPageTokenHeader = "x-next-page-token"
+)
+
+// ListOptionsFromContext builds list options from the incoming request headers.
+// Timestamps are expected in RFC 3339 format.
+func ListOptionsFromContext(ctx context.Context) (*repository.ListOptions, error) {
+	opts := &repository.ListOptions{}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return opts, nil
+	}
+
+	if v := lastValue(md, PageSizeHeader); v != "" {
+		pageSize, err := strconv.Atoi(v)
+		if err != nil || pageSize < 0 {
+			return nil, fmt.Errorf("invalid %s: %q", PageSizeHeader, v)
+		}
+		opts.PageSize = pageSize
+	}
+
+	opts.PageToken = lastValue(md, PageTokenHeader)
+
+	if v := lastValue(md, LockedFilterHeader); v != "" {
+		locked, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %q", LockedFilterHeader, v)
+		}
+		opts.Locked = &locked
+	}
+
+	if v := lastValue(md, CreatedAfterFilterHeader); v != "" {
+		createdAfter, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %q", CreatedAfterFilterHeader, v)
+		}
+		opts.CreatedAfter = createdAfter
+	}
+
+	if v := lastValue(md, CreatedBeforeFilterHeader); v != "" {
+		createdBefore, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %q", CreatedBeforeFilterHeader, v)
+		}
+		opts.CreatedBefore = createdBefore
+	}
+
+	opts.PublicKeyG1Prefix = common.Trim0x(lastValue(md, PublicKeyPrefixFilterHeader))
+
+	return opts, nil
+}
+
+// SetNextPageToken sends the next page token to the client as a response header.
+// Nothing is sent when the token is empty.
+func SetNextPageToken(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+	return grpc.SetHeader(ctx, metadata.Pairs(NextPageTokenHeader, token))
+}
+
+func lastValue(md metadata.MD, key string) string {
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
@@ -0,0 +1,55 @@
+package pagination
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestListOptionsFromContext(t *testing.T) {
+	t.Run("no metadata returns every key", func(t *testing.T) {
+		opts, err := ListOptionsFromContext(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, opts.PageSize)
+		assert.Nil(t, opts.Locked)
+	})
+
+	t.Run("all options", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+			PageSizeHeader, "10",
+			PageTokenHeader, "token",
+			LockedFilterHeader, "true",
+			CreatedAfterFilterHeader, "2025-01-01T00:00:00Z",
+			CreatedBeforeFilterHeader, "2025-02-01T00:00:00Z",
+			PublicKeyPrefixFilterHeader, "0xab",
+		))
+		opts, err := ListOptionsFromContext(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 10, opts.PageSize)
+		assert.Equal(t, "token", opts.PageToken)
+		require.NotNil(t, opts.Locked)
+		assert.True(t, *opts.Locked)
+		assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), opts.CreatedAfter)
+		assert.Equal(t, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), opts.CreatedBefore)
+		assert.Equal(t, "ab", opts.PublicKeyG1Prefix)
+	})
+
+	invalid := map[string]string{
+		PageSizeHeader:            "-1",
+		LockedFilterHeader:        "maybe",
+		CreatedAfterFilterHeader:  "yesterday",
+		CreatedBeforeFilterHeader: "2025-02-01",
+	}
+	for header, value := range invalid {
+		t.Run("invalid "+header, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(header, value))
+			_, err := ListOptionsFromContext(ctx)
+			assert.Error(t, err)
+		})
+	}
+}
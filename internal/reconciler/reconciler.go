@@ -0,0 +1,310 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/Layr-Labs/bn254-keystore-go/curve"
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+
+	"github.com/Layr-Labs/cerberus/internal/common"
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+// Options controls a reconciliation run
+type Options struct {
+	// Repair fixes the inconsistencies that can be fixed safely:
+	// keys in the store without metadata are registered in a locked state,
+	// and metadata without a stored key or with a mismatching G1/G2 pair is locked.
+	// The signer refuses locked keys, whatever their API keys.
+	Repair bool
+
+	// Password is used to read keys from stores that encrypt them
+	// (e.g. filesystem) when registering their metadata during repair.
+	// Without it, the keys of these stores are skipped.
+	Password string
+}
+
+// Action describes a repair performed on a key
+type Action struct {
+	PublicKeyG1 string
+	Description string
+}
+
+// Report is the outcome of a reconciliation run
+type Report struct {
+	// MissingMetadata lists keys present in the store without a metadata row
+	MissingMetadata []string
+
+	// MissingFromStore lists metadata rows whose key is not in the store
+	MissingFromStore []string
+
+	// InvalidKeyPairs lists metadata rows whose G1 and G2 public keys don't match
+	InvalidKeyPairs []string
+
+	// ReplicaDrift lists the keys missing from some replicas of a replicated
+	// store. It is reported only, the keys must be copied to the replicas.
+	ReplicaDrift []store.Drift
+
+	// Repaired lists the repairs performed when Options.Repair is set
+	Repaired []Action
+
+	// Failed lists the keys that could not be repaired
+	Failed []Action
+
+	// Skipped lists the keys that were not registered because their store
+	// encrypts them and no password was given
+	Skipped []Action
+}
+
+// Consistent returns true if no inconsistency was found
+func (r *Report) Consistent() bool {
+	return len(r.MissingMetadata) == 0 &&
+		len(r.MissingFromStore) == 0 &&
+		len(r.InvalidKeyPairs) == 0 &&
+		len(r.ReplicaDrift) == 0
+}
+
+// Reconciler compares the keys in a store with the key metadata in the database
+type Reconciler struct {
+	store           store.Store
+	keyMetadataRepo repository.KeyMetadataRepository
+	logger          *slog.Logger
+}
+
+func New(
+	store store.Store,
+	keyMetadataRepo repository.KeyMetadataRepository,
+	logger *slog.Logger,
+) *Reconciler {
+	return &Reconciler{
+		store:           store,
+		keyMetadataRepo: keyMetadataRepo,
+		logger:          logger.With("component", "reconciler"),
+	}
+}
+
+// Run compares the store with the key metadata and repairs what it can
+// if requested
+func (r *Reconciler) Run(ctx context.Context, opts Options) (*Report, error) {
+	storedKeys, err := r.store.ListKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys from store: %w", err)
+	}
+
+	metadata, err := r.keyMetadataRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list key metadata: %w", err)
+	}
+
+	inStore := make(map[string]struct{}, len(storedKeys))
+	for _, key := range storedKeys {
+		inStore[normalize(key)] = struct{}{}
+	}
+
+	inMetadata := make(map[string]*model.KeyMetadata, len(metadata))
+	for _, m := range metadata {
+		inMetadata[normalize(m.PublicKeyG1)] = m
+	}
+
+	report := &Report{}
+	for key := range inStore {
+		if _, ok := inMetadata[key]; !ok {
+			report.MissingMetadata = append(report.MissingMetadata, key)
+		}
+	}
+	for key, m := range inMetadata {
+		if _, ok := inStore[key]; !ok {
+			report.MissingFromStore = append(report.MissingFromStore, m.PublicKeyG1)
+		}
+
+		valid, err := crypto.CheckPublicKeyPairHex(key, normalize(m.PublicKeyG2))
+		if err != nil {
+			r.logger.Warn("Failed to check G1/G2 public keys", "key", m.PublicKeyG1, "error", err)
+		}
+		if !valid {
+			report.InvalidKeyPairs = append(report.InvalidKeyPairs, m.PublicKeyG1)
+		}
+	}
+	sort.Strings(report.MissingMetadata)
+	sort.Strings(report.MissingFromStore)
+	sort.Strings(report.InvalidKeyPairs)
+
+	for _, key := range report.MissingMetadata {
+		r.logger.Warn("Key found in store without metadata", "key", key)
+	}
+	for _, key := range report.MissingFromStore {
+		r.logger.Warn("Key metadata found without key in store", "key", key)
+	}
+	for _, key := range report.InvalidKeyPairs {
+		r.logger.Warn("Key metadata has mismatching G1 and G2 public keys", "key", key)
+	}
+
+	if reporter, ok := store.As[store.DriftReporter](r.store); ok {
+		drift, err := reporter.Drift(ctx)
+		if err != nil {
+			r.logger.Warn("Failed to compare the replicas of the store", "error", err)
+		}
+		report.ReplicaDrift = drift
+		for _, d := range drift {
+			r.logger.Warn(
+				"Key is missing from replicas",
+				"key", d.PubKey,
+				"replicas", d.MissingFrom,
+			)
+		}
+	}
+
+	if opts.Repair {
+		r.repair(ctx, report, inMetadata, opts.Password)
+	}
+
+	r.logger.Info(
+		"Reconciliation completed",
+		"stored_keys", len(storedKeys),
+		"metadata_rows", len(metadata),
+		"missing_metadata", len(report.MissingMetadata),
+		"missing_from_store", len(report.MissingFromStore),
+		"invalid_key_pairs", len(report.InvalidKeyPairs),
+		"replica_drift", len(report.ReplicaDrift),
+		"repaired", len(report.Repaired),
+		"failed", len(report.Failed),
+		"skipped", len(report.Skipped),
+	)
+	return report, nil
+}
+
+func (r *Reconciler) repair(
+	ctx context.Context,
+	report *Report,
+	inMetadata map[string]*model.KeyMetadata,
+	password string,
+) {
+	for _, key := range report.MissingMetadata {
+		keyStore, storeName, err := r.locate(ctx, key)
+		if err == nil && password == "" && store.ChecksPassword(keyStore) {
+			r.logger.Warn("Skipped registering encrypted key without password", "key", key)
+			report.Skipped = append(report.Skipped, Action{
+				PublicKeyG1: key,
+				Description: "register metadata: the store encrypts the key, a password is required",
+			})
+			continue
+		}
+		if err == nil {
+			err = r.registerKey(ctx, keyStore, storeName, key, password)
+		}
+		if err != nil {
+			r.logger.Error("Failed to register key metadata", "key", key, "error", err)
+			report.Failed = append(report.Failed, Action{
+				PublicKeyG1: key,
+				Description: fmt.Sprintf("register metadata: %v", err),
+			})
+			continue
+		}
+		report.Repaired = append(report.Repaired, Action{
+			PublicKeyG1: key,
+			Description: "registered metadata in locked state",
+		})
+	}
+
+	toLock := make(map[string]string)
+	for _, key := range report.MissingFromStore {
+		toLock[key] = "locked key missing from store"
+	}
+	for _, key := range report.InvalidKeyPairs {
+		toLock[key] = "locked key with mismatching G1 and G2 public keys"
+	}
+	keys := make([]string, 0, len(toLock))
+	for key := range toLock {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if m := inMetadata[normalize(key)]; m != nil && m.Locked {
+			continue
+		}
+		if err := r.keyMetadataRepo.UpdateLockStatus(ctx, key, true); err != nil {
+			r.logger.Error("Failed to lock key", "key", key, "error", err)
+			report.Failed = append(report.Failed, Action{
+				PublicKeyG1: key,
+				Description: fmt.Sprintf("lock key: %v", err),
+			})
+			continue
+		}
+		report.Repaired = append(report.Repaired, Action{
+			PublicKeyG1: key,
+			Description: toLock[key],
+		})
+	}
+}
+
+// RegisterKey creates the metadata of a key found in the store. The key is
+// locked, which the signer refuses, and has no API key, so an operator has to
+// review it, unlock it and generate an API key before it can sign.
+func (r *Reconciler) RegisterKey(ctx context.Context, pubKeyHex string, password string) error {
+	keyStore, storeName, err := r.locate(ctx, pubKeyHex)
+	if err != nil {
+		return err
+	}
+	return r.registerKey(ctx, keyStore, storeName, pubKeyHex, password)
+}
+
+// locate returns the store holding the key and its name
+func (r *Reconciler) locate(ctx context.Context, pubKeyHex string) (store.Store, string, error) {
+	storeName, err := store.Locate(ctx, r.store, pubKeyHex)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to locate key: %w", err)
+	}
+	return store.Route(r.store, storeName)
+}
+
+func (r *Reconciler) registerKey(
+	ctx context.Context,
+	keyStore store.Store,
+	storeName string,
+	pubKeyHex string,
+	password string,
+) error {
+	keyPair, err := keyStore.RetrieveKey(ctx, pubKeyHex, password)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve key: %w", err)
+	}
+
+	privKeyBytes := keyPair.PrivKey.Bytes()
+	ks := &keystore.KeyPair{PrivateKey: privKeyBytes[:]}
+	g1PubKey, err := ks.GetG1PublicKey(curve.BN254)
+	if err != nil {
+		return err
+	}
+	if g1PubKey != pubKeyHex {
+		return errors.New("stored key does not match its public key")
+	}
+
+	g2PubKey, err := ks.GetG2PublicKey(curve.BN254)
+	if err != nil {
+		return err
+	}
+
+	err = r.keyMetadataRepo.Create(ctx, &model.KeyMetadata{
+		PublicKeyG1: g1PubKey,
+		PublicKeyG2: g2PubKey,
+		StoreName:   storeName,
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.keyMetadataRepo.UpdateLockStatus(ctx, g1PubKey, true)
+}
+
+func normalize(key string) string {
+	return strings.ToLower(common.Trim0x(key))
+}
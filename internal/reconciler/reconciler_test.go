@@ -0,0 +1,170 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Layr-Labs/bn254-keystore-go/curve"
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/filesystem"
+	"github.com/Layr-Labs/cerberus/internal/store/memory"
+	"github.com/Layr-Labs/cerberus/internal/store/replicated"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPassword = "p@$$w0rd"
+
+type fakeKeyMetadataRepo struct {
+	repository.KeyMetadataRepository
+	keys map[string]*model.KeyMetadata
+}
+
+func (r *fakeKeyMetadataRepo) Create(_ context.Context, metadata *model.KeyMetadata) error {
+	r.keys[metadata.PublicKeyG1] = metadata
+	return nil
+}
+
+func (r *fakeKeyMetadataRepo) UpdateLockStatus(
+	_ context.Context,
+	publicKeyG1 string,
+	locked bool,
+) error {
+	m, ok := r.keys[publicKeyG1]
+	if !ok {
+		return repository.ErrKeyNotFound
+	}
+	m.Locked = locked
+	return nil
+}
+
+func (r *fakeKeyMetadataRepo) List(_ context.Context) ([]*model.KeyMetadata, error) {
+	keys := make([]*model.KeyMetadata, 0, len(r.keys))
+	for _, m := range r.keys {
+		keys = append(keys, m)
+	}
+	return keys, nil
+}
+
+func newKey(t *testing.T) (*keystore.KeyPair, string, string) {
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	g1, err := keyPair.GetG1PublicKey(curve.BN254)
+	require.NoError(t, err)
+	g2, err := keyPair.GetG2PublicKey(curve.BN254)
+	require.NoError(t, err)
+	return keyPair, g1, g2
+}
+
+func TestReconciler(t *testing.T) {
+	ctx := context.Background()
+	logger := testutils.GetTestLogger()
+	fs := filesystem.NewStore(t.TempDir(), logger)
+	repo := &fakeKeyMetadataRepo{keys: map[string]*model.KeyMetadata{}}
+
+	// Consistent key
+	validKey, validG1, validG2 := newKey(t)
+	_, err := fs.StoreKey(ctx, validKey)
+	require.NoError(t, err)
+	repo.keys[validG1] = &model.KeyMetadata{PublicKeyG1: validG1, PublicKeyG2: validG2}
+
+	// Key in store without metadata
+	orphanKey, orphanG1, orphanG2 := newKey(t)
+	_, err = fs.StoreKey(ctx, orphanKey)
+	require.NoError(t, err)
+
+	// Metadata without key in store
+	_, missingG1, missingG2 := newKey(t)
+	repo.keys[missingG1] = &model.KeyMetadata{PublicKeyG1: missingG1, PublicKeyG2: missingG2}
+
+	// Metadata with mismatching G1 and G2
+	mismatchKey, mismatchG1, _ := newKey(t)
+	_, err = fs.StoreKey(ctx, mismatchKey)
+	require.NoError(t, err)
+	repo.keys[mismatchG1] = &model.KeyMetadata{PublicKeyG1: mismatchG1, PublicKeyG2: validG2}
+
+	r := New(fs, repo, logger)
+
+	report, err := r.Run(ctx, Options{})
+	require.NoError(t, err)
+	assert.False(t, report.Consistent())
+	assert.Equal(t, []string{orphanG1}, report.MissingMetadata)
+	assert.Equal(t, []string{missingG1}, report.MissingFromStore)
+	assert.Equal(t, []string{mismatchG1}, report.InvalidKeyPairs)
+	assert.Empty(t, report.Repaired)
+	assert.Len(t, repo.keys, 3)
+
+	report, err = r.Run(ctx, Options{Repair: true, Password: testPassword})
+	require.NoError(t, err)
+	assert.Len(t, report.Repaired, 3)
+	assert.Empty(t, report.Failed)
+
+	require.Contains(t, repo.keys, orphanG1)
+	assert.Equal(t, orphanG2, repo.keys[orphanG1].PublicKeyG2)
+	assert.True(t, repo.keys[orphanG1].Locked)
+	assert.True(t, repo.keys[missingG1].Locked)
+	assert.True(t, repo.keys[mismatchG1].Locked)
+	assert.False(t, repo.keys[validG1].Locked)
+
+	report, err = r.Run(ctx, Options{})
+	require.NoError(t, err)
+	assert.Empty(t, report.MissingMetadata)
+}
+
+func TestReconcilerEncryptedKeyWithoutPassword(t *testing.T) {
+	ctx := context.Background()
+	logger := testutils.GetTestLogger()
+	fs := filesystem.NewStore(t.TempDir(), logger)
+	repo := &fakeKeyMetadataRepo{keys: map[string]*model.KeyMetadata{}}
+
+	keyPair, g1, _ := newKey(t)
+	_, err := fs.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	// The key can't be read without its password, so it isn't registered
+	report, err := New(fs, repo, logger).Run(ctx, Options{Repair: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{g1}, report.MissingMetadata)
+	require.Len(t, report.Skipped, 1)
+	assert.Equal(t, g1, report.Skipped[0].PublicKeyG1)
+	assert.Empty(t, report.Failed)
+	assert.Empty(t, report.Repaired)
+	assert.Empty(t, repo.keys)
+}
+
+func TestReconcilerReplicaDrift(t *testing.T) {
+	ctx := context.Background()
+	logger := testutils.GetTestLogger()
+	primary, err := memory.NewStore("", logger)
+	require.NoError(t, err)
+	secondary, err := memory.NewStore("", logger)
+	require.NoError(t, err)
+	s, err := replicated.NewStore([]replicated.Replica{
+		{Name: "primary", Store: primary},
+		{Name: "secondary", Store: secondary},
+	}, 2, logger)
+	require.NoError(t, err)
+	repo := &fakeKeyMetadataRepo{keys: map[string]*model.KeyMetadata{}}
+
+	// Key missing from the secondary replica
+	keyPair, g1, g2 := newKey(t)
+	_, err = primary.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+	repo.keys[g1] = &model.KeyMetadata{PublicKeyG1: g1, PublicKeyG2: g2}
+
+	report, err := New(s, repo, logger).Run(ctx, Options{Repair: true})
+	require.NoError(t, err)
+	assert.False(t, report.Consistent())
+	expected := []store.Drift{{PubKey: g1, MissingFrom: []string{"secondary"}}}
+	assert.Equal(t, expected, report.ReplicaDrift)
+	assert.Empty(t, report.MissingMetadata)
+	assert.Empty(t, report.Repaired)
+	assert.False(t, repo.keys[g1].Locked)
+}
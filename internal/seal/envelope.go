@@ -0,0 +1,141 @@
+package seal
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// envelopePrefix marks secrets written by the envelope encryption layer.
+// Secrets without it were written in plaintext before the master key was
+// configured.
+const envelopePrefix = "cerberus-envelope:v1:"
+
+// envelope is a secret encrypted with a random data key, which is itself
+// encrypted with the master key. Both ciphertexts are prefixed with their
+// GCM nonce.
+type envelope struct {
+	// MasterKey is the fingerprint of the master key wrapping the data key
+	MasterKey string `json:"kek"`
+	DataKey   []byte `json:"dek"`
+	Data      []byte `json:"data"`
+}
+
+// IsEnvelope reports whether the secret was written by Encrypt
+func IsEnvelope(secret []byte) bool {
+	return bytes.HasPrefix(secret, []byte(envelopePrefix))
+}
+
+// Encrypt encrypts the plaintext with a new data key wrapped by the master
+// key. The additional data, typically the public key, is authenticated but
+// not stored, and must be passed again to Decrypt.
+func (k *Keeper) Encrypt(plaintext []byte, additionalData []byte) ([]byte, error) {
+	masterKey, err := k.masterKey()
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	defer clear(dataKey)
+
+	data, err := seal(dataKey, plaintext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey, err := seal(masterKey, dataKey, additionalData)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(envelope{
+		MasterKey: k.fingerprint,
+		DataKey:   wrappedKey,
+		Data:      data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(envelopePrefix)+base64.StdEncoding.EncodedLen(len(encoded)))
+	copy(out, envelopePrefix)
+	base64.StdEncoding.Encode(out[len(envelopePrefix):], encoded)
+	return out, nil
+}
+
+// Decrypt decrypts a secret written by Encrypt with the same additional data
+func (k *Keeper) Decrypt(secret []byte, additionalData []byte) ([]byte, error) {
+	if !IsEnvelope(secret) {
+		return nil, errors.New("secret is not envelope encrypted")
+	}
+	masterKey, err := k.masterKey()
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := base64.StdEncoding.DecodeString(string(secret[len(envelopePrefix):]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode envelope: %w", err)
+	}
+	var env envelope
+	if err := json.Unmarshal(encoded, &env); err != nil {
+		return nil, fmt.Errorf("failed to decode envelope: %w", err)
+	}
+	if env.MasterKey != k.fingerprint {
+		return nil, fmt.Errorf(
+			"secret is wrapped by master key %s, not %s",
+			env.MasterKey,
+			k.fingerprint,
+		)
+	}
+
+	dataKey, err := open(masterKey, env.DataKey, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	defer clear(dataKey)
+
+	plaintext, err := open(dataKey, env.Data, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return plaintext, nil
+}
+
+func seal(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,77 @@
+package seal
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const (
+	StatusPath = "/v1/seal/status"
+	UnsealPath = "/v1/seal/unseal"
+)
+
+// UnsealRequest is the body of an unseal request
+type UnsealRequest struct {
+	// Share is a hex encoded share of the master key
+	Share string `json:"share"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewHandler returns the HTTP handler through which operators submit their
+// shares of the master key
+func NewHandler(keeper *Keeper, logger *slog.Logger) http.Handler {
+	logger = logger.With("component", "unseal-server")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(StatusPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, keeper.Status())
+	})
+	mux.HandleFunc(UnsealPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+			return
+		}
+
+		var req UnsealRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request body"})
+			return
+		}
+		share, err := hex.DecodeString(strings.TrimSpace(req.Share))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "share is not hex encoded"})
+			return
+		}
+
+		status, err := keeper.Unseal(share)
+		clear(share)
+		if err != nil {
+			logger.Warn("Rejected unseal share", "remote", r.RemoteAddr, "error", err)
+			code := http.StatusBadRequest
+			if errors.Is(err, ErrInvalidShares) {
+				code = http.StatusForbidden
+			}
+			writeJSON(w, code, errorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,184 @@
+package seal
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// KeySize is the size of the master key and of the data keys in bytes
+const KeySize = 32
+
+var (
+	// ErrSealed is returned when the master key hasn't been unsealed yet
+	ErrSealed = errors.New("master key is sealed")
+
+	// ErrInvalidShares is returned when the submitted shares don't
+	// reconstruct the master key
+	ErrInvalidShares = errors.New("unseal shares don't match the master key")
+)
+
+// Status describes the progress of the unseal flow
+type Status struct {
+	Sealed    bool `json:"sealed"`
+	Threshold int  `json:"threshold"`
+	Progress  int  `json:"progress"`
+}
+
+// Keeper holds the master key which wraps the data key of every secret.
+// A keeper created from shares stays sealed until enough shares have been
+// submitted to reconstruct the master key.
+type Keeper struct {
+	mu          sync.RWMutex
+	key         []byte
+	fingerprint string
+	threshold   int
+	shares      [][]byte
+
+	logger *slog.Logger
+}
+
+// NewKeeper returns an unsealed keeper holding the master key
+func NewKeeper(key []byte, logger *slog.Logger) (*Keeper, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return &Keeper{
+		key:         append([]byte(nil), key...),
+		fingerprint: Fingerprint(key),
+		logger:      logger.With("component", "seal"),
+	}, nil
+}
+
+// NewSealedKeeper returns a sealed keeper which needs threshold shares of
+// the master key with the given fingerprint to unseal
+func NewSealedKeeper(threshold int, fingerprint string, logger *slog.Logger) (*Keeper, error) {
+	if threshold < 2 {
+		return nil, errors.New("unseal threshold must be at least 2")
+	}
+	if fingerprint == "" {
+		return nil, errors.New("master key fingerprint is required")
+	}
+	return &Keeper{
+		fingerprint: strings.ToLower(fingerprint),
+		threshold:   threshold,
+		logger:      logger.With("component", "seal"),
+	}, nil
+}
+
+// GenerateKey returns a new random master key
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// LoadKEKFile reads a hex encoded master key from a file
+func LoadKEKFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("master key file is not hex encoded: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// Fingerprint identifies a master key without revealing it
+func Fingerprint(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("cerberus master key fingerprint"))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// Status returns whether the keeper is sealed and the unseal progress
+func (k *Keeper) Status() Status {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.status()
+}
+
+// Fingerprint returns the fingerprint of the master key
+func (k *Keeper) Fingerprint() string {
+	return k.fingerprint
+}
+
+// Unseal submits one share of the master key. Once the threshold is reached
+// the master key is reconstructed and checked against its fingerprint.
+// Mismatching shares reset the progress so the operators can start over.
+func (k *Keeper) Unseal(share []byte) (Status, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.key != nil {
+		return k.status(), nil
+	}
+	if len(share) != KeySize+1 {
+		return k.status(), fmt.Errorf("share must be %d bytes, got %d", KeySize+1, len(share))
+	}
+	for _, s := range k.shares {
+		if s[KeySize] == share[KeySize] {
+			return k.status(), errors.New("share has already been submitted")
+		}
+	}
+	k.shares = append(k.shares, append([]byte(nil), share...))
+	k.logger.Info("Received unseal share", "progress", len(k.shares), "threshold", k.threshold)
+
+	if len(k.shares) < k.threshold {
+		return k.status(), nil
+	}
+
+	key, err := Combine(k.shares)
+	k.resetShares()
+	if err != nil {
+		return k.status(), err
+	}
+	if subtle.ConstantTimeCompare([]byte(Fingerprint(key)), []byte(k.fingerprint)) != 1 {
+		clear(key)
+		k.logger.Warn("Unseal shares don't match the master key, progress was reset")
+		return k.status(), ErrInvalidShares
+	}
+
+	k.key = key
+	k.logger.Info("Master key unsealed", "fingerprint", k.fingerprint)
+	return k.status(), nil
+}
+
+func (k *Keeper) masterKey() ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.key == nil {
+		return nil, ErrSealed
+	}
+	return k.key, nil
+}
+
+func (k *Keeper) resetShares() {
+	for _, s := range k.shares {
+		clear(s)
+	}
+	k.shares = nil
+}
+
+func (k *Keeper) status() Status {
+	return Status{
+		Sealed:    k.key == nil,
+		Threshold: k.threshold,
+		Progress:  len(k.shares),
+	}
+}
@@ -0,0 +1,130 @@
+package seal
+
+import (
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+func TestEnvelopeEncryption(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+	keeper, err := NewKeeper(key, testLogger)
+	require.NoError(t, err)
+
+	plaintext := []byte("private key")
+	secret, err := keeper.Encrypt(plaintext, []byte("pubkey"))
+	require.NoError(t, err)
+	assert.True(t, IsEnvelope(secret))
+	assert.NotContains(t, string(secret), string(plaintext))
+
+	decrypted, err := keeper.Decrypt(secret, []byte("pubkey"))
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, decrypted)
+
+	// The secret can't be moved to another public key
+	_, err = keeper.Decrypt(secret, []byte("other pubkey"))
+	assert.Error(t, err)
+
+	// Another master key can't decrypt it
+	otherKey, err := GenerateKey()
+	require.NoError(t, err)
+	otherKeeper, err := NewKeeper(otherKey, testLogger)
+	require.NoError(t, err)
+	_, err = otherKeeper.Decrypt(secret, []byte("pubkey"))
+	assert.Error(t, err)
+
+	// Plaintext secrets aren't envelopes
+	assert.False(t, IsEnvelope([]byte(hex.EncodeToString(plaintext))))
+	_, err = keeper.Decrypt(plaintext, []byte("pubkey"))
+	assert.Error(t, err)
+}
+
+func TestUnseal(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+	shares, err := Split(key, 3, 2)
+	require.NoError(t, err)
+
+	keeper, err := NewSealedKeeper(2, Fingerprint(key), testLogger)
+	require.NoError(t, err)
+
+	_, err = keeper.Encrypt([]byte("private key"), nil)
+	assert.ErrorIs(t, err, ErrSealed)
+
+	status, err := keeper.Unseal(shares[0])
+	require.NoError(t, err)
+	assert.Equal(t, Status{Sealed: true, Threshold: 2, Progress: 1}, status)
+
+	// The same share is only counted once
+	_, err = keeper.Unseal(shares[0])
+	assert.Error(t, err)
+
+	status, err = keeper.Unseal(shares[2])
+	require.NoError(t, err)
+	assert.Equal(t, Status{Sealed: false, Threshold: 2, Progress: 0}, status)
+
+	// Secrets encrypted with the unsealed key match the original key
+	secret, err := keeper.Encrypt([]byte("private key"), nil)
+	require.NoError(t, err)
+	original, err := NewKeeper(key, testLogger)
+	require.NoError(t, err)
+	decrypted, err := original.Decrypt(secret, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("private key"), decrypted)
+}
+
+func TestUnsealWrongShares(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+	shares, err := Split(key, 3, 2)
+	require.NoError(t, err)
+
+	otherKey, err := GenerateKey()
+	require.NoError(t, err)
+	otherShares, err := Split(otherKey, 3, 2)
+	require.NoError(t, err)
+
+	keeper, err := NewSealedKeeper(2, Fingerprint(key), testLogger)
+	require.NoError(t, err)
+
+	_, err = keeper.Unseal(shares[0])
+	require.NoError(t, err)
+	status, err := keeper.Unseal(otherShares[1])
+	assert.ErrorIs(t, err, ErrInvalidShares)
+	assert.Equal(t, Status{Sealed: true, Threshold: 2, Progress: 0}, status)
+
+	// The operators can start over
+	_, err = keeper.Unseal(shares[1])
+	require.NoError(t, err)
+	status, err = keeper.Unseal(shares[2])
+	require.NoError(t, err)
+	assert.False(t, status.Sealed)
+}
+
+func TestLoadKEKFile(t *testing.T) {
+	dir := t.TempDir()
+	key, err := GenerateKey()
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "kek")
+	require.NoError(t, os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600))
+	loaded, err := LoadKEKFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, key, loaded)
+
+	short := filepath.Join(dir, "short")
+	require.NoError(t, os.WriteFile(short, []byte("abcd"), 0600))
+	_, err = LoadKEKFile(short)
+	assert.Error(t, err)
+
+	_, err = LoadKEKFile(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
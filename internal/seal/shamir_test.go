@@ -0,0 +1,73 @@
+package seal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret, err := GenerateKey()
+	require.NoError(t, err)
+
+	shares, err := Split(secret, 5, 3)
+	require.NoError(t, err)
+	require.Len(t, shares, 5)
+
+	// Any 3 shares in any order reconstruct the secret
+	for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		var selected [][]byte
+		for _, i := range subset {
+			selected = append(selected, shares[i])
+		}
+		combined, err := Combine(selected)
+		require.NoError(t, err)
+		assert.Equal(t, secret, combined, "shares %v", subset)
+	}
+
+	// Fewer shares than the threshold give a wrong secret
+	combined, err := Combine(shares[:2])
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, combined)
+}
+
+func TestSplitInvalidParameters(t *testing.T) {
+	secret := []byte("secret")
+
+	_, err := Split(nil, 3, 2)
+	assert.Error(t, err)
+
+	_, err = Split(secret, 3, 1)
+	assert.Error(t, err)
+
+	_, err = Split(secret, 2, 3)
+	assert.Error(t, err)
+
+	_, err = Split(secret, 256, 3)
+	assert.Error(t, err)
+}
+
+func TestCombineInvalidShares(t *testing.T) {
+	shares, err := Split([]byte("secret"), 3, 2)
+	require.NoError(t, err)
+
+	_, err = Combine(shares[:1])
+	assert.Error(t, err)
+
+	_, err = Combine([][]byte{shares[0], shares[0]})
+	assert.Error(t, err)
+
+	_, err = Combine([][]byte{shares[0], shares[1][:4]})
+	assert.Error(t, err)
+}
+
+func TestGFArithmetic(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			assert.Equal(t, byte(a), gfDiv(gfMul(byte(a), byte(b)), byte(b)))
+		}
+	}
+	// Known product in the AES field
+	assert.Equal(t, byte(0xc1), gfMul(0x57, 0x83))
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
@@ -15,6 +16,9 @@ import (
 	v1 "github.com/Layr-Labs/cerberus-api/pkg/api/v1"
 
 	"github.com/Layr-Labs/cerberus/internal/configuration"
+	"github.com/Layr-Labs/cerberus/internal/keywatch"
+	"github.com/Layr-Labs/cerberus/internal/reconciler"
+	"github.com/Layr-Labs/cerberus/internal/seal"
 	"github.com/Layr-Labs/cerberus/internal/services/admin"
 	"github.com/Layr-Labs/cerberus/internal/services/kms"
 	"github.com/Layr-Labs/cerberus/internal/services/signing"
@@ -136,16 +140,38 @@ func Start(config *configuration.Configuration, logger *slog.Logger) {
 		config,
 		server.resources.KeyStore,
 		server.resources.KeyMetadataRepo,
+		server.resources.KeyUsageRepo,
+		server.resources.APIKeyRepo,
+		server.resources.APIKeys,
 		logger,
 		server.resources.RpcMetrics,
 	)
 	signingService := signing.NewService(
 		config,
 		server.resources.KeyStore,
+		server.resources.KeyMetadataRepo,
 		logger,
 		server.resources.RpcMetrics,
+		server.resources.UsageTracker,
 	)
 
+	if config.ReconcileOnStartup {
+		keyReconciler := reconciler.New(
+			server.resources.KeyStore,
+			server.resources.KeyMetadataRepo,
+			logger,
+		)
+		go func() {
+			_, err := keyReconciler.Run(
+				context.Background(),
+				reconciler.Options{Repair: config.ReconcileRepair},
+			)
+			if err != nil {
+				logger.Error(fmt.Sprintf("Failed to reconcile keys: %v", err))
+			}
+		}()
+	}
+
 	logger.Info(fmt.Sprintf("Starting gRPC server on port %d...", config.GrpcPort))
 	err := server.AddServiceOnPort(&GrpcServerConfig{
 		Port: config.GrpcPort,
@@ -168,6 +194,9 @@ func Start(config *configuration.Configuration, logger *slog.Logger) {
 			logger,
 			server.resources.RpcMetrics,
 			server.resources.KeyMetadataRepo,
+			server.resources.KeyUsageRepo,
+			server.resources.APIKeyRepo,
+			server.resources.APIKeys,
 		)
 
 		logger.Info(fmt.Sprintf("Starting Admin server on port %d...", config.AdminPort))
@@ -188,6 +217,40 @@ func Start(config *configuration.Configuration, logger *slog.Logger) {
 		}
 	}
 
+	// Accept the unseal shares of the operators
+	if config.MasterKeySource == configuration.ShamirMasterKeySource {
+		go startUnsealServer(config, server.resources.MasterKey, logger)
+	}
+
+	// Act on the key files changed by operators in the keystore directory
+	var keystoreWatch *keywatch.Monitor
+	if server.resources.KeystoreWatcher != nil {
+		var registrar keywatch.Registrar
+		if config.WatchRegisterKeys {
+			registrar = reconciler.New(
+				server.resources.KeyStore,
+				server.resources.KeyMetadataRepo,
+				logger,
+			)
+		}
+		keystoreWatch = keywatch.NewMonitor(
+			server.resources.KeystoreWatcher,
+			signingService,
+			registrar,
+			server.resources.KeyMetadataRepo,
+			config.WatchKeystorePassword,
+			server.resources.KeystoreWatchMetrics,
+			logger,
+		)
+	}
+
+	// Start recording key usage and locking expired keys in the background
+	server.resources.UsageTracker.Start()
+	server.resources.ExpiryMonitor.Start()
+	if keystoreWatch != nil {
+		keystoreWatch.Start()
+	}
+
 	// Start all services
 	// Create a context that can be cancelled
 	ctx, cancel := context.WithCancel(context.Background())
@@ -199,6 +262,38 @@ func Start(config *configuration.Configuration, logger *slog.Logger) {
 		os.Exit(1)
 	}
 
+	// Write the key usage recorded since the last flush
+	server.resources.UsageTracker.Stop()
+	server.resources.ExpiryMonitor.Stop()
+	if keystoreWatch != nil {
+		keystoreWatch.Stop()
+	}
+
+}
+
+// startUnsealServer serves the unseal endpoints, with the TLS certificate of
+// the gRPC servers when configured
+func startUnsealServer(
+	config *configuration.Configuration,
+	keeper *seal.Keeper,
+	logger *slog.Logger,
+) {
+	srv := &http.Server{
+		Addr:              config.UnsealAddress,
+		Handler:           seal.NewHandler(keeper, logger),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	var err error
+	logger.Info(fmt.Sprintf("Starting unseal server on %s...", config.UnsealAddress))
+	if config.TLSCACert != "" && config.TLSServerKey != "" {
+		err = srv.ListenAndServeTLS(config.TLSCACert, config.TLSServerKey)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to start unseal server: %v", err))
+	}
 }
 
 // NewServer creates a new Server instance with shared resources
@@ -1,22 +1,38 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
 
+	"github.com/Layr-Labs/cerberus/internal/apikey"
 	"github.com/Layr-Labs/cerberus/internal/configuration"
 	"github.com/Layr-Labs/cerberus/internal/database"
 	"github.com/Layr-Labs/cerberus/internal/database/repository"
+	memoryrepo "github.com/Layr-Labs/cerberus/internal/database/repository/memory"
 	"github.com/Layr-Labs/cerberus/internal/database/repository/postgres"
+	"github.com/Layr-Labs/cerberus/internal/expiry"
 	"github.com/Layr-Labs/cerberus/internal/metrics"
 	"github.com/Layr-Labs/cerberus/internal/middleware"
+	"github.com/Layr-Labs/cerberus/internal/seal"
 	"github.com/Layr-Labs/cerberus/internal/store"
 	"github.com/Layr-Labs/cerberus/internal/store/awssecretmanager"
+	"github.com/Layr-Labs/cerberus/internal/store/decorator"
 	"github.com/Layr-Labs/cerberus/internal/store/filesystem"
 	"github.com/Layr-Labs/cerberus/internal/store/googlesm"
+	"github.com/Layr-Labs/cerberus/internal/store/hsm"
+	"github.com/Layr-Labs/cerberus/internal/store/k8ssecrets"
+	"github.com/Layr-Labs/cerberus/internal/store/memory"
+	"github.com/Layr-Labs/cerberus/internal/store/pgstore"
+	"github.com/Layr-Labs/cerberus/internal/store/plugin"
+	"github.com/Layr-Labs/cerberus/internal/store/replicated"
+	"github.com/Layr-Labs/cerberus/internal/store/router"
+	"github.com/Layr-Labs/cerberus/internal/store/vault"
+	"github.com/Layr-Labs/cerberus/internal/usage"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
@@ -29,11 +45,22 @@ import (
 
 type SharedResources struct {
 	KeyMetadataRepo repository.KeyMetadataRepository
+	KeyUsageRepo    repository.KeyUsageRepository
+	APIKeyRepo      repository.APIKeyRepository
+	APIKeys         *apikey.Hasher
 	KeyStore        store.Store
+	MasterKey       *seal.Keeper
 	GrpcMiddleware  []grpc.UnaryServerInterceptor
 	RpcMetrics      *metrics.RPCServerMetrics
+	UsageTracker    *usage.Tracker
+	ExpiryMonitor   *expiry.Monitor
 	Logger          *slog.Logger
 
+	// KeystoreWatcher watches the keystore directory, it is nil unless the
+	// watch is enabled
+	KeystoreWatcher      *filesystem.Watcher
+	KeystoreWatchMetrics metrics.KeystoreWatchRecorder
+
 	// Private fields
 	db *sql.DB
 }
@@ -43,45 +70,227 @@ func NewSharedResources(
 	logger *slog.Logger,
 ) *SharedResources {
 
-	// Initialize store
-	keystore, err := initializeStore(config, logger)
+	// Initialize master key, which stays sealed until unsealed by the operators
+	masterKey, err := initializeMasterKey(config, logger)
 	if err != nil {
-		logger.Error(fmt.Sprintf("Failed to initialize store: %v", err))
+		logger.Error(fmt.Sprintf("Failed to initialize master key: %v", err))
 		os.Exit(1)
 	}
 
-	// Initialize database
-	db, err := initializeDatabase(config, logger)
+	// Initialize database and the key metadata, usage and API key repositories
+	db, keyMetadataRepo, keyUsageRepo, apiKeyRepo, err := initializeRepositories(config, logger)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to initialize database: %v", err))
 		os.Exit(1)
 	}
 
-	// Initialize key metadata repository
-	keyMetadataRepo := postgres.NewKeyMetadataRepository(db)
-
 	// Initialize prometheus registry
 	registry := prometheus.NewRegistry()
 	registry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
 	registry.MustRegister(collectors.NewGoCollector())
 	rpcMetrics := metrics.NewRPCServerMetrics("cerberus", registry)
+	keyUsageMetrics := metrics.NewKeyUsageMetrics(
+		"cerberus",
+		registry,
+		config.PerKeyUsageMetrics,
+	)
+	keyExpiryMetrics := metrics.NewKeyExpiryMetrics("cerberus", registry)
+	keystoreWatchMetrics := metrics.NewKeystoreWatchMetrics("cerberus", registry)
+	storeMetrics := metrics.NewStoreMetrics("cerberus", registry)
+
+	// Initialize store, the postgres store shares the database
+	keystore, err := initializeStore(config, masterKey, db, storeMetrics, logger)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to initialize store: %v", err))
+		os.Exit(1)
+	}
+
+	// Initialize key usage tracker
+	usageTracker := usage.NewTracker(
+		keyUsageRepo,
+		keyUsageMetrics,
+		config.UsageFlushInterval,
+		logger,
+	)
+
+	// Initialize key expiry monitor
+	expiryMonitor := expiry.NewMonitor(
+		keyMetadataRepo,
+		keyExpiryMetrics,
+		config.ExpiryCheckInterval,
+		config.ExpiryWarningPeriod,
+		logger,
+	)
+
+	// Initialize keystore directory watcher
+	var keystoreWatcher *filesystem.Watcher
+	if config.WatchKeystoreDir {
+		keystoreWatcher, err = initializeKeystoreWatcher(config, keystore, logger)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to initialize keystore watcher: %v", err))
+			os.Exit(1)
+		}
+	}
 
 	// Start metrics server
 	go startMetricsServer(registry, config.MetricsPort, logger)
 
+	// Initialize API key hasher
+	apiKeys, err := apikey.NewHasher(config.APIKeyPepper)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to initialize API key hasher: %v", err))
+		os.Exit(1)
+	}
+
 	// Initialize grpc middleware
-	grpcMiddleware := initializeGrpcMiddleware(registry, rpcMetrics, keyMetadataRepo)
+	grpcMiddleware := initializeGrpcMiddleware(registry, rpcMetrics, apiKeyRepo, apiKeys, logger)
 
 	return &SharedResources{
 		db:              db,
 		KeyMetadataRepo: keyMetadataRepo,
+		KeyUsageRepo:    keyUsageRepo,
+		APIKeyRepo:      apiKeyRepo,
+		APIKeys:         apiKeys,
 		KeyStore:        keystore,
+		MasterKey:       masterKey,
 		GrpcMiddleware:  grpcMiddleware,
 		RpcMetrics:      rpcMetrics,
+		UsageTracker:    usageTracker,
+		ExpiryMonitor:   expiryMonitor,
 		Logger:          logger,
+
+		KeystoreWatcher:      keystoreWatcher,
+		KeystoreWatchMetrics: keystoreWatchMetrics,
 	}
 }
 
+// KeyResources holds the key store and key metadata repository used by the
+// offline CLI commands, which don't start any server
+type KeyResources struct {
+	KeyMetadataRepo repository.KeyMetadataRepository
+	KeyUsageRepo    repository.KeyUsageRepository
+	APIKeyRepo      repository.APIKeyRepository
+	KeyStore        store.Store
+
+	// Private fields
+	db        *sql.DB
+	config    *configuration.Configuration
+	masterKey *seal.Keeper
+	logger    *slog.Logger
+}
+
+func NewKeyResources(
+	config *configuration.Configuration,
+	logger *slog.Logger,
+) (*KeyResources, error) {
+	masterKey, err := initializeMasterKey(config, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize master key: %w", err)
+	}
+
+	db, keyMetadataRepo, keyUsageRepo, apiKeyRepo, err := initializeRepositories(config, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	keystore, err := initializeStore(
+		config,
+		masterKey,
+		db,
+		metrics.NewNoopStoreMetrics(),
+		logger,
+	)
+	if err != nil {
+		if db != nil {
+			_ = db.Close()
+		}
+		return nil, fmt.Errorf("failed to initialize store: %w", err)
+	}
+
+	return &KeyResources{
+		db:              db,
+		KeyMetadataRepo: keyMetadataRepo,
+		KeyUsageRepo:    keyUsageRepo,
+		APIKeyRepo:      apiKeyRepo,
+		KeyStore:        keystore,
+		config:          config,
+		masterKey:       masterKey,
+		logger:          logger,
+	}, nil
+}
+
+// OpenStore returns the named store if named stores are configured, or else
+// a store of the storage type configured with the parameters of that type.
+// The returned name is the name of the named store, and is empty for a
+// storage type.
+func (r *KeyResources) OpenStore(name string) (store.Store, string, error) {
+	for _, named := range r.config.Stores {
+		if named.Name == name {
+			return store.Route(r.KeyStore, name)
+		}
+	}
+
+	storeConfig := *r.config
+	storeConfig.Stores = nil
+	storeConfig.DefaultStore = ""
+	storeConfig.StorageType = configuration.StorageType(name)
+	if err := storeConfig.Validate(); err != nil {
+		return nil, "", err
+	}
+	s, err := initializeStore(
+		&storeConfig,
+		r.masterKey,
+		r.db,
+		metrics.NewNoopStoreMetrics(),
+		r.logger,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	return s, "", nil
+}
+
+func (r *KeyResources) Close() error {
+	if r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+// initializeRepositories returns the key metadata, usage and API key
+// repositories of the configured database. The returned sql.DB is nil for the
+// memory database.
+func initializeRepositories(
+	config *configuration.Configuration,
+	logger *slog.Logger,
+) (
+	*sql.DB,
+	repository.KeyMetadataRepository,
+	repository.KeyUsageRepository,
+	repository.APIKeyRepository,
+	error,
+) {
+	if config.DatabaseType == configuration.MemoryDatabaseType {
+		logger.Warn("Using the memory database, key metadata is lost on restart")
+		memoryDB := memoryrepo.NewDB()
+		return nil,
+			memoryrepo.NewKeyMetadataRepository(memoryDB),
+			memoryrepo.NewKeyUsageRepository(memoryDB),
+			memoryrepo.NewAPIKeyRepository(memoryDB),
+			nil
+	}
+
+	db, err := initializeDatabase(config, logger)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return db,
+		postgres.NewKeyMetadataRepository(db),
+		postgres.NewKeyUsageRepository(db),
+		postgres.NewAPIKeyRepository(db),
+		nil
+}
+
 func initializeDatabase(
 	config *configuration.Configuration,
 	logger *slog.Logger,
@@ -98,58 +307,327 @@ func initializeDatabase(
 	return db, nil
 }
 
+// initializeMasterKey returns the keeper of the master key wrapping the keys
+// of the cloud stores, or nil when no master key is configured
+func initializeMasterKey(
+	config *configuration.Configuration,
+	logger *slog.Logger,
+) (*seal.Keeper, error) {
+	switch config.MasterKeySource {
+	case configuration.KEKFileMasterKeySource:
+		key, err := seal.LoadKEKFile(config.MasterKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load master key file: %w", err)
+		}
+		defer clear(key)
+
+		keeper, err := seal.NewKeeper(key, logger)
+		if err != nil {
+			return nil, err
+		}
+		if config.MasterKeyFingerprint != "" &&
+			!strings.EqualFold(config.MasterKeyFingerprint, keeper.Fingerprint()) {
+			return nil, fmt.Errorf(
+				"master key file has fingerprint %s, expected %s",
+				keeper.Fingerprint(),
+				config.MasterKeyFingerprint,
+			)
+		}
+		logger.Info("Loaded master key from file", "fingerprint", keeper.Fingerprint())
+		return keeper, nil
+	case configuration.ShamirMasterKeySource:
+		keeper, err := seal.NewSealedKeeper(
+			config.UnsealThreshold,
+			config.MasterKeyFingerprint,
+			logger,
+		)
+		if err != nil {
+			return nil, err
+		}
+		logger.Warn(fmt.Sprintf(
+			"Master key is sealed, %d unseal shares are needed before keys can be used",
+			config.UnsealThreshold,
+		))
+		return keeper, nil
+	default:
+		return nil, nil
+	}
+}
+
 func initializeStore(
 	config *configuration.Configuration,
+	masterKey *seal.Keeper,
+	db *sql.DB,
+	storeMetrics metrics.StoreRecorder,
 	logger *slog.Logger,
 ) (store.Store, error) {
+	if len(config.Stores) > 0 {
+		return initializeRouter(config, masterKey, db, storeMetrics, logger)
+	}
+
 	var keystore store.Store
 	var err error
+	keystoreEncryption := config.CloudSecretFormat == configuration.KeystoreCloudSecretFormat
 	switch config.StorageType {
 	case configuration.FileSystemStorageType:
 		keystore = filesystem.NewStore(config.KeystoreDir, logger)
 	case configuration.AWSSecretManagerStorageType:
+		var awsStore *awssecretmanager.Keystore
 		switch config.AWSAuthenticationMode {
 		case configuration.EnvironmentAWSAuthenticationMode:
-			keystore, err = awssecretmanager.NewStoreWithEnv(
+			awsStore, err = awssecretmanager.NewStoreWithEnv(
 				config.AWSRegion,
 				config.AWSProfile,
 				logger,
 			)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create AWS Secret Manager store: %w", err)
-			}
 			logger.Info("Using environment credentials for AWS Secret Manager")
 		case configuration.SpecifiedAWSAuthenticationMode:
-			keystore, err = awssecretmanager.NewStoreWithSpecifiedCredentials(
+			awsStore, err = awssecretmanager.NewStoreWithSpecifiedCredentials(
 				config.AWSRegion,
 				config.AWSAccessKeyID,
 				config.AWSSecretAccessKey,
 				logger,
 			)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create AWS Secret Manager store: %w", err)
-			}
 			logger.Info("Using specified credentials for AWS Secret Manager")
 		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AWS Secret Manager store: %w", err)
+		}
+		if awsStore != nil {
+			keystore = awsStore.
+				WithMasterKey(masterKey).
+				WithKeystoreEncryption(keystoreEncryption).
+				WithPrefix(config.AWSSecretPrefix).
+				WithSecretOptions(awssecretmanager.SecretOptions{
+					KMSKeyID:       config.AWSKMSKeyID,
+					Tags:           config.AWSTags,
+					ReplicaRegions: config.AWSReplicaRegions,
+				})
+		}
 	case configuration.GoogleSecretManagerStorageType:
-		keystore, err = googlesm.NewKeystore(config.GCPProjectID, logger)
+		gcpStore, err := googlesm.NewKeystore(config.GCPProjectID, logger)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create Google Secret Manager store: %w", err)
 		}
+		keystore = gcpStore.
+			WithMasterKey(masterKey).
+			WithKeystoreEncryption(keystoreEncryption).
+			WithPrefix(config.GCPSecretPrefix).
+			WithSecretOptions(googlesm.SecretOptions{
+				KMSKeyNames:      config.GCPKMSKeyNames,
+				ReplicaLocations: config.GCPReplicaLocations,
+				Labels:           config.GCPLabels,
+			})
+	case configuration.VaultStorageType:
+		keystore, err = vault.NewStore(vault.Config{
+			Address:             config.VaultAddress,
+			Namespace:           config.VaultNamespace,
+			AuthMethod:          vault.AuthMethod(config.VaultAuthMethod),
+			AuthMount:           config.VaultAuthMount,
+			Token:               config.VaultToken,
+			AppRoleID:           config.VaultAppRoleID,
+			AppRoleSecretID:     config.VaultAppRoleSecretID,
+			KubernetesRole:      config.VaultKubernetesRole,
+			KubernetesTokenPath: config.VaultKubernetesTokenPath,
+			KVMount:             config.VaultKVMount,
+			PathPrefix:          config.VaultPathPrefix,
+			TransitMount:        config.VaultTransitMount,
+			TransitKey:          config.VaultTransitKey,
+		}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Vault store: %w", err)
+		}
+		logger.Info(fmt.Sprintf("Using %s auth for Vault", config.VaultAuthMethod))
+	case configuration.MemoryStorageType:
+		keystore, err = memory.NewStore(config.MemorySnapshotFile, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create memory store: %w", err)
+		}
+		if config.MemorySnapshotFile == "" {
+			logger.Warn("Using the memory store without snapshot file, keys are lost on restart")
+		}
+	case configuration.KubernetesStorageType:
+		k8sStore, err := k8ssecrets.NewStoreFromConfig(k8ssecrets.Config{
+			Namespace:  config.KubernetesNamespace,
+			Kubeconfig: config.KubernetesKubeconfig,
+			Context:    config.KubernetesContext,
+		}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Kubernetes secrets store: %w", err)
+		}
+		keystore = k8sStore.
+			WithPrefix(config.KubernetesSecretPrefix).
+			WithLabels(config.KubernetesLabels).
+			WithKeystoreEncryption(keystoreEncryption)
+	case configuration.PostgresStorageType:
+		if db == nil {
+			return nil, fmt.Errorf("postgres store requires the postgres database")
+		}
+		keystore = pgstore.NewStore(db, logger).WithMasterKey(masterKey)
+	case configuration.PluginStorageType:
+		keystore, err = plugin.NewStore(context.Background(), plugin.Config{
+			Address:      config.PluginAddress,
+			Command:      config.PluginCommand,
+			StartTimeout: config.PluginStartTimeout,
+		}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create plugin store: %w", err)
+		}
+	case configuration.PKCS11StorageType:
+		pin, err := hsm.ResolvePIN(config.PKCS11PINSource)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PKCS#11 PIN: %w", err)
+		}
+		keystore, err = hsm.NewStore(hsm.Config{
+			ModulePath:  config.PKCS11ModulePath,
+			TokenLabel:  config.PKCS11TokenLabel,
+			Slot:        config.PKCS11Slot,
+			PIN:         pin,
+			LabelPrefix: config.PKCS11LabelPrefix,
+		}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create PKCS#11 store: %w", err)
+		}
+	case configuration.ReplicatedStorageType:
+		keystore, err = initializeReplicatedStore(config, masterKey, db, storeMetrics, logger)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("unsupported storage type: %s", config.StorageType)
 	}
 
-	return keystore, nil
+	// The replicas of the replicated store are decorated already
+	if config.StorageType == configuration.ReplicatedStorageType {
+		return keystore, nil
+	}
+	return decorateStore(config, keystore, storeMetrics, logger), nil
+}
+
+// decorateStore adds the timeout, the retries, the circuit breaker and the
+// metrics of the storage type to its store
+func decorateStore(
+	config *configuration.Configuration,
+	keystore store.Store,
+	storeMetrics metrics.StoreRecorder,
+	logger *slog.Logger,
+) store.Store {
+	return decorator.Chain(keystore, string(config.StorageType), decorator.Options{
+		Timeout: config.StoreTimeoutOf(config.StorageType),
+		Retry: decorator.RetryPolicy{
+			MaxAttempts: config.StoreRetryAttempts,
+			BaseDelay:   config.StoreRetryBaseDelay,
+			MaxDelay:    config.StoreRetryMaxDelay,
+		},
+		Breaker: decorator.BreakerPolicy{
+			FailureThreshold: config.StoreBreakerThreshold,
+			OpenTimeout:      config.StoreBreakerOpenTimeout,
+		},
+		Metrics: storeMetrics,
+	}, logger)
+}
+
+// initializeRouter creates the store of every named store and routes the
+// keys to them
+func initializeRouter(
+	config *configuration.Configuration,
+	masterKey *seal.Keeper,
+	db *sql.DB,
+	storeMetrics metrics.StoreRecorder,
+	logger *slog.Logger,
+) (store.Store, error) {
+	stores := make([]router.NamedStore, 0, len(config.Stores))
+	for _, named := range config.Stores {
+		storeConfig := *config
+		storeConfig.Stores = nil
+		storeConfig.StorageType = named.StorageType
+		namedStore, err := initializeStore(&storeConfig, masterKey, db, storeMetrics, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create store %s: %w", named.Name, err)
+		}
+		stores = append(stores, router.NamedStore{
+			Name:  named.Name,
+			Store: namedStore,
+		})
+	}
+
+	logger.Info("Using named stores", "stores", config.Stores, "default", config.DefaultStore)
+	return router.NewStore(stores, config.DefaultStore, logger)
+}
+
+// initializeKeystoreWatcher watches the directory of the filesystem store,
+// used directly or as one of the named stores
+func initializeKeystoreWatcher(
+	config *configuration.Configuration,
+	keystore store.Store,
+	logger *slog.Logger,
+) (*filesystem.Watcher, error) {
+	name, ok := config.FileSystemStoreName()
+	if !ok {
+		return nil, fmt.Errorf("no filesystem store to watch")
+	}
+	target, _, err := store.Route(keystore, name)
+	if err != nil {
+		return nil, err
+	}
+	fileStore, ok := store.As[*filesystem.FileStore](target)
+	if !ok {
+		return nil, fmt.Errorf("store %s is not a filesystem store", name)
+	}
+
+	logger.Info("Watching keystore directory", "dir", config.KeystoreDir)
+	return filesystem.NewWatcher(fileStore, logger), nil
+}
+
+// initializeReplicatedStore creates the store of every replica storage type
+// and replicates the keys to them
+func initializeReplicatedStore(
+	config *configuration.Configuration,
+	masterKey *seal.Keeper,
+	db *sql.DB,
+	storeMetrics metrics.StoreRecorder,
+	logger *slog.Logger,
+) (store.Store, error) {
+	replicas := make([]replicated.Replica, 0, len(config.ReplicaStorageTypes))
+	for _, storageType := range config.ReplicaStorageTypes {
+		replicaConfig := *config
+		replicaConfig.StorageType = storageType
+		replicaStore, err := initializeStore(&replicaConfig, masterKey, db, storeMetrics, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s replica: %w", storageType, err)
+		}
+		replicas = append(replicas, replicated.Replica{
+			Name:  string(storageType),
+			Store: replicaStore,
+		})
+	}
+
+	writeQuorum := config.WriteQuorum
+	if writeQuorum == 0 {
+		writeQuorum = len(replicas)/2 + 1
+	}
+	logger.Info(
+		"Using replicated store",
+		"replicas", config.ReplicaStorageTypes,
+		"write_quorum", writeQuorum,
+	)
+	return replicated.NewStore(replicas, writeQuorum, logger)
 }
 
 func initializeGrpcMiddleware(
 	registry *prometheus.Registry,
 	rpcMetrics *metrics.RPCServerMetrics,
-	keyMetadataRepo repository.KeyMetadataRepository,
+	apiKeyRepo repository.APIKeyRepository,
+	apiKeys *apikey.Hasher,
+	logger *slog.Logger,
 ) []grpc.UnaryServerInterceptor {
 	metricsMiddleware := middleware.NewMetricsMiddleware(registry, rpcMetrics)
-	authInterceptor := middleware.AuthInterceptor("signer.v1.Signer", keyMetadataRepo)
+	authInterceptor := middleware.AuthInterceptor(
+		"signer.v1.Signer",
+		apiKeyRepo,
+		apiKeys,
+		logger,
+	)
 	return []grpc.UnaryServerInterceptor{
 		metricsMiddleware.UnaryServerInterceptor(),
 		authInterceptor,
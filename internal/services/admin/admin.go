@@ -2,14 +2,25 @@ package admin
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"time"
 
 	v1 "github.com/Layr-Labs/cerberus-api/pkg/api/v1"
+	"github.com/Layr-Labs/cerberus/internal/apikey"
 	"github.com/Layr-Labs/cerberus/internal/common"
 	"github.com/Layr-Labs/cerberus/internal/configuration"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
 	"github.com/Layr-Labs/cerberus/internal/database/repository"
+	"github.com/Layr-Labs/cerberus/internal/keyversion"
 	"github.com/Layr-Labs/cerberus/internal/metrics"
+	"github.com/Layr-Labs/cerberus/internal/migration"
+	"github.com/Layr-Labs/cerberus/internal/pagination"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/usage"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var _ v1.AdminServer = (*Service)(nil)
@@ -19,24 +30,38 @@ type Service struct {
 	logger          *slog.Logger
 	metrics         metrics.Recorder
 	keyMetadataRepo repository.KeyMetadataRepository
+	keyUsageRepo    repository.KeyUsageRepository
+	apiKeyRepo      repository.APIKeyRepository
+	apiKeys         *apikey.Hasher
 
 	v1.UnimplementedAdminServer
 }
 
+// NewService returns the admin service. apiKeys may be nil for the offline
+// commands which don't create API keys.
 func NewService(
 	config *configuration.Configuration,
 	logger *slog.Logger,
 	metrics metrics.Recorder,
 	keyMetadataRepo repository.KeyMetadataRepository,
+	keyUsageRepo repository.KeyUsageRepository,
+	apiKeyRepo repository.APIKeyRepository,
+	apiKeys *apikey.Hasher,
 ) *Service {
 	return &Service{
 		config:          config,
 		logger:          logger.With("component", "admin"),
 		metrics:         metrics,
 		keyMetadataRepo: keyMetadataRepo,
+		keyUsageRepo:    keyUsageRepo,
+		apiKeyRepo:      apiKeyRepo,
+		apiKeys:         apiKeys,
 	}
 }
 
+// GenerateNewApiKey replaces the default API key of the key in a single step,
+// so that a failure keeps the previous one. The other API keys of the key are
+// kept, CreateAPIKey adds an API key without revoking any.
 func (s *Service) GenerateNewApiKey(
 	ctx context.Context,
 	req *v1.GenerateNewApiKeyRequest,
@@ -46,13 +71,13 @@ func (s *Service) GenerateNewApiKey(
 		return nil, err
 	}
 
-	apiKey, apiKeyHash, err := common.GenerateNewAPIKeyAndHash()
+	apiKey, apiKeyModel, err := s.newAPIKey(apikey.DefaultName, nil, nil)
 	if err != nil {
 		return nil, err
 	}
+	apiKeyModel.PublicKeyG1 = metadata.PublicKeyG1
 
-	err = s.keyMetadataRepo.UpdateAPIKeyHash(ctx, metadata.PublicKeyG1, apiKeyHash)
-	if err != nil {
+	if err := s.apiKeyRepo.Replace(ctx, apiKeyModel); err != nil {
 		return nil, err
 	}
 
@@ -88,15 +113,45 @@ func (s *Service) ListAllKeys(
 	ctx context.Context,
 	req *v1.ListAllKeysRequest,
 ) (*v1.ListAllKeysResponse, error) {
-	keys, err := s.keyMetadataRepo.List(ctx)
+	opts, err := pagination.ListOptionsFromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	keys, nextPageToken, err := s.keyMetadataRepo.ListPage(ctx, opts)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidPageToken) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, err
+	}
+
+	if err := pagination.SetNextPageToken(ctx, nextPageToken); err != nil {
+		return nil, err
+	}
+
+	// Only the usage of the keys of the page is read
+	publicKeyG1s := make([]string, 0, len(keys))
+	for _, key := range keys {
+		publicKeyG1s = append(publicKeyG1s, key.PublicKeyG1)
+	}
+	keyUsage, err := s.keyUsageRepo.ListForKeys(ctx, publicKeyG1s)
 	if err != nil {
 		return nil, err
 	}
+	summaries := usage.Summarize(keyUsage)
 
 	response := &v1.ListAllKeysResponse{
 		Keys: make([]*v1.KeyMetadata, 0, len(keys)),
 	}
+	keySummaries := make([]*usage.Summary, 0, len(keys))
 	for _, key := range keys {
+		summary, ok := summaries[key.PublicKeyG1]
+		if !ok {
+			summary = &usage.Summary{PublicKeyG1: key.PublicKeyG1}
+		}
+		keySummaries = append(keySummaries, summary)
+
 		response.Keys = append(response.Keys, &v1.KeyMetadata{
 			PublicKeyG1: key.PublicKeyG1,
 			PublicKeyG2: key.PublicKeyG2,
@@ -105,5 +160,187 @@ func (s *Service) ListAllKeys(
 			Locked:      key.Locked,
 		})
 	}
+
+	if err := usage.SetKeyUsageHeader(ctx, keySummaries); err != nil {
+		s.logger.Warn("Failed to send key usage", "error", err)
+	}
 	return response, nil
 }
+
+// ListUnusedKeys returns the keys that haven't signed for the given duration.
+// Keys that never signed are included if they are older than the duration.
+func (s *Service) ListUnusedKeys(
+	ctx context.Context,
+	unusedFor time.Duration,
+) ([]*model.KeyUsageSummary, error) {
+	return s.keyUsageRepo.ListUnusedSince(ctx, time.Now().UTC().Add(-unusedFor))
+}
+
+// CreateAPIKey adds an API key to the key and returns it. Signing requests are
+// accepted with any unexpired API key of the key allowed the method, so that
+// clients can move to a new API key before the old one is revoked. Empty
+// scopes allow every method and a nil expiry never expires.
+func (s *Service) CreateAPIKey(
+	ctx context.Context,
+	publicKeyG1 string,
+	name string,
+	scopes []string,
+	expiresAt *time.Time,
+) (string, *model.APIKey, error) {
+	if name == "" {
+		return "", nil, status.Error(codes.InvalidArgument, "api key name is required")
+	}
+	if err := apikey.ValidateScopes(scopes); err != nil {
+		return "", nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "", nil, status.Error(codes.InvalidArgument, "expiry must be in the future")
+	}
+
+	publicKeyG1 = common.Trim0x(publicKeyG1)
+	if err := s.checkKeyExists(ctx, publicKeyG1); err != nil {
+		return "", nil, err
+	}
+
+	apiKey, apiKeyModel, err := s.newAPIKey(name, scopes, expiresAt)
+	if err != nil {
+		return "", nil, err
+	}
+	apiKeyModel.PublicKeyG1 = publicKeyG1
+
+	if err := s.apiKeyRepo.Create(ctx, apiKeyModel); err != nil {
+		if errors.Is(err, repository.ErrAPIKeyAlreadyExists) {
+			return "", nil, status.Errorf(codes.AlreadyExists, "api key %s already exists", name)
+		}
+		return "", nil, err
+	}
+	return apiKey, apiKeyModel, nil
+}
+
+// ListAPIKeys returns the API keys of the key, oldest first
+func (s *Service) ListAPIKeys(ctx context.Context, publicKeyG1 string) ([]*model.APIKey, error) {
+	publicKeyG1 = common.Trim0x(publicKeyG1)
+	if err := s.checkKeyExists(ctx, publicKeyG1); err != nil {
+		return nil, err
+	}
+	return s.apiKeyRepo.List(ctx, publicKeyG1)
+}
+
+// RevokeAPIKey deletes the API key of the key, signing requests using it are
+// rejected from then on
+func (s *Service) RevokeAPIKey(ctx context.Context, publicKeyG1 string, name string) error {
+	err := s.apiKeyRepo.Delete(ctx, common.Trim0x(publicKeyG1), name)
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return status.Errorf(codes.NotFound, "api key %s not found", name)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Service) checkKeyExists(ctx context.Context, publicKeyG1 string) error {
+	_, err := s.keyMetadataRepo.Get(ctx, publicKeyG1)
+	if errors.Is(err, repository.ErrKeyNotFound) {
+		return status.Error(codes.NotFound, "key not found")
+	}
+	return err
+}
+
+// SetKeyValidity sets the time window in which the key may sign. A nil bound
+// opens the window on that side. Unlocking an expired key doesn't extend its
+// validity, the monitor locks it again on its next check.
+func (s *Service) SetKeyValidity(
+	ctx context.Context,
+	publicKeyG1 string,
+	notBefore *time.Time,
+	notAfter *time.Time,
+) error {
+	if notBefore != nil && notAfter != nil && !notBefore.Before(*notAfter) {
+		return status.Error(codes.InvalidArgument, "not before must be earlier than not after")
+	}
+
+	err := s.keyMetadataRepo.UpdateValidity(ctx, common.Trim0x(publicKeyG1), notBefore, notAfter)
+	if err != nil {
+		if errors.Is(err, repository.ErrKeyNotFound) {
+			return status.Error(codes.NotFound, "key not found")
+		}
+		return err
+	}
+	return nil
+}
+
+// MigrateKeys copies the keys of a store to another store, verifying each
+// copy and recording the target store in the key metadata
+func (s *Service) MigrateKeys(
+	ctx context.Context,
+	from store.Store,
+	to store.Store,
+	opts migration.Options,
+) (*migration.Report, error) {
+	return migration.New(from, to, s.keyMetadataRepo, s.logger).Run(ctx, opts)
+}
+
+// ListKeyVersions returns the versions of the key kept by its store, oldest
+// first
+func (s *Service) ListKeyVersions(
+	ctx context.Context,
+	keys store.Store,
+	publicKeyG1 string,
+) ([]store.KeyVersion, error) {
+	return keyversion.New(keys, s.keyMetadataRepo, s.logger).List(ctx, publicKeyG1)
+}
+
+// PinKeyVersion makes the key sign with a version of the key rather than its
+// current version, or unpins the key if the version is empty
+func (s *Service) PinKeyVersion(
+	ctx context.Context,
+	keys store.Store,
+	publicKeyG1 string,
+	versionID string,
+) error {
+	return keyversion.New(keys, s.keyMetadataRepo, s.logger).Pin(ctx, publicKeyG1, versionID)
+}
+
+// RollbackKey makes an earlier version of the key current again, records it
+// in the key metadata and returns its ID
+func (s *Service) RollbackKey(
+	ctx context.Context,
+	keys store.Store,
+	publicKeyG1 string,
+	versionID string,
+) (string, error) {
+	return keyversion.New(keys, s.keyMetadataRepo, s.logger).Rollback(ctx, publicKeyG1, versionID)
+}
+
+// RewrapKey writes the key as a new version envelope encrypted with the
+// current master key, records it in the key metadata and returns its ID
+func (s *Service) RewrapKey(
+	ctx context.Context,
+	keys store.Store,
+	publicKeyG1 string,
+) (string, error) {
+	return keyversion.New(keys, s.keyMetadataRepo, s.logger).Rewrap(ctx, publicKeyG1)
+}
+
+// RecordKeyVersion records the current version of the key in the key
+// metadata after the key was written again, and returns its ID
+func (s *Service) RecordKeyVersion(
+	ctx context.Context,
+	keys store.Store,
+	publicKeyG1 string,
+) (string, error) {
+	return keyversion.New(keys, s.keyMetadataRepo, s.logger).Record(ctx, publicKeyG1)
+}
+
+// newAPIKey generates an API key, which fails without the API key pepper
+func (s *Service) newAPIKey(
+	name string,
+	scopes []string,
+	expiresAt *time.Time,
+) (string, *model.APIKey, error) {
+	if s.apiKeys == nil {
+		return "", nil, status.Error(codes.FailedPrecondition, "API key pepper is not configured")
+	}
+	return s.apiKeys.New(name, scopes, expiresAt)
+}
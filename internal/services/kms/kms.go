@@ -3,18 +3,25 @@ package kms
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/big"
+	"time"
 
 	v1 "github.com/Layr-Labs/cerberus-api/pkg/api/v1"
 
+	"github.com/Layr-Labs/cerberus/internal/apikey"
 	"github.com/Layr-Labs/cerberus/internal/common"
 	"github.com/Layr-Labs/cerberus/internal/configuration"
+	"github.com/Layr-Labs/cerberus/internal/crypto"
 	"github.com/Layr-Labs/cerberus/internal/database/model"
 	"github.com/Layr-Labs/cerberus/internal/database/repository"
 	"github.com/Layr-Labs/cerberus/internal/metrics"
+	"github.com/Layr-Labs/cerberus/internal/pagination"
+	"github.com/Layr-Labs/cerberus/internal/seal"
 	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/usage"
 
 	"github.com/Layr-Labs/bn254-keystore-go/curve"
 	"github.com/Layr-Labs/bn254-keystore-go/keystore"
@@ -30,6 +37,9 @@ type Service struct {
 	store           store.Store
 	metrics         metrics.Recorder
 	keyMetadataRepo repository.KeyMetadataRepository
+	keyUsageRepo    repository.KeyUsageRepository
+	apiKeyRepo      repository.APIKeyRepository
+	apiKeys         *apikey.Hasher
 
 	v1.UnimplementedKeyManagerServer
 }
@@ -38,6 +48,9 @@ func NewService(
 	config *configuration.Configuration,
 	store store.Store,
 	keyMetadataRepo repository.KeyMetadataRepository,
+	keyUsageRepo repository.KeyUsageRepository,
+	apiKeyRepo repository.APIKeyRepository,
+	apiKeys *apikey.Hasher,
 	logger *slog.Logger,
 	metrics metrics.Recorder,
 ) *Service {
@@ -46,6 +59,9 @@ func NewService(
 		store:           store,
 		metrics:         metrics,
 		keyMetadataRepo: keyMetadataRepo,
+		keyUsageRepo:    keyUsageRepo,
+		apiKeyRepo:      apiKeyRepo,
+		apiKeys:         apiKeys,
 		logger:          logger.With("component", "kms"),
 	}
 }
@@ -69,27 +85,16 @@ func (k *Service) GenerateKeyPair(
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	pubKeyHex, err := k.store.StoreKey(ctx, keyPair)
-	if err != nil {
-		k.logger.Error(fmt.Sprintf("Failed to save BLS key pair to file: %v", err))
-		return nil, status.Error(codes.Internal, err.Error())
-	}
-
-	// Generate a new API key and hash
-	apiKey, apiKeyHash, err := common.GenerateNewAPIKeyAndHash()
+	// Generate the default API key of the key
+	apiKey, apiKeyModel, err := k.apiKeys.New(apikey.DefaultName, nil, nil)
 	if err != nil {
 		k.logger.Error(fmt.Sprintf("Failed to generate API key: %v", err))
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	err = k.keyMetadataRepo.Create(ctx, &model.KeyMetadata{
-		PublicKeyG1: pubKeyHex,
-		PublicKeyG2: g2PubKey,
-		ApiKeyHash:  apiKeyHash,
-	})
+	pubKeyHex, err := k.createKey(ctx, keyPair, g2PubKey, apiKeyModel)
 	if err != nil {
-		k.logger.Error(fmt.Sprintf("Failed to save key metadata: %v", err))
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
 
 	// Convert the private key to a hex string
@@ -116,28 +121,28 @@ func (k *Service) ImportKey(
 	var pkBytes []byte
 	var err error
 
+	var privKey *crypto.PrivateKey
 	if pkMnemonic != "" {
 		ks, err := keystore.NewKeyPairFromMnemonic(pkMnemonic, password)
 		if err != nil {
 			k.logger.Error(fmt.Sprintf("Failed to import key pair from mnemonic: %v", err))
 			return nil, status.Error(codes.InvalidArgument, err.Error())
 		}
-		pkBytes = ks.PrivateKey
+		privKey, err = crypto.PrivateKeyFromBytes(ks.PrivateKey)
+		if err != nil {
+			k.logger.Error(fmt.Sprintf("Failed to import key pair from mnemonic: %v", err))
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 	} else {
-		pkInt, ok := new(big.Int).SetString(pkString, 10)
-		if ok {
-			// It's a bigInt
-			pkBytes = pkInt.Bytes()
-		} else {
-			// It's a hex string
-			pkHex := common.Trim0x(pkString)
-			pkBytes, err = hex.DecodeString(pkHex)
-			if err != nil {
-				k.logger.Error(fmt.Sprintf("Failed to import key pair from string: %v", err))
-				return nil, status.Error(codes.InvalidArgument, err.Error())
-			}
+		// Decimal strings take precedence over hex strings, except the
+		// ambiguous ones of 64 digits which are refused
+		privKey, err = crypto.PrivateKeyFromString(pkString)
+		if err != nil {
+			k.logger.Error(fmt.Sprintf("Failed to import key pair from string: %v", err))
+			return nil, status.Error(codes.InvalidArgument, err.Error())
 		}
 	}
+	pkBytes = crypto.PrivateKeyBytes(privKey)
 
 	ks := &keystore.KeyPair{
 		PrivateKey: pkBytes,
@@ -164,48 +169,252 @@ func (k *Service) ImportKey(
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	pubKeyHex, err := k.store.StoreKey(
-		ctx,
-		&keystore.KeyPair{PrivateKey: pkBytes, Password: password},
-	)
+	// Generate the default API key of the key
+	apiKey, apiKeyModel, err := k.apiKeys.New(apikey.DefaultName, nil, nil)
 	if err != nil {
-		k.logger.Error(fmt.Sprintf("Failed to save BLS key pair to file: %v", err))
+		k.logger.Error(fmt.Sprintf("Failed to generate API key: %v", err))
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	// Generate a new API key and hash
-	apiKey, apiKeyHash, err := common.GenerateNewAPIKeyAndHash()
+	pubKeyHex, err := k.createKey(
+		ctx,
+		&keystore.KeyPair{PrivateKey: pkBytes, Password: password},
+		g2PubKey,
+		apiKeyModel,
+	)
 	if err != nil {
-		k.logger.Error(fmt.Sprintf("Failed to generate API key: %v", err))
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, err
 	}
 
-	err = k.keyMetadataRepo.Create(ctx, &model.KeyMetadata{
+	return &v1.ImportKeyResponse{
 		PublicKeyG1: pubKeyHex,
 		PublicKeyG2: g2PubKey,
-		ApiKeyHash:  apiKeyHash,
-	})
+		ApiKey:      apiKey,
+	}, nil
+}
+
+// createKey stores the private key, its metadata and its API key as a single
+// unit of work.
+// If the metadata can't be saved, the stored key is deleted again so that a
+// retry starts from a clean state. A key left in the store by an earlier
+// attempt whose rollback failed is reused if it is the same key.
+// The key is created in the store requested by the StoreHeader and the store
+// is recorded in the metadata. Returned errors are gRPC status errors.
+func (k *Service) createKey(
+	ctx context.Context,
+	keyPair *keystore.KeyPair,
+	g2PubKey string,
+	apiKey *model.APIKey,
+) (string, error) {
+	target, storeName, err := store.Route(k.store, storeNameFromContext(ctx))
 	if err != nil {
-		k.logger.Error(fmt.Sprintf("Failed to save key metadata: %v", err))
-		return nil, status.Error(codes.Internal, err.Error())
+		return "", status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	return &v1.ImportKeyResponse{
+	if atomic, ok := store.As[store.AtomicStorer](target); ok {
+		return k.createKeyAtomically(ctx, atomic, keyPair, g2PubKey, apiKey, storeName)
+	}
+
+	pubKeyHex, err := target.StoreKey(ctx, keyPair)
+	if errors.Is(err, store.ErrKeyAlreadyExists) {
+		pubKeyHex, err = k.adoptStoredKey(ctx, target, keyPair)
+		if err != nil {
+			return "", err
+		}
+	} else if err != nil {
+		k.logger.Error(fmt.Sprintf("Failed to save BLS key pair to store: %v", err))
+		if errors.Is(err, seal.ErrSealed) {
+			return "", status.Error(codes.Unavailable, err.Error())
+		}
+		return "", status.Error(codes.Internal, err.Error())
+	}
+
+	// The version is only recorded for auditing, failing to read it doesn't
+	// fail the creation
+	keyVersion, err := store.CurrentVersion(ctx, target, pubKeyHex)
+	if err != nil {
+		k.logger.Warn(fmt.Sprintf("Failed to get version of key %s: %v", pubKeyHex, err))
+	}
+
+	metadata := k.newKeyMetadata(pubKeyHex, g2PubKey, storeName)
+	metadata.KeyVersion = keyVersion
+
+	err = k.createMetadata(ctx, metadata, apiKey)
+	if err == nil {
+		return pubKeyHex, nil
+	}
+	k.logger.Error(fmt.Sprintf("Failed to save key metadata: %v", err))
+
+	// A concurrent request may have registered the same key in the meantime,
+	// in which case the stored key belongs to it and must not be deleted
+	if _, getErr := k.keyMetadataRepo.Get(ctx, pubKeyHex); getErr == nil {
+		return "", status.Error(codes.AlreadyExists, "key already exists")
+	}
+
+	if rollbackErr := target.DeleteKey(ctx, pubKeyHex); rollbackErr != nil {
+		k.logger.Error(
+			fmt.Sprintf("Failed to roll back stored key %s: %v", pubKeyHex, rollbackErr),
+		)
+		return "", status.Errorf(
+			codes.Internal,
+			"failed to save key metadata: %v; the key %s remains in the store without "+
+				"metadata and failed to be rolled back: %v; retrying the request is safe",
+			err,
+			pubKeyHex,
+			rollbackErr,
+		)
+	}
+
+	k.logger.Info(fmt.Sprintf("Rolled back stored key %s", pubKeyHex))
+	return "", status.Errorf(
+		codes.Internal,
+		"failed to save key metadata, the stored key was rolled back: %v",
+		err,
+	)
+}
+
+// createKeyAtomically stores the key and creates its metadata and API key in
+// one transaction, nothing is saved if any fails
+func (k *Service) createKeyAtomically(
+	ctx context.Context,
+	target store.AtomicStorer,
+	keyPair *keystore.KeyPair,
+	g2PubKey string,
+	apiKey *model.APIKey,
+	storeName string,
+) (string, error) {
+	pubKeyHex, err := target.StoreKeyAtomically(
+		ctx,
+		keyPair,
+		func(ctx context.Context, pubKeyHex string) error {
+			metadata := k.newKeyMetadata(pubKeyHex, g2PubKey, storeName)
+			if err := k.keyMetadataRepo.Create(ctx, metadata); err != nil {
+				return err
+			}
+			apiKey.PublicKeyG1 = pubKeyHex
+			return k.apiKeyRepo.Create(ctx, apiKey)
+		},
+	)
+	if err == nil {
+		return pubKeyHex, nil
+	}
+	if errors.Is(err, store.ErrKeyAlreadyExists) {
+		return "", status.Error(codes.AlreadyExists, "key already exists")
+	}
+	k.logger.Error(fmt.Sprintf("Failed to save BLS key pair and metadata: %v", err))
+	if errors.Is(err, seal.ErrSealed) {
+		return "", status.Error(codes.Unavailable, err.Error())
+	}
+
+	// The metadata may have been registered by a key of another store
+	if pubKeyHex, pubKeyErr := keyPair.GetG1PublicKey(curve.BN254); pubKeyErr == nil {
+		if _, getErr := k.keyMetadataRepo.Get(ctx, pubKeyHex); getErr == nil {
+			return "", status.Error(codes.AlreadyExists, "key already exists")
+		}
+	}
+	return "", status.Errorf(codes.Internal, "failed to save key and metadata: %v", err)
+}
+
+// createMetadata creates the metadata of a new key and its API key. The
+// metadata is deleted again if the API key can't be created, as the key
+// couldn't sign without it.
+func (k *Service) createMetadata(
+	ctx context.Context,
+	metadata *model.KeyMetadata,
+	apiKey *model.APIKey,
+) error {
+	if err := k.keyMetadataRepo.Create(ctx, metadata); err != nil {
+		return err
+	}
+
+	apiKey.PublicKeyG1 = metadata.PublicKeyG1
+	err := k.apiKeyRepo.Create(ctx, apiKey)
+	if err == nil {
+		return nil
+	}
+	if deleteErr := k.keyMetadataRepo.Delete(ctx, metadata.PublicKeyG1); deleteErr != nil {
+		k.logger.Error(fmt.Sprintf(
+			"Failed to delete metadata of %s without API key: %v",
+			metadata.PublicKeyG1,
+			deleteErr,
+		))
+	}
+	return fmt.Errorf("failed to save API key: %w", err)
+}
+
+// newKeyMetadata returns the metadata of a new key, valid for the configured
+// validity period
+func (k *Service) newKeyMetadata(
+	pubKeyHex string,
+	g2PubKey string,
+	storeName string,
+) *model.KeyMetadata {
+	metadata := &model.KeyMetadata{
 		PublicKeyG1: pubKeyHex,
 		PublicKeyG2: g2PubKey,
-		ApiKey:      apiKey,
-	}, nil
+		StoreName:   storeName,
+	}
+	if k.config.KeyValidityPeriod > 0 {
+		notAfter := time.Now().UTC().Add(k.config.KeyValidityPeriod)
+		metadata.NotAfter = &notAfter
+	}
+	return metadata
+}
+
+// adoptStoredKey reuses a key that is already in the store without metadata,
+// which happens when an earlier attempt failed and couldn't roll back.
+// The stored key is only reused if it decrypts with the request password to
+// the same private key.
+func (k *Service) adoptStoredKey(
+	ctx context.Context,
+	target store.Store,
+	keyPair *keystore.KeyPair,
+) (string, error) {
+	pubKeyHex, err := keyPair.GetG1PublicKey(curve.BN254)
+	if err != nil {
+		return "", status.Error(codes.Internal, err.Error())
+	}
+
+	storedKeyPair, err := target.RetrieveKey(ctx, pubKeyHex, keyPair.Password)
+	if err != nil {
+		k.logger.Error(fmt.Sprintf("Failed to retrieve key already in store: %v", err))
+		return "", status.Error(
+			codes.AlreadyExists,
+			"key already exists in store and can't be read with the given password",
+		)
+	}
+
+	storedPrivKey := storedKeyPair.PrivKey.Bytes()
+	if new(big.Int).SetBytes(storedPrivKey[:]).Cmp(new(big.Int).SetBytes(keyPair.PrivateKey)) != 0 {
+		return "", status.Error(codes.AlreadyExists, "a different key already exists in store")
+	}
+
+	k.logger.Info(fmt.Sprintf("Reusing key %s left in store by an earlier attempt", pubKeyHex))
+	return pubKeyHex, nil
 }
 
 func (k *Service) ListKeys(
 	ctx context.Context,
 	req *v1.ListKeysRequest,
 ) (*v1.ListKeysResponse, error) {
-	keys, err := k.keyMetadataRepo.List(ctx)
+	opts, err := pagination.ListOptionsFromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	keys, nextPageToken, err := k.keyMetadataRepo.ListPage(ctx, opts)
 	if err != nil {
+		if errors.Is(err, repository.ErrInvalidPageToken) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		k.logger.Error(fmt.Sprintf("Failed to list keys: %v", err))
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+
+	if err := pagination.SetNextPageToken(ctx, nextPageToken); err != nil {
+		k.logger.Error(fmt.Sprintf("Failed to set next page token: %v", err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
 	pubKeys := make([]*v1.PublicKey, len(keys))
 	for i, key := range keys {
 		pubKeys[i] = &v1.PublicKey{
@@ -226,6 +435,22 @@ func (k *Service) GetKeyMetadata(
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
+	keyUsage, err := k.keyUsageRepo.Get(ctx, metadata.PublicKeyG1)
+	if err != nil {
+		k.logger.Error(fmt.Sprintf("Failed to get key usage: %v", err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	summary, ok := usage.Summarize(keyUsage)[metadata.PublicKeyG1]
+	if !ok {
+		summary = &usage.Summary{PublicKeyG1: metadata.PublicKeyG1}
+	}
+	if err := usage.SetKeyUsageHeader(ctx, []*usage.Summary{summary}); err != nil {
+		k.logger.Warn(fmt.Sprintf("Failed to send key usage: %v", err))
+	}
+	if err := k.setStoreHeader(ctx, metadata.StoreName); err != nil {
+		k.logger.Warn(fmt.Sprintf("Failed to send key store: %v", err))
+	}
+
 	return &v1.GetKeyMetadataResponse{
 		PublicKeyG1: metadata.PublicKeyG1,
 		PublicKeyG2: metadata.PublicKeyG2,
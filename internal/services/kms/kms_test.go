@@ -3,45 +3,66 @@ package kms
 import (
 	"context"
 	"encoding/hex"
-	"os"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/Layr-Labs/bn254-keystore-go/keystore"
 	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
 	v1 "github.com/Layr-Labs/cerberus-api/pkg/api/v1"
 
+	"github.com/Layr-Labs/cerberus/internal/apikey"
 	"github.com/Layr-Labs/cerberus/internal/common/testutils"
 	"github.com/Layr-Labs/cerberus/internal/configuration"
-	"github.com/Layr-Labs/cerberus/internal/database/repository/postgres"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+	memoryrepo "github.com/Layr-Labs/cerberus/internal/database/repository/memory"
 	"github.com/Layr-Labs/cerberus/internal/metrics"
 	"github.com/Layr-Labs/cerberus/internal/store/filesystem"
+	"github.com/Layr-Labs/cerberus/internal/store/memory"
+	"github.com/Layr-Labs/cerberus/internal/store/router"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	_ "github.com/lib/pq"
 )
 
 const testPassword = "p@$$w0rd"
 
-func setup(t *testing.T) (*Service, *filesystem.FileStore, func()) {
+func newTestHasher(t *testing.T) *apikey.Hasher {
+	hasher, err := apikey.NewHasher(strings.Repeat("p", apikey.MinPepperLength))
+	require.NoError(t, err)
+	return hasher
+}
+
+func setup(t *testing.T) (*Service, *memory.Store) {
 	logger := testutils.GetTestLogger()
-	config := &configuration.Configuration{
-		KeystoreDir: "testdata/keystore",
-	}
-	fs := filesystem.NewStore(config.KeystoreDir, logger)
+	config := &configuration.Configuration{}
+	ms, err := memory.NewStore("", logger)
+	require.NoError(t, err)
 	noopMetrics := metrics.NewNoopRPCMetrics()
-	testDB := postgres.SetupTestDB(t)
-	service := NewService(config, fs, testDB.Repo, logger, noopMetrics)
-	cleanup := func() {
-		os.RemoveAll("testdata")
-	}
+	db := memoryrepo.NewDB()
+	service := NewService(
+		config,
+		ms,
+		memoryrepo.NewKeyMetadataRepository(db),
+		memoryrepo.NewKeyUsageRepository(db),
+		memoryrepo.NewAPIKeyRepository(db),
+		newTestHasher(t),
+		logger,
+		noopMetrics,
+	)
 
-	return service, fs, cleanup
+	return service, ms
 }
 
 func TestCreateKey(t *testing.T) {
-	service, fs, cleanup := setup(t)
-	defer cleanup()
+	service, fs := setup(t)
 
 	ctx := context.Background()
 
@@ -61,11 +82,19 @@ func TestCreateKey(t *testing.T) {
 	privBytes := storedKeyPair.PrivKey.Bytes()
 	privKeyHex := hex.EncodeToString(privBytes[:])
 	assert.Equal(t, createResp.PrivateKey, privKeyHex)
+
+	// The key is created with its default API key, allowed every method
+	apiKeys, err := service.apiKeyRepo.List(ctx, createResp.PublicKeyG1)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 1)
+	assert.Equal(t, apikey.DefaultName, apiKeys[0].Name)
+	assert.Empty(t, apiKeys[0].Scopes)
+	assert.True(t, strings.HasPrefix(createResp.ApiKey, apikey.Prefix))
+	assert.True(t, service.apiKeys.Verify(apiKeys[0], createResp.ApiKey))
 }
 
 func TestImportKey(t *testing.T) {
-	service, fs, cleanup := setup(t)
-	defer cleanup()
+	service, fs := setup(t)
 
 	ctx := context.Background()
 
@@ -84,9 +113,34 @@ func TestImportKey(t *testing.T) {
 	assert.Equal(t, hex.EncodeToString(keyPair.PrivateKey), hex.EncodeToString(privKeyBytes[:]))
 }
 
+func TestImportGeneratedKey(t *testing.T) {
+	service, _ := setup(t)
+	ctx := context.Background()
+
+	createResp, err := service.GenerateKeyPair(
+		ctx,
+		&v1.GenerateKeyPairRequest{Password: testPassword},
+	)
+	require.NoError(t, err)
+
+	// The private key printed by GenerateKeyPair imports into another instance
+	other, ms := setup(t)
+	importResp, err := other.ImportKey(ctx, &v1.ImportKeyRequest{
+		PrivateKey: createResp.PrivateKey,
+		Password:   testPassword,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, createResp.PublicKeyG1, importResp.PublicKeyG1)
+	assert.Equal(t, createResp.PublicKeyG2, importResp.PublicKeyG2)
+
+	storedKeyPair, err := ms.RetrieveKey(ctx, importResp.PublicKeyG1, testPassword)
+	require.NoError(t, err)
+	privKeyBytes := storedKeyPair.PrivKey.Bytes()
+	assert.Equal(t, createResp.PrivateKey, hex.EncodeToString(privKeyBytes[:]))
+}
+
 func TestListKeys(t *testing.T) {
-	service, fs, cleanup := setup(t)
-	defer cleanup()
+	service, fs := setup(t)
 
 	ctx := context.Background()
 
@@ -104,3 +158,263 @@ func TestListKeys(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Contains(t, storedKeys, createResp.PublicKeyG1)
 }
+
+type fakeKeyMetadataRepo struct {
+	repository.KeyMetadataRepository
+	keys      map[string]*model.KeyMetadata
+	createErr error
+}
+
+func (r *fakeKeyMetadataRepo) Create(_ context.Context, metadata *model.KeyMetadata) error {
+	if r.createErr != nil {
+		return r.createErr
+	}
+	r.keys[metadata.PublicKeyG1] = metadata
+	return nil
+}
+
+func (r *fakeKeyMetadataRepo) Get(
+	_ context.Context,
+	publicKeyG1 string,
+) (*model.KeyMetadata, error) {
+	m, ok := r.keys[publicKeyG1]
+	if !ok {
+		return nil, repository.ErrKeyNotFound
+	}
+	return m, nil
+}
+
+func setupWithFakeRepo(t *testing.T) (*Service, *filesystem.FileStore, *fakeKeyMetadataRepo) {
+	logger := testutils.GetTestLogger()
+	config := &configuration.Configuration{
+		KeystoreDir: t.TempDir(),
+	}
+	fs := filesystem.NewStore(config.KeystoreDir, logger)
+	repo := &fakeKeyMetadataRepo{keys: map[string]*model.KeyMetadata{}}
+	apiKeyRepo := memoryrepo.NewAPIKeyRepository(memoryrepo.NewDB())
+	service := NewService(
+		config,
+		fs,
+		repo,
+		nil,
+		apiKeyRepo,
+		newTestHasher(t),
+		logger,
+		metrics.NewNoopRPCMetrics(),
+	)
+	return service, fs, repo
+}
+
+func TestImportKeyRollsBackOnMetadataFailure(t *testing.T) {
+	service, fs, repo := setupWithFakeRepo(t)
+	ctx := context.Background()
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+
+	repo.createErr = errors.New("database unavailable")
+	_, err = service.ImportKey(ctx, &v1.ImportKeyRequest{
+		PrivateKey: hex.EncodeToString(keyPair.PrivateKey),
+		Password:   testPassword,
+	})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Contains(t, err.Error(), "rolled back")
+
+	storedKeys, err := fs.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, storedKeys)
+
+	// The retry succeeds once the database is back
+	repo.createErr = nil
+	importResp, err := service.ImportKey(ctx, &v1.ImportKeyRequest{
+		PrivateKey: hex.EncodeToString(keyPair.PrivateKey),
+		Password:   testPassword,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, repo.keys, importResp.PublicKeyG1)
+}
+
+func TestImportKeyReusesKeyLeftInStore(t *testing.T) {
+	service, fs, repo := setupWithFakeRepo(t)
+	ctx := context.Background()
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+
+	// Simulate an earlier attempt that stored the key but neither saved
+	// the metadata nor rolled back
+	pubKeyHex, err := fs.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	_, err = service.ImportKey(ctx, &v1.ImportKeyRequest{
+		PrivateKey: hex.EncodeToString(keyPair.PrivateKey),
+		Password:   "wrong password",
+	})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	assert.NotContains(t, repo.keys, pubKeyHex)
+
+	importResp, err := service.ImportKey(ctx, &v1.ImportKeyRequest{
+		PrivateKey: hex.EncodeToString(keyPair.PrivateKey),
+		Password:   testPassword,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, pubKeyHex, importResp.PublicKeyG1)
+	assert.Contains(t, repo.keys, pubKeyHex)
+}
+
+func TestImportKeyRejectsInvalidScalars(t *testing.T) {
+	service, fs, repo := setupWithFakeRepo(t)
+	ctx := context.Background()
+
+	invalidKeys := []string{
+		"0",
+		"0x0000",
+		"-1",
+		// BN254 scalar field order, which used to wrap around to zero
+		"21888242871839275222246405745257275088548364400416034343698204186575808495617",
+		"0x30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f0000001",
+		// 33 bytes
+		"0x01" + strings.Repeat("00", 32),
+		"not a key",
+		// 64 digits, which could be hex or decimal
+		strings.Repeat("1", 64),
+	}
+	for _, pk := range invalidKeys {
+		_, err := service.ImportKey(ctx, &v1.ImportKeyRequest{
+			PrivateKey: pk,
+			Password:   testPassword,
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), pk)
+	}
+
+	storedKeys, err := fs.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, storedKeys)
+	assert.Empty(t, repo.keys)
+}
+
+func TestGenerateKeyPairInRequestedStore(t *testing.T) {
+	logger := testutils.GetTestLogger()
+	hsmStore, err := memory.NewStore("", logger)
+	require.NoError(t, err)
+	diskStore, err := memory.NewStore("", logger)
+	require.NoError(t, err)
+	keyStore, err := router.NewStore([]router.NamedStore{
+		{Name: "hsm", Store: hsmStore},
+		{Name: "disk", Store: diskStore},
+	}, "disk", logger)
+	require.NoError(t, err)
+
+	db := memoryrepo.NewDB()
+	repo := memoryrepo.NewKeyMetadataRepository(db)
+	service := NewService(
+		&configuration.Configuration{},
+		keyStore,
+		repo,
+		memoryrepo.NewKeyUsageRepository(db),
+		memoryrepo.NewAPIKeyRepository(db),
+		newTestHasher(t),
+		logger,
+		metrics.NewNoopRPCMetrics(),
+	)
+
+	tests := []struct {
+		name      string
+		header    string
+		store     *memory.Store
+		storeName string
+	}{
+		{name: "default store", store: diskStore, storeName: "disk"},
+		{name: "requested store", header: "hsm", store: hsmStore, storeName: "hsm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.header != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(StoreHeader, tt.header))
+			}
+
+			resp, err := service.GenerateKeyPair(
+				ctx,
+				&v1.GenerateKeyPairRequest{Password: testPassword},
+			)
+			require.NoError(t, err)
+
+			_, err = tt.store.RetrieveKey(ctx, resp.PublicKeyG1, testPassword)
+			assert.NoError(t, err)
+			keyMetadata, err := repo.Get(ctx, resp.PublicKeyG1)
+			require.NoError(t, err)
+			assert.Equal(t, tt.storeName, keyMetadata.StoreName)
+		})
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(StoreHeader, "vault"))
+	_, err = service.GenerateKeyPair(ctx, &v1.GenerateKeyPairRequest{Password: testPassword})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// atomicStore stores the keys in memory and keeps them only if the metadata
+// is recorded, as a transactional store does
+type atomicStore struct {
+	*memory.Store
+	calls int
+}
+
+func (s *atomicStore) StoreKeyAtomically(
+	ctx context.Context,
+	keyPair *keystore.KeyPair,
+	record func(ctx context.Context, pubKey string) error,
+) (string, error) {
+	s.calls++
+	pubKey, err := s.StoreKey(ctx, keyPair)
+	if err != nil {
+		return "", err
+	}
+	if err := record(ctx, pubKey); err != nil {
+		return "", errors.Join(err, s.DeleteKey(ctx, pubKey))
+	}
+	return pubKey, nil
+}
+
+func TestImportKeyStoresKeyAndMetadataAtomically(t *testing.T) {
+	logger := testutils.GetTestLogger()
+	ms, err := memory.NewStore("", logger)
+	require.NoError(t, err)
+	as := &atomicStore{Store: ms}
+	repo := &fakeKeyMetadataRepo{keys: map[string]*model.KeyMetadata{}}
+	service := NewService(
+		&configuration.Configuration{},
+		as,
+		repo,
+		nil,
+		memoryrepo.NewAPIKeyRepository(memoryrepo.NewDB()),
+		newTestHasher(t),
+		logger,
+		metrics.NewNoopRPCMetrics(),
+	)
+	ctx := context.Background()
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	req := &v1.ImportKeyRequest{
+		PrivateKey: hex.EncodeToString(keyPair.PrivateKey),
+		Password:   testPassword,
+	}
+
+	repo.createErr = errors.New("database unavailable")
+	_, err = service.ImportKey(ctx, req)
+	assert.Equal(t, codes.Internal, status.Code(err))
+	storedKeys, err := ms.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, storedKeys)
+
+	repo.createErr = nil
+	importResp, err := service.ImportKey(ctx, req)
+	require.NoError(t, err)
+	assert.Contains(t, repo.keys, importResp.PublicKeyG1)
+	assert.Equal(t, 2, as.calls)
+
+	_, err = service.ImportKey(ctx, req)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+}
@@ -0,0 +1,37 @@
+package kms
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+// StoreHeader is the request header naming the store GenerateKeyPair and
+// ImportKey create the key in, the default store is used without it.
+// GetKeyMetadata returns the store holding the key in the same header.
+const StoreHeader = "x-store"
+
+// storeNameFromContext returns the store requested by the incoming request
+func storeNameFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(StoreHeader)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// setStoreHeader sends the store holding the key to the client. The name is
+// resolved so that the keys recorded without a store report the default one.
+func (k *Service) setStoreHeader(ctx context.Context, storeName string) error {
+	if _, name, err := store.Route(k.store, storeName); err == nil {
+		storeName = name
+	}
+	return grpc.SetHeader(ctx, metadata.Pairs(StoreHeader, storeName))
+}
@@ -6,18 +6,37 @@ import (
 	"github.com/Layr-Labs/cerberus/internal/crypto"
 )
 
+// signingKey is a key retrieved from its store
+type signingKey struct {
+	*crypto.KeyPair
+
+	// version is the version of the key in its store, empty for the stores
+	// without versions
+	version string
+
+	// recordedVersion and pinned are the version recorded in the metadata of
+	// the key when it was retrieved. The key is retrieved again once they
+	// change, after it is pinned, rolled back or re-wrapped.
+	recordedVersion string
+	pinned          bool
+}
+
 type KeyStoreMap struct {
 	sync.Map
 }
 
-func (k *KeyStoreMap) Load(key string) (*crypto.KeyPair, bool) {
+func (k *KeyStoreMap) Load(key string) (*signingKey, bool) {
 	value, ok := k.Map.Load(key)
 	if !ok {
 		return nil, false
 	}
-	return value.(*crypto.KeyPair), true
+	return value.(*signingKey), true
 }
 
-func (k *KeyStoreMap) Store(key string, value *crypto.KeyPair) {
+func (k *KeyStoreMap) Store(key string, value *signingKey) {
 	k.Map.Store(key, value)
 }
+
+func (k *KeyStoreMap) Delete(key string) {
+	k.Map.Delete(key)
+}
@@ -0,0 +1,55 @@
+package signing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+)
+
+// metadataTTL is how long the metadata of a key is used before it is read
+// from the database again. It bounds how long a change made by another
+// process, such as a new validity window, takes to apply.
+const metadataTTL = 30 * time.Second
+
+type cachedMetadata struct {
+	// metadata is nil for the keys without metadata
+	metadata *model.KeyMetadata
+	loadedAt time.Time
+}
+
+// metadataCache caches the metadata of the keys, so that signing doesn't
+// read the database on every request
+type metadataCache struct {
+	repo    repository.KeyMetadataRepository
+	ttl     time.Duration
+	entries sync.Map
+}
+
+// get returns the metadata of the key, or nil if the key has no metadata
+func (c *metadataCache) get(ctx context.Context, pubKeyHex string) (*model.KeyMetadata, error) {
+	now := time.Now()
+	if value, ok := c.entries.Load(pubKeyHex); ok {
+		entry := value.(cachedMetadata)
+		if now.Sub(entry.loadedAt) < c.ttl {
+			return entry.metadata, nil
+		}
+	}
+
+	metadata, err := c.repo.Get(ctx, pubKeyHex)
+	if errors.Is(err, repository.ErrKeyNotFound) {
+		metadata, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.entries.Store(pubKeyHex, cachedMetadata{metadata: metadata, loadedAt: now})
+	return metadata, nil
+}
+
+func (c *metadataCache) delete(pubKeyHex string) {
+	c.entries.Delete(pubKeyHex)
+}
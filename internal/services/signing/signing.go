@@ -2,43 +2,177 @@ package signing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	v1 "github.com/Layr-Labs/cerberus-api/pkg/api/v1"
 
 	"github.com/Layr-Labs/cerberus/internal/common"
 	"github.com/Layr-Labs/cerberus/internal/configuration"
 	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
 	"github.com/Layr-Labs/cerberus/internal/metrics"
+	"github.com/Layr-Labs/cerberus/internal/seal"
 	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/usage"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+const (
+	MethodSignGeneric = "SignGeneric"
+	MethodSignG1      = "SignG1"
+)
+
 type Service struct {
-	config  *configuration.Configuration
-	logger  *slog.Logger
-	store   store.Store
-	metrics metrics.Recorder
-	keyMap  KeyStoreMap
+	config   *configuration.Configuration
+	logger   *slog.Logger
+	store    store.Store
+	metadata *metadataCache
+	metrics  metrics.Recorder
+	usage    usage.Recorder
+	keyMap   KeyStoreMap
 	v1.UnimplementedSignerServer
 }
 
 func NewService(
 	config *configuration.Configuration,
 	store store.Store,
+	keyMetadataRepo repository.KeyMetadataRepository,
 	logger *slog.Logger,
 	metrics metrics.Recorder,
+	usage usage.Recorder,
 ) *Service {
 	return &Service{
-		config:  config,
-		store:   store,
-		metrics: metrics,
-		logger:  logger.With("component", "signing"),
-		keyMap:  KeyStoreMap{},
+		config:   config,
+		store:    store,
+		metadata: &metadataCache{repo: keyMetadataRepo, ttl: metadataTTL},
+		metrics:  metrics,
+		usage:    usage,
+		logger:   logger.With("component", "signing"),
+		keyMap:   KeyStoreMap{},
+	}
+}
+
+// EvictKey drops the key and its metadata from the in-memory cache, so that
+// they are retrieved again on the next use of the key
+func (s *Service) EvictKey(pubKeyHex string) {
+	s.keyMap.Delete(pubKeyHex)
+	s.metadata.delete(pubKeyHex)
+}
+
+// checkKeyCanSign refuses keys that are locked or used outside their validity
+// window and returns the metadata of the key, nil for the keys without metadata
+func (s *Service) checkKeyCanSign(
+	ctx context.Context,
+	pubKeyHex string,
+) (*model.KeyMetadata, error) {
+	keyMetadata, err := s.metadata.get(ctx, pubKeyHex)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to get key metadata: %v", err))
+		return nil, status.Error(codes.Internal, "failed to get key metadata")
+	}
+	if keyMetadata == nil {
+		return nil, nil
+	}
+
+	if keyMetadata.Locked {
+		return nil, status.Error(codes.FailedPrecondition, "key is locked")
+	}
+
+	now := time.Now().UTC()
+	if keyMetadata.NotBefore != nil && now.Before(*keyMetadata.NotBefore) {
+		return nil, status.Error(
+			codes.FailedPrecondition,
+			fmt.Sprintf("key is not valid before %s", keyMetadata.NotBefore.Format(time.RFC3339)),
+		)
+	}
+	if keyMetadata.NotAfter != nil && !now.Before(*keyMetadata.NotAfter) {
+		return nil, status.Error(
+			codes.FailedPrecondition,
+			fmt.Sprintf("key expired at %s", keyMetadata.NotAfter.Format(time.RFC3339)),
+		)
+	}
+	return keyMetadata, nil
+}
+
+// loadKey returns the key from the in-memory cache, or retrieves it from its
+// store on a cache miss or once the version recorded in its metadata changed
+func (s *Service) loadKey(
+	ctx context.Context,
+	keyMetadata *model.KeyMetadata,
+	pubKeyHex string,
+	password string,
+) (*signingKey, error) {
+	if keyMetadata == nil {
+		keyMetadata = &model.KeyMetadata{}
+	}
+	key, ok := s.keyMap.Load(pubKeyHex)
+	if ok &&
+		key.recordedVersion == keyMetadata.KeyVersion &&
+		key.pinned == keyMetadata.KeyVersionPinned {
+		return key, nil
+	}
+
+	s.logger.Info(fmt.Sprintf("In memory cache miss. Retrieving key for %s", pubKeyHex))
+	key, err := s.retrieveKey(ctx, keyMetadata, pubKeyHex, password)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to retrieve key: %v", err))
+		return nil, status.Error(retrieveErrorCode(err), err.Error())
+	}
+	s.keyMap.Store(pubKeyHex, key)
+	return key, nil
+}
+
+// retrieveKey retrieves the key from the store recorded in its metadata, from
+// the pinned version of the key if any or else from its current version. The
+// keys without metadata are retrieved from the default store.
+func (s *Service) retrieveKey(
+	ctx context.Context,
+	keyMetadata *model.KeyMetadata,
+	pubKeyHex string,
+	password string,
+) (*signingKey, error) {
+	keyStore, _, err := store.Route(s.store, keyMetadata.StoreName)
+	if err != nil {
+		return nil, err
+	}
+
+	// The current version is resolved first, so that the version signing is
+	// known exactly
+	var versionID string
+	if keyMetadata.KeyVersionPinned {
+		versionID = keyMetadata.KeyVersion
+		s.logger.Info(fmt.Sprintf("Using pinned version %s of key %s", versionID, pubKeyHex))
+	} else {
+		versionID, err = store.CurrentVersion(ctx, keyStore, pubKeyHex)
+		if err != nil {
+			return nil, err
+		}
+		if versionID != keyMetadata.KeyVersion && keyMetadata.PublicKeyG1 != "" {
+			s.logger.Warn(
+				"Current version of key differs from its recorded version",
+				"pubKey", pubKeyHex,
+				"version", versionID,
+				"recorded_version", keyMetadata.KeyVersion,
+			)
+		}
+	}
+
+	keyPair, err := store.RetrieveKeyVersion(ctx, keyStore, pubKeyHex, versionID, password)
+	if err != nil {
+		return nil, err
 	}
+	return &signingKey{
+		KeyPair:         keyPair,
+		version:         versionID,
+		recordedVersion: keyMetadata.KeyVersion,
+		pinned:          keyMetadata.KeyVersionPinned,
+	}, nil
 }
 
 func (s *Service) SignGeneric(
@@ -49,16 +183,15 @@ func (s *Service) SignGeneric(
 	pubKeyHex := common.Trim0x(req.GetPublicKeyG1())
 	password := req.GetPassword()
 
-	if _, ok := s.keyMap.Load(pubKeyHex); !ok {
-		s.logger.Info(fmt.Sprintf("In memory cache miss. Retrieving key for %s", pubKeyHex))
-		blsKey, err := s.store.RetrieveKey(ctx, pubKeyHex, password)
-		if err != nil {
-			s.logger.Error(fmt.Sprintf("Failed to retrieve key: %v", err))
-			return nil, status.Error(codes.Internal, err.Error())
-		}
-		s.keyMap.Store(pubKeyHex, blsKey)
+	keyMetadata, err := s.checkKeyCanSign(ctx, pubKeyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	blsKey, err := s.loadKey(ctx, keyMetadata, pubKeyHex, password)
+	if err != nil {
+		return nil, err
 	}
-	blsKey, _ := s.keyMap.Load(pubKeyHex)
 
 	data := req.GetData()
 	if len(data) > 32 {
@@ -70,7 +203,11 @@ func (s *Service) SignGeneric(
 	copy(byteArray[:], data)
 	// Sign the data with the private key
 	sig := blsKey.SignMessage(byteArray)
-	s.logger.Info(fmt.Sprintf("Signed a message successfully using %s", pubKeyHex))
+	s.usage.Record(pubKeyHex, MethodSignGeneric)
+	s.logger.Info(
+		fmt.Sprintf("Signed a message successfully using %s", pubKeyHex),
+		"key_version", blsKey.version,
+	)
 	signatureBytes := sig.RawBytes()
 	return &v1.SignGenericResponse{Signature: signatureBytes[:]}, nil
 }
@@ -92,22 +229,48 @@ func (s *Service) SignG1(
 		return nil, status.Error(codes.InvalidArgument, "data must be > 0 bytes")
 	}
 
-	if _, ok := s.keyMap.Load(pubKeyHex); !ok {
-		s.logger.Info(fmt.Sprintf("In memory cache miss. Retrieving key for %s", pubKeyHex))
-		blsKey, err := s.store.RetrieveKey(ctx, pubKeyHex, password)
-		if err != nil {
-			s.logger.Error(fmt.Sprintf("Failed to retrieve key: %v", err))
-			return nil, status.Error(codes.Internal, err.Error())
-		}
-		s.keyMap.Store(pubKeyHex, blsKey)
+	keyMetadata, err := s.checkKeyCanSign(ctx, pubKeyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	blsKey, err := s.loadKey(ctx, keyMetadata, pubKeyHex, password)
+	if err != nil {
+		return nil, err
 	}
-	blsKey, _ := s.keyMap.Load(pubKeyHex)
 
 	g1Point := new(crypto.G1Point)
 	g1Point = g1Point.Deserialize(g1Bytes)
 
 	sig := blsKey.SignHashedToCurveMessage(g1Point.G1Affine)
-	s.logger.Info(fmt.Sprintf("Signed a G1 message successfully using %s", pubKeyHex))
+	s.usage.Record(pubKeyHex, MethodSignG1)
+	s.logger.Info(
+		fmt.Sprintf("Signed a G1 message successfully using %s", pubKeyHex),
+		"key_version", blsKey.version,
+	)
 	signatureBytes := sig.RawBytes()
 	return &v1.SignG1Response{Signature: signatureBytes[:]}, nil
 }
+
+// retrieveErrorCode tells the client to retry later while the master key of
+// the store is sealed, or while the store is unavailable or too slow. A store
+// holding an invalid private key is a precondition failure rather than an
+// internal error, the key must be replaced before it can sign.
+func retrieveErrorCode(err error) codes.Code {
+	if errors.Is(err, seal.ErrSealed) || errors.Is(err, store.ErrUnavailable) {
+		return codes.Unavailable
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return codes.DeadlineExceeded
+	}
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return codes.NotFound
+	}
+	if errors.Is(err, store.ErrUnknownStore) ||
+		errors.Is(err, store.ErrVersionNotFound) ||
+		errors.Is(err, store.ErrVersioningUnsupported) ||
+		errors.Is(err, crypto.ErrInvalidPrivateKey) {
+		return codes.FailedPrecondition
+	}
+	return codes.Internal
+}
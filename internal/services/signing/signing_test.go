@@ -3,18 +3,59 @@ package signing
 import (
 	"context"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	v1 "github.com/Layr-Labs/cerberus-api/pkg/api/v1"
 
 	"github.com/Layr-Labs/cerberus/internal/common/testutils"
 	"github.com/Layr-Labs/cerberus/internal/configuration"
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
 	"github.com/Layr-Labs/cerberus/internal/metrics"
+	"github.com/Layr-Labs/cerberus/internal/store"
 	"github.com/Layr-Labs/cerberus/internal/store/filesystem"
+	"github.com/Layr-Labs/cerberus/internal/store/memory"
+	"github.com/Layr-Labs/cerberus/internal/store/router"
+	"github.com/Layr-Labs/cerberus/internal/usage"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+type fakeKeyMetadataRepo struct {
+	repository.KeyMetadataRepository
+	keys map[string]*model.KeyMetadata
+	gets int
+}
+
+func newFakeKeyMetadataRepo(keys ...*model.KeyMetadata) *fakeKeyMetadataRepo {
+	r := &fakeKeyMetadataRepo{keys: make(map[string]*model.KeyMetadata)}
+	for _, k := range keys {
+		r.keys[k.PublicKeyG1] = k
+	}
+	return r
+}
+
+func (r *fakeKeyMetadataRepo) Get(
+	_ context.Context,
+	publicKeyG1 string,
+) (*model.KeyMetadata, error) {
+	r.gets++
+	k, ok := r.keys[publicKeyG1]
+	if !ok {
+		return nil, repository.ErrKeyNotFound
+	}
+	return k, nil
+}
+
 func TestSigning(t *testing.T) {
 	// private key: 0x040ad69253b921aca71dd714cccc3095576fbe1a21f86c9b10cb5b119b1c6899
 	pubKeyHex := "a3111a2232584734d526d62cbb7c9a0d4ce1984a92b7ecb85bde8878fea5d1b0"
@@ -30,7 +71,8 @@ func TestSigning(t *testing.T) {
 	logger := testutils.GetTestLogger()
 	store := filesystem.NewStore(config.KeystoreDir, logger)
 	m := metrics.NewNoopRPCMetrics()
-	signingService := NewService(config, store, logger, m)
+	repo := newFakeKeyMetadataRepo(&model.KeyMetadata{PublicKeyG1: pubKeyHex})
+	signingService := NewService(config, store, repo, logger, m, usage.NewNoopRecorder())
 
 	resp, err := signingService.SignGeneric(context.Background(), &v1.SignGenericRequest{
 		PublicKeyG1: pubKeyHex,
@@ -56,7 +98,8 @@ func TestSigningG1(t *testing.T) {
 	logger := testutils.GetTestLogger()
 	store := filesystem.NewStore(config.KeystoreDir, logger)
 	m := metrics.NewNoopRPCMetrics()
-	signingService := NewService(config, store, logger, m)
+	repo := newFakeKeyMetadataRepo(&model.KeyMetadata{PublicKeyG1: pubKeyHex})
+	signingService := NewService(config, store, repo, logger, m, usage.NewNoopRecorder())
 
 	resp, err := signingService.SignG1(context.Background(), &v1.SignG1Request{
 		PublicKeyG1: pubKeyHex,
@@ -66,3 +109,375 @@ func TestSigningG1(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expectedSig, hex.EncodeToString(resp.Signature))
 }
+
+func TestSigningOutsideValidityWindow(t *testing.T) {
+	pubKeyHex := "a3111a2232584734d526d62cbb7c9a0d4ce1984a92b7ecb85bde8878fea5d1b0"
+	password := "p@$$w0rd"
+	var bytes [32]byte
+	copy(bytes[:], "somedata")
+	var g1Bytes [64]byte
+	copy(g1Bytes[:], "somedata")
+
+	config := &configuration.Configuration{
+		KeystoreDir: "testdata/keystore",
+	}
+	logger := testutils.GetTestLogger()
+	store := filesystem.NewStore(config.KeystoreDir, logger)
+	m := metrics.NewNoopRPCMetrics()
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		metadata *model.KeyMetadata
+		code     codes.Code
+	}{
+		{
+			name:     "locked key",
+			metadata: &model.KeyMetadata{PublicKeyG1: pubKeyHex, Locked: true},
+			code:     codes.FailedPrecondition,
+		},
+		{
+			name:     "not yet valid",
+			metadata: &model.KeyMetadata{PublicKeyG1: pubKeyHex, NotBefore: &future},
+			code:     codes.FailedPrecondition,
+		},
+		{
+			name:     "expired",
+			metadata: &model.KeyMetadata{PublicKeyG1: pubKeyHex, NotAfter: &past},
+			code:     codes.FailedPrecondition,
+		},
+		{
+			name: "within window",
+			metadata: &model.KeyMetadata{
+				PublicKeyG1: pubKeyHex,
+				NotBefore:   &past,
+				NotAfter:    &future,
+			},
+			code: codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeKeyMetadataRepo(tt.metadata)
+			signingService := NewService(config, store, repo, logger, m, usage.NewNoopRecorder())
+
+			_, err := signingService.SignGeneric(context.Background(), &v1.SignGenericRequest{
+				PublicKeyG1: pubKeyHex,
+				Data:        bytes[:],
+				Password:    password,
+			})
+			require.Equal(t, tt.code, status.Code(err))
+
+			_, err = signingService.SignG1(context.Background(), &v1.SignG1Request{
+				PublicKeyG1: pubKeyHex,
+				Data:        g1Bytes[:],
+				Password:    password,
+			})
+			require.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestSigningKeyWithoutMetadata(t *testing.T) {
+	pubKeyHex := "a3111a2232584734d526d62cbb7c9a0d4ce1984a92b7ecb85bde8878fea5d1b0"
+	var bytes [32]byte
+	copy(bytes[:], "somedata")
+
+	config := &configuration.Configuration{
+		KeystoreDir: "testdata/keystore",
+	}
+	logger := testutils.GetTestLogger()
+	store := filesystem.NewStore(config.KeystoreDir, logger)
+	m := metrics.NewNoopRPCMetrics()
+	repo := newFakeKeyMetadataRepo()
+	signingService := NewService(config, store, repo, logger, m, usage.NewNoopRecorder())
+
+	// The key is in the store, it signs without validity window
+	_, err := signingService.SignGeneric(context.Background(), &v1.SignGenericRequest{
+		PublicKeyG1: pubKeyHex,
+		Data:        bytes[:],
+		Password:    "p@$$w0rd",
+	})
+	require.NoError(t, err)
+
+	_, err = signingService.SignGeneric(context.Background(), &v1.SignGenericRequest{
+		PublicKeyG1: strings.Repeat("ab", 32),
+		Data:        bytes[:],
+		Password:    "p@$$w0rd",
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestSigningCachesKeyMetadata(t *testing.T) {
+	pubKeyHex := "a3111a2232584734d526d62cbb7c9a0d4ce1984a92b7ecb85bde8878fea5d1b0"
+	req := &v1.SignGenericRequest{PublicKeyG1: pubKeyHex, Password: "p@$$w0rd"}
+
+	config := &configuration.Configuration{
+		KeystoreDir: "testdata/keystore",
+	}
+	logger := testutils.GetTestLogger()
+	store := filesystem.NewStore(config.KeystoreDir, logger)
+	m := metrics.NewNoopRPCMetrics()
+	repo := newFakeKeyMetadataRepo(&model.KeyMetadata{PublicKeyG1: pubKeyHex})
+	signingService := NewService(config, store, repo, logger, m, usage.NewNoopRecorder())
+
+	for i := 0; i < 3; i++ {
+		_, err := signingService.SignGeneric(context.Background(), req)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, repo.gets)
+
+	// A locked key is refused once the metadata is read again
+	repo.keys[pubKeyHex] = &model.KeyMetadata{PublicKeyG1: pubKeyHex, Locked: true}
+	signingService.EvictKey(pubKeyHex)
+	_, err := signingService.SignGeneric(context.Background(), req)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// So does a new validity window
+	past := time.Now().Add(-time.Hour)
+	repo.keys[pubKeyHex] = &model.KeyMetadata{PublicKeyG1: pubKeyHex, NotAfter: &past}
+	signingService.EvictKey(pubKeyHex)
+	_, err = signingService.SignGeneric(context.Background(), req)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	signingService.metadata.ttl = 0
+	repo.keys[pubKeyHex] = &model.KeyMetadata{PublicKeyG1: pubKeyHex}
+	_, err = signingService.SignGeneric(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, repo.gets)
+}
+
+func TestSigningRoutesToKeyStore(t *testing.T) {
+	pubKeyHex := "a3111a2232584734d526d62cbb7c9a0d4ce1984a92b7ecb85bde8878fea5d1b0"
+	password := "p@$$w0rd"
+	var bytes [32]byte
+	copy(bytes[:], "somedata")
+
+	config := &configuration.Configuration{}
+	logger := testutils.GetTestLogger()
+	m := metrics.NewNoopRPCMetrics()
+
+	// The key is only in the disk store, which isn't the default store
+	hsmStore, err := memory.NewStore("", logger)
+	require.NoError(t, err)
+	keyStore, err := router.NewStore([]router.NamedStore{
+		{Name: "hsm", Store: hsmStore},
+		{Name: "disk", Store: filesystem.NewStore("testdata/keystore", logger)},
+	}, "hsm", logger)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		storeName string
+		code      codes.Code
+	}{
+		{name: "recorded store", storeName: "disk", code: codes.OK},
+		{name: "default store", storeName: "", code: codes.NotFound},
+		{name: "unknown store", storeName: "vault", code: codes.FailedPrecondition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeKeyMetadataRepo(&model.KeyMetadata{
+				PublicKeyG1: pubKeyHex,
+				StoreName:   tt.storeName,
+			})
+			signingService := NewService(config, keyStore, repo, logger, m, usage.NewNoopRecorder())
+
+			_, err := signingService.SignGeneric(context.Background(), &v1.SignGenericRequest{
+				PublicKeyG1: pubKeyHex,
+				Data:        bytes[:],
+				Password:    password,
+			})
+			require.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+// versionedStore serves the key of its store as its only version, "1"
+type versionedStore struct {
+	store.Store
+	store.Versioner
+	retrieved []string
+}
+
+func (s *versionedStore) CurrentVersion(context.Context, string) (string, error) {
+	return "1", nil
+}
+
+func (s *versionedStore) RetrieveKeyVersion(
+	ctx context.Context,
+	pubKey string,
+	versionID string,
+	password string,
+) (*crypto.KeyPair, error) {
+	s.retrieved = append(s.retrieved, versionID)
+	if versionID != "1" {
+		return nil, store.ErrVersionNotFound
+	}
+	return s.Store.RetrieveKey(ctx, pubKey, password)
+}
+
+func TestSigningWithPinnedKeyVersion(t *testing.T) {
+	pubKeyHex := "a3111a2232584734d526d62cbb7c9a0d4ce1984a92b7ecb85bde8878fea5d1b0"
+	password := "p@$$w0rd"
+	var bytes [32]byte
+	copy(bytes[:], "somedata")
+
+	config := &configuration.Configuration{}
+	logger := testutils.GetTestLogger()
+	m := metrics.NewNoopRPCMetrics()
+	fileStore := filesystem.NewStore("testdata/keystore", logger)
+
+	tests := []struct {
+		name       string
+		keyStore   store.Store
+		keyVersion string
+		pinned     bool
+		code       codes.Code
+	}{
+		{name: "current version", keyStore: &versionedStore{Store: fileStore}, keyVersion: "2"},
+		{
+			name:       "pinned version",
+			keyStore:   &versionedStore{Store: fileStore},
+			keyVersion: "1",
+			pinned:     true,
+		},
+		{
+			name:       "missing pinned version",
+			keyStore:   &versionedStore{Store: fileStore},
+			keyVersion: "2",
+			pinned:     true,
+			code:       codes.FailedPrecondition,
+		},
+		{
+			name:       "store without versions",
+			keyStore:   fileStore,
+			keyVersion: "1",
+			pinned:     true,
+			code:       codes.FailedPrecondition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeKeyMetadataRepo(&model.KeyMetadata{
+				PublicKeyG1:      pubKeyHex,
+				KeyVersion:       tt.keyVersion,
+				KeyVersionPinned: tt.pinned,
+			})
+			signingService := NewService(
+				config,
+				tt.keyStore,
+				repo,
+				logger,
+				m,
+				usage.NewNoopRecorder(),
+			)
+
+			_, err := signingService.SignGeneric(context.Background(), &v1.SignGenericRequest{
+				PublicKeyG1: pubKeyHex,
+				Data:        bytes[:],
+				Password:    password,
+			})
+			require.Equal(t, tt.code, status.Code(err))
+
+			// The current version is retrieved by its ID, so that it is known
+			if versioned, ok := tt.keyStore.(*versionedStore); ok && !tt.pinned {
+				assert.Equal(t, []string{"1"}, versioned.retrieved)
+			}
+		})
+	}
+}
+
+func TestSigningReloadsKeyOnVersionChange(t *testing.T) {
+	pubKeyHex := "a3111a2232584734d526d62cbb7c9a0d4ce1984a92b7ecb85bde8878fea5d1b0"
+	req := &v1.SignGenericRequest{PublicKeyG1: pubKeyHex, Password: "p@$$w0rd"}
+
+	logger := testutils.GetTestLogger()
+	keyStore := &versionedStore{Store: filesystem.NewStore("testdata/keystore", logger)}
+	repo := newFakeKeyMetadataRepo(&model.KeyMetadata{PublicKeyG1: pubKeyHex, KeyVersion: "1"})
+	signingService := NewService(
+		&configuration.Configuration{},
+		keyStore,
+		repo,
+		logger,
+		metrics.NewNoopRPCMetrics(),
+		usage.NewNoopRecorder(),
+	)
+	signingService.metadata.ttl = 0
+
+	_, err := signingService.SignGeneric(context.Background(), req)
+	require.NoError(t, err)
+	_, err = signingService.SignGeneric(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, keyStore.retrieved)
+
+	// Pinned by another process, the key is retrieved again once the metadata
+	// is read
+	repo.keys[pubKeyHex] = &model.KeyMetadata{
+		PublicKeyG1:      pubKeyHex,
+		KeyVersion:       "2",
+		KeyVersionPinned: true,
+	}
+	_, err = signingService.SignGeneric(context.Background(), req)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, []string{"1", "2"}, keyStore.retrieved)
+}
+
+// failingStore fails to retrieve the keys with err
+type failingStore struct {
+	store.Store
+	err error
+}
+
+func (s *failingStore) RetrieveKey(context.Context, string, string) (*crypto.KeyPair, error) {
+	return nil, s.err
+}
+
+func TestSigningStoreFailures(t *testing.T) {
+	pubKeyHex := "a3111a2232584734d526d62cbb7c9a0d4ce1984a92b7ecb85bde8878fea5d1b0"
+	logger := testutils.GetTestLogger()
+	repo := newFakeKeyMetadataRepo(&model.KeyMetadata{PublicKeyG1: pubKeyHex})
+
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{
+			name: "circuit open",
+			err:  fmt.Errorf("%w: circuit breaker is open", store.ErrUnavailable),
+			code: codes.Unavailable,
+		},
+		{name: "timeout", err: context.DeadlineExceeded, code: codes.DeadlineExceeded},
+		{
+			name: "invalid private key",
+			err:  fmt.Errorf("%w: zero value", crypto.ErrInvalidPrivateKey),
+			code: codes.FailedPrecondition,
+		},
+		{name: "backend failure", err: errors.New("rate exceeded"), code: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signingService := NewService(
+				&configuration.Configuration{},
+				&failingStore{err: tt.err},
+				repo,
+				logger,
+				metrics.NewNoopRPCMetrics(),
+				usage.NewNoopRecorder(),
+			)
+
+			_, err := signingService.SignGeneric(context.Background(), &v1.SignGenericRequest{
+				PublicKeyG1: pubKeyHex,
+				Data:        []byte("somedata"),
+			})
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package store
+
+import (
+	"context"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+)
+
+// AtomicStorer is implemented by the stores keeping the keys in the database
+// of the key metadata, which can store a key and its metadata atomically
+type AtomicStorer interface {
+	// StoreKeyAtomically stores the key and calls record with the public key
+	// in the same transaction, whose context is passed to record. The key is
+	// only stored if record succeeds.
+	// Returns ErrKeyAlreadyExists if the key is already in the store
+	StoreKeyAtomically(
+		ctx context.Context,
+		keyPair *keystore.KeyPair,
+		record func(ctx context.Context, pubKey string) error,
+	) (string, error)
+}
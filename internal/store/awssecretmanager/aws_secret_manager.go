@@ -3,8 +3,14 @@ package awssecretmanager
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -17,15 +23,104 @@ import (
 
 	"github.com/Layr-Labs/cerberus/internal/common"
 	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/seal"
 	"github.com/Layr-Labs/cerberus/internal/store"
 )
 
-var _ store.Store = (*Keystore)(nil)
+var (
+	_ store.Store               = (*Keystore)(nil)
+	_ store.KeystoreMigrator    = (*Keystore)(nil)
+	_ store.Versioner           = (*Keystore)(nil)
+	_ store.PasswordChecker     = (*Keystore)(nil)
+	_ store.TransientClassifier = (*Keystore)(nil)
+)
+
+// throttlingErrorCodes are the error codes of the AWS APIs throttling the
+// requests, which are answered with a 400 rather than a 429
+var throttlingErrorCodes = map[string]bool{
+	"Throttling":                true,
+	"ThrottlingException":       true,
+	"ThrottledException":        true,
+	"TooManyRequestsException":  true,
+	"RequestLimitExceeded":      true,
+	"RequestThrottled":          true,
+	"RequestThrottledException": true,
+}
+
+const (
+	// DefaultPrefix is prepended to the public keys to name the secrets
+	DefaultPrefix = "cerberus/"
+
+	// currentStage is the staging label of the version read by default
+	currentStage = "AWSCURRENT"
+)
+
+// Client is the part of the Secrets Manager API used by the store, so that
+// another implementation can be injected in tests
+type Client interface {
+	secretsmanager.ListSecretsAPIClient
+	secretsmanager.ListSecretVersionIdsAPIClient
+
+	CreateSecret(
+		ctx context.Context,
+		params *secretsmanager.CreateSecretInput,
+		optFns ...func(*secretsmanager.Options),
+	) (*secretsmanager.CreateSecretOutput, error)
+
+	GetSecretValue(
+		ctx context.Context,
+		params *secretsmanager.GetSecretValueInput,
+		optFns ...func(*secretsmanager.Options),
+	) (*secretsmanager.GetSecretValueOutput, error)
+
+	PutSecretValue(
+		ctx context.Context,
+		params *secretsmanager.PutSecretValueInput,
+		optFns ...func(*secretsmanager.Options),
+	) (*secretsmanager.PutSecretValueOutput, error)
+
+	UpdateSecretVersionStage(
+		ctx context.Context,
+		params *secretsmanager.UpdateSecretVersionStageInput,
+		optFns ...func(*secretsmanager.Options),
+	) (*secretsmanager.UpdateSecretVersionStageOutput, error)
+
+	DeleteSecret(
+		ctx context.Context,
+		params *secretsmanager.DeleteSecretInput,
+		optFns ...func(*secretsmanager.Options),
+	) (*secretsmanager.DeleteSecretOutput, error)
+}
+
+var _ Client = (*secretsmanager.Client)(nil)
 
-const storagePrefix = "cerberus/"
+// SecretOptions are applied to the secrets created by the store
+type SecretOptions struct {
+	// KMSKeyID is the KMS key encrypting the secrets, the key of the account
+	// managed by Secrets Manager is used if it is empty
+	KMSKeyID string
+
+	// Tags are added to the secrets
+	Tags map[string]string
+
+	// ReplicaRegions are the regions the secrets are replicated to, each
+	// replica is encrypted with the key managed by Secrets Manager in its region
+	ReplicaRegions []string
+}
 
 type Keystore struct {
-	smClient *secretsmanager.Client
+	smClient Client
+
+	// prefix is prepended to the public keys to name the secrets, so that
+	// several deployments can share an account
+	prefix        string
+	secretOptions SecretOptions
+
+	// masterKey wraps the stored keys when set
+	masterKey *seal.Keeper
+
+	// keystoreEncryption stores the keys encrypted with their password
+	keystoreEncryption bool
 
 	logger *slog.Logger
 }
@@ -44,11 +139,7 @@ func NewStoreWithEnv(
 		return nil, err
 	}
 
-	svc := secretsmanager.NewFromConfig(cfg)
-	return &Keystore{
-		smClient: svc,
-		logger:   logger.With("component", "aws-secret-manager-store"),
-	}, nil
+	return NewStore(secretsmanager.NewFromConfig(cfg), logger), nil
 }
 
 func NewStoreWithSpecifiedCredentials(
@@ -71,11 +162,66 @@ func NewStoreWithSpecifiedCredentials(
 	if err != nil {
 		return nil, err
 	}
-	svc := secretsmanager.NewFromConfig(cfg)
+	return NewStore(secretsmanager.NewFromConfig(cfg), logger), nil
+}
+
+// NewStore returns a store keeping the keys in Secrets Manager through the
+// given client
+func NewStore(client Client, logger *slog.Logger) *Keystore {
 	return &Keystore{
-		smClient: svc,
+		smClient: client,
+		prefix:   DefaultPrefix,
 		logger:   logger.With("component", "aws-secret-manager-store"),
-	}, nil
+	}
+}
+
+// WithPrefix names the secrets with the prefix rather than DefaultPrefix.
+// Only the secrets with the prefix are listed as keys.
+func (k *Keystore) WithPrefix(prefix string) *Keystore {
+	if prefix != "" {
+		k.prefix = prefix
+	}
+	return k
+}
+
+// WithSecretOptions applies the options to the secrets created from now on
+func (k *Keystore) WithSecretOptions(opts SecretOptions) *Keystore {
+	k.secretOptions = opts
+	return k
+}
+
+// WithMasterKey envelope encrypts the keys stored from now on with data keys
+// wrapped by the master key. Keys stored in plaintext remain readable.
+func (k *Keystore) WithMasterKey(keeper *seal.Keeper) *Keystore {
+	k.masterKey = keeper
+	return k
+}
+
+// WithKeystoreEncryption stores the keys from now on as EIP-2335 keystores
+// encrypted with the password of the key, like the filesystem store does
+func (k *Keystore) WithKeystoreEncryption(enabled bool) *Keystore {
+	k.keystoreEncryption = enabled
+	return k
+}
+
+// ChecksPassword reports whether the keys are stored as keystores. The keys
+// stored before keystore encryption was enabled are returned whatever the
+// password until they are migrated.
+func (k *Keystore) ChecksPassword() bool {
+	return k.keystoreEncryption
+}
+
+// Transient reports the throttled requests and the server errors of Secrets
+// Manager
+func (k *Keystore) Transient(err error) bool {
+	var codeErr interface{ ErrorCode() string }
+	if errors.As(err, &codeErr) && throttlingErrorCodes[codeErr.ErrorCode()] {
+		return true
+	}
+	var httpErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &httpErr) &&
+		(httpErr.HTTPStatusCode() == http.StatusTooManyRequests ||
+			httpErr.HTTPStatusCode() >= http.StatusInternalServerError)
 }
 
 func (k *Keystore) RetrieveKey(
@@ -83,26 +229,12 @@ func (k *Keystore) RetrieveKey(
 	pubKey string,
 	password string,
 ) (*crypto.KeyPair, error) {
-	storageKey := storagePrefix + pubKey
-
-	input := &secretsmanager.GetSecretValueInput{
-		SecretId:     &storageKey,
-		VersionStage: aws.String("AWSCURRENT"),
-	}
-
-	result, err := k.smClient.GetSecretValue(ctx, input)
+	secret, _, err := k.getSecret(ctx, pubKey, "")
 	if err != nil {
 		return nil, err
 	}
 
-	var secretString = *result.SecretString
-
-	kp, err := crypto.NewKeyPairFromHexString(secretString)
-	if err != nil {
-		return nil, err
-	}
-
-	return kp, nil
+	return k.decodeKey(pubKey, secret, password)
 }
 
 func (k *Keystore) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (string, error) {
@@ -111,17 +243,25 @@ func (k *Keystore) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (str
 		return "", err
 	}
 
-	storageKey := storagePrefix + pubKey
+	storageKey := k.storageKey(pubKey)
 
-	skHex := hex.EncodeToString(keyPair.PrivateKey)
+	secretString, err := k.encodeKey(pubKey, keyPair, k.keystoreEncryption)
+	if err != nil {
+		return "", err
+	}
 
 	storeRequest := &secretsmanager.CreateSecretInput{
 		Name:         &storageKey,
-		SecretString: aws.String(skHex),
+		SecretString: aws.String(secretString),
 	}
+	k.applySecretOptions(storeRequest)
 
 	_, err = k.smClient.CreateSecret(ctx, storeRequest)
 	if err != nil {
+		var exists *types.ResourceExistsException
+		if errors.As(err, &exists) {
+			return "", store.ErrKeyAlreadyExists
+		}
 		return "", err
 	}
 
@@ -134,22 +274,495 @@ func (k *Keystore) ListKeys(ctx context.Context) ([]string, error) {
 			{
 				Key: types.FilterNameStringTypeName,
 				Values: []string{
-					storagePrefix,
+					k.prefix,
 				},
 			},
 		},
 	}
 
-	result, err := k.smClient.ListSecrets(ctx, input)
+	// ListSecrets returns at most one page per call, so follow NextToken
+	// until every page has been read
+	var keys []string
+	paginator := secretsmanager.NewListSecretsPaginator(k.smClient, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, secret := range page.SecretList {
+			// The name filter is case insensitive, so it also matches the
+			// secrets of other applications such as Cerberus/...
+			if !strings.HasPrefix(*secret.Name, k.prefix) {
+				continue
+			}
+			// The secrets of a deployment with a longer prefix, such as
+			// cerberus/staging/..., aren't keys of this one
+			pubKey := common.RemovePrefix(*secret.Name, k.prefix)
+			if strings.Contains(pubKey, "/") {
+				continue
+			}
+			keys = append(keys, pubKey)
+		}
+	}
+
+	k.logger.Debug(fmt.Sprintf("Found %d key files", len(keys)))
+	return keys, nil
+}
+
+func (k *Keystore) DeleteKey(ctx context.Context, pubKey string) error {
+	storageKey := k.storageKey(pubKey)
+
+	// Skip the recovery window, otherwise the secret name stays reserved
+	// and the key can't be stored again until the window ends
+	_, err := k.smClient.DeleteSecret(ctx, &secretsmanager.DeleteSecretInput{
+		SecretId:                   &storageKey,
+		ForceDeleteWithoutRecovery: aws.Bool(true),
+	})
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return store.ErrKeyNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (k *Keystore) MigrateToKeystore(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (bool, error) {
+	secret, versionID, err := k.getSecret(ctx, pubKey, "")
+	if err != nil {
+		return false, err
+	}
+
+	encrypted, err := k.isKeystore(pubKey, secret)
+	if err != nil || encrypted {
+		return false, err
+	}
+
+	kp, err := k.decodeKey(pubKey, secret, "")
+	if err != nil {
+		return false, err
+	}
+	secretString, err := k.encodeKey(pubKey, &keystore.KeyPair{
+		PrivateKey: crypto.PrivateKeyBytes(kp.PrivKey),
+		Password:   password,
+	}, true)
+	if err != nil {
+		return false, err
+	}
+
+	storageKey := k.storageKey(pubKey)
+	_, err = k.smClient.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     &storageKey,
+		SecretString: aws.String(secretString),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	// The plaintext version becomes AWSPREVIOUS, remove the label so it is
+	// deprecated and deleted by Secrets Manager
+	_, err = k.smClient.UpdateSecretVersionStage(ctx, &secretsmanager.UpdateSecretVersionStageInput{
+		SecretId:            &storageKey,
+		VersionStage:        aws.String("AWSPREVIOUS"),
+		RemoveFromVersionId: versionID,
+	})
+	if err != nil {
+		k.logger.Warn(
+			"Failed to deprecate the plaintext version of the key",
+			"pubKey", pubKey,
+			"error", err,
+		)
+	}
+	return true, nil
+}
+
+func (k *Keystore) CurrentVersion(ctx context.Context, pubKey string) (string, error) {
+	versions, err := k.ListVersions(ctx, pubKey)
+	if err != nil {
+		return "", err
+	}
+	for _, version := range versions {
+		if version.Current {
+			return version.ID, nil
+		}
+	}
+	return "", store.ErrKeyNotFound
+}
+
+// ListVersions returns the versions of the key, including the deprecated
+// versions without staging label which Secrets Manager hasn't deleted yet
+func (k *Keystore) ListVersions(ctx context.Context, pubKey string) ([]store.KeyVersion, error) {
+	storageKey := k.storageKey(pubKey)
+	paginator := secretsmanager.NewListSecretVersionIdsPaginator(
+		k.smClient,
+		&secretsmanager.ListSecretVersionIdsInput{
+			SecretId:          &storageKey,
+			IncludeDeprecated: aws.Bool(true),
+		},
+	)
+
+	var versions []store.KeyVersion
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			var notFound *types.ResourceNotFoundException
+			if errors.As(err, &notFound) {
+				return nil, store.ErrKeyNotFound
+			}
+			return nil, err
+		}
+		for _, entry := range page.Versions {
+			versions = append(versions, store.KeyVersion{
+				ID:        aws.ToString(entry.VersionId),
+				CreatedAt: aws.ToTime(entry.CreatedDate),
+				Current:   slices.Contains(entry.VersionStages, currentStage),
+			})
+		}
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].CreatedAt.Before(versions[j].CreatedAt)
+	})
+	return versions, nil
+}
+
+func (k *Keystore) RetrieveKeyVersion(
+	ctx context.Context,
+	pubKey string,
+	versionID string,
+	password string,
+) (*crypto.KeyPair, error) {
+	secret, _, err := k.getSecret(ctx, pubKey, versionID)
 	if err != nil {
 		return nil, err
 	}
 
-	k.logger.Debug(fmt.Sprintf("Found %d key files", len(result.SecretList)))
-	var keys []string
-	for _, secret := range result.SecretList {
-		keys = append(keys, common.RemovePrefix(*secret.Name, storagePrefix))
+	return k.decodeKey(pubKey, secret, password)
+}
+
+// RewrapKey writes the version of the key as a new version, envelope
+// encrypted with a new data key wrapped by the current master key. The key
+// stays a raw private key or a keystore as it was.
+func (k *Keystore) RewrapKey(ctx context.Context, pubKey string, versionID string) (string, error) {
+	if k.masterKey == nil {
+		return "", errors.New("re-wrapping keys requires a master key")
 	}
 
-	return keys, nil
+	secret, _, err := k.getSecret(ctx, pubKey, versionID)
+	if err != nil {
+		return "", err
+	}
+
+	var plaintext []byte
+	switch {
+	case seal.IsEnvelope(secret):
+		plaintext, err = k.openEnvelope(pubKey, secret)
+	case store.IsKeystore(secret):
+		plaintext = secret
+	default:
+		plaintext, err = hex.DecodeString(string(secret))
+	}
+	if err != nil {
+		return "", err
+	}
+	defer clear(plaintext)
+
+	wrapped, err := k.masterKey.Encrypt(plaintext, []byte(pubKey))
+	if err != nil {
+		return "", err
+	}
+
+	storageKey := k.storageKey(pubKey)
+	output, err := k.smClient.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     &storageKey,
+		SecretString: aws.String(string(wrapped)),
+	})
+	if err != nil {
+		return "", err
+	}
+	k.logger.Info("Re-wrapped key", "pubKey", pubKey, "version", aws.ToString(output.VersionId))
+	return aws.ToString(output.VersionId), nil
+}
+
+// RollbackKey moves the AWSCURRENT label back to the version, the version
+// it is moved from becomes AWSPREVIOUS
+func (k *Keystore) RollbackKey(
+	ctx context.Context,
+	pubKey string,
+	versionID string,
+) (string, error) {
+	versions, err := k.ListVersions(ctx, pubKey)
+	if err != nil {
+		return "", err
+	}
+
+	var current string
+	found := false
+	for _, version := range versions {
+		if version.Current {
+			current = version.ID
+		}
+		if version.ID == versionID {
+			found = true
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("%w: %s", store.ErrVersionNotFound, versionID)
+	}
+	if current == versionID {
+		return versionID, nil
+	}
+	if err := k.checkRollbackVersion(ctx, pubKey, versionID, versions); err != nil {
+		return "", err
+	}
+
+	storageKey := k.storageKey(pubKey)
+	_, err = k.smClient.UpdateSecretVersionStage(ctx, &secretsmanager.UpdateSecretVersionStageInput{
+		SecretId:            &storageKey,
+		VersionStage:        aws.String(currentStage),
+		MoveToVersionId:     aws.String(versionID),
+		RemoveFromVersionId: aws.String(current),
+	})
+	if err != nil {
+		return "", err
+	}
+	k.logger.Info("Rolled back key", "pubKey", pubKey, "from", current, "to", versionID)
+	return versionID, nil
+}
+
+// applySecretOptions sets the encryption key, tags and replicas of a secret
+// about to be created
+// checkRollbackVersion refuses to roll back to a version holding another key,
+// or to a plaintext version of a key migrated to a keystore since, which
+// would undo the migration
+func (k *Keystore) checkRollbackVersion(
+	ctx context.Context,
+	pubKey string,
+	versionID string,
+	versions []store.KeyVersion,
+) error {
+	secret, _, err := k.getSecret(ctx, pubKey, versionID)
+	if err != nil {
+		return err
+	}
+	versionPubKey, encrypted, err := k.versionKey(pubKey, secret)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(common.Trim0x(versionPubKey), pubKey) {
+		return fmt.Errorf("version holds the key %s", versionPubKey)
+	}
+	if encrypted {
+		return nil
+	}
+
+	for _, version := range versions {
+		if version.ID == versionID {
+			continue
+		}
+		secret, _, err := k.getSecret(ctx, pubKey, version.ID)
+		if err != nil {
+			return err
+		}
+		isKeystore, err := k.isKeystore(pubKey, secret)
+		if err != nil {
+			return err
+		}
+		if isKeystore {
+			return fmt.Errorf(
+				"version %s holds the plaintext key, which version %s encrypts in a keystore",
+				versionID,
+				version.ID,
+			)
+		}
+	}
+	return nil
+}
+
+// versionKey returns the public key of the key held by a secret written by
+// encodeKey, without its password, and whether it is a keystore
+func (k *Keystore) versionKey(pubKey string, secret []byte) (string, bool, error) {
+	// The envelope is bound to the public key, it doesn't open for another
+	var skBytes []byte
+	if seal.IsEnvelope(secret) {
+		plaintext, err := k.openEnvelope(pubKey, secret)
+		if err != nil {
+			return "", false, err
+		}
+		defer clear(plaintext)
+		secret = plaintext
+		skBytes = plaintext
+	}
+
+	if store.IsKeystore(secret) {
+		var ks keystore.Keystore
+		if err := json.Unmarshal(secret, &ks); err != nil {
+			return "", false, fmt.Errorf("failed to decode keystore: %w", err)
+		}
+		return ks.PubKey, true, nil
+	}
+
+	if skBytes == nil {
+		var err error
+		skBytes, err = hex.DecodeString(common.Trim0x(string(secret)))
+		if err != nil {
+			return "", false, fmt.Errorf("failed to decode key: %w", err)
+		}
+		defer clear(skBytes)
+	}
+	versionPubKey, err := keystore.BlsSkToG1Pk(skBytes, string(curve.BN254))
+	if err != nil {
+		return "", false, err
+	}
+	return versionPubKey, false, nil
+}
+
+func (k *Keystore) applySecretOptions(input *secretsmanager.CreateSecretInput) {
+	if k.secretOptions.KMSKeyID != "" {
+		input.KmsKeyId = aws.String(k.secretOptions.KMSKeyID)
+	}
+
+	tagKeys := make([]string, 0, len(k.secretOptions.Tags))
+	for key := range k.secretOptions.Tags {
+		tagKeys = append(tagKeys, key)
+	}
+	sort.Strings(tagKeys)
+	for _, key := range tagKeys {
+		input.Tags = append(input.Tags, types.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(k.secretOptions.Tags[key]),
+		})
+	}
+
+	for _, region := range k.secretOptions.ReplicaRegions {
+		input.AddReplicaRegions = append(input.AddReplicaRegions, types.ReplicaRegionType{
+			Region: aws.String(region),
+		})
+	}
+}
+
+func (k *Keystore) storageKey(pubKey string) string {
+	return k.prefix + pubKey
+}
+
+// getSecret returns a version of the secret of the key, or its current
+// version if versionID is empty, and the ID of the version
+func (k *Keystore) getSecret(
+	ctx context.Context,
+	pubKey string,
+	versionID string,
+) ([]byte, *string, error) {
+	storageKey := k.storageKey(pubKey)
+
+	input := &secretsmanager.GetSecretValueInput{
+		SecretId: &storageKey,
+	}
+	if versionID == "" {
+		input.VersionStage = aws.String(currentStage)
+	} else {
+		input.VersionId = aws.String(versionID)
+	}
+
+	result, err := k.smClient.GetSecretValue(ctx, input)
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			if versionID != "" {
+				return nil, nil, fmt.Errorf("%w: %s", store.ErrVersionNotFound, versionID)
+			}
+			return nil, nil, store.ErrKeyNotFound
+		}
+		return nil, nil, err
+	}
+
+	return []byte(*result.SecretString), result.VersionId, nil
+}
+
+// encodeKey returns the secret holding the key: the hex private key or a
+// keystore encrypted with the password of the key pair, envelope encrypted
+// when a master key is set
+func (k *Keystore) encodeKey(
+	pubKey string,
+	keyPair *keystore.KeyPair,
+	encrypt bool,
+) (string, error) {
+	plaintext := keyPair.PrivateKey
+	if encrypt {
+		ks, err := store.EncryptKeystore(keyPair)
+		if err != nil {
+			return "", err
+		}
+		plaintext = ks
+	}
+
+	if k.masterKey != nil {
+		wrapped, err := k.masterKey.Encrypt(plaintext, []byte(pubKey))
+		if err != nil {
+			return "", err
+		}
+		return string(wrapped), nil
+	}
+	if encrypt {
+		return string(plaintext), nil
+	}
+	return hex.EncodeToString(plaintext), nil
+}
+
+// decodeKey reads the key from a secret written by encodeKey, or from the hex
+// private key stored before the master key was set
+func (k *Keystore) decodeKey(
+	pubKey string,
+	secret []byte,
+	password string,
+) (*crypto.KeyPair, error) {
+	if !seal.IsEnvelope(secret) {
+		if k.masterKey != nil {
+			k.logger.Warn("Key is stored without envelope encryption", "pubKey", pubKey)
+		}
+		if store.IsKeystore(secret) {
+			return store.DecryptKeystore(secret, password)
+		}
+		return crypto.NewKeyPairFromHexString(string(secret))
+	}
+
+	plaintext, err := k.openEnvelope(pubKey, secret)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(plaintext)
+
+	if store.IsKeystore(plaintext) {
+		return store.DecryptKeystore(plaintext, password)
+	}
+	sk, err := crypto.PrivateKeyFromBytes(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.NewKeyPair(sk), nil
+}
+
+// isKeystore reports whether the secret holds a password encrypted keystore
+func (k *Keystore) isKeystore(pubKey string, secret []byte) (bool, error) {
+	if !seal.IsEnvelope(secret) {
+		return store.IsKeystore(secret), nil
+	}
+
+	plaintext, err := k.openEnvelope(pubKey, secret)
+	if err != nil {
+		return false, err
+	}
+	defer clear(plaintext)
+	return store.IsKeystore(plaintext), nil
+}
+
+func (k *Keystore) openEnvelope(pubKey string, secret []byte) ([]byte, error) {
+	if k.masterKey == nil {
+		return nil, errors.New("key is envelope encrypted but no master key is configured")
+	}
+	return k.masterKey.Decrypt(secret, []byte(pubKey))
 }
@@ -0,0 +1,570 @@
+package awssecretmanager
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/seal"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/storetest"
+)
+
+const (
+	testPassword = "p@$$w0rd"
+
+	// fakePageSize is the number of secrets returned by the fake client per
+	// ListSecrets call
+	fakePageSize = 2
+)
+
+type fakeSecret struct {
+	// versions maps the version IDs to the secret strings
+	versions map[string]string
+
+	// stages maps the staging labels to version IDs
+	stages map[string]string
+
+	// created maps the version IDs to their creation time
+	created map[string]time.Time
+
+	// input is the request which created the secret
+	input *secretsmanager.CreateSecretInput
+}
+
+// fakeClient is an in-memory Secrets Manager
+type fakeClient struct {
+	mu          sync.Mutex
+	secrets     map[string]*fakeSecret
+	nextVersion int
+}
+
+var _ Client = (*fakeClient)(nil)
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{secrets: make(map[string]*fakeSecret)}
+}
+
+func (c *fakeClient) CreateSecret(
+	ctx context.Context,
+	params *secretsmanager.CreateSecretInput,
+	optFns ...func(*secretsmanager.Options),
+) (*secretsmanager.CreateSecretOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := aws.ToString(params.Name)
+	if _, ok := c.secrets[name]; ok {
+		return nil, &types.ResourceExistsException{Message: aws.String("secret exists")}
+	}
+	secret := &fakeSecret{
+		versions: make(map[string]string),
+		stages:   make(map[string]string),
+		created:  make(map[string]time.Time),
+		input:    params,
+	}
+	c.secrets[name] = secret
+	versionID := c.addVersion(secret, aws.ToString(params.SecretString))
+	return &secretsmanager.CreateSecretOutput{Name: params.Name, VersionId: &versionID}, nil
+}
+
+func (c *fakeClient) GetSecretValue(
+	ctx context.Context,
+	params *secretsmanager.GetSecretValueInput,
+	optFns ...func(*secretsmanager.Options),
+) (*secretsmanager.GetSecretValueOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	secret, err := c.secret(params.SecretId)
+	if err != nil {
+		return nil, err
+	}
+	versionID := aws.ToString(params.VersionId)
+	if versionID == "" {
+		stage := aws.ToString(params.VersionStage)
+		if stage == "" {
+			stage = "AWSCURRENT"
+		}
+		versionID = secret.stages[stage]
+	}
+	value, ok := secret.versions[versionID]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("version not found")}
+	}
+	return &secretsmanager.GetSecretValueOutput{
+		Name:         params.SecretId,
+		SecretString: aws.String(value),
+		VersionId:    aws.String(versionID),
+	}, nil
+}
+
+func (c *fakeClient) PutSecretValue(
+	ctx context.Context,
+	params *secretsmanager.PutSecretValueInput,
+	optFns ...func(*secretsmanager.Options),
+) (*secretsmanager.PutSecretValueOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	secret, err := c.secret(params.SecretId)
+	if err != nil {
+		return nil, err
+	}
+	versionID := c.addVersion(secret, aws.ToString(params.SecretString))
+	return &secretsmanager.PutSecretValueOutput{Name: params.SecretId, VersionId: &versionID}, nil
+}
+
+func (c *fakeClient) UpdateSecretVersionStage(
+	ctx context.Context,
+	params *secretsmanager.UpdateSecretVersionStageInput,
+	optFns ...func(*secretsmanager.Options),
+) (*secretsmanager.UpdateSecretVersionStageOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	secret, err := c.secret(params.SecretId)
+	if err != nil {
+		return nil, err
+	}
+	stage := aws.ToString(params.VersionStage)
+	if params.RemoveFromVersionId != nil {
+		if secret.stages[stage] != *params.RemoveFromVersionId {
+			return nil, &types.InvalidParameterException{Message: aws.String("stage not attached")}
+		}
+		delete(secret.stages, stage)
+	}
+	if params.MoveToVersionId != nil {
+		secret.stages[stage] = *params.MoveToVersionId
+	}
+	return &secretsmanager.UpdateSecretVersionStageOutput{Name: params.SecretId}, nil
+}
+
+// ListSecretVersionIds returns every version of the secret in a single page,
+// the deprecated versions are only kept by the fake when they are requested
+func (c *fakeClient) ListSecretVersionIds(
+	ctx context.Context,
+	params *secretsmanager.ListSecretVersionIdsInput,
+	optFns ...func(*secretsmanager.Options),
+) (*secretsmanager.ListSecretVersionIdsOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	secret, err := c.secret(params.SecretId)
+	if err != nil {
+		return nil, err
+	}
+	output := &secretsmanager.ListSecretVersionIdsOutput{Name: params.SecretId}
+	for versionID := range secret.versions {
+		var stages []string
+		for stage, id := range secret.stages {
+			if id == versionID {
+				stages = append(stages, stage)
+			}
+		}
+		if len(stages) == 0 && !aws.ToBool(params.IncludeDeprecated) {
+			continue
+		}
+		output.Versions = append(output.Versions, types.SecretVersionsListEntry{
+			VersionId:     aws.String(versionID),
+			VersionStages: stages,
+			CreatedDate:   aws.Time(secret.created[versionID]),
+		})
+	}
+	return output, nil
+}
+
+func (c *fakeClient) DeleteSecret(
+	ctx context.Context,
+	params *secretsmanager.DeleteSecretInput,
+	optFns ...func(*secretsmanager.Options),
+) (*secretsmanager.DeleteSecretOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.secret(params.SecretId); err != nil {
+		return nil, err
+	}
+	delete(c.secrets, aws.ToString(params.SecretId))
+	return &secretsmanager.DeleteSecretOutput{Name: params.SecretId}, nil
+}
+
+// ListSecrets returns the secrets matching the name filters, which match a
+// case insensitive prefix like the real API does
+func (c *fakeClient) ListSecrets(
+	ctx context.Context,
+	params *secretsmanager.ListSecretsInput,
+	optFns ...func(*secretsmanager.Options),
+) (*secretsmanager.ListSecretsOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var names []string
+	for name := range c.secrets {
+		if matchesFilters(name, params.Filters) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	start := 0
+	if params.NextToken != nil {
+		var err error
+		start, err = strconv.Atoi(*params.NextToken)
+		if err != nil {
+			return nil, &types.InvalidNextTokenException{Message: params.NextToken}
+		}
+	}
+	end := min(start+fakePageSize, len(names))
+
+	output := &secretsmanager.ListSecretsOutput{}
+	for _, name := range names[start:end] {
+		output.SecretList = append(output.SecretList, types.SecretListEntry{Name: aws.String(name)})
+	}
+	if end < len(names) {
+		output.NextToken = aws.String(strconv.Itoa(end))
+	}
+	return output, nil
+}
+
+func (c *fakeClient) secret(secretID *string) (*fakeSecret, error) {
+	secret, ok := c.secrets[aws.ToString(secretID)]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("secret not found")}
+	}
+	return secret, nil
+}
+
+// addVersion adds a version to the secret and moves the AWSCURRENT label to
+// it, the version it is moved from becomes AWSPREVIOUS
+func (c *fakeClient) addVersion(secret *fakeSecret, value string) string {
+	c.nextVersion++
+	versionID := fmt.Sprintf("version-%d", c.nextVersion)
+	secret.versions[versionID] = value
+	secret.created[versionID] = time.Unix(int64(c.nextVersion), 0)
+	if current, ok := secret.stages["AWSCURRENT"]; ok {
+		secret.stages["AWSPREVIOUS"] = current
+	}
+	secret.stages["AWSCURRENT"] = versionID
+	return versionID
+}
+
+func matchesFilters(name string, filters []types.Filter) bool {
+	for _, filter := range filters {
+		if filter.Key != types.FilterNameStringTypeName {
+			continue
+		}
+		matches := false
+		for _, value := range filter.Values {
+			if strings.HasPrefix(strings.ToLower(name), strings.ToLower(value)) {
+				matches = true
+			}
+		}
+		if !matches {
+			return false
+		}
+	}
+	return true
+}
+
+func addForeignSecrets(t *testing.T, client Client) {
+	for _, name := range []string{"other-app/secret", "Cerberus/notes"} {
+		_, err := client.CreateSecret(context.Background(), &secretsmanager.CreateSecretInput{
+			Name:         aws.String(name),
+			SecretString: aws.String("not a key"),
+		})
+		require.NoError(t, err)
+	}
+}
+
+func newMasterKey(t *testing.T) *seal.Keeper {
+	key, err := seal.GenerateKey()
+	require.NoError(t, err)
+	keeper, err := seal.NewKeeper(key, testutils.GetTestLogger())
+	require.NoError(t, err)
+	return keeper
+}
+
+func TestKeystoreConformance(t *testing.T) {
+	logger := testutils.GetTestLogger()
+	opts := storetest.Options{
+		AddForeignEntry: func(t *testing.T, s store.Store) {
+			addForeignSecrets(t, s.(*Keystore).smClient)
+		},
+	}
+
+	t.Run("Raw", func(t *testing.T) {
+		opts := opts
+		opts.IgnoresPassword = true
+		storetest.Run(t, func(t *testing.T) store.Store {
+			return NewStore(newFakeClient(), logger)
+		}, opts)
+	})
+
+	t.Run("KeystoreWithMasterKey", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) store.Store {
+			return NewStore(newFakeClient(), logger).
+				WithMasterKey(newMasterKey(t)).
+				WithKeystoreEncryption(true)
+		}, opts)
+	})
+}
+
+func TestMigrateToKeystore(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeClient()
+	s := NewStore(client, testutils.GetTestLogger())
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	pubKey, err := s.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	migrated, err := s.MigrateToKeystore(ctx, pubKey, testPassword)
+	require.NoError(t, err)
+	assert.True(t, migrated)
+
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.NoError(t, err)
+	_, err = s.RetrieveKey(ctx, pubKey, "wrong password")
+	assert.Error(t, err)
+
+	// The plaintext version is no longer staged
+	secret := client.secrets[DefaultPrefix+pubKey]
+	assert.NotContains(t, secret.stages, "AWSPREVIOUS")
+
+	migrated, err = s.MigrateToKeystore(ctx, pubKey, testPassword)
+	require.NoError(t, err)
+	assert.False(t, migrated)
+}
+
+func TestKeyVersions(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore(newFakeClient(), testutils.GetTestLogger())
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	pubKey, err := s.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+	plaintextVersion, err := s.CurrentVersion(ctx, pubKey)
+	require.NoError(t, err)
+
+	_, err = s.MigrateToKeystore(ctx, pubKey, testPassword)
+	require.NoError(t, err)
+	keystoreVersion, err := s.CurrentVersion(ctx, pubKey)
+	require.NoError(t, err)
+	assert.NotEqual(t, plaintextVersion, keystoreVersion)
+
+	versions, err := s.ListVersions(ctx, pubKey)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, plaintextVersion, versions[0].ID)
+	assert.False(t, versions[0].Current)
+	assert.Equal(t, keystoreVersion, versions[1].ID)
+	assert.True(t, versions[1].Current)
+
+	// Every version holds the same key
+	kp, err := s.RetrieveKeyVersion(ctx, pubKey, plaintextVersion, "")
+	require.NoError(t, err)
+	pubKeyBytes := kp.PubKey.Bytes()
+	assert.Equal(t, pubKey, hex.EncodeToString(pubKeyBytes[:]))
+	_, err = s.RetrieveKeyVersion(ctx, pubKey, keystoreVersion, "")
+	assert.ErrorIs(t, err, store.ErrInvalidPassword)
+	_, err = s.RetrieveKeyVersion(ctx, pubKey, "unknown", "")
+	assert.ErrorIs(t, err, store.ErrVersionNotFound)
+
+	// Rolling back to the plaintext version would undo the migration
+	_, err = s.RollbackKey(ctx, pubKey, plaintextVersion)
+	assert.ErrorContains(t, err, "plaintext")
+
+	// A version holding another key is refused too
+	otherKeyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	otherKeystore, err := store.EncryptKeystore(otherKeyPair)
+	require.NoError(t, err)
+	output, err := s.smClient.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(DefaultPrefix + pubKey),
+		SecretString: aws.String(string(otherKeystore)),
+	})
+	require.NoError(t, err)
+	otherVersion := aws.ToString(output.VersionId)
+
+	current, err := s.RollbackKey(ctx, pubKey, keystoreVersion)
+	require.NoError(t, err)
+	assert.Equal(t, keystoreVersion, current)
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.NoError(t, err)
+
+	_, err = s.RollbackKey(ctx, pubKey, otherVersion)
+	assert.ErrorContains(t, err, "version holds the key")
+	current, err = s.CurrentVersion(ctx, pubKey)
+	require.NoError(t, err)
+	assert.Equal(t, keystoreVersion, current)
+
+	_, err = s.RollbackKey(ctx, pubKey, "unknown")
+	assert.ErrorIs(t, err, store.ErrVersionNotFound)
+
+	// Without a master key there is nothing to re-wrap with
+	_, err = s.RewrapKey(ctx, pubKey, "")
+	assert.Error(t, err)
+
+	s.WithMasterKey(newMasterKey(t))
+	for _, versionID := range []string{"", keystoreVersion} {
+		wrappedVersion, err := s.RewrapKey(ctx, pubKey, versionID)
+		require.NoError(t, err)
+		current, err := s.CurrentVersion(ctx, pubKey)
+		require.NoError(t, err)
+		assert.Equal(t, wrappedVersion, current)
+
+		secret := s.smClient.(*fakeClient).secrets[DefaultPrefix+pubKey]
+		assert.True(t, seal.IsEnvelope([]byte(secret.versions[wrappedVersion])))
+	}
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.NoError(t, err)
+}
+
+func TestPrefixAndSecretOptions(t *testing.T) {
+	ctx := context.Background()
+	logger := testutils.GetTestLogger()
+	client := newFakeClient()
+
+	// Two deployments sharing the account only see their own keys
+	defaultStore := NewStore(client, logger)
+	prefixedStore := NewStore(client, logger).
+		WithPrefix("cerberus/staging/").
+		WithSecretOptions(SecretOptions{
+			KMSKeyID:       "alias/cerberus",
+			Tags:           map[string]string{"team": "avs", "env": "staging"},
+			ReplicaRegions: []string{"eu-west-1", "us-west-2"},
+		})
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	pubKey, err := defaultStore.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+	keyPair, err = keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	prefixedPubKey, err := prefixedStore.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	keys, err := defaultStore.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{pubKey}, keys)
+	keys, err = prefixedStore.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{prefixedPubKey}, keys)
+	_, err = prefixedStore.RetrieveKey(ctx, pubKey, "")
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	assert.Nil(t, client.secrets[DefaultPrefix+pubKey].input.KmsKeyId)
+	input := client.secrets["cerberus/staging/"+prefixedPubKey].input
+	assert.Equal(t, "alias/cerberus", aws.ToString(input.KmsKeyId))
+	assert.Equal(t, []types.Tag{
+		{Key: aws.String("env"), Value: aws.String("staging")},
+		{Key: aws.String("team"), Value: aws.String("avs")},
+	}, input.Tags)
+	assert.Equal(t, []types.ReplicaRegionType{
+		{Region: aws.String("eu-west-1")},
+		{Region: aws.String("us-west-2")},
+	}, input.AddReplicaRegions)
+}
+
+// startLocalStack starts LocalStack with Secrets Manager and returns a client
+// connected to it
+func startLocalStack(t *testing.T) *secretsmanager.Client {
+	ctx := context.Background()
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "localstack/localstack:3.8",
+			ExposedPorts: []string{"4566/tcp"},
+			Env: map[string]string{
+				"SERVICES": "secretsmanager",
+			},
+			WaitingFor: wait.ForHTTP("/_localstack/health").WithPort("4566/tcp"),
+		},
+		Started: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = container.Terminate(context.Background())
+	})
+
+	host, err := container.Host(ctx)
+	require.NoError(t, err)
+	port, err := container.MappedPort(ctx, "4566")
+	require.NoError(t, err)
+
+	return secretsmanager.New(secretsmanager.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(fmt.Sprintf("http://%s:%s", host, port.Port())),
+		Credentials:  credentials.NewStaticCredentialsProvider("test", "test", ""),
+	})
+}
+
+// deleteSecrets deletes every secret so that the next test starts empty
+func deleteSecrets(t *testing.T, client *secretsmanager.Client) {
+	ctx := context.Background()
+	paginator := secretsmanager.NewListSecretsPaginator(client, &secretsmanager.ListSecretsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		require.NoError(t, err)
+		for _, secret := range page.SecretList {
+			_, err := client.DeleteSecret(ctx, &secretsmanager.DeleteSecretInput{
+				SecretId:                   secret.Name,
+				ForceDeleteWithoutRecovery: aws.Bool(true),
+			})
+			require.NoError(t, err)
+		}
+	}
+}
+
+func TestWithContainer_LocalStack(t *testing.T) {
+	client := startLocalStack(t)
+	logger := testutils.GetTestLogger()
+
+	storetest.Run(t, func(t *testing.T) store.Store {
+		deleteSecrets(t, client)
+		return NewStore(client, logger).WithKeystoreEncryption(true)
+	}, storetest.Options{
+		AddForeignEntry: func(t *testing.T, s store.Store) {
+			addForeignSecrets(t, client)
+		},
+	})
+}
+
+// apiError is an error of an AWS API
+type apiError struct {
+	code       string
+	statusCode int
+}
+
+func (e *apiError) Error() string       { return e.code }
+func (e *apiError) ErrorCode() string   { return e.code }
+func (e *apiError) HTTPStatusCode() int { return e.statusCode }
+
+func TestTransient(t *testing.T) {
+	k := &Keystore{}
+	assert.True(t, k.Transient(&apiError{code: "ThrottlingException", statusCode: 400}))
+	assert.True(t, k.Transient(fmt.Errorf("get secret: %w", &apiError{statusCode: 503})))
+	assert.False(t, k.Transient(&apiError{code: "AccessDeniedException", statusCode: 400}))
+	assert.False(t, k.Transient(store.ErrKeyNotFound))
+}
@@ -0,0 +1,128 @@
+package decorator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/metrics"
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+type BreakerPolicy struct {
+	// FailureThreshold is the number of consecutive backend failures opening
+	// the circuit. Zero disables the circuit breaker.
+	FailureThreshold int
+
+	// OpenTimeout is the time the circuit stays open, failing fast with
+	// store.ErrUnavailable, before a single trial operation is let through
+	OpenTimeout time.Duration
+}
+
+// Breaker is a circuit breaker shared by the operations of a backend
+type Breaker struct {
+	policy   BreakerPolicy
+	backend  string
+	recorder metrics.StoreRecorder
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	// trial is true while the trial operation of a half-open circuit runs
+	trial bool
+
+	now func() time.Time
+}
+
+func NewBreaker(
+	policy BreakerPolicy,
+	backend string,
+	recorder metrics.StoreRecorder,
+	logger *slog.Logger,
+) *Breaker {
+	recorder.SetCircuitOpen(backend, false)
+	return &Breaker{
+		policy:   policy,
+		backend:  backend,
+		recorder: recorder,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Middleware fails fast while the circuit is open
+func (b *Breaker) Middleware(
+	ctx context.Context,
+	op string,
+	call func(ctx context.Context) error,
+) error {
+	trial, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = call(ctx)
+	b.record(trial, isBackendFailure(ctx, err), err != nil && ctx.Err() != nil)
+	return err
+}
+
+// allow returns an error while the circuit is open. Once the open timeout
+// has elapsed, a single trial operation is allowed.
+func (b *Breaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return false, nil
+	}
+	retryAt := b.openedAt.Add(b.policy.OpenTimeout)
+	if b.trial || b.now().Before(retryAt) {
+		return false, fmt.Errorf(
+			"%w: circuit breaker of %s is open after %d consecutive failures",
+			store.ErrUnavailable,
+			b.backend,
+			b.failures,
+		)
+	}
+	b.trial = true
+	return true, nil
+}
+
+// record updates the circuit with the result of an operation. The trial
+// operation closes the circuit when it succeeds and opens it again when it
+// fails. An operation given up by the caller tells nothing about the backend.
+func (b *Breaker) record(trial bool, failed bool, abandoned bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial {
+		b.trial = false
+	}
+	if abandoned {
+		return
+	}
+
+	if !failed {
+		if b.open {
+			b.logger.Info("Store circuit breaker closed")
+			b.recorder.SetCircuitOpen(b.backend, false)
+		}
+		b.failures = 0
+		b.open = false
+		return
+	}
+
+	b.failures++
+	if trial || (!b.open && b.failures >= b.policy.FailureThreshold) {
+		if !b.open {
+			b.logger.Warn("Store circuit breaker opened", "failures", b.failures)
+			b.recorder.SetCircuitOpen(b.backend, true)
+		}
+		b.open = true
+		b.openedAt = b.now()
+	}
+}
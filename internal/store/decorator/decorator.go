@@ -0,0 +1,208 @@
+// Package decorator wraps the stores with retries, timeouts, a circuit
+// breaker and metrics, so that remote backends degrade gracefully under
+// throttling and outages.
+package decorator
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/metrics"
+	"github.com/Layr-Labs/cerberus/internal/seal"
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+var (
+	_ store.Store   = (*decorated)(nil)
+	_ store.Wrapper = (*decorated)(nil)
+)
+
+// Operations of the stores, used in the metrics
+const (
+	OpRetrieveKey = "retrieve_key"
+	OpStoreKey    = "store_key"
+	OpListKeys    = "list_keys"
+	OpDeleteKey   = "delete_key"
+
+	// Operations of the store.Versioner stores
+	OpCurrentVersion     = "current_version"
+	OpListVersions       = "list_versions"
+	OpRetrieveKeyVersion = "retrieve_key_version"
+	OpRewrapKey          = "rewrap_key"
+	OpRollbackKey        = "rollback_key"
+)
+
+// Results of the operations, used in the metrics
+const (
+	ResultSuccess         = "success"
+	ResultNotFound        = "not_found"
+	ResultAlreadyExists   = "already_exists"
+	ResultInvalidPassword = "invalid_password"
+	ResultUnavailable     = "unavailable"
+	ResultTimeout         = "timeout"
+	ResultError           = "error"
+)
+
+// Middleware runs an operation of a store, call being the operation of the
+// decorated store
+type Middleware func(ctx context.Context, op string, call func(ctx context.Context) error) error
+
+type Options struct {
+	// Timeout of each call to the backend, retries have their own. Zero
+	// means no timeout.
+	Timeout time.Duration
+
+	Retry   RetryPolicy
+	Breaker BreakerPolicy
+
+	// Metrics defaults to no metrics
+	Metrics metrics.StoreRecorder
+}
+
+// Chain decorates the store of the backend with, from the outside in, the
+// metrics, the circuit breaker, the retries and the timeout of each call. The
+// stores implementing store.TransientClassifier tell which of their errors
+// are retried and counted by the circuit breaker, besides the failures of the
+// connection to the backend.
+func Chain(s store.Store, backend string, opts Options, logger *slog.Logger) store.Store {
+	recorder := opts.Metrics
+	if recorder == nil {
+		recorder = metrics.NewNoopStoreMetrics()
+	}
+	logger = logger.With("component", "store-decorator", "backend", backend)
+
+	if classifier, ok := store.As[store.TransientClassifier](s); ok {
+		s = Decorate(s, classify(classifier))
+	}
+	if opts.Timeout > 0 {
+		s = Decorate(s, Timeout(opts.Timeout))
+	}
+	if opts.Retry.MaxAttempts > 1 {
+		s = Decorate(s, Retry(opts.Retry, backend, recorder, logger))
+	}
+	if opts.Breaker.FailureThreshold > 0 {
+		s = Decorate(s, NewBreaker(opts.Breaker, backend, recorder, logger).Middleware)
+	}
+	return Decorate(s, Metrics(backend, recorder))
+}
+
+// Decorate returns a store running the operations of s through the
+// middleware. The store implements the optional store.Versioner and
+// store.AtomicStorer interfaces of s, whose operations run through the
+// middleware too.
+func Decorate(s store.Store, m Middleware) store.Store {
+	return decorate(&decorated{next: s, run: m})
+}
+
+type decorated struct {
+	next store.Store
+	run  Middleware
+}
+
+func (d *decorated) Unwrap() store.Store {
+	return d.next
+}
+
+func (d *decorated) RetrieveKey(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (*crypto.KeyPair, error) {
+	var keyPair *crypto.KeyPair
+	err := d.run(ctx, OpRetrieveKey, func(ctx context.Context) error {
+		var err error
+		keyPair, err = d.next.RetrieveKey(ctx, pubKey, password)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keyPair, nil
+}
+
+func (d *decorated) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (string, error) {
+	var pubKey string
+	err := d.run(ctx, OpStoreKey, func(ctx context.Context) error {
+		var err error
+		pubKey, err = d.next.StoreKey(ctx, keyPair)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return pubKey, nil
+}
+
+func (d *decorated) ListKeys(ctx context.Context) ([]string, error) {
+	var keys []string
+	err := d.run(ctx, OpListKeys, func(ctx context.Context) error {
+		var err error
+		keys, err = d.next.ListKeys(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (d *decorated) DeleteKey(ctx context.Context, pubKey string) error {
+	return d.run(ctx, OpDeleteKey, func(ctx context.Context) error {
+		return d.next.DeleteKey(ctx, pubKey)
+	})
+}
+
+// Timeout cancels each call to the store after the timeout
+func Timeout(timeout time.Duration) Middleware {
+	return func(ctx context.Context, op string, call func(ctx context.Context) error) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return call(ctx)
+	}
+}
+
+// Metrics records the duration and the result of the operations
+func Metrics(backend string, recorder metrics.StoreRecorder) Middleware {
+	return func(ctx context.Context, op string, call func(ctx context.Context) error) error {
+		done := recorder.RecordOperation(backend, op)
+		err := call(ctx)
+		done(Result(err))
+		return err
+	}
+}
+
+// Result returns the result of an operation which returned err
+func Result(err error) string {
+	switch {
+	case err == nil:
+		return ResultSuccess
+	case errors.Is(err, store.ErrKeyNotFound), errors.Is(err, store.ErrVersionNotFound):
+		return ResultNotFound
+	case errors.Is(err, store.ErrKeyAlreadyExists):
+		return ResultAlreadyExists
+	case errors.Is(err, store.ErrInvalidPassword):
+		return ResultInvalidPassword
+	case errors.Is(err, store.ErrUnavailable), errors.Is(err, seal.ErrSealed):
+		return ResultUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return ResultTimeout
+	default:
+		return ResultError
+	}
+}
+
+// isBackendFailure reports whether err is a transient failure of the backend,
+// which is retried and counted by the circuit breaker. The answers of a
+// working backend, such as a missing key or an invalid key, and the requests
+// given up by the caller aren't.
+func isBackendFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	return isTransient(err)
+}
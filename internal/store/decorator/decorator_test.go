@@ -0,0 +1,524 @@
+package decorator
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
+	"github.com/googleapis/gax-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/seal"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/googlesm"
+	"github.com/Layr-Labs/cerberus/internal/store/memory"
+	"github.com/Layr-Labs/cerberus/internal/store/storetest"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+)
+
+const testPassword = "p@$$w0rd"
+
+var errThrottled = status.Error(codes.ResourceExhausted, "rate exceeded")
+
+// flakyStore fails the operations of a memory store with the errors of
+// failures, in order, then lets them through
+type flakyStore struct {
+	*memory.Store
+
+	failures []error
+	calls    int
+	block    bool
+}
+
+func (s *flakyStore) fail(ctx context.Context) error {
+	s.calls++
+	if s.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if len(s.failures) == 0 {
+		return nil
+	}
+	err := s.failures[0]
+	s.failures = s.failures[1:]
+	return err
+}
+
+func (s *flakyStore) RetrieveKey(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (*crypto.KeyPair, error) {
+	if err := s.fail(ctx); err != nil {
+		return nil, err
+	}
+	return s.Store.RetrieveKey(ctx, pubKey, password)
+}
+
+func (s *flakyStore) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (string, error) {
+	if err := s.fail(ctx); err != nil {
+		return "", err
+	}
+	return s.Store.StoreKey(ctx, keyPair)
+}
+
+// flakyVersioner is a flakyStore keeping a single version of the keys, which
+// stores the keys atomically
+type flakyVersioner struct {
+	*flakyStore
+}
+
+func (s *flakyVersioner) CurrentVersion(ctx context.Context, pubKey string) (string, error) {
+	return "1", s.fail(ctx)
+}
+
+func (s *flakyVersioner) ListVersions(
+	ctx context.Context,
+	pubKey string,
+) ([]store.KeyVersion, error) {
+	return []store.KeyVersion{{ID: "1", Current: true}}, s.fail(ctx)
+}
+
+func (s *flakyVersioner) RetrieveKeyVersion(
+	ctx context.Context,
+	pubKey string,
+	versionID string,
+	password string,
+) (*crypto.KeyPair, error) {
+	if versionID != "1" {
+		return nil, store.ErrVersionNotFound
+	}
+	return s.RetrieveKey(ctx, pubKey, password)
+}
+
+func (s *flakyVersioner) RewrapKey(
+	ctx context.Context,
+	pubKey string,
+	versionID string,
+) (string, error) {
+	return "1", s.fail(ctx)
+}
+
+func (s *flakyVersioner) RollbackKey(
+	ctx context.Context,
+	pubKey string,
+	versionID string,
+) (string, error) {
+	return "1", s.fail(ctx)
+}
+
+func (s *flakyVersioner) StoreKeyAtomically(
+	ctx context.Context,
+	keyPair *keystore.KeyPair,
+	record func(ctx context.Context, pubKey string) error,
+) (string, error) {
+	pubKey, err := s.StoreKey(ctx, keyPair)
+	if err != nil {
+		return "", err
+	}
+	return pubKey, record(ctx, pubKey)
+}
+
+// recorder counts the recorded metrics
+type recorder struct {
+	results map[string]int
+	retries int
+	open    bool
+}
+
+func newRecorder() *recorder {
+	return &recorder{results: make(map[string]int)}
+}
+
+func (r *recorder) RecordOperation(backend string, operation string) func(result string) {
+	return func(result string) {
+		r.results[operation+"/"+result]++
+	}
+}
+
+func (r *recorder) RecordRetry(backend string, operation string) {
+	r.retries++
+}
+
+func (r *recorder) SetCircuitOpen(backend string, open bool) {
+	r.open = open
+}
+
+func newFlakyStore(t *testing.T, failures ...error) *flakyStore {
+	s, err := memory.NewStore("", testutils.GetTestLogger())
+	require.NoError(t, err)
+	return &flakyStore{Store: s, failures: failures}
+}
+
+func storeKey(t *testing.T, s store.Store) string {
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	pubKey, err := s.StoreKey(context.Background(), keyPair)
+	require.NoError(t, err)
+	return pubKey
+}
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    5 * time.Millisecond,
+}
+
+func TestChainConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return Chain(newFlakyStore(t), "memory", Options{
+			Timeout: time.Second,
+			Retry:   testRetryPolicy,
+			Breaker: BreakerPolicy{FailureThreshold: 3, OpenTimeout: time.Second},
+			Metrics: newRecorder(),
+		}, testutils.GetTestLogger())
+	}, storetest.Options{})
+}
+
+func TestChainExposesBackend(t *testing.T) {
+	backend := newFlakyStore(t)
+	s := Chain(backend, "memory", Options{
+		Timeout: time.Second,
+		Retry:   testRetryPolicy,
+	}, testutils.GetTestLogger())
+
+	found, ok := store.As[*flakyStore](s)
+	require.True(t, ok)
+	assert.Same(t, backend, found)
+
+	_, ok = store.As[store.Versioner](s)
+	assert.False(t, ok)
+}
+
+func TestChainDecoratesOptionalInterfaces(t *testing.T) {
+	ctx := context.Background()
+	backend := &flakyVersioner{flakyStore: newFlakyStore(t)}
+	pubKey := storeKey(t, backend)
+	metrics := newRecorder()
+	s := Chain(backend, "memory", Options{
+		Timeout: time.Second,
+		Retry:   testRetryPolicy,
+		Breaker: BreakerPolicy{FailureThreshold: 3, OpenTimeout: time.Second},
+		Metrics: metrics,
+	}, testutils.GetTestLogger())
+
+	versioner, ok := store.As[store.Versioner](s)
+	require.True(t, ok)
+	assert.NotSame(t, backend, versioner, "the versions must be read through the decorators")
+
+	backend.calls = 0
+	backend.failures = []error{errThrottled}
+	_, err := versioner.RetrieveKeyVersion(ctx, pubKey, "1", testPassword)
+	require.NoError(t, err)
+	assert.Equal(t, 2, backend.calls)
+	assert.Equal(t, 1, metrics.retries)
+	assert.Equal(t, 1, metrics.results[OpRetrieveKeyVersion+"/"+ResultSuccess])
+
+	_, err = versioner.RetrieveKeyVersion(ctx, pubKey, "2", testPassword)
+	assert.ErrorIs(t, err, store.ErrVersionNotFound)
+	assert.Equal(t, 1, metrics.results[OpRetrieveKeyVersion+"/"+ResultNotFound])
+
+	_, err = versioner.RollbackKey(ctx, pubKey, "1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, metrics.results[OpRollbackKey+"/"+ResultSuccess])
+
+	atomic, ok := store.As[store.AtomicStorer](s)
+	require.True(t, ok)
+	assert.NotSame(t, backend, atomic, "the keys must be stored through the decorators")
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	record := func(ctx context.Context, pubKey string) error { return nil }
+	_, err = atomic.StoreKeyAtomically(ctx, keyPair, record)
+	require.NoError(t, err)
+	assert.Equal(t, 1, metrics.results[OpStoreKey+"/"+ResultSuccess])
+
+	s = Chain(newFlakyStore(t), "memory", Options{}, testutils.GetTestLogger())
+	_, ok = store.As[store.AtomicStorer](s)
+	assert.False(t, ok)
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	logger := testutils.GetTestLogger()
+
+	t.Run("RetriesBackendFailures", func(t *testing.T) {
+		backend := newFlakyStore(t)
+		pubKey := storeKey(t, backend)
+		backend.calls = 0
+		backend.failures = []error{errThrottled, errThrottled}
+		metrics := newRecorder()
+		s := Chain(backend, "memory", Options{Retry: testRetryPolicy, Metrics: metrics}, logger)
+
+		_, err := s.RetrieveKey(ctx, pubKey, testPassword)
+		require.NoError(t, err)
+		assert.Equal(t, 3, backend.calls)
+		assert.Equal(t, 2, metrics.retries)
+		assert.Equal(t, 1, metrics.results[OpRetrieveKey+"/"+ResultSuccess])
+	})
+
+	t.Run("GivesUp", func(t *testing.T) {
+		backend := newFlakyStore(t, errThrottled, errThrottled, errThrottled, errThrottled)
+		metrics := newRecorder()
+		s := Chain(backend, "memory", Options{Retry: testRetryPolicy, Metrics: metrics}, logger)
+
+		_, err := s.RetrieveKey(ctx, storeKey(t, newFlakyStore(t)), testPassword)
+		assert.ErrorIs(t, err, errThrottled)
+		assert.Equal(t, 3, backend.calls)
+		assert.Equal(t, 1, metrics.results[OpRetrieveKey+"/"+ResultError])
+	})
+
+	t.Run("KeepsAnswers", func(t *testing.T) {
+		backend := newFlakyStore(t, store.ErrKeyNotFound, store.ErrInvalidPassword)
+		s := Chain(backend, "memory", Options{Retry: testRetryPolicy}, logger)
+
+		_, err := s.RetrieveKey(ctx, "missing", testPassword)
+		assert.ErrorIs(t, err, store.ErrKeyNotFound)
+		_, err = s.RetrieveKey(ctx, "missing", "wrong password")
+		assert.ErrorIs(t, err, store.ErrInvalidPassword)
+		assert.Equal(t, 2, backend.calls)
+	})
+
+	t.Run("DoesNotRetryWrites", func(t *testing.T) {
+		backend := newFlakyStore(t, errThrottled)
+		s := Chain(backend, "memory", Options{Retry: testRetryPolicy}, logger)
+
+		keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+		require.NoError(t, err)
+		_, err = s.StoreKey(ctx, keyPair)
+		assert.ErrorIs(t, err, errThrottled)
+		assert.Equal(t, 1, backend.calls)
+	})
+
+	t.Run("Delays", func(t *testing.T) {
+		policy := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 40 * time.Millisecond}
+		for attempt, maxDelay := range map[int]time.Duration{
+			1: 10 * time.Millisecond,
+			2: 20 * time.Millisecond,
+			3: 40 * time.Millisecond,
+			8: 40 * time.Millisecond,
+		} {
+			for i := 0; i < 20; i++ {
+				delay := policy.delay(attempt)
+				assert.GreaterOrEqual(t, delay, time.Duration(0))
+				assert.LessOrEqual(t, delay, maxDelay)
+			}
+		}
+	})
+}
+
+func TestTimeout(t *testing.T) {
+	backend := newFlakyStore(t)
+	backend.block = true
+	metrics := newRecorder()
+	s := Chain(backend, "memory", Options{
+		Timeout: 10 * time.Millisecond,
+		Retry:   testRetryPolicy,
+		Metrics: metrics,
+	}, testutils.GetTestLogger())
+
+	_, err := s.RetrieveKey(context.Background(), "pubkey", testPassword)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 3, backend.calls, "each attempt has its own timeout")
+	assert.Equal(t, 1, metrics.results[OpRetrieveKey+"/"+ResultTimeout])
+}
+
+func TestBreaker(t *testing.T) {
+	ctx := context.Background()
+	backend := newFlakyStore(t)
+	pubKey := storeKey(t, backend)
+
+	metrics := newRecorder()
+	breaker := NewBreaker(
+		BreakerPolicy{FailureThreshold: 2, OpenTimeout: time.Minute},
+		"memory",
+		metrics,
+		testutils.GetTestLogger(),
+	)
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	s := Decorate(backend, breaker.Middleware)
+
+	// A missing key isn't a failure of the backend
+	_, err := s.RetrieveKey(ctx, "missing", testPassword)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	backend.calls = 0
+	backend.failures = []error{errThrottled, errThrottled, errThrottled}
+	for i := 0; i < 2; i++ {
+		_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+		assert.ErrorIs(t, err, errThrottled)
+	}
+	assert.True(t, metrics.open)
+
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.ErrorIs(t, err, store.ErrUnavailable)
+	assert.Equal(t, 2, backend.calls, "open circuit must not call the backend")
+
+	// The failed trial opens the circuit again
+	now = now.Add(time.Minute)
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.ErrorIs(t, err, errThrottled)
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.ErrorIs(t, err, store.ErrUnavailable)
+
+	// The successful trial closes it
+	now = now.Add(time.Minute)
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.NoError(t, err)
+	assert.False(t, metrics.open)
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.NoError(t, err)
+}
+
+func TestPermanentErrors(t *testing.T) {
+	ctx := context.Background()
+	permanentErrors := []error{
+		crypto.ErrInvalidPrivateKey,
+		store.ErrVersionNotFound,
+		fmt.Errorf("failed to decode key: %w", hex.ErrLength),
+		errors.New("public key mismatch"),
+		status.Error(codes.InvalidArgument, "invalid key"),
+	}
+
+	backend := &flakyVersioner{flakyStore: newFlakyStore(t)}
+	pubKey := storeKey(t, backend)
+	metrics := newRecorder()
+	s := Chain(backend, "memory", Options{
+		Retry:   testRetryPolicy,
+		Breaker: BreakerPolicy{FailureThreshold: 2, OpenTimeout: time.Minute},
+		Metrics: metrics,
+	}, testutils.GetTestLogger())
+
+	backend.calls = 0
+	for _, permanentErr := range permanentErrors {
+		backend.failures = []error{permanentErr}
+		_, err := s.RetrieveKey(ctx, pubKey, testPassword)
+		assert.ErrorIs(t, err, permanentErr)
+	}
+	assert.Equal(t, len(permanentErrors), backend.calls, "permanent errors must not be retried")
+	assert.Zero(t, metrics.retries)
+	assert.False(t, metrics.open, "permanent errors must not open the circuit")
+
+	versioner, ok := store.As[store.Versioner](s)
+	require.True(t, ok)
+	for i := 0; i < 3; i++ {
+		_, err := versioner.RetrieveKeyVersion(ctx, pubKey, "2", testPassword)
+		assert.ErrorIs(t, err, store.ErrVersionNotFound)
+	}
+	assert.False(t, metrics.open)
+
+	_, err := s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.NoError(t, err)
+}
+
+// classifyingStore is a flaky store telling which of its errors are transient
+type classifyingStore struct {
+	*flakyStore
+	transient error
+}
+
+func (s *classifyingStore) Transient(err error) bool {
+	return errors.Is(err, s.transient)
+}
+
+func TestChainClassifiesTransientErrors(t *testing.T) {
+	ctx := context.Background()
+	errBusy := errors.New("backend busy")
+	backend := &classifyingStore{flakyStore: newFlakyStore(t), transient: errBusy}
+	pubKey := storeKey(t, backend)
+	metrics := newRecorder()
+	s := Chain(backend, "memory", Options{
+		Retry:   testRetryPolicy,
+		Breaker: BreakerPolicy{FailureThreshold: 3, OpenTimeout: time.Minute},
+		Metrics: metrics,
+	}, testutils.GetTestLogger())
+
+	backend.calls = 0
+	backend.failures = []error{fmt.Errorf("get key: %w", errBusy)}
+	_, err := s.RetrieveKey(ctx, pubKey, testPassword)
+	require.NoError(t, err)
+	assert.Equal(t, 2, backend.calls)
+	assert.Equal(t, 1, metrics.retries)
+
+	// The errors the store doesn't classify are kept as they are
+	errOther := errors.New("other error")
+	backend.failures = []error{errOther}
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.Same(t, errOther, err)
+	assert.Equal(t, 1, metrics.retries)
+}
+
+// unavailableSecretManager is a Google Secret Manager failing every read with
+// an Unavailable gRPC status
+type unavailableSecretManager struct {
+	googlesm.Client
+	calls int
+}
+
+func (c *unavailableSecretManager) AccessSecretVersion(
+	context.Context,
+	*secretmanagerpb.AccessSecretVersionRequest,
+	...gax.CallOption,
+) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	c.calls++
+	return nil, status.Error(codes.Unavailable, "secret manager unavailable")
+}
+
+func TestChainRetriesGoogleSecretManagerOutage(t *testing.T) {
+	ctx := context.Background()
+	logger := testutils.GetTestLogger()
+	client := &unavailableSecretManager{}
+	metrics := newRecorder()
+	s := Chain(googlesm.NewStore(client, "project", logger), "google-secrets-manager", Options{
+		Retry:   testRetryPolicy,
+		Breaker: BreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Minute},
+		Metrics: metrics,
+	}, logger)
+
+	_, err := s.RetrieveKey(ctx, strings.Repeat("ab", 32), testPassword)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, testRetryPolicy.MaxAttempts, client.calls)
+	assert.Equal(t, testRetryPolicy.MaxAttempts-1, metrics.retries)
+	assert.True(t, metrics.open, "the outage must open the circuit")
+
+	_, err = s.RetrieveKey(ctx, strings.Repeat("ab", 32), testPassword)
+	assert.ErrorIs(t, err, store.ErrUnavailable)
+	assert.Equal(t, testRetryPolicy.MaxAttempts, client.calls)
+}
+
+func TestIsTransient(t *testing.T) {
+	errBackend := errors.New("backend error")
+	for _, tt := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"Timeout", fmt.Errorf("get secret: %w", context.DeadlineExceeded), true},
+		{"Network", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{"GRPCUnavailable", status.Error(codes.Unavailable, "unavailable"), true},
+		{"WrappedGRPCUnavailable", fmt.Errorf("get: %w", status.Error(codes.Unavailable, "")), true},
+		{"GRPCNotFound", status.Error(codes.NotFound, "not found"), false},
+		{"ClassifiedByStore", fmt.Errorf("get: %w", &transientError{err: errBackend}), true},
+		{"BackendError", errBackend, false},
+		{"InvalidKey", crypto.ErrInvalidPrivateKey, false},
+		{"Sealed", seal.ErrSealed, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isTransient(tt.err))
+		})
+	}
+}
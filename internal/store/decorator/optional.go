@@ -0,0 +1,159 @@
+package decorator
+
+import (
+	"context"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+var (
+	_ store.Versioner    = (*versionedStore)(nil)
+	_ store.AtomicStorer = (*decoratedAtomicStore)(nil)
+	_ store.Versioner    = (*versionedAtomicStore)(nil)
+	_ store.AtomicStorer = (*versionedAtomicStore)(nil)
+)
+
+// decorate returns d, implementing the optional interfaces of the decorated
+// store, so that store.As finds them on the decorator and their operations
+// run through the middleware too
+func decorate(d *decorated) store.Store {
+	versioner, isVersioner := store.As[store.Versioner](d.next)
+	atomic, isAtomic := store.As[store.AtomicStorer](d.next)
+
+	switch {
+	case isVersioner && isAtomic:
+		return &versionedAtomicStore{
+			versionedStore: &versionedStore{decorated: d, versioner: versioner},
+			atomicStorer:   &atomicStorer{atomic: atomic, run: d.run},
+		}
+	case isVersioner:
+		return &versionedStore{decorated: d, versioner: versioner}
+	case isAtomic:
+		return &decoratedAtomicStore{
+			decorated:    d,
+			atomicStorer: &atomicStorer{atomic: atomic, run: d.run},
+		}
+	default:
+		return d
+	}
+}
+
+type versionedStore struct {
+	*decorated
+	versioner store.Versioner
+}
+
+type decoratedAtomicStore struct {
+	*decorated
+	*atomicStorer
+}
+
+type versionedAtomicStore struct {
+	*versionedStore
+	*atomicStorer
+}
+
+func (v *versionedStore) CurrentVersion(ctx context.Context, pubKey string) (string, error) {
+	return v.runVersionID(ctx, OpCurrentVersion, func(ctx context.Context) (string, error) {
+		return v.versioner.CurrentVersion(ctx, pubKey)
+	})
+}
+
+func (v *versionedStore) ListVersions(
+	ctx context.Context,
+	pubKey string,
+) ([]store.KeyVersion, error) {
+	var versions []store.KeyVersion
+	err := v.run(ctx, OpListVersions, func(ctx context.Context) error {
+		var err error
+		versions, err = v.versioner.ListVersions(ctx, pubKey)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (v *versionedStore) RetrieveKeyVersion(
+	ctx context.Context,
+	pubKey string,
+	versionID string,
+	password string,
+) (*crypto.KeyPair, error) {
+	var keyPair *crypto.KeyPair
+	err := v.run(ctx, OpRetrieveKeyVersion, func(ctx context.Context) error {
+		var err error
+		keyPair, err = v.versioner.RetrieveKeyVersion(ctx, pubKey, versionID, password)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keyPair, nil
+}
+
+func (v *versionedStore) RewrapKey(
+	ctx context.Context,
+	pubKey string,
+	versionID string,
+) (string, error) {
+	return v.runVersionID(ctx, OpRewrapKey, func(ctx context.Context) (string, error) {
+		return v.versioner.RewrapKey(ctx, pubKey, versionID)
+	})
+}
+
+func (v *versionedStore) RollbackKey(
+	ctx context.Context,
+	pubKey string,
+	versionID string,
+) (string, error) {
+	return v.runVersionID(ctx, OpRollbackKey, func(ctx context.Context) (string, error) {
+		return v.versioner.RollbackKey(ctx, pubKey, versionID)
+	})
+}
+
+// runVersionID runs an operation returning the ID of a version of a key
+func (v *versionedStore) runVersionID(
+	ctx context.Context,
+	op string,
+	call func(ctx context.Context) (string, error),
+) (string, error) {
+	var versionID string
+	err := v.run(ctx, op, func(ctx context.Context) error {
+		var err error
+		versionID, err = call(ctx)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return versionID, nil
+}
+
+// atomicStorer decorates the StoreKeyAtomically of a store, it is embedded
+// next to the decorated store
+type atomicStorer struct {
+	atomic store.AtomicStorer
+	run    Middleware
+}
+
+func (a *atomicStorer) StoreKeyAtomically(
+	ctx context.Context,
+	keyPair *keystore.KeyPair,
+	record func(ctx context.Context, pubKey string) error,
+) (string, error) {
+	var pubKey string
+	err := a.run(ctx, OpStoreKey, func(ctx context.Context) error {
+		var err error
+		pubKey, err = a.atomic.StoreKeyAtomically(ctx, keyPair, record)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return pubKey, nil
+}
@@ -0,0 +1,90 @@
+package decorator
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/metrics"
+)
+
+type RetryPolicy struct {
+	// MaxAttempts is the number of calls of an operation, including the
+	// first one. Values below 2 disable the retries.
+	MaxAttempts int
+
+	// BaseDelay is the maximum delay before the first retry, doubled for each
+	// following retry up to MaxDelay. The delays are randomized between zero
+	// and their maximum, so that the callers throttled together don't retry
+	// together.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Retry retries the reads failing because of the backend. Writes aren't
+// retried: a write which failed after reaching the backend would be reported
+// as a conflict by its retry.
+func Retry(
+	policy RetryPolicy,
+	backend string,
+	recorder metrics.StoreRecorder,
+	logger *slog.Logger,
+) Middleware {
+	return func(ctx context.Context, op string, call func(ctx context.Context) error) error {
+		if !isRead(op) {
+			return call(ctx)
+		}
+
+		var err error
+		for attempt := 1; ; attempt++ {
+			err = call(ctx)
+			if attempt >= policy.MaxAttempts || !isBackendFailure(ctx, err) {
+				return err
+			}
+
+			delay := policy.delay(attempt)
+			logger.Warn(
+				"Store operation failed, retrying",
+				"operation", op,
+				"attempt", attempt,
+				"delay", delay,
+				"error", err,
+			)
+			recorder.RecordRetry(backend, op)
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			}
+		}
+	}
+}
+
+// delay returns the randomized delay before the retry following the attempt
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	maxDelay := p.BaseDelay
+	for i := 1; i < attempt && maxDelay < p.MaxDelay; i++ {
+		maxDelay *= 2
+	}
+	if p.MaxDelay > 0 && maxDelay > p.MaxDelay {
+		maxDelay = p.MaxDelay
+	}
+	if maxDelay <= 0 {
+		return 0
+	}
+	return rand.N(maxDelay + 1)
+}
+
+// isRead reports whether the operation only reads the store
+func isRead(op string) bool {
+	switch op {
+	case OpRetrieveKey, OpListKeys, OpCurrentVersion, OpListVersions, OpRetrieveKeyVersion:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,68 @@
+package decorator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+// transientError marks an error which the store reported as transient
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// classify marks the errors which the store reports as transient, so that the
+// retries and the circuit breaker recognize the failures of its backend
+func classify(classifier store.TransientClassifier) Middleware {
+	return func(ctx context.Context, op string, call func(ctx context.Context) error) error {
+		err := call(ctx)
+		if err != nil && classifier.Transient(err) {
+			return &transientError{err: err}
+		}
+		return err
+	}
+}
+
+// isTransient reports whether err is a failure of the backend which may not
+// happen again: an error the store reported as transient, or a failure of the
+// connection to the backend, such as a network error, a timeout, or an
+// unavailable or throttled gRPC service. The other errors, such as an invalid
+// key, fail again when retried.
+func isTransient(err error) bool {
+	var marked *transientError
+	if errors.As(err, &marked) {
+		return true
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// gRPC backends, such as Google Secret Manager and the store plugins
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+			return true
+		}
+	}
+
+	return false
+}
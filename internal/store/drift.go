@@ -0,0 +1,20 @@
+package store
+
+import "context"
+
+// Drift describes a key which is missing from some of the backends of a store
+// keeping several copies of the keys
+type Drift struct {
+	PubKey string
+
+	// MissingFrom lists the names of the backends without the key
+	MissingFrom []string
+}
+
+// DriftReporter is implemented by the stores which replicate the keys to
+// several backends
+type DriftReporter interface {
+	// Drift compares the keys of every backend and returns the keys missing
+	// from some of them
+	Drift(ctx context.Context) ([]Drift, error)
+}
@@ -0,0 +1,13 @@
+package store
+
+import "errors"
+
+var (
+	ErrKeyNotFound      = errors.New("key not found in store")
+	ErrKeyAlreadyExists = errors.New("key already exists in store")
+	ErrInvalidPassword  = errors.New("invalid password for key")
+
+	// ErrUnavailable is returned without calling the backend while it is
+	// considered down, such as when a circuit breaker is open
+	ErrUnavailable = errors.New("store is unavailable")
+)
@@ -2,27 +2,51 @@ package filesystem
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
 
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/Layr-Labs/cerberus/internal/crypto"
 	"github.com/Layr-Labs/cerberus/internal/store"
 
 	"github.com/Layr-Labs/bn254-keystore-go/curve"
 	"github.com/Layr-Labs/bn254-keystore-go/keystore"
-
-	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 )
 
-const keyFileExtension = ".json"
+const (
+	keyFileExtension = ".json"
+
+	// lockFileName is the file locked while the keys are written or deleted,
+	// so that several processes can share the keystore directory
+	lockFileName = ".cerberus.lock"
+
+	// pubKeyLength is the length of a hex encoded compressed G1 public key
+	pubKeyLength = 64
+
+	dirPerm  os.FileMode = 0700
+	filePerm os.FileMode = 0600
+)
 
-var _ store.Store = (*FileStore)(nil)
+var (
+	_ store.Store           = (*FileStore)(nil)
+	_ store.PasswordChecker = (*FileStore)(nil)
+)
 
 type FileStore struct {
 	keystoreDir string
 
+	// written are the keys stored by this store and not yet seen by the
+	// Watcher, which only reports the keys added by others. It is nil while
+	// the store isn't watched.
+	mu      sync.Mutex
+	written map[string]struct{}
+
 	logger *slog.Logger
 }
 
@@ -31,10 +55,17 @@ func NewStore(
 	logger *slog.Logger,
 ) *FileStore {
 	logger = logger.With("component", "filesystem-store")
-	if err := os.MkdirAll(keystoreDir, 0755); err != nil {
+	if err := os.MkdirAll(keystoreDir, dirPerm); err != nil {
 		logger.Error(fmt.Sprintf("Error creating keystore directory: %v", err))
 		os.Exit(1)
 	}
+	if info, err := os.Stat(keystoreDir); err == nil && info.Mode().Perm()&0077 != 0 {
+		logger.Warn(
+			"Keystore directory is accessible by other users, restrict it to mode 0700",
+			"keystore", keystoreDir,
+			"mode", info.Mode().Perm().String(),
+		)
+	}
 	logger.Info("Created keystore directory successfully", "keystore", keystoreDir)
 	return &FileStore{
 		keystoreDir: keystoreDir,
@@ -42,30 +73,65 @@ func NewStore(
 	}
 }
 
+// ChecksPassword returns true, the keys are encrypted keystores
+func (s *FileStore) ChecksPassword() bool {
+	return true
+}
+
 func (s *FileStore) RetrieveKey(
 	ctx context.Context,
 	pubKey string,
 	password string,
 ) (*crypto.KeyPair, error) {
-	path := s.keystoreDir + "/" + pubKey + ".json"
+	path, err := s.keyPath(pubKey)
+	if err != nil {
+		return nil, err
+	}
 	return readPrivateKeyFromFile(path, password)
 }
 
+// StoreKey writes the key to a temporary file which is synced and renamed to
+// the key file, so that a crash never leaves a partial key file behind
 func (s *FileStore) StoreKey(
 	ctx context.Context,
 	keyPair *keystore.KeyPair,
 ) (string, error) {
-	keyStore, err := keyPair.Encrypt(keystore.KDFScrypt, curve.BN254)
+	pubKey, err := keystore.BlsSkToG1Pk(keyPair.PrivateKey, string(curve.BN254))
+	if err != nil {
+		return "", err
+	}
+	path, err := s.keyPath(pubKey)
 	if err != nil {
 		return "", err
 	}
 
-	err = keyStore.SaveWithPubKeyHex(s.keystoreDir, "")
+	keyStore, err := store.EncryptKeystore(keyPair)
 	if err != nil {
 		return "", err
 	}
 
-	return keyStore.PubKey, nil
+	unlock, err := lockDir(filepath.Join(s.keystoreDir, lockFileName))
+	if err != nil {
+		return "", fmt.Errorf("failed to lock keystore directory: %w", err)
+	}
+	defer unlock()
+
+	if _, err := os.Stat(path); err == nil {
+		return "", store.ErrKeyAlreadyExists
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	if err := s.writeFile(path, keyStore); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	if s.written != nil {
+		s.written[pubKey] = struct{}{}
+	}
+	s.mu.Unlock()
+	return pubKey, nil
 }
 
 func (s *FileStore) ListKeys(ctx context.Context) ([]string, error) {
@@ -74,28 +140,140 @@ func (s *FileStore) ListKeys(ctx context.Context) ([]string, error) {
 		return nil, err
 	}
 
-	s.logger.Debug(fmt.Sprintf("Found %d key files", len(files)))
-	pubKeys := make([]string, len(files))
-	for i, file := range files {
-		pubKeys[i] = file.Name()[0 : len(file.Name())-len(keyFileExtension)]
+	// Other files and directories may be kept in the keystore directory, only
+	// the regular files named after a public key are listed
+	pubKeys := make([]string, 0, len(files))
+	for _, file := range files {
+		if !file.Type().IsRegular() {
+			continue
+		}
+		pubKey, ok := strings.CutSuffix(file.Name(), keyFileExtension)
+		if !ok || !validPubKey(pubKey) {
+			continue
+		}
+		pubKeys = append(pubKeys, pubKey)
 	}
 
+	s.logger.Debug(fmt.Sprintf("Found %d key files", len(pubKeys)))
 	return pubKeys, nil
 }
 
+func (s *FileStore) DeleteKey(ctx context.Context, pubKey string) error {
+	path, err := s.keyPath(pubKey)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockDir(filepath.Join(s.keystoreDir, lockFileName))
+	if err != nil {
+		return fmt.Errorf("failed to lock keystore directory: %w", err)
+	}
+	defer unlock()
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store.ErrKeyNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return syncDir(s.keystoreDir)
+}
+
+// consumeWritten returns true if the key was stored by this store since the
+// last call for the key
+func (s *FileStore) consumeWritten(pubKey string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.written[pubKey]
+	delete(s.written, pubKey)
+	return ok
+}
+
+// keyPath returns the path of the key file. The public key must be a hex
+// encoded G1 public key, so that a request can't name a file outside the
+// keystore directory.
+func (s *FileStore) keyPath(pubKey string) (string, error) {
+	if !validPubKey(pubKey) {
+		return "", fmt.Errorf("%w: invalid public key %q", store.ErrKeyNotFound, pubKey)
+	}
+	return filepath.Join(s.keystoreDir, pubKey+keyFileExtension), nil
+}
+
+// writeFile atomically creates the file with the data
+func (s *FileStore) writeFile(path string, data []byte) error {
+	// The temporary file has no key file extension, a leftover of a crash is
+	// never listed as a key
+	tmp, err := os.CreateTemp(s.keystoreDir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Removing the renamed temporary file fails harmlessly
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if err := tmp.Chmod(filePerm); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(s.keystoreDir)
+}
+
+// syncDir persists the directory entries, such as a renamed or removed file
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// validPubKey returns true if the public key is a hex encoded compressed G1
+// point, the only names the store gives to key files
+func validPubKey(pubKey string) bool {
+	if len(pubKey) != pubKeyLength {
+		return false
+	}
+	_, err := hex.DecodeString(pubKey)
+	return err == nil
+}
+
 func readPrivateKeyFromFile(path string, password string) (*crypto.KeyPair, error) {
 	ks := new(keystore.Keystore)
 	err := ks.FromFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, store.ErrKeyNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	skBytes, err := ks.Decrypt(password)
+	skBytes, err := store.DecryptPrivateKey(ks, password)
 	if err != nil {
 		return nil, err
 	}
 
-	privKey := new(fr.Element).SetBytes(skBytes)
+	privKey, err := crypto.PrivateKeyFromBytes(skBytes)
+	if err != nil {
+		return nil, err
+	}
 	keyPair := crypto.NewKeyPair(privKey)
 	return keyPair, nil
 }
@@ -4,14 +4,19 @@ import (
 	"context"
 	"encoding/hex"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/Layr-Labs/bn254-keystore-go/keystore"
 	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
 
 	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/storetest"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -61,6 +66,129 @@ func TestFileStoreKeyOperators(t *testing.T) {
 	}
 }
 
+func TestFileStoreDuplicateAndDelete(t *testing.T) {
+	ctx := context.Background()
+	logger := testutils.GetTestLogger()
+	fs := NewStore(t.TempDir(), logger)
+	testPassword := "p@$$w0rd"
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	assert.NoError(t, err, "Failed to generate key pair")
+
+	pubKeyHex, err := fs.StoreKey(ctx, keyPair)
+	assert.NoError(t, err, "Failed to store key")
+
+	_, err = fs.StoreKey(ctx, keyPair)
+	assert.ErrorIs(t, err, store.ErrKeyAlreadyExists)
+
+	err = fs.DeleteKey(ctx, pubKeyHex)
+	assert.NoError(t, err, "Failed to delete key")
+
+	_, err = fs.RetrieveKey(ctx, pubKeyHex, testPassword)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	err = fs.DeleteKey(ctx, pubKeyHex)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+}
+
+func TestFileStoreConformance(t *testing.T) {
+	logger := testutils.GetTestLogger()
+	newStore := func(t *testing.T) store.Store {
+		return NewStore(t.TempDir(), logger)
+	}
+
+	storetest.Run(t, newStore, storetest.Options{
+		AddForeignEntry: func(t *testing.T, s store.Store) {
+			dir := s.(*FileStore).keystoreDir
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), nil, 0644))
+			require.NoError(t, os.Mkdir(filepath.Join(dir, "backup.json"), 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), nil, 0644))
+			// Leftover of a write interrupted by a crash
+			tmp := filepath.Join(dir, "."+strings.Repeat("ab", 32)+".json.123.tmp")
+			require.NoError(t, os.WriteFile(tmp, nil, 0600))
+		},
+	})
+}
+
+func TestFileStoreRejectsInvalidPublicKeys(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	fs := NewStore(filepath.Join(dir, "keystore"), testutils.GetTestLogger())
+
+	// A key file outside the keystore directory must not be reachable
+	outside := filepath.Join(dir, "outside.json")
+	require.NoError(t, os.WriteFile(outside, []byte("{}"), 0600))
+
+	for _, pubKey := range []string{
+		"../outside",
+		"../" + strings.Repeat("a", 61),
+		strings.Repeat("g", 64),
+		strings.Repeat("a", 63),
+		"",
+	} {
+		_, err := fs.RetrieveKey(ctx, pubKey, "")
+		assert.ErrorIs(t, err, store.ErrKeyNotFound, pubKey)
+		assert.ErrorIs(t, fs.DeleteKey(ctx, pubKey), store.ErrKeyNotFound, pubKey)
+	}
+	assert.FileExists(t, outside)
+}
+
+func TestFileStorePermissions(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "keystore")
+	fs := NewStore(dir, testutils.GetTestLogger())
+
+	keyPair, err := keystore.NewKeyPair("p@$$w0rd", mnemonic.English)
+	require.NoError(t, err)
+	pubKeyHex, err := fs.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	info, err = os.Stat(filepath.Join(dir, pubKeyHex+keyFileExtension))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// No temporary file is left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.False(t, strings.HasSuffix(entry.Name(), ".tmp"), entry.Name())
+	}
+}
+
+func TestFileStoreConcurrentWriters(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	logger := testutils.GetTestLogger()
+
+	keyPair, err := keystore.NewKeyPair("p@$$w0rd", mnemonic.English)
+	require.NoError(t, err)
+
+	// Two stores sharing the directory, as two processes would
+	stores := []*FileStore{NewStore(dir, logger), NewStore(dir, logger)}
+	errs := make(chan error, len(stores))
+	for _, fs := range stores {
+		go func(fs *FileStore) {
+			_, err := fs.StoreKey(ctx, keyPair)
+			errs <- err
+		}(fs)
+	}
+
+	stored := 0
+	for range stores {
+		err := <-errs
+		if err == nil {
+			stored++
+		} else {
+			assert.ErrorIs(t, err, store.ErrKeyAlreadyExists)
+		}
+	}
+	assert.Equal(t, 1, stored)
+}
+
 func cleanup() {
 	err := os.RemoveAll(tmpDir)
 	if err != nil {
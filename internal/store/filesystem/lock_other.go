@@ -0,0 +1,14 @@
+//go:build !unix
+
+package filesystem
+
+import "sync"
+
+// dirLock serializes the writes of this process only, file locks aren't
+// supported on this platform
+var dirLock sync.Mutex
+
+func lockDir(lockPath string) (func(), error) {
+	dirLock.Lock()
+	return dirLock.Unlock, nil
+}
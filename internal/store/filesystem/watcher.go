@@ -0,0 +1,147 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// defaultSettleDelay is how long the directory must be quiet before the
+// changes are reported, so that a file being copied is reported once complete
+const defaultSettleDelay = 500 * time.Millisecond
+
+type EventType string
+
+const (
+	KeyCreated EventType = "created"
+	KeyChanged EventType = "changed"
+	KeyRemoved EventType = "removed"
+)
+
+// Event describes a key file created, changed or removed in the keystore
+// directory
+type Event struct {
+	Type   EventType
+	PubKey string
+}
+
+// Watcher reports the key files created, changed and removed in the keystore
+// directory by operators or other processes. The keys created by the store
+// itself are not reported.
+type Watcher struct {
+	store       *FileStore
+	settleDelay time.Duration
+
+	logger *slog.Logger
+}
+
+func NewWatcher(s *FileStore, logger *slog.Logger) *Watcher {
+	s.mu.Lock()
+	if s.written == nil {
+		s.written = make(map[string]struct{})
+	}
+	s.mu.Unlock()
+
+	return &Watcher{
+		store:       s,
+		settleDelay: defaultSettleDelay,
+		logger:      logger.With("component", "filesystem-watcher"),
+	}
+}
+
+// Run watches the keystore directory and calls handle for every change until
+// the context is done
+func (w *Watcher) Run(ctx context.Context, handle func(Event)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(w.store.keystoreDir); err != nil {
+		return fmt.Errorf("failed to watch keystore directory: %w", err)
+	}
+
+	// The keys present when the watch starts are known, the later changes
+	// are compared with them
+	pubKeys, err := w.store.ListKeys(ctx)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]struct{}, len(pubKeys))
+	for _, pubKey := range pubKeys {
+		known[pubKey] = struct{}{}
+	}
+
+	pending := make(map[string]struct{})
+	settle := time.NewTimer(w.settleDelay)
+	settle.Stop()
+	defer settle.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return errors.New("keystore directory watcher closed")
+			}
+			if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			pubKey, ok := strings.CutSuffix(filepath.Base(event.Name), keyFileExtension)
+			if !ok || !validPubKey(pubKey) {
+				continue
+			}
+			pending[pubKey] = struct{}{}
+			settle.Reset(w.settleDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return errors.New("keystore directory watcher closed")
+			}
+			w.logger.Error("Keystore directory watcher failed", "error", err)
+		case <-settle.C:
+			for pubKey := range pending {
+				if event, ok := w.resolve(pubKey, known); ok {
+					handle(event)
+				}
+			}
+			clear(pending)
+		}
+	}
+}
+
+// resolve compares the key file with its known state to tell whether it was
+// created, changed or removed, and updates the known state
+func (w *Watcher) resolve(pubKey string, known map[string]struct{}) (Event, bool) {
+	path, err := w.store.keyPath(pubKey)
+	if err != nil {
+		return Event{}, false
+	}
+	_, wasKnown := known[pubKey]
+	info, err := os.Stat(path)
+	exists := err == nil && info.Mode().IsRegular()
+
+	switch {
+	case exists && wasKnown:
+		return Event{Type: KeyChanged, PubKey: pubKey}, true
+	case exists:
+		known[pubKey] = struct{}{}
+		if w.store.consumeWritten(pubKey) {
+			return Event{}, false
+		}
+		return Event{Type: KeyCreated, PubKey: pubKey}, true
+	case wasKnown:
+		delete(known, pubKey)
+		return Event{Type: KeyRemoved, PubKey: pubKey}, true
+	default:
+		return Event{}, false
+	}
+}
@@ -0,0 +1,80 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := testutils.GetTestLogger()
+
+	fs := NewStore(t.TempDir(), logger)
+	watcher := NewWatcher(fs, logger)
+	watcher.settleDelay = 50 * time.Millisecond
+
+	events := make(chan Event, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- watcher.Run(ctx, func(event Event) {
+			events <- event
+		})
+	}()
+
+	next := func() Event {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no watcher event")
+			return Event{}
+		}
+	}
+
+	// Give the watcher time to start watching the directory
+	time.Sleep(100 * time.Millisecond)
+
+	// Keys stored by the store itself are not reported
+	keyPair, err := keystore.NewKeyPair("p@$$w0rd", mnemonic.English)
+	require.NoError(t, err)
+	_, err = fs.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	// A keystore copied into the directory by an operator is
+	other := NewStore(t.TempDir(), logger)
+	keyPair, err = keystore.NewKeyPair("p@$$w0rd", mnemonic.English)
+	require.NoError(t, err)
+	pubKeyHex, err := other.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(other.keystoreDir, pubKeyHex+keyFileExtension))
+	require.NoError(t, err)
+
+	path := filepath.Join(fs.keystoreDir, pubKeyHex+keyFileExtension)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	assert.Equal(t, Event{Type: KeyCreated, PubKey: pubKeyHex}, next())
+
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	assert.Equal(t, Event{Type: KeyChanged, PubKey: pubKeyHex}, next())
+
+	require.NoError(t, os.Remove(path))
+	assert.Equal(t, Event{Type: KeyRemoved, PubKey: pubKeyHex}, next())
+
+	// Other files are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(fs.keystoreDir, "notes.txt"), nil, 0600))
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Empty(t, events)
+}
@@ -3,34 +3,129 @@ package googlesm
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
-	"regexp"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/googleapis/gax-go/v2"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/Layr-Labs/bn254-keystore-go/curve"
 	"github.com/Layr-Labs/bn254-keystore-go/keystore"
 
+	"github.com/Layr-Labs/cerberus/internal/common"
 	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/seal"
 	"github.com/Layr-Labs/cerberus/internal/store"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
 )
 
-var _ store.Store = (*Keystore)(nil)
+var (
+	_ store.Store            = (*Keystore)(nil)
+	_ store.KeystoreMigrator = (*Keystore)(nil)
+	_ store.Versioner        = (*Keystore)(nil)
+	_ store.PasswordChecker  = (*Keystore)(nil)
+)
 
 const (
-	storagePrefix = "cerberus"
+	// DefaultPrefix is prepended to the public keys to name the secrets, it
+	// is also the value of the ProjectKey label of the secrets
+	DefaultPrefix = "cerberus"
 	ProjectKey    = "project"
 )
 
+// Client is the part of the Secret Manager API used by the store, so that
+// another implementation can be injected in tests
+type Client interface {
+	CreateSecret(
+		ctx context.Context,
+		req *secretmanagerpb.CreateSecretRequest,
+		opts ...gax.CallOption,
+	) (*secretmanagerpb.Secret, error)
+
+	GetSecretVersion(
+		ctx context.Context,
+		req *secretmanagerpb.GetSecretVersionRequest,
+		opts ...gax.CallOption,
+	) (*secretmanagerpb.SecretVersion, error)
+
+	AddSecretVersion(
+		ctx context.Context,
+		req *secretmanagerpb.AddSecretVersionRequest,
+		opts ...gax.CallOption,
+	) (*secretmanagerpb.SecretVersion, error)
+
+	AccessSecretVersion(
+		ctx context.Context,
+		req *secretmanagerpb.AccessSecretVersionRequest,
+		opts ...gax.CallOption,
+	) (*secretmanagerpb.AccessSecretVersionResponse, error)
+
+	DestroySecretVersion(
+		ctx context.Context,
+		req *secretmanagerpb.DestroySecretVersionRequest,
+		opts ...gax.CallOption,
+	) (*secretmanagerpb.SecretVersion, error)
+
+	DeleteSecret(
+		ctx context.Context,
+		req *secretmanagerpb.DeleteSecretRequest,
+		opts ...gax.CallOption,
+	) error
+
+	ListSecrets(
+		ctx context.Context,
+		req *secretmanagerpb.ListSecretsRequest,
+		opts ...gax.CallOption,
+	) *secretmanager.SecretIterator
+
+	ListSecretVersions(
+		ctx context.Context,
+		req *secretmanagerpb.ListSecretVersionsRequest,
+		opts ...gax.CallOption,
+	) *secretmanager.SecretVersionIterator
+}
+
+var _ Client = (*secretmanager.Client)(nil)
+
+// SecretOptions are applied to the secrets created by the store
+type SecretOptions struct {
+	// KMSKeyNames are the Cloud KMS keys encrypting the secrets (CMEK): a
+	// global key with automatic replication, or a key per replica location
+	KMSKeyNames []string
+
+	// ReplicaLocations are the locations of the user managed replicas of the
+	// secrets, they are automatically replicated if it is empty
+	ReplicaLocations []string
+
+	// Labels are added to the secrets besides the ProjectKey label
+	Labels map[string]string
+}
+
 type Keystore struct {
-	smClient  *secretmanager.Client
+	smClient  Client
 	projectID string
 
+	// prefix is prepended to the public keys to name the secrets, so that
+	// several deployments can share a project
+	prefix        string
+	secretOptions SecretOptions
+
+	// masterKey wraps the stored keys when set
+	masterKey *seal.Keeper
+
+	// keystoreEncryption stores the keys encrypted with their password
+	keystoreEncryption bool
+
 	logger *slog.Logger
 }
 
@@ -41,11 +136,54 @@ func NewKeystore(projectID string, logger *slog.Logger) (*Keystore, error) {
 		return nil, err
 	}
 
+	return NewStore(client, projectID, logger), nil
+}
+
+// NewStore returns a store keeping the keys in the Secret Manager of the
+// project through the given client
+func NewStore(client Client, projectID string, logger *slog.Logger) *Keystore {
 	return &Keystore{
 		smClient:  client,
 		projectID: projectID,
+		prefix:    DefaultPrefix,
 		logger:    logger.With("component", "google-secret-manager-store"),
-	}, nil
+	}
+}
+
+// WithPrefix names and labels the secrets with the prefix rather than
+// DefaultPrefix. Only the secrets with the prefix are listed as keys.
+func (k *Keystore) WithPrefix(prefix string) *Keystore {
+	if prefix != "" {
+		k.prefix = prefix
+	}
+	return k
+}
+
+// WithSecretOptions applies the options to the secrets created from now on
+func (k *Keystore) WithSecretOptions(opts SecretOptions) *Keystore {
+	k.secretOptions = opts
+	return k
+}
+
+// WithMasterKey envelope encrypts the keys stored from now on with data keys
+// wrapped by the master key. Keys stored in plaintext remain readable.
+func (k *Keystore) WithMasterKey(keeper *seal.Keeper) *Keystore {
+	k.masterKey = keeper
+	return k
+}
+
+// WithKeystoreEncryption stores the keys from now on as EIP-2335 keystores
+// encrypted with the password of the key, like the filesystem store does
+func (k *Keystore) WithKeystoreEncryption(enabled bool) *Keystore {
+	k.keystoreEncryption = enabled
+	return k
+}
+
+// ChecksPassword reports whether the keys are stored as keystores. The keys
+// stored before keystore encryption was enabled are returned whatever the
+// password until they are migrated.
+func (k *Keystore) ChecksPassword() bool {
+	return k.keystoreEncryption
 }
 
 func (k Keystore) RetrieveKey(
@@ -53,25 +191,12 @@ func (k Keystore) RetrieveKey(
 	pubKey string,
 	password string,
 ) (*crypto.KeyPair, error) {
-	// Build the request
-	secretID := storagePrefix + pubKey
-	accessRequest := &secretmanagerpb.AccessSecretVersionRequest{
-		Name: fmt.Sprintf("projects/%s/secrets/%s/versions/latest", k.projectID, secretID),
-	}
-
-	// Access the secret version
-	result, err := k.smClient.AccessSecretVersion(ctx, accessRequest)
-	if err != nil {
-		return nil, fmt.Errorf("failed to access secret version: %v", err)
-	}
-
-	privKeyHex := hex.EncodeToString(result.Payload.Data)
-	kp, err := crypto.NewKeyPairFromHexString(privKeyHex)
+	payload, _, err := k.accessSecret(ctx, pubKey, "")
 	if err != nil {
 		return nil, err
 	}
 
-	return kp, nil
+	return k.decodeKey(pubKey, payload, password)
 }
 
 func (k Keystore) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (string, error) {
@@ -80,46 +205,74 @@ func (k Keystore) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (stri
 		return "", err
 	}
 
-	storageKey := storagePrefix + pubKey
+	storageKey := k.prefix + pubKey
 
 	createSecretReq := &secretmanagerpb.CreateSecretRequest{
 		Parent:   fmt.Sprintf("projects/%s", k.projectID),
 		SecretId: storageKey,
 		Secret: &secretmanagerpb.Secret{
-			Labels: map[string]string{
-				ProjectKey: storagePrefix,
-			},
-			Replication: &secretmanagerpb.Replication{
-				Replication: &secretmanagerpb.Replication_Automatic_{
-					Automatic: &secretmanagerpb.Replication_Automatic{},
-				},
-			},
+			Labels:      k.labels(),
+			Replication: k.replication(),
 		},
 	}
 
-	secret, err := k.smClient.CreateSecret(ctx, createSecretReq)
+	secretName := k.secretName(storageKey)
+	created := true
+	_, err = k.smClient.CreateSecret(ctx, createSecretReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to create secret: %v", err)
+		if status.Code(err) != codes.AlreadyExists {
+			return "", fmt.Errorf("failed to create secret: %w", err)
+		}
+
+		// A previous attempt may have created the secret and failed before
+		// adding its version, in which case the secret is reused
+		_, err = k.smClient.GetSecretVersion(ctx, &secretmanagerpb.GetSecretVersionRequest{
+			Name: secretName + "/versions/latest",
+		})
+		if err == nil {
+			return "", store.ErrKeyAlreadyExists
+		}
+		if status.Code(err) != codes.NotFound {
+			return "", fmt.Errorf("failed to get secret version: %w", err)
+		}
+		created = false
+	}
+
+	payload, err := k.encodeKey(pubKey, keyPair, k.keystoreEncryption)
+	if err != nil {
+		return "", err
 	}
 
 	// Add a secret version
 	addSecretVersionReq := &secretmanagerpb.AddSecretVersionRequest{
-		Parent: secret.Name,
+		Parent: secretName,
 		Payload: &secretmanagerpb.SecretPayload{
-			Data: keyPair.PrivateKey,
+			Data: payload,
 		},
 	}
 
 	version, err := k.smClient.AddSecretVersion(ctx, addSecretVersionReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to add secret version: %v", err)
+		if created {
+			deleteErr := k.smClient.DeleteSecret(ctx, &secretmanagerpb.DeleteSecretRequest{
+				Name: secretName,
+			})
+			if deleteErr != nil {
+				k.logger.Error(
+					"Failed to delete secret without version",
+					"secret", secretName,
+					"error", deleteErr,
+				)
+			}
+		}
+		return "", fmt.Errorf("failed to add secret version: %w", err)
 	}
 	k.logger.Info("Stored key in secret manager with version", "version", version.Name)
 	return pubKey, nil
 }
 
 func (k Keystore) ListKeys(ctx context.Context) ([]string, error) {
-	filter := fmt.Sprintf("labels.%s=%s", ProjectKey, storagePrefix)
+	filter := fmt.Sprintf("labels.%s=%s", ProjectKey, k.prefix)
 	listRequest := &secretmanagerpb.ListSecretsRequest{
 		Parent: fmt.Sprintf("projects/%s", k.projectID),
 		Filter: filter,
@@ -133,26 +286,449 @@ func (k Keystore) ListKeys(ctx context.Context) ([]string, error) {
 			if errors.Is(err, iterator.Done) {
 				break
 			}
-			return nil, fmt.Errorf("failed to list secrets: %v", err)
+			return nil, fmt.Errorf("failed to list secrets: %w", err)
+		}
+		// Other applications may use the same label, only the secrets named
+		// after a key are listed
+		pubKey, ok := k.getPubKey(secret.GetName())
+		if !ok {
+			continue
 		}
-		keys = append(keys, getPubKey(secret.GetName()))
+		keys = append(keys, pubKey)
 	}
 	k.logger.Debug(fmt.Sprintf("Found %d key files", len(keys)))
 	return keys, nil
 }
 
+func (k Keystore) DeleteKey(ctx context.Context, pubKey string) error {
+	err := k.smClient.DeleteSecret(ctx, &secretmanagerpb.DeleteSecretRequest{
+		Name: k.secretName(k.prefix + pubKey),
+	})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return store.ErrKeyNotFound
+		}
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+	return nil
+}
+
+func (k Keystore) MigrateToKeystore(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (bool, error) {
+	payload, versionName, err := k.accessSecret(ctx, pubKey, "")
+	if err != nil {
+		return false, err
+	}
+
+	encrypted, err := k.isKeystore(pubKey, payload)
+	if err != nil || encrypted {
+		return false, err
+	}
+
+	kp, err := k.decodeKey(pubKey, payload, "")
+	if err != nil {
+		return false, err
+	}
+	payload, err = k.encodeKey(pubKey, &keystore.KeyPair{
+		PrivateKey: crypto.PrivateKeyBytes(kp.PrivKey),
+		Password:   password,
+	}, true)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = k.smClient.AddSecretVersion(ctx, &secretmanagerpb.AddSecretVersionRequest{
+		Parent: k.secretName(k.prefix + pubKey),
+		Payload: &secretmanagerpb.SecretPayload{
+			Data: payload,
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to add secret version: %w", err)
+	}
+
+	// Destroy the plaintext version so it can't be accessed anymore
+	_, err = k.smClient.DestroySecretVersion(ctx, &secretmanagerpb.DestroySecretVersionRequest{
+		Name: versionName,
+	})
+	if err != nil {
+		k.logger.Warn(
+			"Failed to destroy the plaintext version of the key",
+			"version", versionName,
+			"error", err,
+		)
+	}
+	return true, nil
+}
+
+func (k Keystore) CurrentVersion(ctx context.Context, pubKey string) (string, error) {
+	version, err := k.smClient.GetSecretVersion(ctx, &secretmanagerpb.GetSecretVersionRequest{
+		Name: k.versionName(pubKey, ""),
+	})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return "", store.ErrKeyNotFound
+		}
+		return "", fmt.Errorf("failed to get secret version: %w", err)
+	}
+	return path.Base(version.GetName()), nil
+}
+
+// ListVersions returns the enabled versions of the key, the disabled and
+// destroyed versions can't be accessed
+func (k Keystore) ListVersions(ctx context.Context, pubKey string) ([]store.KeyVersion, error) {
+	it := k.smClient.ListSecretVersions(ctx, &secretmanagerpb.ListSecretVersionsRequest{
+		Parent: k.secretName(k.prefix + pubKey),
+	})
+
+	var versions []store.KeyVersion
+	latest := 0
+	for {
+		version, err := it.Next()
+		if err != nil {
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+			if status.Code(err) == codes.NotFound {
+				return nil, store.ErrKeyNotFound
+			}
+			return nil, fmt.Errorf("failed to list secret versions: %w", err)
+		}
+
+		// The latest alias resolves to the most recent version, whatever its
+		// state
+		id := path.Base(version.GetName())
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected secret version %s", version.GetName())
+		}
+		latest = max(latest, n)
+
+		if version.GetState() != secretmanagerpb.SecretVersion_ENABLED {
+			continue
+		}
+		versions = append(versions, store.KeyVersion{
+			ID:        id,
+			CreatedAt: version.GetCreateTime().AsTime(),
+		})
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		a, _ := strconv.Atoi(versions[i].ID)
+		b, _ := strconv.Atoi(versions[j].ID)
+		return a < b
+	})
+	for i := range versions {
+		versions[i].Current = versions[i].ID == strconv.Itoa(latest)
+	}
+	return versions, nil
+}
+
+func (k Keystore) RetrieveKeyVersion(
+	ctx context.Context,
+	pubKey string,
+	versionID string,
+	password string,
+) (*crypto.KeyPair, error) {
+	payload, _, err := k.accessSecret(ctx, pubKey, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return k.decodeKey(pubKey, payload, password)
+}
+
+// RewrapKey adds the payload of the version as a new version, envelope
+// encrypted with a new data key wrapped by the current master key. The key
+// stays a raw private key or a keystore as it was.
+func (k Keystore) RewrapKey(ctx context.Context, pubKey string, versionID string) (string, error) {
+	if k.masterKey == nil {
+		return "", errors.New("re-wrapping keys requires a master key")
+	}
+
+	payload, _, err := k.accessSecret(ctx, pubKey, versionID)
+	if err != nil {
+		return "", err
+	}
+	if seal.IsEnvelope(payload) {
+		payload, err = k.openEnvelope(pubKey, payload)
+		if err != nil {
+			return "", err
+		}
+		defer clear(payload)
+	}
+
+	wrapped, err := k.masterKey.Encrypt(payload, []byte(pubKey))
+	if err != nil {
+		return "", err
+	}
+	newVersionID, err := k.addVersion(ctx, pubKey, wrapped)
+	if err != nil {
+		return "", err
+	}
+	k.logger.Info("Re-wrapped key", "pubKey", pubKey, "version", newVersionID)
+	return newVersionID, nil
+}
+
+// RollbackKey adds the payload of the version as a new version, since the
+// latest version is always the current one
+func (k Keystore) RollbackKey(
+	ctx context.Context,
+	pubKey string,
+	versionID string,
+) (string, error) {
+	current, err := k.CurrentVersion(ctx, pubKey)
+	if err != nil {
+		return "", err
+	}
+
+	payload, _, err := k.accessSecret(ctx, pubKey, versionID)
+	if err != nil {
+		return "", err
+	}
+	if current == versionID {
+		return versionID, nil
+	}
+	if err := k.checkVersionKey(pubKey, payload); err != nil {
+		return "", fmt.Errorf("version %s can't be rolled back to: %w", versionID, err)
+	}
+
+	newVersionID, err := k.addVersion(ctx, pubKey, payload)
+	if err != nil {
+		return "", err
+	}
+	k.logger.Info(
+		"Rolled back key",
+		"pubKey", pubKey,
+		"from", current,
+		"to", versionID,
+		"version", newVersionID,
+	)
+	return newVersionID, nil
+}
+
+// checkVersionKey checks that the payload of a version holds the key of
+// pubKey, so that the key of another secret can't become current. The public
+// key of a keystore is read from the keystore, which can't be decrypted
+// without the password.
+func (k Keystore) checkVersionKey(pubKey string, payload []byte) error {
+	// The envelope is bound to the public key, it doesn't open for another
+	if seal.IsEnvelope(payload) {
+		plaintext, err := k.openEnvelope(pubKey, payload)
+		if err != nil {
+			return err
+		}
+		defer clear(plaintext)
+		payload = plaintext
+	}
+
+	var versionPubKey string
+	if store.IsKeystore(payload) {
+		var ks keystore.Keystore
+		if err := json.Unmarshal(payload, &ks); err != nil {
+			return fmt.Errorf("failed to decode keystore: %w", err)
+		}
+		versionPubKey = ks.PubKey
+	} else {
+		var err error
+		versionPubKey, err = keystore.BlsSkToG1Pk(payload, string(curve.BN254))
+		if err != nil {
+			return err
+		}
+	}
+
+	if !strings.EqualFold(common.Trim0x(versionPubKey), pubKey) {
+		return fmt.Errorf("version holds the key %s", versionPubKey)
+	}
+	return nil
+}
+
+// addVersion adds a version holding the payload to the secret of the key and
+// returns the ID of the version
+func (k Keystore) addVersion(ctx context.Context, pubKey string, payload []byte) (string, error) {
+	version, err := k.smClient.AddSecretVersion(ctx, &secretmanagerpb.AddSecretVersionRequest{
+		Parent: k.secretName(k.prefix + pubKey),
+		Payload: &secretmanagerpb.SecretPayload{
+			Data: payload,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to add secret version: %w", err)
+	}
+	return path.Base(version.GetName()), nil
+}
+
+// accessSecret returns the payload of a version of the key, or of its latest
+// version if versionID is empty, and the resource name of that version
+func (k Keystore) accessSecret(
+	ctx context.Context,
+	pubKey string,
+	versionID string,
+) ([]byte, string, error) {
+	// The version IDs are numbers, aliases such as latest aren't versions
+	if _, err := strconv.Atoi(versionID); versionID != "" && err != nil {
+		return nil, "", fmt.Errorf("%w: %s", store.ErrVersionNotFound, versionID)
+	}
+
+	accessRequest := &secretmanagerpb.AccessSecretVersionRequest{
+		Name: k.versionName(pubKey, versionID),
+	}
+
+	result, err := k.smClient.AccessSecretVersion(ctx, accessRequest)
+	if err != nil {
+		code := status.Code(err)
+		if versionID != "" && (code == codes.NotFound || code == codes.FailedPrecondition) {
+			return nil, "", fmt.Errorf("%w: %s", store.ErrVersionNotFound, versionID)
+		}
+		if code == codes.NotFound {
+			return nil, "", store.ErrKeyNotFound
+		}
+		return nil, "", fmt.Errorf("failed to access secret version: %w", err)
+	}
+
+	return result.Payload.Data, result.Name, nil
+}
+
+// encodeKey returns the payload holding the key: the raw private key or a
+// keystore encrypted with the password of the key pair, envelope encrypted
+// when a master key is set
+func (k Keystore) encodeKey(
+	pubKey string,
+	keyPair *keystore.KeyPair,
+	encrypt bool,
+) ([]byte, error) {
+	payload := keyPair.PrivateKey
+	if encrypt {
+		ks, err := store.EncryptKeystore(keyPair)
+		if err != nil {
+			return nil, err
+		}
+		payload = ks
+	}
+
+	if k.masterKey != nil {
+		return k.masterKey.Encrypt(payload, []byte(pubKey))
+	}
+	return payload, nil
+}
+
+// decodeKey reads the key from a payload written by encodeKey
+func (k Keystore) decodeKey(
+	pubKey string,
+	payload []byte,
+	password string,
+) (*crypto.KeyPair, error) {
+	if seal.IsEnvelope(payload) {
+		plaintext, err := k.openEnvelope(pubKey, payload)
+		if err != nil {
+			return nil, err
+		}
+		defer clear(plaintext)
+		payload = plaintext
+	} else if k.masterKey != nil {
+		k.logger.Warn("Key is stored without envelope encryption", "pubKey", pubKey)
+	}
+
+	if store.IsKeystore(payload) {
+		return store.DecryptKeystore(payload, password)
+	}
+	return crypto.NewKeyPairFromHexString(hex.EncodeToString(payload))
+}
+
+// isKeystore reports whether the payload holds a password encrypted keystore
+func (k Keystore) isKeystore(pubKey string, payload []byte) (bool, error) {
+	if !seal.IsEnvelope(payload) {
+		return store.IsKeystore(payload), nil
+	}
+
+	plaintext, err := k.openEnvelope(pubKey, payload)
+	if err != nil {
+		return false, err
+	}
+	defer clear(plaintext)
+	return store.IsKeystore(plaintext), nil
+}
+
+func (k Keystore) openEnvelope(pubKey string, payload []byte) ([]byte, error) {
+	if k.masterKey == nil {
+		return nil, errors.New("key is envelope encrypted but no master key is configured")
+	}
+	return k.masterKey.Decrypt(payload, []byte(pubKey))
+}
+
+// labels returns the labels of the secrets, the ProjectKey label used to list
+// the keys can't be overridden
+func (k Keystore) labels() map[string]string {
+	labels := make(map[string]string, len(k.secretOptions.Labels)+1)
+	for key, value := range k.secretOptions.Labels {
+		labels[key] = value
+	}
+	labels[ProjectKey] = k.prefix
+	return labels
+}
+
+// replication returns the replication policy of the secrets, encrypted with
+// the KMS key of each location when keys are configured
+func (k Keystore) replication() *secretmanagerpb.Replication {
+	if len(k.secretOptions.ReplicaLocations) == 0 {
+		automatic := &secretmanagerpb.Replication_Automatic{}
+		if len(k.secretOptions.KMSKeyNames) > 0 {
+			automatic.CustomerManagedEncryption = &secretmanagerpb.CustomerManagedEncryption{
+				KmsKeyName: k.secretOptions.KMSKeyNames[0],
+			}
+		}
+		return &secretmanagerpb.Replication{
+			Replication: &secretmanagerpb.Replication_Automatic_{Automatic: automatic},
+		}
+	}
+
+	userManaged := &secretmanagerpb.Replication_UserManaged{}
+	for _, location := range k.secretOptions.ReplicaLocations {
+		replica := &secretmanagerpb.Replication_UserManaged_Replica{Location: location}
+		if keyName, ok := KMSKeyForLocation(k.secretOptions.KMSKeyNames, location); ok {
+			replica.CustomerManagedEncryption = &secretmanagerpb.CustomerManagedEncryption{
+				KmsKeyName: keyName,
+			}
+		}
+		userManaged.Replicas = append(userManaged.Replicas, replica)
+	}
+	return &secretmanagerpb.Replication{
+		Replication: &secretmanagerpb.Replication_UserManaged_{UserManaged: userManaged},
+	}
+}
+
+// KMSKeyForLocation returns the key of the location among the Cloud KMS key
+// names, of the form projects/<project>/locations/<location>/keyRings/...
+func KMSKeyForLocation(keyNames []string, location string) (string, bool) {
+	for _, keyName := range keyNames {
+		if strings.Contains(keyName, "/locations/"+location+"/") {
+			return keyName, true
+		}
+	}
+	return "", false
+}
+
+func (k Keystore) secretName(secretID string) string {
+	return fmt.Sprintf("projects/%s/secrets/%s", k.projectID, secretID)
+}
+
+// versionName returns the resource name of a version of the key, or of its
+// latest version if versionID is empty
+func (k Keystore) versionName(pubKey string, versionID string) string {
+	if versionID == "" {
+		versionID = "latest"
+	}
+	return k.secretName(k.prefix+pubKey) + "/versions/" + versionID
+}
+
 // getPubKey extracts the public key from the secret manager resource name
 // The resource name is in the format:
 //
 //	projects/<project-id>/secrets/cerberus<pubkey>
-func getPubKey(resource string) string {
-	regex := fmt.Sprintf("%s(.*)", storagePrefix)
-
-	re := regexp.MustCompile(regex)
-	matches := re.FindStringSubmatch(resource)
-	if len(matches) > 1 {
-		result := matches[1] // Gets the content of first capturing group
-		return result
-	}
-	return ""
+func (k Keystore) getPubKey(resource string) (string, bool) {
+	pubKey, ok := strings.CutPrefix(resource, k.secretName(k.prefix))
+	return pubKey, ok && pubKey != ""
 }
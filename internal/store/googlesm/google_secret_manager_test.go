@@ -0,0 +1,516 @@
+package googlesm
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/seal"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/storetest"
+)
+
+const (
+	testPassword = "p@$$w0rd"
+
+	// testProjectID contains the storage prefix on purpose, so that the
+	// public keys must be extracted from the end of the resource names
+	testProjectID = "cerberus-test"
+
+	// emulatorPageSize is the number of secrets returned by the emulator per
+	// ListSecrets call when the request doesn't set a page size
+	emulatorPageSize = 2
+)
+
+type emulatedSecret struct {
+	secret   *secretmanagerpb.Secret
+	versions []*secretmanagerpb.SecretVersion
+	payloads [][]byte
+}
+
+// emulator is an in-memory Secret Manager server implementing the part of
+// the API used by the store
+type emulator struct {
+	secretmanagerpb.UnimplementedSecretManagerServiceServer
+
+	mu      sync.Mutex
+	secrets map[string]*emulatedSecret
+}
+
+// startEmulator starts the emulator and returns a client connected to it
+func startEmulator(t *testing.T) (*emulator, *secretmanager.Client) {
+	e := &emulator{secrets: make(map[string]*emulatedSecret)}
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	secretmanagerpb.RegisterSecretManagerServiceServer(server, e)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///emulator",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	client, err := secretmanager.NewClient(context.Background(), option.WithGRPCConn(conn))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return e, client
+}
+
+func (e *emulator) CreateSecret(
+	ctx context.Context,
+	req *secretmanagerpb.CreateSecretRequest,
+) (*secretmanagerpb.Secret, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	name := req.GetParent() + "/secrets/" + req.GetSecretId()
+	if _, ok := e.secrets[name]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, "secret %s already exists", name)
+	}
+	secret := &secretmanagerpb.Secret{
+		Name:        name,
+		Labels:      req.GetSecret().GetLabels(),
+		Replication: req.GetSecret().GetReplication(),
+	}
+	e.secrets[name] = &emulatedSecret{secret: secret}
+	return secret, nil
+}
+
+func (e *emulator) AddSecretVersion(
+	ctx context.Context,
+	req *secretmanagerpb.AddSecretVersionRequest,
+) (*secretmanagerpb.SecretVersion, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	secret, ok := e.secrets[req.GetParent()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "secret %s not found", req.GetParent())
+	}
+	version := &secretmanagerpb.SecretVersion{
+		Name:       fmt.Sprintf("%s/versions/%d", req.GetParent(), len(secret.versions)+1),
+		CreateTime: timestamppb.New(time.Unix(int64(len(secret.versions)+1), 0)),
+		State:      secretmanagerpb.SecretVersion_ENABLED,
+	}
+	secret.versions = append(secret.versions, version)
+	secret.payloads = append(secret.payloads, req.GetPayload().GetData())
+	return version, nil
+}
+
+func (e *emulator) GetSecretVersion(
+	ctx context.Context,
+	req *secretmanagerpb.GetSecretVersionRequest,
+) (*secretmanagerpb.SecretVersion, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	secret, i, err := e.version(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	return secret.versions[i], nil
+}
+
+func (e *emulator) AccessSecretVersion(
+	ctx context.Context,
+	req *secretmanagerpb.AccessSecretVersionRequest,
+) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	secret, i, err := e.version(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	version := secret.versions[i]
+	if version.GetState() != secretmanagerpb.SecretVersion_ENABLED {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s is %s", version.Name, version.State)
+	}
+	return &secretmanagerpb.AccessSecretVersionResponse{
+		Name:    version.GetName(),
+		Payload: &secretmanagerpb.SecretPayload{Data: secret.payloads[i]},
+	}, nil
+}
+
+func (e *emulator) DestroySecretVersion(
+	ctx context.Context,
+	req *secretmanagerpb.DestroySecretVersionRequest,
+) (*secretmanagerpb.SecretVersion, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	secret, i, err := e.version(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	secret.versions[i].State = secretmanagerpb.SecretVersion_DESTROYED
+	secret.payloads[i] = nil
+	return secret.versions[i], nil
+}
+
+func (e *emulator) DeleteSecret(
+	ctx context.Context,
+	req *secretmanagerpb.DeleteSecretRequest,
+) (*emptypb.Empty, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.secrets[req.GetName()]; !ok {
+		return nil, status.Errorf(codes.NotFound, "secret %s not found", req.GetName())
+	}
+	delete(e.secrets, req.GetName())
+	return &emptypb.Empty{}, nil
+}
+
+// ListSecretVersions returns every version of the secret in a single page,
+// newest first like the real API
+func (e *emulator) ListSecretVersions(
+	ctx context.Context,
+	req *secretmanagerpb.ListSecretVersionsRequest,
+) (*secretmanagerpb.ListSecretVersionsResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	secret, ok := e.secrets[req.GetParent()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "secret %s not found", req.GetParent())
+	}
+	resp := &secretmanagerpb.ListSecretVersionsResponse{TotalSize: int32(len(secret.versions))}
+	for i := len(secret.versions) - 1; i >= 0; i-- {
+		resp.Versions = append(resp.Versions, secret.versions[i])
+	}
+	return resp, nil
+}
+
+// ListSecrets returns the secrets of the project, only filters on a single
+// label of the form labels.<key>=<value> are supported
+func (e *emulator) ListSecrets(
+	ctx context.Context,
+	req *secretmanagerpb.ListSecretsRequest,
+) (*secretmanagerpb.ListSecretsResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var labelKey, labelValue string
+	if req.GetFilter() != "" {
+		label, value, _ := strings.Cut(req.GetFilter(), "=")
+		key, ok := strings.CutPrefix(label, "labels.")
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unsupported filter %q", label)
+		}
+		labelKey, labelValue = key, value
+	}
+
+	var names []string
+	for name, secret := range e.secrets {
+		if !strings.HasPrefix(name, req.GetParent()+"/secrets/") {
+			continue
+		}
+		if labelKey != "" && secret.secret.GetLabels()[labelKey] != labelValue {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	start := 0
+	if req.GetPageToken() != "" {
+		var err error
+		start, err = strconv.Atoi(req.GetPageToken())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page token")
+		}
+	}
+	pageSize := int(req.GetPageSize())
+	if pageSize == 0 {
+		pageSize = emulatorPageSize
+	}
+	end := min(start+pageSize, len(names))
+
+	resp := &secretmanagerpb.ListSecretsResponse{TotalSize: int32(len(names))}
+	for _, name := range names[start:end] {
+		resp.Secrets = append(resp.Secrets, e.secrets[name].secret)
+	}
+	if end < len(names) {
+		resp.NextPageToken = strconv.Itoa(end)
+	}
+	return resp, nil
+}
+
+// version returns the secret of the version resource name and the index of
+// the version, the latest alias resolves to the most recent version
+func (e *emulator) version(name string) (*emulatedSecret, int, error) {
+	secretName, versionID, _ := strings.Cut(name, "/versions/")
+	secret, ok := e.secrets[secretName]
+	if !ok {
+		return nil, 0, status.Errorf(codes.NotFound, "secret %s not found", secretName)
+	}
+	if len(secret.versions) == 0 {
+		return nil, 0, status.Errorf(codes.NotFound, "secret %s has no versions", secretName)
+	}
+	if versionID == "latest" {
+		return secret, len(secret.versions) - 1, nil
+	}
+	i, err := strconv.Atoi(versionID)
+	if err != nil || i < 1 || i > len(secret.versions) {
+		return nil, 0, status.Errorf(codes.NotFound, "version %s not found", name)
+	}
+	return secret, i - 1, nil
+}
+
+func addForeignSecrets(t *testing.T, client Client) {
+	ctx := context.Background()
+	parent := "projects/" + testProjectID
+	foreign := []*secretmanagerpb.CreateSecretRequest{
+		{Parent: parent, SecretId: "other-app", Secret: &secretmanagerpb.Secret{}},
+		{
+			Parent:   parent,
+			SecretId: "config",
+			Secret: &secretmanagerpb.Secret{
+				Labels: map[string]string{ProjectKey: DefaultPrefix},
+			},
+		},
+	}
+	for _, req := range foreign {
+		_, err := client.CreateSecret(ctx, req)
+		require.NoError(t, err)
+	}
+}
+
+func newMasterKey(t *testing.T) *seal.Keeper {
+	key, err := seal.GenerateKey()
+	require.NoError(t, err)
+	keeper, err := seal.NewKeeper(key, testutils.GetTestLogger())
+	require.NoError(t, err)
+	return keeper
+}
+
+func TestKeystoreConformance(t *testing.T) {
+	logger := testutils.GetTestLogger()
+	opts := storetest.Options{
+		AddForeignEntry: func(t *testing.T, s store.Store) {
+			addForeignSecrets(t, s.(*Keystore).smClient)
+		},
+	}
+
+	t.Run("Raw", func(t *testing.T) {
+		opts := opts
+		opts.IgnoresPassword = true
+		storetest.Run(t, func(t *testing.T) store.Store {
+			_, client := startEmulator(t)
+			return NewStore(client, testProjectID, logger)
+		}, opts)
+	})
+
+	t.Run("KeystoreWithMasterKey", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) store.Store {
+			_, client := startEmulator(t)
+			return NewStore(client, testProjectID, logger).
+				WithMasterKey(newMasterKey(t)).
+				WithKeystoreEncryption(true)
+		}, opts)
+	})
+}
+
+func TestMigrateToKeystore(t *testing.T) {
+	ctx := context.Background()
+	e, client := startEmulator(t)
+	s := NewStore(client, testProjectID, testutils.GetTestLogger())
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	pubKey, err := s.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	migrated, err := s.MigrateToKeystore(ctx, pubKey, testPassword)
+	require.NoError(t, err)
+	assert.True(t, migrated)
+
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.NoError(t, err)
+	_, err = s.RetrieveKey(ctx, pubKey, "wrong password")
+	assert.Error(t, err)
+
+	// The plaintext version is destroyed
+	secret := e.secrets[s.secretName(DefaultPrefix+pubKey)]
+	require.Len(t, secret.versions, 2)
+	assert.Equal(t, secretmanagerpb.SecretVersion_DESTROYED, secret.versions[0].GetState())
+	assert.Nil(t, secret.payloads[0])
+
+	migrated, err = s.MigrateToKeystore(ctx, pubKey, testPassword)
+	require.NoError(t, err)
+	assert.False(t, migrated)
+}
+
+func TestKeyVersions(t *testing.T) {
+	ctx := context.Background()
+	e, client := startEmulator(t)
+	s := NewStore(client, testProjectID, testutils.GetTestLogger())
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	pubKey, err := s.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+	firstVersion, err := s.CurrentVersion(ctx, pubKey)
+	require.NoError(t, err)
+	assert.Equal(t, "1", firstVersion)
+
+	// Without a master key there is nothing to re-wrap with
+	_, err = s.RewrapKey(ctx, pubKey, "")
+	assert.Error(t, err)
+
+	s.WithMasterKey(newMasterKey(t))
+	wrappedVersion, err := s.RewrapKey(ctx, pubKey, "")
+	require.NoError(t, err)
+	assert.Equal(t, "2", wrappedVersion)
+	secret := e.secrets[s.secretName(DefaultPrefix+pubKey)]
+	assert.True(t, seal.IsEnvelope(secret.payloads[1]))
+
+	versions, err := s.ListVersions(ctx, pubKey)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, "1", versions[0].ID)
+	assert.False(t, versions[0].Current)
+	assert.Equal(t, "2", versions[1].ID)
+	assert.True(t, versions[1].Current)
+
+	// Every version holds the same key
+	for _, versionID := range []string{firstVersion, wrappedVersion} {
+		kp, err := s.RetrieveKeyVersion(ctx, pubKey, versionID, "")
+		require.NoError(t, err)
+		pubKeyBytes := kp.PubKey.Bytes()
+		assert.Equal(t, pubKey, hex.EncodeToString(pubKeyBytes[:]))
+	}
+	for _, versionID := range []string{"3", "latest", "../1"} {
+		_, err = s.RetrieveKeyVersion(ctx, pubKey, versionID, "")
+		assert.ErrorIs(t, err, store.ErrVersionNotFound, versionID)
+	}
+
+	// The rollback adds a copy of the version
+	current, err := s.RollbackKey(ctx, pubKey, firstVersion)
+	require.NoError(t, err)
+	assert.Equal(t, "3", current)
+	assert.Equal(t, secret.payloads[0], secret.payloads[2])
+	_, err = s.RetrieveKey(ctx, pubKey, "")
+	assert.NoError(t, err)
+
+	// Destroyed versions can't be read, listed or rolled back to
+	_, err = client.DestroySecretVersion(ctx, &secretmanagerpb.DestroySecretVersionRequest{
+		Name: s.versionName(pubKey, "1"),
+	})
+	require.NoError(t, err)
+	_, err = s.RollbackKey(ctx, pubKey, "1")
+	assert.ErrorIs(t, err, store.ErrVersionNotFound)
+	versions, err = s.ListVersions(ctx, pubKey)
+	require.NoError(t, err)
+	assert.Len(t, versions, 2)
+
+	// A version holding another key isn't made current
+	otherKeyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	_, err = client.AddSecretVersion(ctx, &secretmanagerpb.AddSecretVersionRequest{
+		Parent:  s.secretName(DefaultPrefix + pubKey),
+		Payload: &secretmanagerpb.SecretPayload{Data: otherKeyPair.PrivateKey},
+	})
+	require.NoError(t, err)
+	_, err = s.RollbackKey(ctx, pubKey, "3")
+	require.NoError(t, err)
+	_, err = s.RollbackKey(ctx, pubKey, "4")
+	assert.Error(t, err)
+	current, err = s.CurrentVersion(ctx, pubKey)
+	require.NoError(t, err)
+	assert.Equal(t, "5", current)
+}
+
+func TestPrefixAndSecretOptions(t *testing.T) {
+	ctx := context.Background()
+	e, client := startEmulator(t)
+	logger := testutils.GetTestLogger()
+
+	keyName := func(location string) string {
+		return "projects/kms/locations/" + location + "/keyRings/cerberus/cryptoKeys/keys"
+	}
+	staging := NewStore(client, testProjectID, logger).
+		WithPrefix("staging").
+		WithSecretOptions(SecretOptions{
+			KMSKeyNames:      []string{keyName("us-east1"), keyName("europe-west1")},
+			ReplicaLocations: []string{"europe-west1", "us-east1"},
+			Labels:           map[string]string{"team": "avs", ProjectKey: "ignored"},
+		})
+	production := NewStore(client, testProjectID, logger)
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	pubKey, err := staging.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	secret := e.secrets[staging.secretName("staging"+pubKey)]
+	require.NotNil(t, secret)
+	assert.Equal(
+		t,
+		map[string]string{"team": "avs", ProjectKey: "staging"},
+		secret.secret.GetLabels(),
+	)
+	replicas := secret.secret.GetReplication().GetUserManaged().GetReplicas()
+	require.Len(t, replicas, 2)
+	for i, location := range []string{"europe-west1", "us-east1"} {
+		assert.Equal(t, location, replicas[i].GetLocation())
+		kmsKeyName := replicas[i].GetCustomerManagedEncryption().GetKmsKeyName()
+		assert.Equal(t, keyName(location), kmsKeyName)
+	}
+
+	// The deployments sharing the project don't see each other's keys
+	keys, err := staging.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{pubKey}, keys)
+	keys, err = production.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	_, err = production.RetrieveKey(ctx, pubKey, testPassword)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+	_, err = staging.RetrieveKey(ctx, pubKey, testPassword)
+	assert.NoError(t, err)
+
+	// A single key encrypts the automatically replicated secrets
+	global := NewStore(client, testProjectID, logger).
+		WithSecretOptions(SecretOptions{KMSKeyNames: []string{keyName("global")}})
+	pubKey, err = global.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+	secret = e.secrets[global.secretName(DefaultPrefix+pubKey)]
+	automatic := secret.secret.GetReplication().GetAutomatic()
+	require.NotNil(t, automatic)
+	assert.Equal(t, keyName("global"), automatic.GetCustomerManagedEncryption().GetKmsKeyName())
+}
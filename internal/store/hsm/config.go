@@ -0,0 +1,64 @@
+package hsm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	DefaultLabelPrefix = "cerberus/"
+
+	// PIN source prefixes
+	envPINSource  = "env:"
+	filePINSource = "file:"
+	pinPINSource  = "pin:"
+)
+
+var ErrNotSupported = errors.New(
+	"PKCS#11 is not supported by this build, it requires cgo on Linux or macOS",
+)
+
+type Config struct {
+	// ModulePath is the path of the PKCS#11 library of the HSM
+	ModulePath string
+
+	// TokenLabel selects the slot by the label of its token. Slot is used
+	// when it's empty.
+	TokenLabel string
+	Slot       uint
+
+	// PIN of the user of the token
+	PIN string
+
+	// LabelPrefix is prepended to the public key in the label of the objects
+	LabelPrefix string
+}
+
+// ResolvePIN reads the PIN from its source, which is one of env:<variable>,
+// file:<path> or pin:<value>
+func ResolvePIN(source string) (string, error) {
+	switch {
+	case strings.HasPrefix(source, envPINSource):
+		name := strings.TrimPrefix(source, envPINSource)
+		pin, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return pin, nil
+	case strings.HasPrefix(source, filePINSource):
+		pin, err := os.ReadFile(strings.TrimPrefix(source, filePINSource))
+		if err != nil {
+			return "", fmt.Errorf("failed to read PIN file: %w", err)
+		}
+		return strings.TrimSpace(string(pin)), nil
+	case strings.HasPrefix(source, pinPINSource):
+		return strings.TrimPrefix(source, pinPINSource), nil
+	default:
+		return "", fmt.Errorf(
+			"unsupported PIN source %q, must start with env:, file: or pin:",
+			source,
+		)
+	}
+}
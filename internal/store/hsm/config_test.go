@@ -0,0 +1,34 @@
+package hsm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePIN(t *testing.T) {
+	t.Setenv("CERBERUS_TEST_PIN", "1234")
+	pinFile := filepath.Join(t.TempDir(), "pin")
+	require.NoError(t, os.WriteFile(pinFile, []byte("5678\n"), 0600))
+
+	pin, err := ResolvePIN("env:CERBERUS_TEST_PIN")
+	require.NoError(t, err)
+	assert.Equal(t, "1234", pin)
+
+	pin, err = ResolvePIN("file:" + pinFile)
+	require.NoError(t, err)
+	assert.Equal(t, "5678", pin)
+
+	pin, err = ResolvePIN("pin:0000")
+	require.NoError(t, err)
+	assert.Equal(t, "0000", pin)
+
+	_, err = ResolvePIN("env:CERBERUS_TEST_PIN_NOT_SET")
+	assert.Error(t, err)
+
+	_, err = ResolvePIN("1234")
+	assert.Error(t, err)
+}
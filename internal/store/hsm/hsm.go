@@ -0,0 +1,380 @@
+//go:build cgo && (linux || darwin)
+
+package hsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+
+	"github.com/Layr-Labs/bn254-keystore-go/curve"
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+var _ store.Store = (*Store)(nil)
+
+const (
+	// application is set on every data object holding a wrapped key
+	application = "cerberus"
+
+	wrappingKeyLabel = "wrapping-key"
+
+	// Wrapped keys are stored as formatVersion || IV || AES-GCM ciphertext
+	formatVersion = 1
+	gcmIVSize     = 12
+	gcmTagBits    = 128
+)
+
+// Store keeps the private keys on a PKCS#11 token, encrypted with an AES key
+// that never leaves the token. BN254 isn't a mechanism HSMs support, so the
+// keys are decrypted by the token and the signing happens in process.
+type Store struct {
+	ctx         *pkcs11.Ctx
+	labelPrefix string
+
+	// PKCS#11 sessions can't be used concurrently
+	mu          sync.Mutex
+	session     pkcs11.SessionHandle
+	wrappingKey pkcs11.ObjectHandle
+
+	logger *slog.Logger
+}
+
+func NewStore(config Config, logger *slog.Logger) (*Store, error) {
+	ctx := pkcs11.New(config.ModulePath)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 module %s", config.ModulePath)
+	}
+
+	s := &Store{
+		ctx:         ctx,
+		labelPrefix: config.LabelPrefix,
+		logger:      logger.With("component", "pkcs11-store"),
+	}
+	if s.labelPrefix == "" {
+		s.labelPrefix = DefaultLabelPrefix
+	}
+
+	if err := s.open(config); err != nil {
+		ctx.Destroy()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) open(config Config) error {
+	err := s.ctx.Initialize()
+	if err != nil && !isError(err, pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		return fmt.Errorf("failed to initialize PKCS#11 module: %w", err)
+	}
+
+	slot, err := s.findSlot(config)
+	if err != nil {
+		return err
+	}
+
+	s.session, err = s.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return fmt.Errorf("failed to open session on slot %d: %w", slot, err)
+	}
+
+	err = s.ctx.Login(s.session, pkcs11.CKU_USER, config.PIN)
+	if err != nil && !isError(err, pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+		_ = s.ctx.CloseSession(s.session)
+		return fmt.Errorf("failed to log in to token: %w", err)
+	}
+
+	s.wrappingKey, err = s.findOrCreateWrappingKey()
+	if err != nil {
+		s.closeSession()
+		return err
+	}
+	return nil
+}
+
+// Close logs out of the token and unloads the PKCS#11 module
+func (s *Store) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeSession()
+	_ = s.ctx.Finalize()
+	s.ctx.Destroy()
+}
+
+func (s *Store) RetrieveKey(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (*crypto.KeyPair, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	label := s.labelPrefix + pubKey
+	object, err := s.findDataObject(label)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := s.ctx.GetAttributeValue(s.session, object, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", pubKey, err)
+	}
+
+	skBytes, err := s.unwrap(attrs[0].Value, []byte(label))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key %s: %w", pubKey, err)
+	}
+	// The token decrypts into ordinary process memory, and the key pair
+	// derived from it is cached by the signer, so only the intermediate
+	// copy can be wiped
+	defer clear(skBytes)
+
+	privKey, err := crypto.PrivateKeyFromBytes(skBytes)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.NewKeyPair(privKey), nil
+}
+
+func (s *Store) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (string, error) {
+	pubKey, err := keystore.BlsSkToG1Pk(keyPair.PrivateKey, string(curve.BN254))
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	label := s.labelPrefix + pubKey
+	if _, err := s.findDataObject(label); err == nil {
+		return "", store.ErrKeyAlreadyExists
+	} else if !errors.Is(err, store.ErrKeyNotFound) {
+		return "", err
+	}
+
+	wrapped, err := s.wrap(keyPair.PrivateKey, []byte(label))
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap key: %w", err)
+	}
+
+	_, err = s.ctx.CreateObject(s.session, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_DATA),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_MODIFIABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_APPLICATION, application),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, wrapped),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create key object: %w", err)
+	}
+
+	return pubKey, nil
+}
+
+func (s *Store) ListKeys(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	objects, err := s.findObjects([]*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_DATA),
+		pkcs11.NewAttribute(pkcs11.CKA_APPLICATION, application),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(objects))
+	for _, object := range objects {
+		attrs, err := s.ctx.GetAttributeValue(s.session, object, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, nil),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object label: %w", err)
+		}
+		label := string(attrs[0].Value)
+		// Objects of other signers sharing the token
+		if !strings.HasPrefix(label, s.labelPrefix) {
+			continue
+		}
+		keys = append(keys, strings.TrimPrefix(label, s.labelPrefix))
+	}
+
+	s.logger.Debug(fmt.Sprintf("Found %d keys", len(keys)))
+	return keys, nil
+}
+
+func (s *Store) DeleteKey(ctx context.Context, pubKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	object, err := s.findDataObject(s.labelPrefix + pubKey)
+	if err != nil {
+		return err
+	}
+	return s.ctx.DestroyObject(s.session, object)
+}
+
+func (s *Store) findSlot(config Config) (uint, error) {
+	if config.TokenLabel == "" {
+		return config.Slot, nil
+	}
+
+	slots, err := s.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list slots: %w", err)
+	}
+	for _, slot := range slots {
+		info, err := s.ctx.GetTokenInfo(slot)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get token info of slot %d: %w", slot, err)
+		}
+		if strings.TrimRight(info.Label, " \x00") == config.TokenLabel {
+			return slot, nil
+		}
+	}
+	return 0, fmt.Errorf("no token with label %q", config.TokenLabel)
+}
+
+// findOrCreateWrappingKey returns the AES key wrapping the private keys,
+// generating it on the token the first time
+func (s *Store) findOrCreateWrappingKey() (pkcs11.ObjectHandle, error) {
+	label := s.labelPrefix + wrappingKeyLabel
+	objects, err := s.findObjects([]*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	})
+	if err != nil {
+		return 0, err
+	}
+	switch len(objects) {
+	case 0:
+	case 1:
+		return objects[0], nil
+	default:
+		return 0, fmt.Errorf("found %d wrapping keys labeled %q", len(objects), label)
+	}
+
+	s.logger.Info(fmt.Sprintf("Generating wrapping key %q on the token", label))
+	key, err := s.ctx.GenerateKey(
+		s.session,
+		[]*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_KEY_GEN, nil)},
+		[]*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+			pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+			pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, 32),
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+			pkcs11.NewAttribute(pkcs11.CKA_ENCRYPT, true),
+			pkcs11.NewAttribute(pkcs11.CKA_DECRYPT, true),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+		},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate wrapping key: %w", err)
+	}
+	return key, nil
+}
+
+// wrap encrypts the private key with the wrapping key, binding it to the
+// label of its object so that wrapped keys can't be swapped
+func (s *Store) wrap(skBytes []byte, label []byte) ([]byte, error) {
+	iv, err := s.ctx.GenerateRandom(s.session, gcmIVSize)
+	if err != nil {
+		return nil, err
+	}
+
+	params := pkcs11.NewGCMParams(iv, label, gcmTagBits)
+	defer params.Free()
+	mechanism := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}
+	if err := s.ctx.EncryptInit(s.session, mechanism, s.wrappingKey); err != nil {
+		return nil, err
+	}
+	ciphertext, err := s.ctx.Encrypt(s.session, skBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Some tokens pick their own IV
+	if actualIV := params.IV(); len(actualIV) == gcmIVSize {
+		iv = actualIV
+	}
+
+	wrapped := make([]byte, 0, 1+gcmIVSize+len(ciphertext))
+	wrapped = append(wrapped, formatVersion)
+	wrapped = append(wrapped, iv...)
+	return append(wrapped, ciphertext...), nil
+}
+
+func (s *Store) unwrap(wrapped []byte, label []byte) ([]byte, error) {
+	if len(wrapped) < 1+gcmIVSize || wrapped[0] != formatVersion {
+		return nil, errors.New("unsupported wrapped key format")
+	}
+	iv := wrapped[1 : 1+gcmIVSize]
+
+	params := pkcs11.NewGCMParams(iv, label, gcmTagBits)
+	defer params.Free()
+	mechanism := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}
+	if err := s.ctx.DecryptInit(s.session, mechanism, s.wrappingKey); err != nil {
+		return nil, err
+	}
+	return s.ctx.Decrypt(s.session, wrapped[1+gcmIVSize:])
+}
+
+func (s *Store) findDataObject(label string) (pkcs11.ObjectHandle, error) {
+	objects, err := s.findObjects([]*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_DATA),
+		pkcs11.NewAttribute(pkcs11.CKA_APPLICATION, application),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(objects) == 0 {
+		return 0, store.ErrKeyNotFound
+	}
+	return objects[0], nil
+}
+
+func (s *Store) findObjects(template []*pkcs11.Attribute) ([]pkcs11.ObjectHandle, error) {
+	if err := s.ctx.FindObjectsInit(s.session, template); err != nil {
+		return nil, fmt.Errorf("failed to search objects: %w", err)
+	}
+	defer func() { _ = s.ctx.FindObjectsFinal(s.session) }()
+
+	var objects []pkcs11.ObjectHandle
+	for {
+		batch, _, err := s.ctx.FindObjects(s.session, 100)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search objects: %w", err)
+		}
+		if len(batch) == 0 {
+			return objects, nil
+		}
+		objects = append(objects, batch...)
+	}
+}
+
+func (s *Store) closeSession() {
+	_ = s.ctx.Logout(s.session)
+	_ = s.ctx.CloseSession(s.session)
+}
+
+func isError(err error, code uint) bool {
+	var p11Err pkcs11.Error
+	return errors.As(err, &p11Err) && uint(p11Err) == code
+}
@@ -0,0 +1,156 @@
+//go:build cgo && (linux || darwin)
+
+package hsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/storetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testTokenLabel = "cerberus-test"
+	testPIN        = "1234"
+	testSOPIN      = "5678"
+	testPassword   = "p@$$w0rd"
+)
+
+var softHSMModulePaths = []string{
+	"/usr/lib/softhsm/libsofthsm2.so",
+	"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+	"/usr/lib/aarch64-linux-gnu/softhsm/libsofthsm2.so",
+	"/usr/local/lib/softhsm/libsofthsm2.so",
+	"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+}
+
+// setupSoftHSM initializes a SoftHSM2 token in a temporary directory and
+// returns the path of the module. The test is skipped without SoftHSM2.
+func setupSoftHSM(t *testing.T) string {
+	modulePath := os.Getenv("SOFTHSM2_MODULE")
+	if modulePath == "" {
+		for _, p := range softHSMModulePaths {
+			if _, err := os.Stat(p); err == nil {
+				modulePath = p
+				break
+			}
+		}
+	}
+	if modulePath == "" {
+		t.Skip("SoftHSM2 is not installed")
+	}
+	if _, err := exec.LookPath("softhsm2-util"); err != nil {
+		t.Skip("softhsm2-util is not installed")
+	}
+
+	dir := t.TempDir()
+	tokenDir := filepath.Join(dir, "tokens")
+	require.NoError(t, os.Mkdir(tokenDir, 0700))
+	confPath := filepath.Join(dir, "softhsm2.conf")
+	conf := fmt.Sprintf("directories.tokendir = %s\nobjectstore.backend = file\n", tokenDir)
+	require.NoError(t, os.WriteFile(confPath, []byte(conf), 0600))
+	t.Setenv("SOFTHSM2_CONF", confPath)
+
+	out, err := exec.Command(
+		"softhsm2-util",
+		"--init-token", "--free",
+		"--label", testTokenLabel,
+		"--pin", testPIN,
+		"--so-pin", testSOPIN,
+	).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	return modulePath
+}
+
+func TestHSMStoreKeyOperations(t *testing.T) {
+	modulePath := setupSoftHSM(t)
+	ctx := context.Background()
+	config := Config{
+		ModulePath:  modulePath,
+		TokenLabel:  testTokenLabel,
+		PIN:         testPIN,
+		LabelPrefix: "cerberus-test/",
+	}
+
+	hs, err := NewStore(config, testutils.GetTestLogger())
+	require.NoError(t, err)
+
+	keys, err := hs.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+
+	pubKeyHex, err := hs.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	_, err = hs.StoreKey(ctx, keyPair)
+	assert.ErrorIs(t, err, store.ErrKeyAlreadyExists)
+
+	storedKeyPair, err := hs.RetrieveKey(ctx, pubKeyHex, testPassword)
+	require.NoError(t, err)
+	pkBytes := storedKeyPair.PrivKey.Bytes()
+	assert.Equal(t, keyPair.PrivateKey, pkBytes[:])
+
+	keys, err = hs.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{pubKeyHex}, keys)
+
+	// The keys and the wrapping key survive a restart
+	hs.Close()
+	hs, err = NewStore(config, testutils.GetTestLogger())
+	require.NoError(t, err)
+	defer hs.Close()
+
+	storedKeyPair, err = hs.RetrieveKey(ctx, pubKeyHex, testPassword)
+	require.NoError(t, err)
+	pkBytes = storedKeyPair.PrivKey.Bytes()
+	assert.Equal(t, keyPair.PrivateKey, pkBytes[:])
+
+	require.NoError(t, hs.DeleteKey(ctx, pubKeyHex))
+	_, err = hs.RetrieveKey(ctx, pubKeyHex, testPassword)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+	assert.ErrorIs(t, hs.DeleteKey(ctx, pubKeyHex), store.ErrKeyNotFound)
+}
+
+func TestHSMStoreWrongPIN(t *testing.T) {
+	modulePath := setupSoftHSM(t)
+
+	_, err := NewStore(Config{
+		ModulePath: modulePath,
+		TokenLabel: testTokenLabel,
+		PIN:        "0000",
+	}, testutils.GetTestLogger())
+	assert.Error(t, err)
+}
+
+func TestHSMStoreConformance(t *testing.T) {
+	modulePath := setupSoftHSM(t)
+
+	// The subtests share the token, each one gets its own label prefix
+	storetest.Run(t, func(t *testing.T) store.Store {
+		hs, err := NewStore(Config{
+			ModulePath:  modulePath,
+			TokenLabel:  testTokenLabel,
+			PIN:         testPIN,
+			LabelPrefix: t.Name() + "/",
+		}, testutils.GetTestLogger())
+		require.NoError(t, err)
+		t.Cleanup(hs.Close)
+		return hs
+	}, storetest.Options{IgnoresPassword: true})
+}
@@ -0,0 +1,43 @@
+//go:build !cgo || !(linux || darwin)
+
+package hsm
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+var _ store.Store = (*Store)(nil)
+
+type Store struct{}
+
+func NewStore(config Config, logger *slog.Logger) (*Store, error) {
+	return nil, ErrNotSupported
+}
+
+func (s *Store) Close() {}
+
+func (s *Store) RetrieveKey(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (*crypto.KeyPair, error) {
+	return nil, ErrNotSupported
+}
+
+func (s *Store) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (string, error) {
+	return "", ErrNotSupported
+}
+
+func (s *Store) ListKeys(ctx context.Context) ([]string, error) {
+	return nil, ErrNotSupported
+}
+
+func (s *Store) DeleteKey(ctx context.Context, pubKey string) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,372 @@
+package k8ssecrets
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/Layr-Labs/bn254-keystore-go/curve"
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+var (
+	_ store.Store               = (*Store)(nil)
+	_ store.KeystoreMigrator    = (*Store)(nil)
+	_ store.PasswordChecker     = (*Store)(nil)
+	_ store.TransientClassifier = (*Store)(nil)
+)
+
+const (
+	// DefaultPrefix is prepended to the public keys to name the secrets
+	DefaultPrefix = "cerberus-"
+
+	// ManagedByLabel marks the secrets owned by cerberus, only these secrets
+	// are listed, read and deleted as keys
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedByValue = "cerberus"
+
+	// PublicKeyAnnotation records the public key of the secret
+	PublicKeyAnnotation = "cerberus.layr-labs.io/public-key"
+
+	// Data fields of the secret. A key holds the raw private key or an
+	// EIP-2335 keystore encrypted with the password of the key.
+	privateKeyField = "private_key"
+	keystoreField   = "keystore"
+
+	// listPageSize is the number of secrets requested per list call
+	listPageSize = 100
+)
+
+type Config struct {
+	// Namespace of the secrets, defaults to the namespace of the kubeconfig
+	// context, or of the pod when running in the cluster
+	Namespace string
+
+	// Kubeconfig is the path of the kubeconfig file. The KUBECONFIG variable
+	// and ~/.kube/config are used when it is empty, and the in-cluster
+	// service account when none of them exists.
+	Kubeconfig string
+
+	// Context is the kubeconfig context, defaults to the current context
+	Context string
+}
+
+type Store struct {
+	client    kubernetes.Interface
+	namespace string
+
+	// prefix is prepended to the public keys to name the secrets, so that
+	// several deployments can share a namespace
+	prefix             string
+	labels             map[string]string
+	keystoreEncryption bool
+
+	logger *slog.Logger
+}
+
+// NewStoreFromConfig returns a store connected to the cluster of the
+// kubeconfig, or to the cluster it runs in
+func NewStoreFromConfig(config Config, logger *slog.Logger) (*Store, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = config.Kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		loadingRules,
+		&clientcmd.ConfigOverrides{CurrentContext: config.Context},
+	)
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Kubernetes client configuration: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	namespace := config.Namespace
+	if namespace == "" {
+		namespace, _, err = clientConfig.Namespace()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Kubernetes namespace: %w", err)
+		}
+	}
+
+	return NewStore(client, namespace, logger), nil
+}
+
+// NewStore returns a store keeping the keys as secrets of the namespace
+// through the given client
+func NewStore(client kubernetes.Interface, namespace string, logger *slog.Logger) *Store {
+	return &Store{
+		client:    client,
+		namespace: namespace,
+		prefix:    DefaultPrefix,
+		logger:    logger.With("component", "k8s-secrets-store", "namespace", namespace),
+	}
+}
+
+// WithPrefix names the secrets with the prefix rather than DefaultPrefix
+func (s *Store) WithPrefix(prefix string) *Store {
+	if prefix != "" {
+		s.prefix = prefix
+	}
+	return s
+}
+
+// WithLabels adds the labels to the secrets created from now on, the
+// ManagedByLabel can't be overridden
+func (s *Store) WithLabels(labels map[string]string) *Store {
+	s.labels = labels
+	return s
+}
+
+// WithKeystoreEncryption stores the keys from now on as EIP-2335 keystores
+// encrypted with the password of the key, like the filesystem store does
+func (s *Store) WithKeystoreEncryption(enabled bool) *Store {
+	s.keystoreEncryption = enabled
+	return s
+}
+
+// ChecksPassword reports whether the keys are stored as keystores. The keys
+// stored before keystore encryption was enabled are returned whatever the
+// password until they are migrated.
+func (s *Store) ChecksPassword() bool {
+	return s.keystoreEncryption
+}
+
+// Transient reports the timeouts, throttled requests and server errors of the
+// Kubernetes API server
+func (s *Store) Transient(err error) bool {
+	return apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err)
+}
+
+func (s *Store) RetrieveKey(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (*crypto.KeyPair, error) {
+	secret, err := s.getSecret(ctx, pubKey)
+	if err != nil {
+		return nil, err
+	}
+	return decodeKey(secret, password)
+}
+
+func (s *Store) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (string, error) {
+	pubKey, err := keystore.BlsSkToG1Pk(keyPair.PrivateKey, string(curve.BN254))
+	if err != nil {
+		return "", err
+	}
+
+	data, err := encodeKey(keyPair, s.keystoreEncryption)
+	if err != nil {
+		return "", err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        s.secretName(pubKey),
+			Namespace:   s.namespace,
+			Labels:      s.secretLabels(),
+			Annotations: map[string]string{PublicKeyAnnotation: pubKey},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+	_, err = s.client.CoreV1().Secrets(s.namespace).Create(ctx, secret, metav1.CreateOptions{})
+	if err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return "", store.ErrKeyAlreadyExists
+		}
+		return "", fmt.Errorf("failed to create secret: %w", err)
+	}
+
+	s.logger.Info("Stored key in Kubernetes secret", "secret", secret.Name)
+	return pubKey, nil
+}
+
+func (s *Store) ListKeys(ctx context.Context) ([]string, error) {
+	selector := labels.SelectorFromSet(labels.Set{ManagedByLabel: ManagedByValue}).String()
+
+	keys := make([]string, 0)
+	opts := metav1.ListOptions{LabelSelector: selector, Limit: listPageSize}
+	for {
+		list, err := s.client.CoreV1().Secrets(s.namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list secrets: %w", err)
+		}
+		for _, secret := range list.Items {
+			// Other deployments may share the namespace with another prefix
+			pubKey, ok := s.getPubKey(secret.Name)
+			if !ok {
+				continue
+			}
+			keys = append(keys, pubKey)
+		}
+
+		if list.Continue == "" {
+			break
+		}
+		opts.Continue = list.Continue
+	}
+
+	s.logger.Debug(fmt.Sprintf("Found %d keys", len(keys)))
+	return keys, nil
+}
+
+func (s *Store) DeleteKey(ctx context.Context, pubKey string) error {
+	secret, err := s.getSecret(ctx, pubKey)
+	if err != nil {
+		return err
+	}
+
+	// The precondition makes sure a secret recreated in the meantime, which
+	// may not be ours, isn't deleted
+	err = s.client.CoreV1().Secrets(s.namespace).Delete(ctx, secret.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &secret.UID},
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return store.ErrKeyNotFound
+		}
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+	return nil
+}
+
+// MigrateToKeystore replaces the raw private key of the secret by a keystore
+// encrypted with the password. The update fails if the secret was changed
+// since it was read.
+func (s *Store) MigrateToKeystore(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (bool, error) {
+	secret, err := s.getSecret(ctx, pubKey)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := secret.Data[keystoreField]; ok {
+		return false, nil
+	}
+
+	kp, err := decodeKey(secret, "")
+	if err != nil {
+		return false, err
+	}
+	secret.Data, err = encodeKey(&keystore.KeyPair{
+		PrivateKey: crypto.PrivateKeyBytes(kp.PrivKey),
+		Password:   password,
+	}, true)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = s.client.CoreV1().Secrets(s.namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, store.ErrKeyNotFound
+		}
+		return false, fmt.Errorf("failed to update secret: %w", err)
+	}
+	return true, nil
+}
+
+// getSecret returns the secret of the key. Secrets without the ManagedByLabel
+// aren't keys even if they are named after one.
+func (s *Store) getSecret(ctx context.Context, pubKey string) (*corev1.Secret, error) {
+	if !isPubKey(pubKey) {
+		return nil, store.ErrKeyNotFound
+	}
+
+	secret, err := s.client.CoreV1().Secrets(s.namespace).Get(
+		ctx,
+		s.secretName(pubKey),
+		metav1.GetOptions{},
+	)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, store.ErrKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get secret: %w", err)
+	}
+	if secret.Labels[ManagedByLabel] != ManagedByValue {
+		s.logger.Warn("Secret named after a key isn't managed by cerberus", "secret", secret.Name)
+		return nil, store.ErrKeyNotFound
+	}
+	return secret, nil
+}
+
+// secretLabels returns the labels of the secrets
+func (s *Store) secretLabels() map[string]string {
+	secretLabels := make(map[string]string, len(s.labels)+1)
+	for key, value := range s.labels {
+		secretLabels[key] = value
+	}
+	secretLabels[ManagedByLabel] = ManagedByValue
+	return secretLabels
+}
+
+func (s *Store) secretName(pubKey string) string {
+	return s.prefix + pubKey
+}
+
+// getPubKey extracts the public key from the name of a secret
+func (s *Store) getPubKey(name string) (string, bool) {
+	pubKey, ok := strings.CutPrefix(name, s.prefix)
+	return pubKey, ok && isPubKey(pubKey)
+}
+
+// isPubKey reports whether the string is a hex encoded compressed G1 public
+// key, the secret names can't hold anything else
+func isPubKey(pubKey string) bool {
+	if len(pubKey) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(pubKey)
+	return err == nil && strings.ToLower(pubKey) == pubKey
+}
+
+// encodeKey returns the data of the secret holding the key: the raw private
+// key or a keystore encrypted with the password of the key pair
+func encodeKey(keyPair *keystore.KeyPair, encrypt bool) (map[string][]byte, error) {
+	if !encrypt {
+		return map[string][]byte{privateKeyField: keyPair.PrivateKey}, nil
+	}
+
+	ks, err := store.EncryptKeystore(keyPair)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{keystoreField: ks}, nil
+}
+
+// decodeKey reads the key from the data written by encodeKey
+func decodeKey(secret *corev1.Secret, password string) (*crypto.KeyPair, error) {
+	if ks, ok := secret.Data[keystoreField]; ok {
+		return store.DecryptKeystore(ks, password)
+	}
+
+	skBytes, ok := secret.Data[privateKeyField]
+	if !ok {
+		return nil, errors.New("secret has no private key")
+	}
+	return crypto.NewKeyPairFromHexString(hex.EncodeToString(skBytes))
+}
@@ -0,0 +1,166 @@
+package k8ssecrets
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/storetest"
+)
+
+const (
+	testPassword  = "p@$$w0rd"
+	testNamespace = "signers"
+)
+
+func createSecret(t *testing.T, s *Store, name string, labels map[string]string) {
+	_, err := s.client.CoreV1().Secrets(s.namespace).Create(
+		context.Background(),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: s.namespace, Labels: labels},
+			Data:       map[string][]byte{privateKeyField: []byte("not a key")},
+		},
+		metav1.CreateOptions{},
+	)
+	require.NoError(t, err)
+}
+
+func TestStoreConformance(t *testing.T) {
+	logger := testutils.GetTestLogger()
+	opts := storetest.Options{
+		IgnoresPassword: true,
+		AddForeignEntry: func(t *testing.T, s store.Store) {
+			ks := s.(*Store)
+			managed := map[string]string{ManagedByLabel: ManagedByValue}
+			createSecret(t, ks, "cerberus-config", managed)
+			createSecret(t, ks, "other-"+strings.Repeat("ab", 32), managed)
+			createSecret(t, ks, DefaultPrefix+strings.Repeat("cd", 32), nil)
+		},
+	}
+
+	t.Run("Raw", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) store.Store {
+			return NewStore(fake.NewSimpleClientset(), testNamespace, logger)
+		}, opts)
+	})
+
+	opts.IgnoresPassword = false
+	t.Run("Keystore", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) store.Store {
+			return NewStore(fake.NewSimpleClientset(), testNamespace, logger).
+				WithKeystoreEncryption(true)
+		}, opts)
+	})
+}
+
+func TestSecretMetadata(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	s := NewStore(client, testNamespace, testutils.GetTestLogger()).
+		WithPrefix("staging-").
+		WithLabels(map[string]string{"team": "avs", ManagedByLabel: "someone-else"})
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	pubKey, err := s.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	secret, err := client.CoreV1().Secrets(testNamespace).Get(
+		ctx,
+		"staging-"+pubKey,
+		metav1.GetOptions{},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "avs", ManagedByLabel: ManagedByValue}, secret.Labels)
+	assert.Equal(t, pubKey, secret.Annotations[PublicKeyAnnotation])
+	assert.Equal(t, corev1.SecretTypeOpaque, secret.Type)
+	assert.Equal(t, keyPair.PrivateKey, secret.Data[privateKeyField])
+
+	// A deployment with another prefix doesn't see the key
+	other := NewStore(client, testNamespace, testutils.GetTestLogger())
+	keys, err := other.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	keys, err = s.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{pubKey}, keys)
+}
+
+func TestUnmanagedSecretsAreNotKeys(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore(fake.NewSimpleClientset(), testNamespace, testutils.GetTestLogger())
+
+	pubKey := strings.Repeat("ab", 32)
+	createSecret(t, s, DefaultPrefix+pubKey, map[string]string{"app": "other"})
+
+	_, err := s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+	assert.ErrorIs(t, s.DeleteKey(ctx, pubKey), store.ErrKeyNotFound)
+
+	_, err = s.client.CoreV1().Secrets(testNamespace).Get(
+		ctx,
+		DefaultPrefix+pubKey,
+		metav1.GetOptions{},
+	)
+	assert.NoError(t, err, "unmanaged secret must not be deleted")
+
+	for _, invalid := range []string{"", "../" + pubKey, strings.ToUpper(pubKey)} {
+		_, err = s.RetrieveKey(ctx, invalid, testPassword)
+		assert.ErrorIs(t, err, store.ErrKeyNotFound, invalid)
+	}
+}
+
+func TestMigrateToKeystore(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	s := NewStore(client, testNamespace, testutils.GetTestLogger())
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	pubKey, err := s.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	migrated, err := s.MigrateToKeystore(ctx, pubKey, testPassword)
+	require.NoError(t, err)
+	assert.True(t, migrated)
+
+	secret, err := client.CoreV1().Secrets(testNamespace).Get(
+		ctx,
+		DefaultPrefix+pubKey,
+		metav1.GetOptions{},
+	)
+	require.NoError(t, err)
+	assert.NotContains(t, secret.Data, privateKeyField)
+	assert.True(t, store.IsKeystore(secret.Data[keystoreField]))
+
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.NoError(t, err)
+	_, err = s.RetrieveKey(ctx, pubKey, "wrong password")
+	assert.ErrorIs(t, err, store.ErrInvalidPassword)
+
+	migrated, err = s.MigrateToKeystore(ctx, pubKey, testPassword)
+	require.NoError(t, err)
+	assert.False(t, migrated)
+}
+
+func TestTransient(t *testing.T) {
+	s := &Store{}
+	secrets := schema.GroupResource{Resource: "secrets"}
+	assert.True(t, s.Transient(apierrors.NewTooManyRequests("slow down", 1)))
+	assert.True(t, s.Transient(apierrors.NewServiceUnavailable("unavailable")))
+	assert.False(t, s.Transient(apierrors.NewForbidden(secrets, "key", errors.New("denied"))))
+	assert.False(t, s.Transient(store.ErrKeyNotFound))
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Layr-Labs/bn254-keystore-go/curve"
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+)
+
+// KeystoreMigrator is implemented by the stores which can re-encrypt the keys
+// they hold in plaintext as password encrypted keystores
+type KeystoreMigrator interface {
+	// MigrateToKeystore encrypts the stored key with the password
+	// Returns false without changing anything if the key is already encrypted
+	// Returns ErrKeyNotFound if the key is not in the store
+	MigrateToKeystore(ctx context.Context, pubKey string, password string) (bool, error)
+}
+
+// EncryptKeystore encrypts the private key with the password of the key pair
+// into the same EIP-2335 keystore JSON the filesystem store writes
+func EncryptKeystore(keyPair *keystore.KeyPair) ([]byte, error) {
+	ks, err := keyPair.Encrypt(keystore.KDFScrypt, curve.BN254)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(ks)
+}
+
+// IsKeystore reports whether the secret is a keystore JSON rather than a raw
+// private key
+func IsKeystore(secret []byte) bool {
+	trimmed := bytes.TrimSpace(secret)
+	return len(trimmed) > 32 && trimmed[0] == '{' && json.Valid(trimmed)
+}
+
+// DecryptKeystore decrypts a keystore JSON with the password
+func DecryptKeystore(secret []byte, password string) (*crypto.KeyPair, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(secret, &data); err != nil {
+		return nil, err
+	}
+
+	ks := new(keystore.Keystore)
+	if err := ks.FromJSON(data); err != nil {
+		return nil, err
+	}
+
+	skBytes, err := DecryptPrivateKey(ks, password)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(skBytes)
+
+	privKey, err := crypto.PrivateKeyFromBytes(skBytes)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.NewKeyPair(privKey), nil
+}
+
+// DecryptPrivateKey decrypts the private key of the keystore with the password
+// Returns ErrInvalidPassword if the password doesn't match the keystore
+func DecryptPrivateKey(ks *keystore.Keystore, password string) ([]byte, error) {
+	skBytes, err := ks.Decrypt(password)
+	if err != nil {
+		// The keystore package has no sentinel for a wrong password, it fails
+		// the checksum verification with this message
+		if err.Error() == "checksum message error" {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPassword, err)
+		}
+		return nil, err
+	}
+	return skBytes, nil
+}
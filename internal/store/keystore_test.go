@@ -0,0 +1,42 @@
+package store
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+)
+
+func TestKeystoreRoundTrip(t *testing.T) {
+	skHex := "040ad69253b921aca71dd714cccc3095576fbe1a21f86c9b10cb5b119b1c6899"
+	skBytes, err := hex.DecodeString(skHex)
+	require.NoError(t, err)
+
+	secret, err := EncryptKeystore(&keystore.KeyPair{PrivateKey: skBytes, Password: "p@ssw0rd"})
+	require.NoError(t, err)
+	assert.True(t, IsKeystore(secret))
+	assert.NotContains(t, string(secret), skHex)
+
+	kp, err := DecryptKeystore(secret, "p@ssw0rd")
+	require.NoError(t, err)
+	assert.Equal(t, skBytes, crypto.PrivateKeyBytes(kp.PrivKey))
+
+	_, err = DecryptKeystore(secret, "wrong")
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+}
+
+func TestIsKeystore(t *testing.T) {
+	// A raw private key starting with '{' is not a keystore
+	raw := make([]byte, 32)
+	raw[0] = '{'
+	assert.False(t, IsKeystore(raw))
+
+	skHex := "040ad69253b921aca71dd714cccc3095576fbe1a21f86c9b10cb5b119b1c6899"
+	assert.False(t, IsKeystore([]byte(skHex)))
+	assert.False(t, IsKeystore([]byte(`{"crypto": "truncated`)))
+	assert.False(t, IsKeystore(nil))
+}
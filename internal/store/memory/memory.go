@@ -0,0 +1,228 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/Layr-Labs/bn254-keystore-go/curve"
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+var (
+	_ store.Store           = (*Store)(nil)
+	_ store.PasswordChecker = (*Store)(nil)
+)
+
+// Store keeps the keys in memory, for development and tests. The keys are
+// lost on restart unless a snapshot file is set, in which case the keys are
+// restored from it and it is rewritten after every change.
+// The snapshot holds the private keys in plaintext.
+type Store struct {
+	mu           sync.RWMutex
+	keys         map[string]*entry
+	snapshotFile string
+
+	logger *slog.Logger
+}
+
+// entry is a stored key as it appears in the snapshot
+type entry struct {
+	PrivateKey string `json:"private_key"`
+
+	// PasswordHash is the SHA-256 of the password the key was stored with
+	PasswordHash string `json:"password_hash"`
+}
+
+// NewStore returns an empty store, or the store restored from the snapshot
+// file if it exists
+func NewStore(snapshotFile string, logger *slog.Logger) (*Store, error) {
+	s := &Store{
+		keys:         make(map[string]*entry),
+		snapshotFile: snapshotFile,
+		logger:       logger.With("component", "memory-store"),
+	}
+	if snapshotFile == "" {
+		return s, nil
+	}
+
+	f, err := os.Open(snapshotFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := s.Restore(f); err != nil {
+		return nil, fmt.Errorf("failed to restore snapshot %s: %w", snapshotFile, err)
+	}
+	s.logger.Info(fmt.Sprintf("Restored %d keys from snapshot", len(s.keys)), "file", snapshotFile)
+	return s, nil
+}
+
+// ChecksPassword returns true, the hash of the password is kept with the key
+func (s *Store) ChecksPassword() bool {
+	return true
+}
+
+func (s *Store) RetrieveKey(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (*crypto.KeyPair, error) {
+	s.mu.RLock()
+	e, ok := s.keys[pubKey]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, store.ErrKeyNotFound
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashPassword(password)), []byte(e.PasswordHash)) != 1 {
+		return nil, store.ErrInvalidPassword
+	}
+
+	return crypto.NewKeyPairFromHexString(e.PrivateKey)
+}
+
+func (s *Store) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (string, error) {
+	pubKey, err := keystore.BlsSkToG1Pk(keyPair.PrivateKey, string(curve.BN254))
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.keys[pubKey]; ok {
+		return "", store.ErrKeyAlreadyExists
+	}
+	s.keys[pubKey] = &entry{
+		PrivateKey:   hex.EncodeToString(keyPair.PrivateKey),
+		PasswordHash: hashPassword(keyPair.Password),
+	}
+
+	if err := s.saveSnapshot(); err != nil {
+		delete(s.keys, pubKey)
+		return "", err
+	}
+	return pubKey, nil
+}
+
+func (s *Store) ListKeys(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pubKeys := make([]string, 0, len(s.keys))
+	for pubKey := range s.keys {
+		pubKeys = append(pubKeys, pubKey)
+	}
+	sort.Strings(pubKeys)
+	return pubKeys, nil
+}
+
+func (s *Store) DeleteKey(ctx context.Context, pubKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.keys[pubKey]
+	if !ok {
+		return store.ErrKeyNotFound
+	}
+	delete(s.keys, pubKey)
+
+	if err := s.saveSnapshot(); err != nil {
+		s.keys[pubKey] = e
+		return err
+	}
+	return nil
+}
+
+// Snapshot writes every key of the store as JSON
+func (s *Store) Snapshot(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.writeSnapshot(w)
+}
+
+// Restore replaces the keys of the store with the keys of a snapshot
+func (s *Store) Restore(r io.Reader) error {
+	keys := make(map[string]*entry)
+	if err := json.NewDecoder(r).Decode(&keys); err != nil {
+		return err
+	}
+	for pubKey, e := range keys {
+		if e == nil {
+			return fmt.Errorf("empty entry for key %s", pubKey)
+		}
+		kp, err := crypto.NewKeyPairFromHexString(e.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("invalid private key for key %s: %w", pubKey, err)
+		}
+		g1 := kp.PubKey.Bytes()
+		if hex.EncodeToString(g1[:]) != pubKey {
+			return fmt.Errorf("private key doesn't match key %s", pubKey)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+	return nil
+}
+
+func (s *Store) writeSnapshot(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s.keys)
+}
+
+// saveSnapshot rewrites the snapshot file, if any. The new snapshot is
+// written to a temporary file first so a crash never leaves a partial one.
+func (s *Store) saveSnapshot() error {
+	if s.snapshotFile == "" {
+		return nil
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.snapshotFile), ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := f.Chmod(0600); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := s.writeSnapshot(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.snapshotFile)
+}
+
+func hashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/storetest"
+)
+
+const testPassword = "p@$$w0rd"
+
+func TestStore(t *testing.T) {
+	s, err := NewStore("", testutils.GetTestLogger())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+
+	pubKey, err := s.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	_, err = s.StoreKey(ctx, keyPair)
+	assert.ErrorIs(t, err, store.ErrKeyAlreadyExists)
+
+	kp, err := s.RetrieveKey(ctx, pubKey, testPassword)
+	require.NoError(t, err)
+	sk := kp.PrivKey.Bytes()
+	assert.Equal(t, hex.EncodeToString(keyPair.PrivateKey), hex.EncodeToString(sk[:]))
+
+	_, err = s.RetrieveKey(ctx, pubKey, "wrong")
+	assert.ErrorIs(t, err, store.ErrInvalidPassword)
+
+	keys, err := s.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{pubKey}, keys)
+
+	require.NoError(t, s.DeleteKey(ctx, pubKey))
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+	assert.ErrorIs(t, s.DeleteKey(ctx, pubKey), store.ErrKeyNotFound)
+}
+
+func TestStoreConformance(t *testing.T) {
+	logger := testutils.GetTestLogger()
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s, err := NewStore(filepath.Join(t.TempDir(), "keys.json"), logger)
+		require.NoError(t, err)
+		return s
+	}, storetest.Options{})
+}
+
+func TestStoreSnapshotFile(t *testing.T) {
+	snapshotFile := filepath.Join(t.TempDir(), "keys.json")
+	logger := testutils.GetTestLogger()
+	ctx := context.Background()
+
+	s, err := NewStore(snapshotFile, logger)
+	require.NoError(t, err)
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	pubKey, err := s.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	info, err := os.Stat(snapshotFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// A new store restores the keys from the snapshot
+	restored, err := NewStore(snapshotFile, logger)
+	require.NoError(t, err)
+	_, err = restored.RetrieveKey(ctx, pubKey, testPassword)
+	require.NoError(t, err)
+
+	require.NoError(t, restored.DeleteKey(ctx, pubKey))
+	restored, err = NewStore(snapshotFile, logger)
+	require.NoError(t, err)
+	keys, err := restored.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestStoreRestoreRejectsMismatchingKeys(t *testing.T) {
+	s, err := NewStore("", testutils.GetTestLogger())
+	require.NoError(t, err)
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	pubKey, err := s.StoreKey(context.Background(), keyPair)
+	require.NoError(t, err)
+
+	var snapshot bytes.Buffer
+	require.NoError(t, s.Snapshot(&snapshot))
+	require.NoError(t, s.Restore(bytes.NewReader(snapshot.Bytes())))
+
+	otherPubKey := "0" + pubKey[1:]
+	if pubKey[0] == '0' {
+		otherPubKey = "1" + pubKey[1:]
+	}
+	tampered := bytes.Replace(snapshot.Bytes(), []byte(pubKey), []byte(otherPubKey), 1)
+	assert.Error(t, s.Restore(bytes.NewReader(tampered)))
+
+	// The store is unchanged by a failed restore
+	_, err = s.RetrieveKey(context.Background(), pubKey, testPassword)
+	assert.NoError(t, err)
+}
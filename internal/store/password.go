@@ -0,0 +1,17 @@
+package store
+
+// PasswordChecker is implemented by the stores which encrypt the keys with
+// their password, and check it when retrieving them
+type PasswordChecker interface {
+	// ChecksPassword reports whether RetrieveKey fails with ErrInvalidPassword
+	// when the password doesn't match the key
+	ChecksPassword() bool
+}
+
+// ChecksPassword reports whether s, or the store it decorates, checks the
+// password of the keys. The stores which don't implement PasswordChecker
+// return the keys whatever the password.
+func ChecksPassword(s Store) bool {
+	checker, ok := As[PasswordChecker](s)
+	return ok && checker.ChecksPassword()
+}
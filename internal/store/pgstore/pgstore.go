@@ -0,0 +1,269 @@
+package pgstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/Layr-Labs/bn254-keystore-go/curve"
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/database/repository/postgres"
+	"github.com/Layr-Labs/cerberus/internal/seal"
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+var (
+	_ store.Store               = (*Store)(nil)
+	_ store.AtomicStorer        = (*Store)(nil)
+	_ store.PasswordChecker     = (*Store)(nil)
+	_ store.TransientClassifier = (*Store)(nil)
+)
+
+const (
+	storeKeyQuery = `
+        INSERT INTO public.key_store (public_key_g1, payload, created_at)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (public_key_g1) DO NOTHING
+    `
+
+	retrieveKeyQuery = `
+        SELECT payload
+        FROM public.key_store
+        WHERE public_key_g1 = $1
+    `
+
+	listKeysQuery = `
+        SELECT public_key_g1
+        FROM public.key_store
+        ORDER BY public_key_g1
+    `
+
+	deleteKeyQuery = `
+        DELETE FROM public.key_store
+        WHERE public_key_g1 = $1
+    `
+)
+
+// Store keeps the keys in the key_store table of the Postgres database of the
+// key metadata. The keys are always stored as EIP-2335 keystores encrypted
+// with the password of the key, envelope encrypted when a master key is set.
+type Store struct {
+	db        *sql.DB
+	masterKey *seal.Keeper
+
+	logger *slog.Logger
+}
+
+func NewStore(db *sql.DB, logger *slog.Logger) *Store {
+	return &Store{
+		db:     db,
+		logger: logger.With("component", "postgres-store"),
+	}
+}
+
+// WithMasterKey envelope encrypts the keys stored from now on with data keys
+// wrapped by the master key. Keys stored without it stay readable.
+func (s *Store) WithMasterKey(keeper *seal.Keeper) *Store {
+	s.masterKey = keeper
+	return s
+}
+
+// ChecksPassword returns true, the keys are encrypted keystores
+func (s *Store) ChecksPassword() bool {
+	return true
+}
+
+// Transient reports the broken connections, and the Postgres errors of
+// connection failures, lack of resources, shutdowns and serialization failures
+func (s *Store) Transient(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code.Class() {
+	case "08", "53", "57":
+		return true
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
+
+func (s *Store) RetrieveKey(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (*crypto.KeyPair, error) {
+	var payload []byte
+	err := s.db.QueryRowContext(ctx, retrieveKeyQuery, pubKey).Scan(&payload)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve key: %w", err)
+	}
+
+	return s.decodeKey(pubKey, payload, password)
+}
+
+func (s *Store) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (string, error) {
+	pubKey, payload, err := s.encodeKey(keyPair)
+	if err != nil {
+		return "", err
+	}
+
+	if err := insertKey(ctx, s.db, pubKey, payload); err != nil {
+		return "", err
+	}
+	return pubKey, nil
+}
+
+// StoreKeyAtomically stores the key and runs record in the same transaction,
+// record sees the transaction through postgres.WithTx
+func (s *Store) StoreKeyAtomically(
+	ctx context.Context,
+	keyPair *keystore.KeyPair,
+	record func(ctx context.Context, pubKey string) error,
+) (string, error) {
+	pubKey, payload, err := s.encodeKey(keyPair)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		// No-op once the transaction is committed
+		_ = tx.Rollback()
+	}()
+
+	if err := insertKey(ctx, tx, pubKey, payload); err != nil {
+		return "", err
+	}
+	if err := record(postgres.WithTx(ctx, tx), pubKey); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return pubKey, nil
+}
+
+func (s *Store) ListKeys(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, listKeysQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0)
+	for rows.Next() {
+		var pubKey string
+		if err := rows.Scan(&pubKey); err != nil {
+			return nil, fmt.Errorf("failed to scan key: %w", err)
+		}
+		keys = append(keys, pubKey)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	s.logger.Debug(fmt.Sprintf("Found %d keys", len(keys)))
+	return keys, nil
+}
+
+func (s *Store) DeleteKey(ctx context.Context, pubKey string) error {
+	result, err := s.db.ExecContext(ctx, deleteKeyQuery, pubKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete key: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete key: %w", err)
+	}
+	if rows == 0 {
+		return store.ErrKeyNotFound
+	}
+	return nil
+}
+
+// execer is implemented by sql.DB and sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// insertKey inserts the payload of the key unless the key is already stored
+func insertKey(ctx context.Context, db execer, pubKey string, payload []byte) error {
+	result, err := db.ExecContext(ctx, storeKeyQuery, pubKey, payload, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to store key: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to store key: %w", err)
+	}
+	if rows == 0 {
+		return store.ErrKeyAlreadyExists
+	}
+	return nil
+}
+
+// encodeKey returns the public key and the payload holding the key: a
+// keystore encrypted with the password of the key pair, envelope encrypted
+// when a master key is set
+func (s *Store) encodeKey(keyPair *keystore.KeyPair) (string, []byte, error) {
+	pubKey, err := keystore.BlsSkToG1Pk(keyPair.PrivateKey, string(curve.BN254))
+	if err != nil {
+		return "", nil, err
+	}
+
+	payload, err := store.EncryptKeystore(keyPair)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if s.masterKey != nil {
+		payload, err = s.masterKey.Encrypt(payload, []byte(pubKey))
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	return pubKey, payload, nil
+}
+
+// decodeKey reads the key from a payload written by encodeKey
+func (s *Store) decodeKey(
+	pubKey string,
+	payload []byte,
+	password string,
+) (*crypto.KeyPair, error) {
+	if seal.IsEnvelope(payload) {
+		if s.masterKey == nil {
+			return nil, errors.New("key is envelope encrypted but no master key is configured")
+		}
+		plaintext, err := s.masterKey.Decrypt(payload, []byte(pubKey))
+		if err != nil {
+			return nil, err
+		}
+		defer clear(plaintext)
+		payload = plaintext
+	} else if s.masterKey != nil {
+		s.logger.Warn("Key is stored without envelope encryption", "pubKey", pubKey)
+	}
+
+	return store.DecryptKeystore(payload, password)
+}
@@ -0,0 +1,133 @@
+package pgstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lib/pq"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository/postgres"
+	"github.com/Layr-Labs/cerberus/internal/seal"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/storetest"
+)
+
+const testPassword = "p@$$w0rd"
+
+// setupTestDB starts a Postgres container with the key_store and key
+// metadata tables
+func setupTestDB(t *testing.T) *sql.DB {
+	container, err := postgres.CreateTestContainer(t)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = container.DB.Close()
+		_ = container.Container.Terminate(context.Background())
+	})
+	return container.DB
+}
+
+func newMasterKey(t *testing.T) *seal.Keeper {
+	key, err := seal.GenerateKey()
+	require.NoError(t, err)
+	keeper, err := seal.NewKeeper(key, testutils.GetTestLogger())
+	require.NoError(t, err)
+	return keeper
+}
+
+func TestWithContainer_PostgresStore(t *testing.T) {
+	db := setupTestDB(t)
+	logger := testutils.GetTestLogger()
+
+	t.Run("Conformance", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) store.Store {
+			_, err := db.Exec("TRUNCATE public.key_store")
+			require.NoError(t, err)
+			return NewStore(db, logger)
+		}, storetest.Options{})
+	})
+
+	t.Run("MasterKey", func(t *testing.T) {
+		ctx := context.Background()
+		_, err := db.Exec("TRUNCATE public.key_store")
+		require.NoError(t, err)
+		s := NewStore(db, logger).WithMasterKey(newMasterKey(t))
+
+		keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+		require.NoError(t, err)
+		pubKey, err := s.StoreKey(ctx, keyPair)
+		require.NoError(t, err)
+
+		var payload []byte
+		err = db.QueryRow(retrieveKeyQuery, pubKey).Scan(&payload)
+		require.NoError(t, err)
+		assert.True(t, seal.IsEnvelope(payload))
+
+		_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+		assert.NoError(t, err)
+		_, err = NewStore(db, logger).RetrieveKey(ctx, pubKey, testPassword)
+		assert.Error(t, err)
+	})
+
+	t.Run("StoreKeyAtomically", func(t *testing.T) {
+		ctx := context.Background()
+		_, err := db.Exec("TRUNCATE public.key_store, public.keys_metadata")
+		require.NoError(t, err)
+		s := NewStore(db, logger)
+		repo := postgres.NewKeyMetadataRepository(db)
+
+		keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+		require.NoError(t, err)
+
+		// The key isn't stored when the metadata fails
+		recordErr := errors.New("record failed")
+		_, err = s.StoreKeyAtomically(ctx, keyPair, func(ctx context.Context, pubKey string) error {
+			require.NoError(t, repo.Create(ctx, &model.KeyMetadata{
+				PublicKeyG1: pubKey,
+				PublicKeyG2: "g2",
+			}))
+			return recordErr
+		})
+		assert.ErrorIs(t, err, recordErr)
+		keys, err := s.ListKeys(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+		metadata, err := repo.List(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, metadata, "metadata must be rolled back with the key")
+
+		createMetadata := func(ctx context.Context, pubKey string) error {
+			return repo.Create(ctx, &model.KeyMetadata{PublicKeyG1: pubKey, PublicKeyG2: "g2"})
+		}
+		pubKey, err := s.StoreKeyAtomically(ctx, keyPair, createMetadata)
+		require.NoError(t, err)
+		_, err = repo.Get(ctx, pubKey)
+		assert.NoError(t, err)
+		_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+		assert.NoError(t, err)
+
+		_, err = s.StoreKeyAtomically(ctx, keyPair, func(ctx context.Context, pubKey string) error {
+			t.Fatal("record must not be called for a stored key")
+			return nil
+		})
+		assert.ErrorIs(t, err, store.ErrKeyAlreadyExists)
+	})
+}
+
+func TestTransient(t *testing.T) {
+	s := &Store{}
+	assert.True(t, s.Transient(&pq.Error{Code: "08006"}))
+	assert.True(t, s.Transient(&pq.Error{Code: "40P01"}))
+	assert.True(t, s.Transient(driver.ErrBadConn))
+	assert.False(t, s.Transient(&pq.Error{Code: "23505"}))
+	assert.False(t, s.Transient(store.ErrKeyNotFound))
+}
@@ -0,0 +1,337 @@
+// Package plugin implements a store.Store backed by an out-of-process store
+// plugin speaking the storeplugin protocol, and the adapter serving any
+// store.Store as a plugin.
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/bn254-keystore-go/curve"
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/pkg/storeplugin"
+)
+
+var (
+	_ store.Store           = (*Store)(nil)
+	_ store.PasswordChecker = (*Store)(nil)
+)
+
+const (
+	// DefaultStartTimeout is the time a launched plugin has to report it is
+	// serving
+	DefaultStartTimeout = 10 * time.Second
+
+	// healthPollInterval is the interval of the health checks of a launched
+	// plugin until it is serving
+	healthPollInterval = 100 * time.Millisecond
+
+	// socketName is the name of the socket of a launched plugin, in a
+	// temporary directory only readable by cerberus
+	socketName = "plugin.sock"
+)
+
+type Config struct {
+	// Address of a running plugin, unix:///path/to/socket or a loopback
+	// host:port
+	Address string
+
+	// Command launches the plugin, its first element is the executable. The
+	// plugin must listen on the Unix socket of the storeplugin.AddressEnv
+	// environment variable. Address is ignored when it is set.
+	Command []string
+
+	// StartTimeout defaults to DefaultStartTimeout
+	StartTimeout time.Duration
+}
+
+type Store struct {
+	conn   *grpc.ClientConn
+	client *storeplugin.Client
+
+	name         string
+	capabilities []string
+
+	// cmd is the process of the plugin and socketDir the directory of its
+	// socket, when the plugin was launched by the store
+	cmd       *exec.Cmd
+	exited    chan struct{}
+	socketDir string
+	closeOnce sync.Once
+
+	logger *slog.Logger
+}
+
+// NewStore connects to the plugin, or launches it, and checks it implements
+// the protocol version of cerberus
+func NewStore(ctx context.Context, config Config, logger *slog.Logger) (*Store, error) {
+	s := &Store{logger: logger.With("component", "plugin-store")}
+
+	address := config.Address
+	if len(config.Command) > 0 {
+		var err error
+		address, err = s.launch(config.Command)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, _, err := storeplugin.ParseAddress(address); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	conn, err := grpc.NewClient(
+		address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to create plugin client: %w", err)
+	}
+	s.conn = conn
+	s.client = storeplugin.NewClient(conn)
+
+	if s.cmd != nil {
+		startTimeout := config.StartTimeout
+		if startTimeout == 0 {
+			startTimeout = DefaultStartTimeout
+		}
+		if err := s.waitServing(ctx, startTimeout); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+
+	if err := s.checkCapabilities(ctx); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the connection to the plugin and stops the plugin if it was
+// launched by the store
+func (s *Store) Close() {
+	s.closeOnce.Do(func() {
+		if s.conn != nil {
+			_ = s.conn.Close()
+		}
+		if s.cmd != nil {
+			_ = s.cmd.Process.Kill()
+			<-s.exited
+		}
+		if s.socketDir != "" {
+			_ = os.RemoveAll(s.socketDir)
+		}
+	})
+}
+
+// Name returns the name the plugin reported
+func (s *Store) Name() string {
+	return s.name
+}
+
+// HasCapability reports whether the plugin reported the capability
+func (s *Store) HasCapability(capability string) bool {
+	return slices.Contains(s.capabilities, capability)
+}
+
+// ChecksPassword reports whether the plugin has the password encryption
+// capability
+func (s *Store) ChecksPassword() bool {
+	return s.HasCapability(storeplugin.CapabilityPasswordEncryption)
+}
+
+// Health returns an error unless the plugin reports it is serving
+func (s *Store) Health(ctx context.Context) error {
+	resp, err := s.client.Health(ctx)
+	if err != nil {
+		return fmt.Errorf("plugin health check failed: %w", err)
+	}
+	if resp.Status != storeplugin.StatusServing {
+		return fmt.Errorf("plugin is %s: %s", resp.Status, resp.Message)
+	}
+	return nil
+}
+
+func (s *Store) RetrieveKey(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (*crypto.KeyPair, error) {
+	resp, err := s.client.RetrieveKey(ctx, &storeplugin.RetrieveKeyRequest{
+		PublicKey: pubKey,
+		Password:  password,
+	})
+	if err != nil {
+		return nil, fromStatus("retrieve key", err)
+	}
+	defer clear(resp.PrivateKey)
+
+	sk, err := crypto.PrivateKeyFromBytes(resp.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("plugin returned an invalid private key: %w", err)
+	}
+
+	// A key other than the requested one must never be used for signing
+	g1PubKey, err := keystore.BlsSkToG1Pk(crypto.PrivateKeyBytes(sk), string(curve.BN254))
+	if err != nil {
+		return nil, fmt.Errorf("plugin returned an invalid private key: %w", err)
+	}
+	if g1PubKey != pubKey {
+		return nil, errors.New("plugin returned the private key of another public key")
+	}
+	return crypto.NewKeyPair(sk), nil
+}
+
+func (s *Store) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (string, error) {
+	pubKey, err := keystore.BlsSkToG1Pk(keyPair.PrivateKey, string(curve.BN254))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.client.StoreKey(ctx, &storeplugin.StoreKeyRequest{
+		PublicKey:  pubKey,
+		PrivateKey: keyPair.PrivateKey,
+		Password:   keyPair.Password,
+	})
+	if err != nil {
+		return "", fromStatus("store key", err)
+	}
+	return pubKey, nil
+}
+
+func (s *Store) ListKeys(ctx context.Context) ([]string, error) {
+	resp, err := s.client.ListKeys(ctx)
+	if err != nil {
+		return nil, fromStatus("list keys", err)
+	}
+
+	keys := resp.PublicKeys
+	if keys == nil {
+		keys = make([]string, 0)
+	}
+	s.logger.Debug(fmt.Sprintf("Found %d keys", len(keys)))
+	return keys, nil
+}
+
+func (s *Store) DeleteKey(ctx context.Context, pubKey string) error {
+	if !s.HasCapability(storeplugin.CapabilityDeleteKey) {
+		return fmt.Errorf("plugin %s doesn't support deleting keys", s.name)
+	}
+
+	_, err := s.client.DeleteKey(ctx, &storeplugin.DeleteKeyRequest{PublicKey: pubKey})
+	if err != nil {
+		return fromStatus("delete key", err)
+	}
+	return nil
+}
+
+// launch starts the plugin listening on a socket in a new temporary directory
+// and returns the address of the socket
+func (s *Store) launch(command []string) (string, error) {
+	socketDir, err := os.MkdirTemp("", "cerberus-plugin-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create plugin socket directory: %w", err)
+	}
+	s.socketDir = socketDir
+	address := "unix://" + filepath.Join(socketDir, socketName)
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = append(os.Environ(), storeplugin.AddressEnv+"="+address)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	setProcAttr(cmd)
+	if err := cmd.Start(); err != nil {
+		_ = os.RemoveAll(socketDir)
+		return "", fmt.Errorf("failed to launch plugin: %w", err)
+	}
+	s.cmd = cmd
+	s.exited = make(chan struct{})
+
+	go func() {
+		err := cmd.Wait()
+		close(s.exited)
+		s.logger.Warn("Plugin exited", "error", err)
+	}()
+
+	s.logger.Info("Launched plugin", "command", command[0], "pid", cmd.Process.Pid)
+	return address, nil
+}
+
+// waitServing polls the health of the launched plugin until it is serving
+func (s *Store) waitServing(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(healthPollInterval)
+	defer ticker.Stop()
+	for {
+		err := s.Health(ctx)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.exited:
+			return errors.New("plugin exited before serving")
+		case <-ctx.Done():
+			return fmt.Errorf("plugin isn't serving after %s: %w", timeout, err)
+		}
+	}
+}
+
+// checkCapabilities reads the capabilities of the plugin and checks its
+// protocol version
+func (s *Store) checkCapabilities(ctx context.Context) error {
+	resp, err := s.client.Capabilities(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get plugin capabilities: %w", err)
+	}
+	if resp.ProtocolVersion != storeplugin.ProtocolVersion {
+		return fmt.Errorf(
+			"plugin %s implements protocol version %d, expected %d",
+			resp.Name,
+			resp.ProtocolVersion,
+			storeplugin.ProtocolVersion,
+		)
+	}
+
+	s.name = resp.Name
+	s.capabilities = resp.Capabilities
+	if !s.HasCapability(storeplugin.CapabilityPasswordEncryption) {
+		s.logger.Warn("Plugin doesn't encrypt the keys with their password", "plugin", s.name)
+	}
+	s.logger.Info("Connected to plugin", "plugin", s.name, "capabilities", s.capabilities)
+	return nil
+}
+
+// fromStatus maps the status codes of the protocol to the store errors
+func fromStatus(op string, err error) error {
+	switch status.Code(err) {
+	case codes.NotFound:
+		return store.ErrKeyNotFound
+	case codes.AlreadyExists:
+		return store.ErrKeyAlreadyExists
+	case codes.PermissionDenied:
+		return store.ErrInvalidPassword
+	default:
+		return fmt.Errorf("plugin failed to %s: %w", op, err)
+	}
+}
@@ -0,0 +1,231 @@
+package plugin_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/filesystem"
+	"github.com/Layr-Labs/cerberus/internal/store/memory"
+	"github.com/Layr-Labs/cerberus/internal/store/plugin"
+	"github.com/Layr-Labs/cerberus/pkg/storeplugin"
+	"github.com/Layr-Labs/cerberus/pkg/storeplugin/plugintest"
+)
+
+const (
+	testPassword = "p@$$w0rd"
+
+	// keystoreDirEnv is the keystore directory of the test binary launched
+	// as a plugin
+	keystoreDirEnv = "PLUGIN_TEST_KEYSTORE_DIR"
+)
+
+// TestMain serves the filesystem store when the test binary is launched as a
+// plugin by the tests
+func TestMain(m *testing.M) {
+	if os.Getenv(storeplugin.AddressEnv) != "" {
+		fileStore := filesystem.NewStore(os.Getenv(keystoreDirEnv), testutils.GetTestLogger())
+		srv := plugin.NewServer("test", fileStore, storeplugin.CapabilityPasswordEncryption)
+		if err := storeplugin.Serve(context.Background(), "", srv); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestPluginConformance(t *testing.T) {
+	logger := testutils.GetTestLogger()
+
+	t.Run("Address", func(t *testing.T) {
+		plugintest.Run(t, func(t *testing.T) plugintest.Plugin {
+			s, err := memory.NewStore("", logger)
+			require.NoError(t, err)
+			srv := plugin.NewServer("memory", s, storeplugin.CapabilityPasswordEncryption)
+			return plugintest.Plugin{Address: plugintest.Serve(t, srv)}
+		})
+	})
+
+	t.Run("Command", func(t *testing.T) {
+		plugintest.Run(t, func(t *testing.T) plugintest.Plugin {
+			t.Setenv(keystoreDirEnv, t.TempDir())
+			return plugintest.Plugin{Command: []string{os.Args[0], "-test.run=^$"}}
+		})
+	})
+
+	t.Run("WithoutCapabilities", func(t *testing.T) {
+		plugintest.Run(t, func(t *testing.T) plugintest.Plugin {
+			s, err := memory.NewStore("", logger)
+			require.NoError(t, err)
+			srv := &fakeServer{Server: plugin.NewServer("memory", s), capabilities: []string{}}
+			return plugintest.Plugin{Address: plugintest.Serve(t, srv)}
+		})
+	})
+}
+
+func TestPluginChecks(t *testing.T) {
+	ctx := context.Background()
+	logger := testutils.GetTestLogger()
+
+	newServer := func(t *testing.T) *fakeServer {
+		s, err := memory.NewStore("", logger)
+		require.NoError(t, err)
+		return &fakeServer{Server: plugin.NewServer("memory", s)}
+	}
+
+	t.Run("ProtocolVersion", func(t *testing.T) {
+		srv := newServer(t)
+		srv.protocolVersion = storeplugin.ProtocolVersion + 1
+		_, err := plugin.NewStore(ctx, plugin.Config{
+			Address: plugintest.Serve(t, srv),
+		}, logger)
+		assert.ErrorContains(t, err, "protocol version")
+	})
+
+	t.Run("ForeignKey", func(t *testing.T) {
+		srv := newServer(t)
+		s, err := plugin.NewStore(ctx, plugin.Config{Address: plugintest.Serve(t, srv)}, logger)
+		require.NoError(t, err)
+		defer s.Close()
+
+		keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+		require.NoError(t, err)
+		pubKey, err := s.StoreKey(ctx, keyPair)
+		require.NoError(t, err)
+		other, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+		require.NoError(t, err)
+		otherPubKey, err := s.StoreKey(ctx, other)
+		require.NoError(t, err)
+
+		// The plugin answers with the key of another public key
+		srv.retrieveAs = pubKey
+		_, err = s.RetrieveKey(ctx, otherPubKey, testPassword)
+		assert.ErrorContains(t, err, "another public key")
+	})
+
+	t.Run("CannotDelete", func(t *testing.T) {
+		srv := newServer(t)
+		srv.capabilities = []string{}
+		s, err := plugin.NewStore(ctx, plugin.Config{Address: plugintest.Serve(t, srv)}, logger)
+		require.NoError(t, err)
+		defer s.Close()
+
+		keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+		require.NoError(t, err)
+		pubKey, err := s.StoreKey(ctx, keyPair)
+		require.NoError(t, err)
+		assert.ErrorContains(t, s.DeleteKey(ctx, pubKey), "doesn't support deleting keys")
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		srv := newServer(t)
+		s, err := plugin.NewStore(ctx, plugin.Config{Address: plugintest.Serve(t, srv)}, logger)
+		require.NoError(t, err)
+		defer s.Close()
+
+		srv.unavailable = true
+		_, err = s.ListKeys(ctx)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.NotErrorIs(t, err, store.ErrKeyNotFound)
+		assert.Error(t, s.Health(ctx))
+	})
+
+	t.Run("PluginExits", func(t *testing.T) {
+		_, err := plugin.NewStore(ctx, plugin.Config{Command: []string{"false"}}, logger)
+		assert.ErrorContains(t, err, "exited before serving")
+	})
+
+	t.Run("RemoteAddress", func(t *testing.T) {
+		_, err := plugin.NewStore(ctx, plugin.Config{Address: "10.0.0.1:8080"}, logger)
+		assert.ErrorContains(t, err, "loopback")
+	})
+}
+
+// fakeServer overrides the answers of a plugin server
+type fakeServer struct {
+	*plugin.Server
+
+	protocolVersion int32
+	capabilities    []string
+	retrieveAs      string
+	unavailable     bool
+}
+
+func (s *fakeServer) Capabilities(
+	ctx context.Context,
+	req *storeplugin.CapabilitiesRequest,
+) (*storeplugin.CapabilitiesResponse, error) {
+	resp, err := s.Server.Capabilities(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if s.protocolVersion != 0 {
+		resp.ProtocolVersion = s.protocolVersion
+	}
+	if s.capabilities != nil {
+		resp.Capabilities = s.capabilities
+	}
+	return resp, nil
+}
+
+func (s *fakeServer) Health(
+	ctx context.Context,
+	req *storeplugin.HealthRequest,
+) (*storeplugin.HealthResponse, error) {
+	if s.unavailable {
+		return &storeplugin.HealthResponse{
+			Status:  storeplugin.StatusNotServing,
+			Message: "backend is down",
+		}, nil
+	}
+	return s.Server.Health(ctx, req)
+}
+
+func (s *fakeServer) RetrieveKey(
+	ctx context.Context,
+	req *storeplugin.RetrieveKeyRequest,
+) (*storeplugin.RetrieveKeyResponse, error) {
+	if s.retrieveAs != "" {
+		req.PublicKey = s.retrieveAs
+	}
+	return s.Server.RetrieveKey(ctx, req)
+}
+
+func (s *fakeServer) ListKeys(
+	ctx context.Context,
+	req *storeplugin.ListKeysRequest,
+) (*storeplugin.ListKeysResponse, error) {
+	if s.unavailable {
+		return nil, status.Error(codes.Unavailable, "backend is down")
+	}
+	return s.Server.ListKeys(ctx, req)
+}
+
+func TestServerRejectsMismatchedPublicKey(t *testing.T) {
+	s, err := memory.NewStore("", testutils.GetTestLogger())
+	require.NoError(t, err)
+	srv := plugin.NewServer("memory", s)
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	_, err = srv.StoreKey(context.Background(), &storeplugin.StoreKeyRequest{
+		PublicKey:  strings.Repeat("ab", 32),
+		PrivateKey: keyPair.PrivateKey,
+		Password:   testPassword,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	keys, err := s.ListKeys(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
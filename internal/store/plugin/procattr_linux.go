@@ -0,0 +1,13 @@
+//go:build linux
+
+package plugin
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcAttr kills the plugin when cerberus dies without closing the store
+func setProcAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}
+}
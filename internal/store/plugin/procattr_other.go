@@ -0,0 +1,9 @@
+//go:build !linux
+
+package plugin
+
+import "os/exec"
+
+// setProcAttr does nothing, the plugin outlives cerberus if it dies without
+// closing the store on this platform
+func setProcAttr(cmd *exec.Cmd) {}
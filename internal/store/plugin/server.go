@@ -0,0 +1,122 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Layr-Labs/bn254-keystore-go/curve"
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/pkg/storeplugin"
+)
+
+var _ storeplugin.Server = (*Server)(nil)
+
+// Server serves a store.Store as a plugin
+type Server struct {
+	name         string
+	store        store.Store
+	capabilities []string
+}
+
+// NewServer returns a plugin serving the store under the name. The
+// capabilities must match the store, all stores support CapabilityDeleteKey.
+func NewServer(name string, s store.Store, capabilities ...string) *Server {
+	return &Server{
+		name:         name,
+		store:        s,
+		capabilities: append([]string{storeplugin.CapabilityDeleteKey}, capabilities...),
+	}
+}
+
+func (s *Server) Capabilities(
+	context.Context,
+	*storeplugin.CapabilitiesRequest,
+) (*storeplugin.CapabilitiesResponse, error) {
+	return &storeplugin.CapabilitiesResponse{
+		ProtocolVersion: storeplugin.ProtocolVersion,
+		Name:            s.name,
+		Capabilities:    s.capabilities,
+	}, nil
+}
+
+func (s *Server) Health(
+	context.Context,
+	*storeplugin.HealthRequest,
+) (*storeplugin.HealthResponse, error) {
+	return &storeplugin.HealthResponse{Status: storeplugin.StatusServing}, nil
+}
+
+func (s *Server) RetrieveKey(
+	ctx context.Context,
+	req *storeplugin.RetrieveKeyRequest,
+) (*storeplugin.RetrieveKeyResponse, error) {
+	keyPair, err := s.store.RetrieveKey(ctx, req.PublicKey, req.Password)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &storeplugin.RetrieveKeyResponse{
+		PrivateKey: crypto.PrivateKeyBytes(keyPair.PrivKey),
+	}, nil
+}
+
+func (s *Server) StoreKey(
+	ctx context.Context,
+	req *storeplugin.StoreKeyRequest,
+) (*storeplugin.StoreKeyResponse, error) {
+	pubKey, err := keystore.BlsSkToG1Pk(req.PrivateKey, string(curve.BN254))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid private key: %v", err)
+	}
+	if pubKey != req.PublicKey {
+		return nil, status.Error(codes.InvalidArgument, "public key doesn't match the private key")
+	}
+
+	_, err = s.store.StoreKey(ctx, &keystore.KeyPair{
+		PrivateKey: req.PrivateKey,
+		Password:   req.Password,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &storeplugin.StoreKeyResponse{}, nil
+}
+
+func (s *Server) ListKeys(
+	ctx context.Context,
+	_ *storeplugin.ListKeysRequest,
+) (*storeplugin.ListKeysResponse, error) {
+	keys, err := s.store.ListKeys(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &storeplugin.ListKeysResponse{PublicKeys: keys}, nil
+}
+
+func (s *Server) DeleteKey(
+	ctx context.Context,
+	req *storeplugin.DeleteKeyRequest,
+) (*storeplugin.DeleteKeyResponse, error) {
+	if err := s.store.DeleteKey(ctx, req.PublicKey); err != nil {
+		return nil, toStatus(err)
+	}
+	return &storeplugin.DeleteKeyResponse{}, nil
+}
+
+// toStatus maps the store errors to the status codes of the protocol
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, store.ErrKeyNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, store.ErrKeyAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, store.ErrInvalidPassword):
+		return status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
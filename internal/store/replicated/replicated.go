@@ -0,0 +1,358 @@
+package replicated
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/Layr-Labs/bn254-keystore-go/curve"
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+var (
+	_ store.Store           = (*Store)(nil)
+	_ store.DriftReporter   = (*Store)(nil)
+	_ store.PasswordChecker = (*Store)(nil)
+)
+
+// Replica is one of the backends the keys are replicated to
+type Replica struct {
+	Name  string
+	Store store.Store
+}
+
+// Store writes every key to several replicas and reads it from the first
+// replica holding it, in the order of the replicas, so that the keys remain
+// available while a backend is down.
+// A key is stored once it is written to a write quorum of replicas, the
+// replicas which failed are reported by Drift until the key is copied to them.
+// The replicas must all check the password of the keys or none of them, so
+// that the password is enforced whichever replica returns the key.
+type Store struct {
+	replicas       []Replica
+	writeQuorum    int
+	checksPassword bool
+
+	logger *slog.Logger
+}
+
+func NewStore(replicas []Replica, writeQuorum int, logger *slog.Logger) (*Store, error) {
+	if len(replicas) < 2 {
+		return nil, errors.New("at least two replicas are required")
+	}
+	if writeQuorum < 1 || writeQuorum > len(replicas) {
+		return nil, fmt.Errorf("write quorum must be between 1 and %d", len(replicas))
+	}
+
+	names := make(map[string]struct{}, len(replicas))
+	for _, r := range replicas {
+		if _, ok := names[r.Name]; ok {
+			return nil, fmt.Errorf("duplicate replica %s", r.Name)
+		}
+		names[r.Name] = struct{}{}
+	}
+
+	checksPassword := store.ChecksPassword(replicas[0].Store)
+	for _, r := range replicas[1:] {
+		if store.ChecksPassword(r.Store) != checksPassword {
+			return nil, fmt.Errorf(
+				"replicas %s and %s don't both check the password of the keys, "+
+					"the keys must be password encrypted in every replica or in none",
+				replicas[0].Name,
+				r.Name,
+			)
+		}
+	}
+
+	return &Store{
+		replicas:       replicas,
+		writeQuorum:    writeQuorum,
+		checksPassword: checksPassword,
+		logger:         logger.With("component", "replicated-store"),
+	}, nil
+}
+
+// ChecksPassword reports whether the replicas check the password of the keys
+func (s *Store) ChecksPassword() bool {
+	return s.checksPassword
+}
+
+func (s *Store) RetrieveKey(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (*crypto.KeyPair, error) {
+	var errs []error
+	for i, r := range s.replicas {
+		kp, err := r.Store.RetrieveKey(ctx, pubKey, password)
+		if err == nil {
+			if i > 0 {
+				s.logger.Warn(
+					"Retrieved key from fallback replica",
+					"pubKey", pubKey,
+					"replica", r.Name,
+				)
+			}
+			return kp, nil
+		}
+
+		// The other replicas check the password too, a wrong password isn't
+		// retried on them
+		if errors.Is(err, store.ErrInvalidPassword) {
+			return nil, err
+		}
+		if errors.Is(err, store.ErrKeyNotFound) {
+			continue
+		}
+		s.logger.Warn(
+			"Failed to retrieve key from replica",
+			"pubKey", pubKey,
+			"replica", r.Name,
+			"error", err,
+		)
+		errs = append(errs, fmt.Errorf("replica %s: %w", r.Name, err))
+	}
+
+	// The key is only missing if every replica could be read
+	if len(errs) == 0 {
+		return nil, store.ErrKeyNotFound
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (s *Store) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (string, error) {
+	pubKey, err := keystore.BlsSkToG1Pk(keyPair.PrivateKey, string(curve.BN254))
+	if err != nil {
+		return "", err
+	}
+
+	errs := s.forEach(func(_ int, r Replica) error {
+		_, err := r.Store.StoreKey(ctx, keyPair)
+		return err
+	})
+
+	// The replicas already holding the key count toward the write quorum, so
+	// that storing a key again copies it to the replicas missing it
+	var created []Replica
+	var failed []error
+	existing := 0
+	for i, err := range errs {
+		r := s.replicas[i]
+		if errors.Is(err, store.ErrKeyAlreadyExists) {
+			err = s.checkExistingKey(ctx, r, pubKey, keyPair)
+			if err == nil {
+				existing++
+				continue
+			}
+		} else if err == nil {
+			created = append(created, r)
+			continue
+		}
+		failed = append(failed, fmt.Errorf("replica %s: %w", r.Name, err))
+	}
+
+	if len(created) == 0 && existing > 0 {
+		return "", store.ErrKeyAlreadyExists
+	}
+	if stored := len(created) + existing; stored < s.writeQuorum {
+		// Only the copies written by this call are deleted
+		s.rollback(ctx, pubKey, created)
+		return "", fmt.Errorf(
+			"key stored in %d replicas, the write quorum is %d: %w",
+			stored,
+			s.writeQuorum,
+			errors.Join(failed...),
+		)
+	}
+
+	for _, err := range failed {
+		s.logger.Warn("Key not stored in replica", "pubKey", pubKey, "error", err)
+	}
+	return pubKey, nil
+}
+
+// ListKeys returns the keys of every replica. Enough replicas must be listed
+// to include at least one of the replicas each key was written to.
+func (s *Store) ListKeys(ctx context.Context) ([]string, error) {
+	lists := make([][]string, len(s.replicas))
+	errs := s.forEach(func(i int, r Replica) error {
+		keys, err := r.Store.ListKeys(ctx)
+		lists[i] = keys
+		return err
+	})
+
+	var failed []error
+	seen := make(map[string]struct{})
+	for i, err := range errs {
+		if err != nil {
+			name := s.replicas[i].Name
+			s.logger.Warn("Failed to list keys of replica", "replica", name, "error", err)
+			failed = append(failed, fmt.Errorf("replica %s: %w", name, err))
+			continue
+		}
+		for _, key := range lists[i] {
+			seen[key] = struct{}{}
+		}
+	}
+
+	listed := len(s.replicas) - len(failed)
+	if listed < s.readQuorum() {
+		return nil, fmt.Errorf(
+			"listed %d replicas, %d are required: %w",
+			listed,
+			s.readQuorum(),
+			errors.Join(failed...),
+		)
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// DeleteKey deletes the key from every replica, it fails if a replica holding
+// the key may not have deleted it
+func (s *Store) DeleteKey(ctx context.Context, pubKey string) error {
+	errs := s.forEach(func(_ int, r Replica) error {
+		return r.Store.DeleteKey(ctx, pubKey)
+	})
+
+	deleted := 0
+	var failed []error
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			deleted++
+		case errors.Is(err, store.ErrKeyNotFound):
+		default:
+			failed = append(failed, fmt.Errorf("replica %s: %w", s.replicas[i].Name, err))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf(
+			"failed to delete key from %d replicas: %w",
+			len(failed),
+			errors.Join(failed...),
+		)
+	}
+	if deleted == 0 {
+		return store.ErrKeyNotFound
+	}
+	return nil
+}
+
+// Drift lists the keys of every replica and returns the keys missing from
+// some of them. Every replica must be reachable.
+func (s *Store) Drift(ctx context.Context) ([]store.Drift, error) {
+	lists := make([][]string, len(s.replicas))
+	errs := s.forEach(func(i int, r Replica) error {
+		keys, err := r.Store.ListKeys(ctx)
+		lists[i] = keys
+		return err
+	})
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys of replica %s: %w", s.replicas[i].Name, err)
+		}
+	}
+
+	present := make(map[string][]bool)
+	for i, keys := range lists {
+		for _, key := range keys {
+			if present[key] == nil {
+				present[key] = make([]bool, len(s.replicas))
+			}
+			present[key][i] = true
+		}
+	}
+
+	drift := make([]store.Drift, 0)
+	for key, in := range present {
+		var missing []string
+		for i, ok := range in {
+			if !ok {
+				missing = append(missing, s.replicas[i].Name)
+			}
+		}
+		if len(missing) > 0 {
+			drift = append(drift, store.Drift{PubKey: key, MissingFrom: missing})
+		}
+	}
+	sort.Slice(drift, func(i, j int) bool {
+		return drift[i].PubKey < drift[j].PubKey
+	})
+	return drift, nil
+}
+
+// checkExistingKey checks that the key a replica already holds is the key
+// being stored, readable with its password
+func (s *Store) checkExistingKey(
+	ctx context.Context,
+	r Replica,
+	pubKey string,
+	keyPair *keystore.KeyPair,
+) error {
+	kp, err := r.Store.RetrieveKey(ctx, pubKey, keyPair.Password)
+	if errors.Is(err, store.ErrInvalidPassword) {
+		return fmt.Errorf("key is stored with another password: %w", store.ErrKeyAlreadyExists)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read existing key: %w", err)
+	}
+
+	sk, err := crypto.PrivateKeyFromBytes(keyPair.PrivateKey)
+	if err != nil {
+		return err
+	}
+	if !kp.PrivKey.Equal(sk) {
+		return fmt.Errorf("another key is stored: %w", store.ErrKeyAlreadyExists)
+	}
+	return nil
+}
+
+// readQuorum is the number of replicas which must be listed so that at least
+// one of them is part of the write quorum of every key
+func (s *Store) readQuorum() int {
+	return len(s.replicas) - s.writeQuorum + 1
+}
+
+// rollback deletes a key which didn't reach the write quorum from the
+// replicas it was written to
+func (s *Store) rollback(ctx context.Context, pubKey string, replicas []Replica) {
+	for _, r := range replicas {
+		if err := r.Store.DeleteKey(ctx, pubKey); err != nil {
+			s.logger.Error(
+				"Failed to roll back key from replica",
+				"pubKey", pubKey,
+				"replica", r.Name,
+				"error", err,
+			)
+		}
+	}
+}
+
+// forEach calls fn concurrently for every replica and returns the errors in
+// the order of the replicas
+func (s *Store) forEach(fn func(i int, r Replica) error) []error {
+	errs := make([]error, len(s.replicas))
+	var wg sync.WaitGroup
+	for i, r := range s.replicas {
+		wg.Add(1)
+		go func(i int, r Replica) {
+			defer wg.Done()
+			errs[i] = fn(i, r)
+		}(i, r)
+	}
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,295 @@
+package replicated
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Layr-Labs/bn254-keystore-go/curve"
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/memory"
+	"github.com/Layr-Labs/cerberus/internal/store/storetest"
+)
+
+const testPassword = "p@$$w0rd"
+
+var errUnavailable = errors.New("backend unavailable")
+
+// flakyStore wraps a store and fails every call while it is down
+type flakyStore struct {
+	store.Store
+	down bool
+
+	// ignoresPassword retrieves the keys whatever the password, like the
+	// stores keeping them without password encryption
+	ignoresPassword bool
+}
+
+func (s *flakyStore) RetrieveKey(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (*crypto.KeyPair, error) {
+	if s.down {
+		return nil, errUnavailable
+	}
+	if s.ignoresPassword {
+		password = testPassword
+	}
+	return s.Store.RetrieveKey(ctx, pubKey, password)
+}
+
+func (s *flakyStore) ChecksPassword() bool {
+	return !s.ignoresPassword
+}
+
+func (s *flakyStore) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (string, error) {
+	if s.down {
+		return "", errUnavailable
+	}
+	return s.Store.StoreKey(ctx, keyPair)
+}
+
+func (s *flakyStore) ListKeys(ctx context.Context) ([]string, error) {
+	if s.down {
+		return nil, errUnavailable
+	}
+	return s.Store.ListKeys(ctx)
+}
+
+func (s *flakyStore) DeleteKey(ctx context.Context, pubKey string) error {
+	if s.down {
+		return errUnavailable
+	}
+	return s.Store.DeleteKey(ctx, pubKey)
+}
+
+func newReplicas(t *testing.T, names ...string) ([]Replica, []*flakyStore) {
+	replicas := make([]Replica, len(names))
+	backends := make([]*flakyStore, len(names))
+	for i, name := range names {
+		s, err := memory.NewStore("", testutils.GetTestLogger())
+		require.NoError(t, err)
+		backends[i] = &flakyStore{Store: s}
+		replicas[i] = Replica{Name: name, Store: backends[i]}
+	}
+	return replicas, backends
+}
+
+func newKeyPair(t *testing.T) *keystore.KeyPair {
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	return keyPair
+}
+
+func TestNewStore(t *testing.T) {
+	logger := testutils.GetTestLogger()
+
+	replicas, _ := newReplicas(t, "primary")
+	_, err := NewStore(replicas, 1, logger)
+	assert.Error(t, err)
+
+	replicas, _ = newReplicas(t, "primary", "secondary")
+	_, err = NewStore(replicas, 0, logger)
+	assert.Error(t, err)
+	_, err = NewStore(replicas, 3, logger)
+	assert.Error(t, err)
+
+	replicas, _ = newReplicas(t, "primary", "primary")
+	_, err = NewStore(replicas, 1, logger)
+	assert.Error(t, err)
+
+	// A replica returning the keys whatever the password would bypass the
+	// password of the others
+	replicas, backends := newReplicas(t, "primary", "secondary")
+	backends[0].ignoresPassword = true
+	_, err = NewStore(replicas, 1, logger)
+	assert.Error(t, err)
+
+	backends[1].ignoresPassword = true
+	s, err := NewStore(replicas, 1, logger)
+	require.NoError(t, err)
+	assert.False(t, s.ChecksPassword())
+}
+
+func TestStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		replicas, _ := newReplicas(t, "primary", "secondary")
+		s, err := NewStore(replicas, 2, testutils.GetTestLogger())
+		require.NoError(t, err)
+		return s
+	}, storetest.Options{})
+}
+
+func TestRetrieveKeyFailover(t *testing.T) {
+	ctx := context.Background()
+	replicas, backends := newReplicas(t, "primary", "secondary")
+	s, err := NewStore(replicas, 2, testutils.GetTestLogger())
+	require.NoError(t, err)
+
+	pubKey, err := s.StoreKey(ctx, newKeyPair(t))
+	require.NoError(t, err)
+
+	backends[0].down = true
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.NoError(t, err)
+
+	// The key may be on the unavailable replica, so it isn't reported missing
+	_, err = s.RetrieveKey(ctx, newPubKey(t), testPassword)
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.NotErrorIs(t, err, store.ErrKeyNotFound)
+
+	backends[1].down = true
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.ErrorIs(t, err, errUnavailable)
+}
+
+func TestRetrieveKeyInvalidPassword(t *testing.T) {
+	ctx := context.Background()
+	replicas, backends := newReplicas(t, "primary", "secondary")
+	s, err := NewStore(replicas, 2, testutils.GetTestLogger())
+	require.NoError(t, err)
+	assert.True(t, s.ChecksPassword())
+
+	pubKey, err := s.StoreKey(ctx, newKeyPair(t))
+	require.NoError(t, err)
+
+	_, err = s.RetrieveKey(ctx, pubKey, "wrong password")
+	assert.ErrorIs(t, err, store.ErrInvalidPassword)
+
+	// The password is checked by whichever replica returns the key
+	backends[0].down = true
+	_, err = s.RetrieveKey(ctx, pubKey, "wrong password")
+	assert.ErrorIs(t, err, store.ErrInvalidPassword)
+}
+
+func TestStoreKeyWriteQuorum(t *testing.T) {
+	ctx := context.Background()
+	logger := testutils.GetTestLogger()
+
+	t.Run("NotReached", func(t *testing.T) {
+		replicas, backends := newReplicas(t, "primary", "secondary")
+		s, err := NewStore(replicas, 2, logger)
+		require.NoError(t, err)
+
+		backends[1].down = true
+		_, err = s.StoreKey(ctx, newKeyPair(t))
+		assert.ErrorIs(t, err, errUnavailable)
+
+		// The key is rolled back from the replica it was written to
+		keys, err := backends[0].ListKeys(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+	})
+
+	t.Run("Reached", func(t *testing.T) {
+		replicas, backends := newReplicas(t, "primary", "secondary")
+		s, err := NewStore(replicas, 1, logger)
+		require.NoError(t, err)
+
+		backends[1].down = true
+		pubKey, err := s.StoreKey(ctx, newKeyPair(t))
+		require.NoError(t, err)
+
+		_, err = s.Drift(ctx)
+		assert.ErrorIs(t, err, errUnavailable)
+
+		backends[1].down = false
+		drift, err := s.Drift(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []store.Drift{{PubKey: pubKey, MissingFrom: []string{"secondary"}}}, drift)
+	})
+}
+
+func TestStoreKeyAlreadyExistsInReplica(t *testing.T) {
+	ctx := context.Background()
+	replicas, backends := newReplicas(t, "primary", "secondary")
+	s, err := NewStore(replicas, 2, testutils.GetTestLogger())
+	require.NoError(t, err)
+
+	t.Run("SameKey", func(t *testing.T) {
+		keyPair := newKeyPair(t)
+		pubKey, err := backends[1].StoreKey(ctx, keyPair)
+		require.NoError(t, err)
+
+		// The key is copied to the replica missing it
+		_, err = s.StoreKey(ctx, keyPair)
+		require.NoError(t, err)
+		drift, err := s.Drift(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, drift)
+
+		_, err = s.StoreKey(ctx, keyPair)
+		assert.ErrorIs(t, err, store.ErrKeyAlreadyExists)
+		_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+		assert.NoError(t, err)
+	})
+
+	t.Run("OtherPassword", func(t *testing.T) {
+		keyPair := newKeyPair(t)
+		otherKeyPair := *keyPair
+		otherKeyPair.Password = "other password"
+		pubKey, err := backends[1].StoreKey(ctx, &otherKeyPair)
+		require.NoError(t, err)
+
+		_, err = s.StoreKey(ctx, keyPair)
+		assert.ErrorIs(t, err, store.ErrKeyAlreadyExists)
+
+		// The copy written to the primary is rolled back, the existing copy
+		// is kept
+		_, err = backends[0].RetrieveKey(ctx, pubKey, testPassword)
+		assert.ErrorIs(t, err, store.ErrKeyNotFound)
+		_, err = backends[1].RetrieveKey(ctx, pubKey, otherKeyPair.Password)
+		assert.NoError(t, err)
+	})
+}
+
+func TestListKeysReadQuorum(t *testing.T) {
+	ctx := context.Background()
+	replicas, backends := newReplicas(t, "a", "b", "c")
+	s, err := NewStore(replicas, 2, testutils.GetTestLogger())
+	require.NoError(t, err)
+
+	pubKey, err := s.StoreKey(ctx, newKeyPair(t))
+	require.NoError(t, err)
+
+	// With a write quorum of 2, any 2 of the 3 replicas hold every key
+	backends[0].down = true
+	keys, err := s.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{pubKey}, keys)
+
+	backends[1].down = true
+	_, err = s.ListKeys(ctx)
+	assert.ErrorIs(t, err, errUnavailable)
+}
+
+func TestDeleteKeyReplicaDown(t *testing.T) {
+	ctx := context.Background()
+	replicas, backends := newReplicas(t, "primary", "secondary")
+	s, err := NewStore(replicas, 2, testutils.GetTestLogger())
+	require.NoError(t, err)
+
+	pubKey, err := s.StoreKey(ctx, newKeyPair(t))
+	require.NoError(t, err)
+
+	backends[1].down = true
+	assert.ErrorIs(t, s.DeleteKey(ctx, pubKey), errUnavailable)
+
+	backends[1].down = false
+	require.NoError(t, s.DeleteKey(ctx, pubKey))
+	assert.ErrorIs(t, s.DeleteKey(ctx, pubKey), store.ErrKeyNotFound)
+}
+
+func newPubKey(t *testing.T) string {
+	pubKey, err := keystore.BlsSkToG1Pk(newKeyPair(t).PrivateKey, string(curve.BN254))
+	require.NoError(t, err)
+	return pubKey
+}
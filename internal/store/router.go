@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DefaultStoreName is the name of the store when a single store is configured
+const DefaultStoreName = "default"
+
+var ErrUnknownStore = errors.New("unknown store")
+
+// Router is implemented by the stores holding several named stores, so that
+// each key is kept in the store recorded in its metadata
+type Router interface {
+	// Route returns the store with the name and its name, or the default store
+	// if the name is empty
+	// Returns ErrUnknownStore if no store has the name
+	Route(name string) (Store, string, error)
+
+	// Locate returns the name of the first store holding the key
+	// Returns ErrKeyNotFound if no store holds it
+	Locate(ctx context.Context, pubKey string) (string, error)
+}
+
+// Route returns the named store of s and its name. A store which isn't a
+// Router is the only store, named DefaultStoreName.
+func Route(s Store, name string) (Store, string, error) {
+	if r, ok := s.(Router); ok {
+		return r.Route(name)
+	}
+	if name != "" && name != DefaultStoreName {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnknownStore, name)
+	}
+	return s, DefaultStoreName, nil
+}
+
+// Locate returns the name of the store of s holding the key. A store which
+// isn't a Router is the only store, named DefaultStoreName.
+func Locate(ctx context.Context, s Store, pubKey string) (string, error) {
+	if r, ok := s.(Router); ok {
+		return r.Locate(ctx, pubKey)
+	}
+	return DefaultStoreName, nil
+}
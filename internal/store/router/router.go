@@ -0,0 +1,264 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+var (
+	_ store.Store            = (*Store)(nil)
+	_ store.Router           = (*Store)(nil)
+	_ store.KeystoreMigrator = (*Store)(nil)
+	_ store.DriftReporter    = (*Store)(nil)
+	_ store.PasswordChecker  = (*Store)(nil)
+)
+
+// NamedStore is one of the stores the keys are routed to
+type NamedStore struct {
+	Name  string
+	Store store.Store
+}
+
+// Store holds several named stores. The services route each key to the store
+// recorded in its metadata with Route, the store.Store methods act on all the
+// stores so that the keys can be listed and reconciled as a whole.
+type Store struct {
+	stores       []NamedStore
+	defaultStore NamedStore
+
+	logger *slog.Logger
+}
+
+// NewStore returns a store routing to the named stores, the keys are created
+// in the default store unless another store is requested. The first store is
+// the default store if defaultName is empty.
+func NewStore(stores []NamedStore, defaultName string, logger *slog.Logger) (*Store, error) {
+	if len(stores) == 0 {
+		return nil, errors.New("at least one store is required")
+	}
+
+	names := make(map[string]struct{}, len(stores))
+	for _, s := range stores {
+		if s.Name == "" {
+			return nil, errors.New("store name is required")
+		}
+		if _, ok := names[s.Name]; ok {
+			return nil, fmt.Errorf("duplicate store %s", s.Name)
+		}
+		names[s.Name] = struct{}{}
+	}
+
+	r := &Store{
+		stores: stores,
+		logger: logger.With("component", "store-router"),
+	}
+	if defaultName == "" {
+		defaultName = stores[0].Name
+	}
+	for _, s := range stores {
+		if s.Name == defaultName {
+			r.defaultStore = s
+		}
+	}
+	if r.defaultStore.Store == nil {
+		return nil, fmt.Errorf("%w: default store %s", store.ErrUnknownStore, defaultName)
+	}
+	return r, nil
+}
+
+// Route returns the named store. The keys created while a single store was
+// configured are recorded in store.DefaultStoreName, which is the default
+// store unless a store has this name.
+func (r *Store) Route(name string) (store.Store, string, error) {
+	for _, s := range r.stores {
+		if s.Name == name {
+			return s.Store, s.Name, nil
+		}
+	}
+	if name == "" || name == store.DefaultStoreName {
+		return r.defaultStore.Store, r.defaultStore.Name, nil
+	}
+	return nil, "", fmt.Errorf("%w: %s", store.ErrUnknownStore, name)
+}
+
+func (r *Store) Locate(ctx context.Context, pubKey string) (string, error) {
+	for _, s := range r.stores {
+		keys, err := s.Store.ListKeys(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to list keys of store %s: %w", s.Name, err)
+		}
+		if slices.Contains(keys, pubKey) {
+			return s.Name, nil
+		}
+	}
+	return "", store.ErrKeyNotFound
+}
+
+// Names returns the names of the stores in the configured order
+func (r *Store) Names() []string {
+	names := make([]string, 0, len(r.stores))
+	for _, s := range r.stores {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// ChecksPassword reports whether any of the stores checks the password of the
+// keys, so that the keys are never assumed to be readable without it
+func (r *Store) ChecksPassword() bool {
+	for _, s := range r.stores {
+		if store.ChecksPassword(s.Store) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetrieveKey returns the key from the first store holding it. The services
+// retrieve the keys from the store recorded in their metadata instead.
+func (r *Store) RetrieveKey(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (*crypto.KeyPair, error) {
+	var errs []error
+	for _, s := range r.stores {
+		kp, err := s.Store.RetrieveKey(ctx, pubKey, password)
+		if err == nil {
+			return kp, nil
+		}
+		if errors.Is(err, store.ErrInvalidPassword) {
+			return nil, err
+		}
+		if errors.Is(err, store.ErrKeyNotFound) {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("store %s: %w", s.Name, err))
+	}
+
+	// The key is only missing if every store could be read
+	if len(errs) == 0 {
+		return nil, store.ErrKeyNotFound
+	}
+	return nil, errors.Join(errs...)
+}
+
+// StoreKey stores the key in the default store
+func (r *Store) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (string, error) {
+	return r.defaultStore.Store.StoreKey(ctx, keyPair)
+}
+
+// ListKeys returns the keys of every store, all the stores must be listed
+func (r *Store) ListKeys(ctx context.Context) ([]string, error) {
+	seen := make(map[string]struct{})
+	for _, s := range r.stores {
+		keys, err := s.Store.ListKeys(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys of store %s: %w", s.Name, err)
+		}
+		for _, key := range keys {
+			seen[key] = struct{}{}
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// DeleteKey deletes the key from every store holding it
+func (r *Store) DeleteKey(ctx context.Context, pubKey string) error {
+	deleted := 0
+	var errs []error
+	for _, s := range r.stores {
+		err := s.Store.DeleteKey(ctx, pubKey)
+		switch {
+		case err == nil:
+			deleted++
+		case errors.Is(err, store.ErrKeyNotFound):
+		default:
+			errs = append(errs, fmt.Errorf("store %s: %w", s.Name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if deleted == 0 {
+		return store.ErrKeyNotFound
+	}
+	return nil
+}
+
+// MigrateToKeystore migrates the key in the stores holding it which support
+// the migration. A key held by a store which doesn't keep plaintext keys is
+// left unchanged.
+func (r *Store) MigrateToKeystore(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (bool, error) {
+	found := false
+	migrated := false
+	for _, s := range r.stores {
+		migrator, ok := store.As[store.KeystoreMigrator](s.Store)
+		if !ok {
+			keys, err := s.Store.ListKeys(ctx)
+			if err != nil {
+				return migrated, fmt.Errorf("store %s: %w", s.Name, err)
+			}
+			found = found || slices.Contains(keys, pubKey)
+			continue
+		}
+		ok, err := migrator.MigrateToKeystore(ctx, pubKey, password)
+		if errors.Is(err, store.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return migrated, fmt.Errorf("store %s: %w", s.Name, err)
+		}
+		found = true
+		migrated = migrated || ok
+	}
+
+	if !found {
+		return false, store.ErrKeyNotFound
+	}
+	return migrated, nil
+}
+
+// Drift returns the drift of the stores replicating their keys, the backends
+// are prefixed with the name of the store
+func (r *Store) Drift(ctx context.Context) ([]store.Drift, error) {
+	drift := make([]store.Drift, 0)
+	for _, s := range r.stores {
+		reporter, ok := store.As[store.DriftReporter](s.Store)
+		if !ok {
+			continue
+		}
+		storeDrift, err := reporter.Drift(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("store %s: %w", s.Name, err)
+		}
+		for _, d := range storeDrift {
+			missing := make([]string, 0, len(d.MissingFrom))
+			for _, backend := range d.MissingFrom {
+				missing = append(missing, s.Name+"/"+backend)
+			}
+			drift = append(drift, store.Drift{PubKey: d.PubKey, MissingFrom: missing})
+		}
+	}
+	return drift, nil
+}
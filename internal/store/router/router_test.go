@@ -0,0 +1,120 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/memory"
+	"github.com/Layr-Labs/cerberus/internal/store/storetest"
+)
+
+const testPassword = "p@$$w0rd"
+
+func newStores(t *testing.T, names ...string) []NamedStore {
+	stores := make([]NamedStore, len(names))
+	for i, name := range names {
+		s, err := memory.NewStore("", testutils.GetTestLogger())
+		require.NoError(t, err)
+		stores[i] = NamedStore{Name: name, Store: s}
+	}
+	return stores
+}
+
+func newKeyPair(t *testing.T) *keystore.KeyPair {
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	return keyPair
+}
+
+func TestNewStore(t *testing.T) {
+	logger := testutils.GetTestLogger()
+
+	_, err := NewStore(nil, "", logger)
+	assert.Error(t, err)
+
+	_, err = NewStore(newStores(t, "hsm", "hsm"), "", logger)
+	assert.Error(t, err)
+
+	_, err = NewStore(newStores(t, "hsm", ""), "", logger)
+	assert.Error(t, err)
+
+	_, err = NewStore(newStores(t, "hsm", "disk"), "vault", logger)
+	assert.ErrorIs(t, err, store.ErrUnknownStore)
+
+	r, err := NewStore(newStores(t, "hsm", "disk"), "", logger)
+	require.NoError(t, err)
+	_, name, err := r.Route("")
+	require.NoError(t, err)
+	assert.Equal(t, "hsm", name)
+	assert.Equal(t, []string{"hsm", "disk"}, r.Names())
+}
+
+func TestStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		r, err := NewStore(newStores(t, "hsm", "disk"), "disk", testutils.GetTestLogger())
+		require.NoError(t, err)
+		return r
+	}, storetest.Options{})
+}
+
+func TestRoute(t *testing.T) {
+	ctx := context.Background()
+	stores := newStores(t, "hsm", "disk")
+	r, err := NewStore(stores, "disk", testutils.GetTestLogger())
+	require.NoError(t, err)
+
+	target, name, err := r.Route("hsm")
+	require.NoError(t, err)
+	assert.Equal(t, "hsm", name)
+	pubKey, err := target.StoreKey(ctx, newKeyPair(t))
+	require.NoError(t, err)
+
+	// The key is only in the routed store
+	_, err = stores[0].Store.RetrieveKey(ctx, pubKey, testPassword)
+	assert.NoError(t, err)
+	_, err = stores[1].Store.RetrieveKey(ctx, pubKey, testPassword)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+
+	// The router finds it in any store
+	name, err = r.Locate(ctx, pubKey)
+	require.NoError(t, err)
+	assert.Equal(t, "hsm", name)
+	_, err = r.Locate(ctx, "missing")
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+	_, err = r.RetrieveKey(ctx, pubKey, testPassword)
+	assert.NoError(t, err)
+	keys, err := r.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{pubKey}, keys)
+
+	_, _, err = r.Route("vault")
+	assert.ErrorIs(t, err, store.ErrUnknownStore)
+
+	// The keys created with a single store are in the default store
+	_, name, err = r.Route(store.DefaultStoreName)
+	require.NoError(t, err)
+	assert.Equal(t, "disk", name)
+}
+
+func TestRouteSingleStore(t *testing.T) {
+	s := newStores(t, "memory")[0].Store
+
+	target, name, err := store.Route(s, "")
+	require.NoError(t, err)
+	assert.Equal(t, store.DefaultStoreName, name)
+	assert.Equal(t, s, target)
+
+	_, name, err = store.Route(s, store.DefaultStoreName)
+	require.NoError(t, err)
+	assert.Equal(t, store.DefaultStoreName, name)
+
+	_, _, err = store.Route(s, "hsm")
+	assert.ErrorIs(t, err, store.ErrUnknownStore)
+}
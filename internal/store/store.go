@@ -8,19 +8,27 @@ import (
 	"github.com/Layr-Labs/cerberus/internal/crypto"
 )
 
+// Store is implemented by the backends keeping the private keys. Every
+// implementation must pass the conformance suite of the storetest package.
 type Store interface {
 	// RetrieveKey retrieves the private key from the store
 	// using the public key and password
 	// Returns the private key or an error if it fails
+	// Returns ErrKeyNotFound if the key is not in the store
 	// Public key is used to identify the key in the store
 	RetrieveKey(ctx context.Context, pubKey string, password string) (*crypto.KeyPair, error)
 
 	// StoreKey stores the private key in the store
 	// using the public key as identifier
 	// Returns an error if it fails
+	// Returns ErrKeyAlreadyExists if the key is already in the store
 	// Password is used to encrypt the private key before storing if it is provided
 	StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (string, error)
 
 	// ListKeys returns a list of public keys stored in the store
 	ListKeys(ctx context.Context) ([]string, error)
+
+	// DeleteKey permanently deletes the private key identified by the public key
+	// Returns ErrKeyNotFound if the key is not in the store
+	DeleteKey(ctx context.Context, pubKey string) error
 }
@@ -0,0 +1,205 @@
+// Package storetest provides the conformance suite that every store.Store
+// implementation must pass, so that the backends behave the same way in the
+// edge cases the signer relies on.
+package storetest
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/Layr-Labs/bn254-keystore-go/curve"
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+const (
+	testPassword = "p@$$w0rd"
+
+	// listedKeys is the number of keys stored to check ListKeys, more than
+	// the page size of the fake backends so that pagination is exercised
+	listedKeys = 5
+)
+
+type Options struct {
+	// IgnoresPassword is set for stores that keep the keys without password
+	// encryption and don't check the password when retrieving them
+	IgnoresPassword bool
+
+	// AddForeignEntry adds an entry to the backend of the store which isn't
+	// a key, such as a stray file or a secret of another application.
+	// ListKeys must not return it.
+	AddForeignEntry func(t *testing.T, s store.Store)
+
+	// CannotDelete is set for stores whose DeleteKey always fails, such as
+	// the plugins without the delete capability
+	CannotDelete bool
+}
+
+// Run runs the conformance suite against the stores returned by newStore,
+// which must return an empty store for each subtest
+func Run(t *testing.T, newStore func(t *testing.T) store.Store, opts Options) {
+	t.Run("StoreAndRetrieve", func(t *testing.T) {
+		testStoreAndRetrieve(t, newStore(t))
+	})
+	t.Run("StoreDuplicate", func(t *testing.T) {
+		testStoreDuplicate(t, newStore(t))
+	})
+	t.Run("RetrieveMissing", func(t *testing.T) {
+		testRetrieveMissing(t, newStore(t))
+	})
+	t.Run("RetrieveWrongPassword", func(t *testing.T) {
+		if opts.IgnoresPassword {
+			t.Skip("store doesn't check the password")
+		}
+		testRetrieveWrongPassword(t, newStore(t))
+	})
+	t.Run("ListKeys", func(t *testing.T) {
+		testListKeys(t, newStore(t))
+	})
+	t.Run("ListForeignEntries", func(t *testing.T) {
+		if opts.AddForeignEntry == nil {
+			t.Skip("store has no foreign entries")
+		}
+		s := newStore(t)
+		opts.AddForeignEntry(t, s)
+		testListForeignEntries(t, s)
+	})
+	t.Run("DeleteKey", func(t *testing.T) {
+		if opts.CannotDelete {
+			t.Skip("store can't delete keys")
+		}
+		testDeleteKey(t, newStore(t))
+	})
+	t.Run("DeleteMissing", func(t *testing.T) {
+		if opts.CannotDelete {
+			t.Skip("store can't delete keys")
+		}
+		testDeleteMissing(t, newStore(t))
+	})
+}
+
+func testStoreAndRetrieve(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	keyPair := newKeyPair(t)
+
+	pubKey, err := s.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	kp, err := s.RetrieveKey(ctx, pubKey, testPassword)
+	require.NoError(t, err)
+	pk := kp.PubKey.Bytes()
+	assert.Equal(t, pubKey, hex.EncodeToString(pk[:]), "public key mismatch")
+	sk := kp.PrivKey.Bytes()
+	assert.Equal(
+		t,
+		hex.EncodeToString(keyPair.PrivateKey),
+		hex.EncodeToString(sk[:]),
+		"private key mismatch",
+	)
+}
+
+func testStoreDuplicate(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	keyPair := newKeyPair(t)
+
+	pubKey, err := s.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	_, err = s.StoreKey(ctx, keyPair)
+	assert.ErrorIs(t, err, store.ErrKeyAlreadyExists)
+
+	// The existing key must be left untouched
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.NoError(t, err)
+}
+
+func testRetrieveMissing(t *testing.T, s store.Store) {
+	_, err := s.RetrieveKey(context.Background(), newPubKey(t), testPassword)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+}
+
+func testRetrieveWrongPassword(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	pubKey, err := s.StoreKey(ctx, newKeyPair(t))
+	require.NoError(t, err)
+
+	_, err = s.RetrieveKey(ctx, pubKey, "wrong password")
+	assert.ErrorIs(t, err, store.ErrInvalidPassword)
+}
+
+func testListKeys(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	keys, err := s.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	pubKeys := make([]string, 0, listedKeys)
+	for i := 0; i < listedKeys; i++ {
+		pubKey, err := s.StoreKey(ctx, newKeyPair(t))
+		require.NoError(t, err)
+		pubKeys = append(pubKeys, pubKey)
+	}
+
+	keys, err = s.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, pubKeys, keys)
+}
+
+func testListForeignEntries(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	pubKey, err := s.StoreKey(ctx, newKeyPair(t))
+	require.NoError(t, err)
+
+	keys, err := s.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{pubKey}, keys)
+}
+
+func testDeleteKey(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	keyPair := newKeyPair(t)
+
+	pubKey, err := s.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteKey(ctx, pubKey))
+
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+	assert.ErrorIs(t, s.DeleteKey(ctx, pubKey), store.ErrKeyNotFound)
+
+	keys, err := s.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.NotContains(t, keys, pubKey)
+
+	// A deleted key can be stored again
+	_, err = s.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+	_, err = s.RetrieveKey(ctx, pubKey, testPassword)
+	assert.NoError(t, err)
+}
+
+func testDeleteMissing(t *testing.T, s store.Store) {
+	err := s.DeleteKey(context.Background(), newPubKey(t))
+	assert.ErrorIs(t, err, store.ErrKeyNotFound)
+}
+
+func newKeyPair(t *testing.T) *keystore.KeyPair {
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	return keyPair
+}
+
+// newPubKey returns the public key of a key pair which isn't stored
+func newPubKey(t *testing.T) string {
+	pubKey, err := keystore.BlsSkToG1Pk(newKeyPair(t).PrivateKey, string(curve.BN254))
+	require.NoError(t, err)
+	return pubKey
+}
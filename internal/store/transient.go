@@ -0,0 +1,11 @@
+package store
+
+// TransientClassifier is implemented by the stores which recognize the
+// transient failures of their backend, such as a throttled request or an
+// unavailable service, so that the decorators retry them without knowing the
+// errors of every backend
+type TransientClassifier interface {
+	// Transient reports whether err, returned by the store, is a failure of
+	// the backend which may not happen again
+	Transient(err error) bool
+}
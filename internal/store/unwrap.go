@@ -0,0 +1,30 @@
+package store
+
+// Wrapper is implemented by the stores decorating another store, such as
+// the retry and timeout decorators
+type Wrapper interface {
+	// Unwrap returns the decorated store
+	Unwrap() Store
+}
+
+// As returns the first store implementing T among s and the stores it
+// decorates. The optional interfaces of a backend, such as Versioner, must be
+// looked up with As rather than a type assertion, so that they are found
+// behind the wrappers which don't implement them. The decorators of the
+// decorator package implement the Versioner and AtomicStorer interfaces of
+// their store, so that these operations are decorated too.
+func As[T any](s Store) (T, bool) {
+	for s != nil {
+		if t, ok := s.(T); ok {
+			return t, true
+		}
+		w, ok := s.(Wrapper)
+		if !ok {
+			break
+		}
+		s = w.Unwrap()
+	}
+
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,209 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	DefaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	loginTimeout    = 30 * time.Second
+	minLoginBackoff = time.Second
+	maxLoginBackoff = time.Minute
+)
+
+// login authenticates the client with the configured auth method. It returns
+// the auth secret to renew, or nil when the token can't be renewed.
+func (s *Store) login(ctx context.Context) (*api.Secret, error) {
+	switch s.config.AuthMethod {
+	case TokenAuthMethod:
+		return s.loginWithToken(ctx)
+	case AppRoleAuthMethod:
+		return s.loginWithAuthMount(ctx, map[string]interface{}{
+			"role_id":   s.config.AppRoleID,
+			"secret_id": s.config.AppRoleSecretID,
+		})
+	case KubernetesAuthMethod:
+		tokenPath := s.config.KubernetesTokenPath
+		if tokenPath == "" {
+			tokenPath = DefaultKubernetesTokenPath
+		}
+		jwt, err := os.ReadFile(tokenPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read service account token: %w", err)
+		}
+		return s.loginWithAuthMount(ctx, map[string]interface{}{
+			"role": s.config.KubernetesRole,
+			"jwt":  strings.TrimSpace(string(jwt)),
+		})
+	default:
+		return nil, fmt.Errorf("unsupported Vault auth method: %s", s.config.AuthMethod)
+	}
+}
+
+func (s *Store) loginWithToken(ctx context.Context) (*api.Secret, error) {
+	s.client.SetToken(s.config.Token)
+
+	// Look the token up to fail early when it's invalid
+	self, err := s.client.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	renewable, err := self.TokenIsRenewable()
+	if err != nil {
+		return nil, err
+	}
+	if !renewable {
+		return nil, nil
+	}
+
+	ttl, err := self.TokenTTL()
+	if err != nil {
+		return nil, err
+	}
+	return &api.Secret{
+		Auth: &api.SecretAuth{
+			ClientToken:   s.config.Token,
+			Renewable:     true,
+			LeaseDuration: int(ttl.Seconds()),
+		},
+	}, nil
+}
+
+func (s *Store) loginWithAuthMount(
+	ctx context.Context,
+	data map[string]interface{},
+) (*api.Secret, error) {
+	mount := s.config.AuthMount
+	if mount == "" {
+		mount = string(s.config.AuthMethod)
+	}
+
+	secret, err := s.client.Logical().WriteWithContext(ctx, path.Join("auth", mount, "login"), data)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Auth == nil {
+		return nil, errors.New("login response has no auth information")
+	}
+
+	s.client.SetToken(secret.Auth.ClientToken)
+	if !secret.Auth.Renewable {
+		return nil, nil
+	}
+	return secret, nil
+}
+
+// renewToken keeps the token valid until Close is called. Tokens from AppRole
+// and Kubernetes logins are replaced by logging in again once they can no
+// longer be renewed.
+func (s *Store) renewToken(authSecret *api.Secret) {
+	defer close(s.done)
+
+	for {
+		if authSecret != nil {
+			if stopped := s.watchToken(authSecret); stopped {
+				return
+			}
+		} else if s.config.AuthMethod != TokenAuthMethod {
+			// The token can't be renewed, use it until most of its TTL passed
+			if stopped := s.waitForTokenExpiry(); stopped {
+				return
+			}
+		}
+
+		if s.config.AuthMethod == TokenAuthMethod {
+			if authSecret != nil {
+				s.logger.Error("Vault token can no longer be renewed, restart with a new token")
+			}
+			<-s.stop
+			return
+		}
+
+		authSecret = s.loginWithRetry()
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+	}
+}
+
+// watchToken renews the token until it reaches its max TTL. It returns true
+// when the store is closed.
+func (s *Store) watchToken(authSecret *api.Secret) bool {
+	watcher, err := s.client.NewLifetimeWatcher(&api.LifetimeWatcherInput{Secret: authSecret})
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to watch Vault token: %v", err))
+		return false
+	}
+	go watcher.Start()
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return true
+		case err := <-watcher.DoneCh():
+			if err != nil {
+				s.logger.Error(fmt.Sprintf("Failed to renew Vault token: %v", err))
+			}
+			return false
+		case <-watcher.RenewCh():
+			s.logger.Debug("Renewed Vault token")
+		}
+	}
+}
+
+// loginWithRetry logs in again, retrying with backoff until it succeeds or
+// the store is closed. Like login it returns nil for tokens that can't be renewed.
+func (s *Store) loginWithRetry() *api.Secret {
+	backoff := minLoginBackoff
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), loginTimeout)
+		authSecret, err := s.login(ctx)
+		cancel()
+		if err == nil {
+			s.logger.Info("Logged in to Vault again")
+			return authSecret
+		}
+		s.logger.Error(fmt.Sprintf("Failed to log in to Vault: %v", err))
+
+		select {
+		case <-s.stop:
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxLoginBackoff)
+	}
+}
+
+// waitForTokenExpiry waits until most of the TTL of the current token has
+// passed. It returns true when the store is closed.
+func (s *Store) waitForTokenExpiry() bool {
+	wait := maxLoginBackoff
+	ctx, cancel := context.WithTimeout(context.Background(), loginTimeout)
+	self, err := s.client.Auth().Token().LookupSelfWithContext(ctx)
+	cancel()
+	if err == nil {
+		if ttl, err := self.TokenTTL(); err == nil && ttl > 0 {
+			wait = ttl * 4 / 5
+		}
+	}
+
+	select {
+	case <-s.stop:
+		return true
+	case <-time.After(wait):
+		return false
+	}
+}
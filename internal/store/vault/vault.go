@@ -0,0 +1,314 @@
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/Layr-Labs/bn254-keystore-go/curve"
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+	"github.com/Layr-Labs/cerberus/internal/store"
+)
+
+var (
+	_ store.Store               = (*Store)(nil)
+	_ store.TransientClassifier = (*Store)(nil)
+)
+
+const (
+	// Fields of the KV secret. Keys wrapped by Transit are stored as
+	// ciphertext, the others as the hex encoded private key.
+	privateKeyField = "private_key"
+	ciphertextField = "ciphertext"
+)
+
+type AuthMethod string
+
+const (
+	TokenAuthMethod      AuthMethod = "token"
+	AppRoleAuthMethod    AuthMethod = "approle"
+	KubernetesAuthMethod AuthMethod = "kubernetes"
+)
+
+type Config struct {
+	Address   string
+	Namespace string
+
+	AuthMethod AuthMethod
+	// AuthMount is the mount of the auth method, defaults to the method name
+	AuthMount string
+
+	// Token auth parameters
+	Token string
+
+	// AppRole auth parameters
+	AppRoleID       string
+	AppRoleSecretID string
+
+	// Kubernetes auth parameters
+	KubernetesRole      string
+	KubernetesTokenPath string
+
+	// KVMount is the mount of the KV v2 secrets engine and PathPrefix the
+	// path under it where the keys are stored
+	KVMount    string
+	PathPrefix string
+
+	// TransitKey is the name of the Transit key wrapping the private keys.
+	// Keys are stored unwrapped when empty.
+	TransitMount string
+	TransitKey   string
+}
+
+type Store struct {
+	client *api.Client
+	kv     *api.KVv2
+	config Config
+
+	logger *slog.Logger
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewStore(config Config, logger *slog.Logger) (*Store, error) {
+	clientConfig := api.DefaultConfig()
+	if clientConfig.Error != nil {
+		return nil, fmt.Errorf("failed to configure Vault client: %w", clientConfig.Error)
+	}
+	clientConfig.Address = config.Address
+
+	client, err := api.NewClient(clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vault client: %w", err)
+	}
+	if config.Namespace != "" {
+		client.SetNamespace(config.Namespace)
+	}
+	// Never pick up a token from the environment or the token helper,
+	// the configured auth method is the only source
+	client.ClearToken()
+
+	s := &Store{
+		client: client,
+		kv:     client.KVv2(config.KVMount),
+		config: config,
+		logger: logger.With("component", "vault-store"),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	authSecret, err := s.login(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate with Vault: %w", err)
+	}
+
+	go s.renewToken(authSecret)
+
+	return s, nil
+}
+
+// Close stops renewing the Vault token
+func (s *Store) Close() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+}
+
+func (s *Store) RetrieveKey(
+	ctx context.Context,
+	pubKey string,
+	password string,
+) (*crypto.KeyPair, error) {
+	secret, err := s.kv.Get(ctx, s.secretPath(pubKey))
+	if err != nil {
+		if errors.Is(err, api.ErrSecretNotFound) {
+			return nil, store.ErrKeyNotFound
+		}
+		return nil, err
+	}
+	// The latest version was deleted
+	if secret.Data == nil {
+		return nil, store.ErrKeyNotFound
+	}
+
+	skBytes, err := s.decodePrivateKey(ctx, secret.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	privKey, err := crypto.PrivateKeyFromBytes(skBytes)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.NewKeyPair(privKey), nil
+}
+
+func (s *Store) StoreKey(ctx context.Context, keyPair *keystore.KeyPair) (string, error) {
+	pubKey, err := keystore.BlsSkToG1Pk(keyPair.PrivateKey, string(curve.BN254))
+	if err != nil {
+		return "", err
+	}
+
+	data, err := s.encodePrivateKey(ctx, keyPair.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	// A check-and-set version of 0 only writes the secret if it doesn't exist yet
+	_, err = s.kv.Put(ctx, s.secretPath(pubKey), data, api.WithCheckAndSet(0))
+	if err != nil {
+		if isCheckAndSetError(err) {
+			return "", store.ErrKeyAlreadyExists
+		}
+		return "", err
+	}
+
+	return pubKey, nil
+}
+
+func (s *Store) ListKeys(ctx context.Context) ([]string, error) {
+	listPath := path.Join(s.config.KVMount, "metadata", s.config.PathPrefix)
+	secret, err := s.client.Logical().ListWithContext(ctx, listPath)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return []string{}, nil
+	}
+
+	entries, ok := secret.Data["keys"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected response listing %s", listPath)
+	}
+
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name, ok := entry.(string)
+		// Skip sub folders, keys are stored directly under the prefix
+		if !ok || strings.HasSuffix(name, "/") {
+			continue
+		}
+		keys = append(keys, name)
+	}
+
+	s.logger.Debug(fmt.Sprintf("Found %d keys", len(keys)))
+	return keys, nil
+}
+
+func (s *Store) DeleteKey(ctx context.Context, pubKey string) error {
+	secretPath := s.secretPath(pubKey)
+
+	if _, err := s.kv.GetMetadata(ctx, secretPath); err != nil {
+		if errors.Is(err, api.ErrSecretNotFound) {
+			return store.ErrKeyNotFound
+		}
+		return err
+	}
+
+	// Delete every version, otherwise the key can't be stored again
+	return s.kv.DeleteMetadata(ctx, secretPath)
+}
+
+// Transient reports the throttled requests and the server errors of Vault,
+// such as a sealed or standby server
+func (s *Store) Transient(err error) bool {
+	var respErr *api.ResponseError
+	return errors.As(err, &respErr) &&
+		(respErr.StatusCode == http.StatusTooManyRequests ||
+			respErr.StatusCode >= http.StatusInternalServerError)
+}
+
+func (s *Store) secretPath(pubKey string) string {
+	return path.Join(s.config.PathPrefix, pubKey)
+}
+
+func (s *Store) encodePrivateKey(
+	ctx context.Context,
+	skBytes []byte,
+) (map[string]interface{}, error) {
+	if s.config.TransitKey == "" {
+		return map[string]interface{}{privateKeyField: hex.EncodeToString(skBytes)}, nil
+	}
+
+	secret, err := s.client.Logical().WriteWithContext(
+		ctx,
+		path.Join(s.config.TransitMount, "encrypt", s.config.TransitKey),
+		map[string]interface{}{"plaintext": base64.StdEncoding.EncodeToString(skBytes)},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap key with Transit: %w", err)
+	}
+	if secret == nil {
+		return nil, errors.New("failed to wrap key with Transit: empty response")
+	}
+
+	ciphertext, ok := secret.Data["ciphertext"].(string)
+	if !ok {
+		return nil, errors.New("failed to wrap key with Transit: missing ciphertext")
+	}
+	return map[string]interface{}{ciphertextField: ciphertext}, nil
+}
+
+// decodePrivateKey reads the private key of a secret. Unwrapped keys stay
+// readable after Transit wrapping is turned on, so existing keys keep working.
+func (s *Store) decodePrivateKey(
+	ctx context.Context,
+	data map[string]interface{},
+) ([]byte, error) {
+	if skHex, ok := data[privateKeyField].(string); ok {
+		return hex.DecodeString(skHex)
+	}
+
+	ciphertext, ok := data[ciphertextField].(string)
+	if !ok {
+		return nil, errors.New("secret has no private key")
+	}
+	if s.config.TransitKey == "" {
+		return nil, errors.New("key is wrapped by Transit but no Transit key is configured")
+	}
+
+	secret, err := s.client.Logical().WriteWithContext(
+		ctx,
+		path.Join(s.config.TransitMount, "decrypt", s.config.TransitKey),
+		map[string]interface{}{"ciphertext": ciphertext},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key with Transit: %w", err)
+	}
+	if secret == nil {
+		return nil, errors.New("failed to unwrap key with Transit: empty response")
+	}
+
+	plaintext, ok := secret.Data["plaintext"].(string)
+	if !ok {
+		return nil, errors.New("failed to unwrap key with Transit: missing plaintext")
+	}
+	return base64.StdEncoding.DecodeString(plaintext)
+}
+
+func isCheckAndSetError(err error) bool {
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	for _, e := range respErr.Errors {
+		if strings.Contains(e, "check-and-set") {
+			return true
+		}
+	}
+	return false
+}
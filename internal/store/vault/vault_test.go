@@ -0,0 +1,258 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Layr-Labs/bn254-keystore-go/keystore"
+	"github.com/Layr-Labs/bn254-keystore-go/mnemonic"
+	"github.com/hashicorp/vault/api"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/storetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	rootToken    = "root"
+	testPassword = "p@$$w0rd"
+	transitKey   = "cerberus"
+)
+
+// startVault starts a Vault dev server with the Transit and AppRole engines
+// enabled, and returns its address and the AppRole credentials
+func startVault(t *testing.T) (string, string, string) {
+	ctx := context.Background()
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "hashicorp/vault:1.15",
+			ExposedPorts: []string{"8200/tcp"},
+			Env: map[string]string{
+				"VAULT_DEV_ROOT_TOKEN_ID":  rootToken,
+				"VAULT_DEV_LISTEN_ADDRESS": "0.0.0.0:8200",
+				"SKIP_SETCAP":              "true",
+			},
+			WaitingFor: wait.ForHTTP("/v1/sys/health").WithPort("8200/tcp"),
+		},
+		Started: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = container.Terminate(context.Background())
+	})
+
+	host, err := container.Host(ctx)
+	require.NoError(t, err)
+	port, err := container.MappedPort(ctx, "8200")
+	require.NoError(t, err)
+	address := fmt.Sprintf("http://%s:%s", host, port.Port())
+
+	clientConfig := api.DefaultConfig()
+	clientConfig.Address = address
+	client, err := api.NewClient(clientConfig)
+	require.NoError(t, err)
+	client.SetToken(rootToken)
+
+	err = client.Sys().MountWithContext(ctx, "transit", &api.MountInput{Type: "transit"})
+	require.NoError(t, err)
+	_, err = client.Logical().WriteWithContext(ctx, "transit/keys/"+transitKey, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, client.Sys().EnableAuthWithOptionsWithContext(
+		ctx,
+		"approle",
+		&api.EnableAuthOptions{Type: "approle"},
+	))
+	err = client.Sys().PutPolicyWithContext(ctx, "cerberus", `
+		path "secret/*" {
+			capabilities = ["create", "read", "update", "delete", "list"]
+		}
+	`)
+	require.NoError(t, err)
+
+	rolePath := "auth/approle/role/cerberus"
+	_, err = client.Logical().WriteWithContext(ctx, rolePath, map[string]interface{}{
+		"token_policies": "cerberus",
+		"token_ttl":      "1h",
+	})
+	require.NoError(t, err)
+
+	roleID, err := client.Logical().ReadWithContext(ctx, rolePath+"/role-id")
+	require.NoError(t, err)
+	secretID, err := client.Logical().WriteWithContext(ctx, rolePath+"/secret-id", nil)
+	require.NoError(t, err)
+
+	return address, roleID.Data["role_id"].(string), secretID.Data["secret_id"].(string)
+}
+
+func TestWithContainer_VaultStore(t *testing.T) {
+	address, roleID, secretID := startVault(t)
+
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{
+			name: "token auth",
+			config: Config{
+				Address:    address,
+				AuthMethod: TokenAuthMethod,
+				Token:      rootToken,
+				KVMount:    "secret",
+				PathPrefix: "cerberus/token",
+			},
+		},
+		{
+			name: "token auth with transit",
+			config: Config{
+				Address:      address,
+				AuthMethod:   TokenAuthMethod,
+				Token:        rootToken,
+				KVMount:      "secret",
+				PathPrefix:   "cerberus/transit",
+				TransitMount: "transit",
+				TransitKey:   transitKey,
+			},
+		},
+		{
+			name: "approle auth",
+			config: Config{
+				Address:         address,
+				AuthMethod:      AppRoleAuthMethod,
+				AppRoleID:       roleID,
+				AppRoleSecretID: secretID,
+				KVMount:         "secret",
+				PathPrefix:      "cerberus/approle",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			vs, err := NewStore(tt.config, testutils.GetTestLogger())
+			require.NoError(t, err)
+			t.Cleanup(vs.Close)
+
+			keys, err := vs.ListKeys(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, keys)
+
+			keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+			require.NoError(t, err)
+
+			pubKeyHex, err := vs.StoreKey(ctx, keyPair)
+			require.NoError(t, err)
+
+			_, err = vs.StoreKey(ctx, keyPair)
+			assert.ErrorIs(t, err, store.ErrKeyAlreadyExists)
+
+			storedKeyPair, err := vs.RetrieveKey(ctx, pubKeyHex, testPassword)
+			require.NoError(t, err)
+			pkBytes := storedKeyPair.PrivKey.Bytes()
+			assert.Equal(t, keyPair.PrivateKey, pkBytes[:])
+
+			keys, err = vs.ListKeys(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []string{pubKeyHex}, keys)
+
+			require.NoError(t, vs.DeleteKey(ctx, pubKeyHex))
+			_, err = vs.RetrieveKey(ctx, pubKeyHex, testPassword)
+			assert.ErrorIs(t, err, store.ErrKeyNotFound)
+			assert.ErrorIs(t, vs.DeleteKey(ctx, pubKeyHex), store.ErrKeyNotFound)
+
+			// A deleted key can be stored again
+			_, err = vs.StoreKey(ctx, keyPair)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestWithContainer_VaultStoreConformance(t *testing.T) {
+	address, _, _ := startVault(t)
+
+	for name, transit := range map[string]string{"plain": "", "transit": transitKey} {
+		t.Run(name, func(t *testing.T) {
+			// The subtests share the server, each one gets its own path prefix
+			storetest.Run(t, func(t *testing.T) store.Store {
+				vs, err := NewStore(Config{
+					Address:      address,
+					AuthMethod:   TokenAuthMethod,
+					Token:        rootToken,
+					KVMount:      "secret",
+					PathPrefix:   "cerberus/" + t.Name(),
+					TransitMount: "transit",
+					TransitKey:   transit,
+				}, testutils.GetTestLogger())
+				require.NoError(t, err)
+				t.Cleanup(vs.Close)
+				return vs
+			}, storetest.Options{IgnoresPassword: true})
+		})
+	}
+}
+
+func TestWithContainer_VaultStoreEnableTransit(t *testing.T) {
+	address, _, _ := startVault(t)
+	ctx := context.Background()
+
+	config := Config{
+		Address:    address,
+		AuthMethod: TokenAuthMethod,
+		Token:      rootToken,
+		KVMount:    "secret",
+		PathPrefix: "cerberus",
+	}
+	plainStore, err := NewStore(config, testutils.GetTestLogger())
+	require.NoError(t, err)
+	t.Cleanup(plainStore.Close)
+
+	keyPair, err := keystore.NewKeyPair(testPassword, mnemonic.English)
+	require.NoError(t, err)
+	pubKeyHex, err := plainStore.StoreKey(ctx, keyPair)
+	require.NoError(t, err)
+
+	// Keys stored before Transit was turned on stay readable
+	config.TransitMount = "transit"
+	config.TransitKey = transitKey
+	transitStore, err := NewStore(config, testutils.GetTestLogger())
+	require.NoError(t, err)
+	t.Cleanup(transitStore.Close)
+
+	storedKeyPair, err := transitStore.RetrieveKey(ctx, pubKeyHex, testPassword)
+	require.NoError(t, err)
+	pkBytes := storedKeyPair.PrivKey.Bytes()
+	assert.Equal(t, keyPair.PrivateKey, pkBytes[:])
+}
+
+func TestVaultStoreInvalidToken(t *testing.T) {
+	_, err := NewStore(Config{
+		Address:    "http://127.0.0.1:1",
+		AuthMethod: TokenAuthMethod,
+		Token:      "invalid",
+		KVMount:    "secret",
+	}, testutils.GetTestLogger())
+	assert.Error(t, err)
+
+	_, err = NewStore(Config{
+		Address:    "http://127.0.0.1:1",
+		AuthMethod: "unknown",
+		KVMount:    "secret",
+	}, testutils.GetTestLogger())
+	assert.Error(t, err)
+}
+
+func TestTransient(t *testing.T) {
+	s := &Store{}
+	assert.True(t, s.Transient(fmt.Errorf("read: %w", &api.ResponseError{StatusCode: 503})))
+	assert.True(t, s.Transient(&api.ResponseError{StatusCode: 429}))
+	assert.False(t, s.Transient(&api.ResponseError{StatusCode: 403}))
+	assert.False(t, s.Transient(store.ErrKeyNotFound))
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/crypto"
+)
+
+var (
+	ErrVersionNotFound       = errors.New("key version not found in store")
+	ErrVersioningUnsupported = errors.New("store doesn't keep key versions")
+)
+
+// KeyVersion describes a version of a key in a store keeping the history of
+// the keys. Every version of a key holds the same private key, possibly
+// encrypted differently.
+type KeyVersion struct {
+	// ID identifies the version in the backend
+	ID        string
+	CreatedAt time.Time
+
+	// Current is true for the version read by RetrieveKey
+	Current bool
+}
+
+// Versioner is implemented by the stores keeping the history of the keys,
+// such as the cloud secret managers
+type Versioner interface {
+	// CurrentVersion returns the ID of the version read by RetrieveKey
+	// Returns ErrKeyNotFound if the key is not in the store
+	CurrentVersion(ctx context.Context, pubKey string) (string, error)
+
+	// ListVersions returns the versions of the key which can still be read,
+	// oldest first
+	// Returns ErrKeyNotFound if the key is not in the store
+	ListVersions(ctx context.Context, pubKey string) ([]KeyVersion, error)
+
+	// RetrieveKeyVersion retrieves the private key from a version of the key
+	// Returns ErrVersionNotFound if the version doesn't exist or can't be
+	// read anymore
+	RetrieveKeyVersion(
+		ctx context.Context,
+		pubKey string,
+		versionID string,
+		password string,
+	) (*crypto.KeyPair, error)
+
+	// RewrapKey writes a version of the key, or the current version if
+	// versionID is empty, as a new current version envelope encrypted with
+	// the current master key, and returns the ID of the new version
+	RewrapKey(ctx context.Context, pubKey string, versionID string) (string, error)
+
+	// RollbackKey makes an earlier version of the key current again and
+	// returns the ID of the current version
+	RollbackKey(ctx context.Context, pubKey string, versionID string) (string, error)
+}
+
+// RetrieveKeyVersion retrieves a version of the key from s, or its current
+// version if versionID is empty
+func RetrieveKeyVersion(
+	ctx context.Context,
+	s Store,
+	pubKey string,
+	versionID string,
+	password string,
+) (*crypto.KeyPair, error) {
+	if versionID == "" {
+		return s.RetrieveKey(ctx, pubKey, password)
+	}
+	v, ok := As[Versioner](s)
+	if !ok {
+		return nil, ErrVersioningUnsupported
+	}
+	return v.RetrieveKeyVersion(ctx, pubKey, versionID, password)
+}
+
+// CurrentVersion returns the ID of the current version of the key in s, or
+// an empty ID if s doesn't keep versions
+func CurrentVersion(ctx context.Context, s Store, pubKey string) (string, error) {
+	v, ok := As[Versioner](s)
+	if !ok {
+		return "", nil
+	}
+	versionID, err := v.CurrentVersion(ctx, pubKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get current key version: %w", err)
+	}
+	return versionID, nil
+}
@@ -0,0 +1,64 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// KeyUsageHeader is the response header carrying the usage of each returned
+// key as a JSON encoded Summary, one value per key. The cerberus-api responses
+// have no usage fields yet.
+const KeyUsageHeader = "x-key-usage"
+
+// Summary is the usage of a key over all signing methods
+type Summary struct {
+	PublicKeyG1            string           `json:"public_key_g1"`
+	LastUsedAt             *time.Time       `json:"last_used_at,omitempty"`
+	SignatureCount         int64            `json:"signature_count"`
+	SignatureCountByMethod map[string]int64 `json:"signature_count_by_method,omitempty"`
+}
+
+// Summarize aggregates the per method usage by key
+func Summarize(usage []*model.KeyUsage) map[string]*Summary {
+	summaries := make(map[string]*Summary)
+	for _, u := range usage {
+		s, ok := summaries[u.PublicKeyG1]
+		if !ok {
+			s = &Summary{
+				PublicKeyG1:            u.PublicKeyG1,
+				SignatureCountByMethod: make(map[string]int64),
+			}
+			summaries[u.PublicKeyG1] = s
+		}
+		s.SignatureCount += u.SignatureCount
+		s.SignatureCountByMethod[u.Method] += u.SignatureCount
+		if s.LastUsedAt == nil || u.LastUsedAt.After(*s.LastUsedAt) {
+			lastUsedAt := u.LastUsedAt
+			s.LastUsedAt = &lastUsedAt
+		}
+	}
+	return summaries
+}
+
+// SetKeyUsageHeader sends the usage of the keys to the client as a response header
+func SetKeyUsageHeader(ctx context.Context, summaries []*Summary) error {
+	if len(summaries) == 0 {
+		return nil
+	}
+
+	md := metadata.MD{}
+	for _, s := range summaries {
+		value, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		md.Append(KeyUsageHeader, string(value))
+	}
+	return grpc.SetHeader(ctx, md)
+}
@@ -0,0 +1,160 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+	"github.com/Layr-Labs/cerberus/internal/metrics"
+)
+
+const flushTimeout = 10 * time.Second
+
+// Recorder records that a key was used to sign
+type Recorder interface {
+	Record(publicKeyG1 string, method string)
+}
+
+type usageKey struct {
+	publicKeyG1 string
+	method      string
+}
+
+// Tracker counts signatures in memory and writes them to the database in
+// batches from a background goroutine, so that signing never waits on it
+type Tracker struct {
+	keyUsageRepo  repository.KeyUsageRepository
+	metrics       metrics.KeyUsageRecorder
+	flushInterval time.Duration
+	logger        *slog.Logger
+
+	mu      sync.Mutex
+	pending map[usageKey]*model.KeyUsage
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+var _ Recorder = (*Tracker)(nil)
+
+func NewTracker(
+	keyUsageRepo repository.KeyUsageRepository,
+	metrics metrics.KeyUsageRecorder,
+	flushInterval time.Duration,
+	logger *slog.Logger,
+) *Tracker {
+	return &Tracker{
+		keyUsageRepo:  keyUsageRepo,
+		metrics:       metrics,
+		flushInterval: flushInterval,
+		logger:        logger.With("component", "usage-tracker"),
+		pending:       make(map[usageKey]*model.KeyUsage),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+func (t *Tracker) Record(publicKeyG1 string, method string) {
+	t.metrics.RecordSignature(publicKeyG1, method)
+
+	now := time.Now().UTC()
+	key := usageKey{publicKeyG1: publicKeyG1, method: method}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u, ok := t.pending[key]
+	if !ok {
+		u = &model.KeyUsage{PublicKeyG1: publicKeyG1, Method: method}
+		t.pending[key] = u
+	}
+	u.SignatureCount++
+	u.LastUsedAt = now
+}
+
+// Start flushes the recorded usage periodically until Stop is called
+func (t *Tracker) Start() {
+	go func() {
+		defer close(t.done)
+		ticker := time.NewTicker(t.flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.flushWithTimeout()
+			case <-t.stop:
+				t.flushWithTimeout()
+				return
+			}
+		}
+	}()
+}
+
+// Stop flushes the remaining usage and stops the background flushes
+func (t *Tracker) Stop() {
+	close(t.stop)
+	<-t.done
+}
+
+// Flush writes the usage recorded since the last flush to the database.
+// On failure the usage is kept and written with the next flush.
+func (t *Tracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	batch := t.pending
+	t.pending = make(map[usageKey]*model.KeyUsage)
+	t.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	usage := make([]*model.KeyUsage, 0, len(batch))
+	for _, u := range batch {
+		usage = append(usage, u)
+	}
+
+	if err := t.keyUsageRepo.RecordUsage(ctx, usage); err != nil {
+		t.requeue(batch)
+		return err
+	}
+
+	t.logger.Debug(fmt.Sprintf("Flushed usage of %d keys", len(usage)))
+	return nil
+}
+
+func (t *Tracker) requeue(batch map[usageKey]*model.KeyUsage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, u := range batch {
+		current, ok := t.pending[key]
+		if !ok {
+			t.pending[key] = u
+			continue
+		}
+		current.SignatureCount += u.SignatureCount
+		if u.LastUsedAt.After(current.LastUsedAt) {
+			current.LastUsedAt = u.LastUsedAt
+		}
+	}
+}
+
+func (t *Tracker) flushWithTimeout() {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	if err := t.Flush(ctx); err != nil {
+		t.logger.Error(fmt.Sprintf("Failed to flush key usage: %v", err))
+	}
+}
+
+type NoopRecorder struct{}
+
+func NewNoopRecorder() *NoopRecorder {
+	return &NoopRecorder{}
+}
+
+func (NoopRecorder) Record(publicKeyG1 string, method string) {}
+
+var _ Recorder = (*NoopRecorder)(nil)
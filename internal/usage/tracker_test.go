@@ -0,0 +1,82 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/database/model"
+	"github.com/Layr-Labs/cerberus/internal/database/repository"
+	"github.com/Layr-Labs/cerberus/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeKeyUsageRepo struct {
+	repository.KeyUsageRepository
+	recorded  []*model.KeyUsage
+	recordErr error
+}
+
+func (r *fakeKeyUsageRepo) RecordUsage(_ context.Context, usage []*model.KeyUsage) error {
+	if r.recordErr != nil {
+		return r.recordErr
+	}
+	r.recorded = append(r.recorded, usage...)
+	return nil
+}
+
+func TestTrackerFlush(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeKeyUsageRepo{}
+	tracker := NewTracker(
+		repo,
+		metrics.NewNoopKeyUsageMetrics(),
+		time.Hour,
+		testutils.GetTestLogger(),
+	)
+
+	tracker.Record("key_1", "SignGeneric")
+	tracker.Record("key_1", "SignGeneric")
+	tracker.Record("key_1", "SignG1")
+
+	// A failed flush keeps the usage for the next one
+	repo.recordErr = errors.New("database unavailable")
+	require.Error(t, tracker.Flush(ctx))
+	tracker.Record("key_1", "SignGeneric")
+
+	repo.recordErr = nil
+	require.NoError(t, tracker.Flush(ctx))
+
+	summaries := Summarize(repo.recorded)
+	require.Contains(t, summaries, "key_1")
+	assert.Equal(t, int64(4), summaries["key_1"].SignatureCount)
+	assert.Equal(t, int64(3), summaries["key_1"].SignatureCountByMethod["SignGeneric"])
+	assert.Equal(t, int64(1), summaries["key_1"].SignatureCountByMethod["SignG1"])
+	assert.NotNil(t, summaries["key_1"].LastUsedAt)
+
+	// Nothing left to write
+	repo.recorded = nil
+	require.NoError(t, tracker.Flush(ctx))
+	assert.Empty(t, repo.recorded)
+}
+
+func TestTrackerStopFlushes(t *testing.T) {
+	repo := &fakeKeyUsageRepo{}
+	tracker := NewTracker(
+		repo,
+		metrics.NewNoopKeyUsageMetrics(),
+		time.Hour,
+		testutils.GetTestLogger(),
+	)
+
+	tracker.Start()
+	tracker.Record("key_1", "SignG1")
+	tracker.Stop()
+
+	require.Len(t, repo.recorded, 1)
+	assert.Equal(t, int64(1), repo.recorded[0].SignatureCount)
+}
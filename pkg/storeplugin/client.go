@@ -0,0 +1,46 @@
+package storeplugin
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// Client calls a plugin
+type Client struct {
+	c StorePluginClient
+}
+
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{c: NewStorePluginClient(cc)}
+}
+
+func (c *Client) Capabilities(ctx context.Context) (*CapabilitiesResponse, error) {
+	return c.c.Capabilities(ctx, &CapabilitiesRequest{})
+}
+
+func (c *Client) Health(ctx context.Context) (*HealthResponse, error) {
+	return c.c.Health(ctx, &HealthRequest{})
+}
+
+func (c *Client) RetrieveKey(
+	ctx context.Context,
+	req *RetrieveKeyRequest,
+) (*RetrieveKeyResponse, error) {
+	return c.c.RetrieveKey(ctx, req)
+}
+
+func (c *Client) StoreKey(ctx context.Context, req *StoreKeyRequest) (*StoreKeyResponse, error) {
+	return c.c.StoreKey(ctx, req)
+}
+
+func (c *Client) ListKeys(ctx context.Context) (*ListKeysResponse, error) {
+	return c.c.ListKeys(ctx, &ListKeysRequest{})
+}
+
+func (c *Client) DeleteKey(
+	ctx context.Context,
+	req *DeleteKeyRequest,
+) (*DeleteKeyResponse, error) {
+	return c.c.DeleteKey(ctx, req)
+}
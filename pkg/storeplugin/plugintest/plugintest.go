@@ -0,0 +1,91 @@
+// Package plugintest checks that a store plugin implements the storeplugin
+// protocol the way cerberus expects, by running the conformance suite of the
+// built-in stores through the plugin store of cerberus.
+package plugintest
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/Layr-Labs/cerberus/internal/common/testutils"
+	"github.com/Layr-Labs/cerberus/internal/store"
+	"github.com/Layr-Labs/cerberus/internal/store/plugin"
+	"github.com/Layr-Labs/cerberus/internal/store/storetest"
+	"github.com/Layr-Labs/cerberus/pkg/storeplugin"
+)
+
+// knownCapabilities are the capabilities of this protocol version
+var knownCapabilities = []string{
+	storeplugin.CapabilityDeleteKey,
+	storeplugin.CapabilityPasswordEncryption,
+}
+
+// Plugin is a plugin under test, either running on Address or launched by
+// Command like cerberus does
+type Plugin struct {
+	Address string
+	Command []string
+}
+
+// Run runs the checks against the plugins returned by newPlugin, which must
+// return a plugin with no keys for each subtest
+func Run(t *testing.T, newPlugin func(t *testing.T) Plugin) {
+	s := connect(t, newPlugin(t))
+
+	t.Run("Capabilities", func(t *testing.T) {
+		assert.NotEmpty(t, s.Name(), "plugin must report a name")
+		for _, capability := range knownCapabilities {
+			t.Logf("%s: %t", capability, s.HasCapability(capability))
+		}
+	})
+	t.Run("Health", func(t *testing.T) {
+		assert.NoError(t, s.Health(context.Background()))
+	})
+
+	t.Run("Conformance", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) store.Store {
+			return connect(t, newPlugin(t))
+		}, storetest.Options{
+			IgnoresPassword: !s.HasCapability(storeplugin.CapabilityPasswordEncryption),
+			CannotDelete:    !s.HasCapability(storeplugin.CapabilityDeleteKey),
+		})
+	})
+}
+
+// Serve serves the plugin in the test process on a Unix socket until the end
+// of the test, and returns its address
+func Serve(t *testing.T, srv storeplugin.Server) string {
+	socket := filepath.Join(t.TempDir(), "plugin.sock")
+	lis, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	s := grpc.NewServer()
+	storeplugin.RegisterServer(s, srv)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(func() {
+		s.Stop()
+		_ = os.Remove(socket)
+	})
+	return "unix://" + socket
+}
+
+// connect returns the plugin store of cerberus connected to the plugin,
+// closed at the end of the test
+func connect(t *testing.T, p Plugin) *plugin.Store {
+	s, err := plugin.NewStore(context.Background(), plugin.Config{
+		Address: p.Address,
+		Command: p.Command,
+	}, testutils.GetTestLogger())
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	return s
+}
@@ -0,0 +1,42 @@
+// Package storeplugin defines the protocol between cerberus and the
+// out-of-process store plugins, so that custody backends can be added
+// without changing cerberus.
+//
+// A plugin is a gRPC server of the StorePlugin service of storeplugin.proto,
+// listening on a Unix socket or a loopback address, so plugins can be written
+// in any language with a gRPC library. Private keys and passwords cross the
+// connection, which must never leave the host.
+//
+// Plugins report errors with gRPC status codes:
+//   - NotFound when the key isn't in the store
+//   - AlreadyExists when storing a key which is already in the store
+//   - PermissionDenied when the password doesn't decrypt the key
+//   - Unimplemented for the methods of the capabilities they don't have
+//
+// The messages and the gRPC service are generated from storeplugin.proto with
+// make generate-store-plugin.
+package storeplugin
+
+// ProtocolVersion is the version of the protocol. A change of the messages or
+// of their meaning which isn't backward compatible increases it.
+const ProtocolVersion = 1
+
+// AddressEnv is the environment variable holding the address a plugin
+// launched by cerberus must listen on, such as unix:///tmp/plugin.sock
+const AddressEnv = "CERBERUS_PLUGIN_ADDRESS"
+
+// Capabilities of the plugins beyond retrieving, storing and listing keys
+const (
+	// CapabilityDeleteKey is set by the plugins implementing DeleteKey
+	CapabilityDeleteKey = "delete_key"
+
+	// CapabilityPasswordEncryption is set by the plugins encrypting the keys
+	// with the password of the requests, and checking it when retrieving them
+	CapabilityPasswordEncryption = "password_encryption"
+)
+
+// Health statuses of the plugins
+const (
+	StatusServing    = "SERVING"
+	StatusNotServing = "NOT_SERVING"
+)
@@ -0,0 +1,91 @@
+package storeplugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server is implemented by the plugins, the methods are described in the
+// StorePlugin service of storeplugin.proto
+type Server = StorePluginServer
+
+// UnimplementedServer can be embedded by the plugins without DeleteKey
+type UnimplementedServer struct{}
+
+func (UnimplementedServer) DeleteKey(
+	context.Context,
+	*DeleteKeyRequest,
+) (*DeleteKeyResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteKey not implemented")
+}
+
+// RegisterServer registers the plugin on the gRPC server
+func RegisterServer(s grpc.ServiceRegistrar, srv Server) {
+	RegisterStorePluginServer(s, srv)
+}
+
+// Serve serves the plugin on the address until the context is done. The
+// address is read from AddressEnv when empty, and a stale Unix socket left by
+// a previous run is removed.
+func Serve(ctx context.Context, address string, srv Server) error {
+	if address == "" {
+		address = os.Getenv(AddressEnv)
+	}
+	network, addr, err := ParseAddress(address)
+	if err != nil {
+		return err
+	}
+	if network == "unix" {
+		if err := os.Remove(addr); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	lis, err := net.Listen(network, addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	s := grpc.NewServer()
+	RegisterServer(s, srv)
+
+	go func() {
+		<-ctx.Done()
+		s.GracefulStop()
+	}()
+	return s.Serve(lis)
+}
+
+// ParseAddress returns the network and the address of a plugin address,
+// either unix:///path/to/socket or a host:port on the loopback interface.
+// The keys cross the connection, so other hosts are refused.
+func ParseAddress(address string) (string, string, error) {
+	if path, ok := strings.CutPrefix(address, "unix://"); ok {
+		if path == "" {
+			return "", "", fmt.Errorf("plugin address %q has no socket path", address)
+		}
+		return "unix", path, nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid plugin address %q: %w", address, err)
+	}
+	if host != "localhost" {
+		ip := net.ParseIP(host)
+		if ip == nil || !ip.IsLoopback() {
+			return "", "", fmt.Errorf(
+				"plugin address %q must be a Unix socket or a loopback address",
+				address,
+			)
+		}
+	}
+	return "tcp", address, nil
+}
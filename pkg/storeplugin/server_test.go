@@ -0,0 +1,35 @@
+package storeplugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddress(t *testing.T) {
+	valid := map[string][2]string{
+		"unix:///run/cerberus/plugin.sock": {"unix", "/run/cerberus/plugin.sock"},
+		"localhost:7000":                   {"tcp", "localhost:7000"},
+		"127.0.0.1:7000":                   {"tcp", "127.0.0.1:7000"},
+		"[::1]:7000":                       {"tcp", "[::1]:7000"},
+	}
+	for address, expected := range valid {
+		network, addr, err := ParseAddress(address)
+		require.NoError(t, err, address)
+		assert.Equal(t, expected[0], network, address)
+		assert.Equal(t, expected[1], addr, address)
+	}
+
+	for _, address := range []string{
+		"",
+		"unix://",
+		"10.0.0.1:7000",
+		"plugin.example.com:7000",
+		"0.0.0.0:7000",
+		"localhost",
+	} {
+		_, _, err := ParseAddress(address)
+		assert.Error(t, err, address)
+	}
+}
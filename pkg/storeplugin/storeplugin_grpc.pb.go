@@ -0,0 +1,339 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: storeplugin.proto
+
+// Protocol between cerberus and the out-of-process store plugins. Plugins
+// generate their server from this file, in any language with a gRPC library.
+//
+// The private keys and passwords cross the connection in clear, which must
+// never leave the host: plugins listen on a Unix socket or a loopback address.
+
+package storeplugin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	StorePlugin_Capabilities_FullMethodName = "/cerberus.storeplugin.v1.StorePlugin/Capabilities"
+	StorePlugin_Health_FullMethodName       = "/cerberus.storeplugin.v1.StorePlugin/Health"
+	StorePlugin_RetrieveKey_FullMethodName  = "/cerberus.storeplugin.v1.StorePlugin/RetrieveKey"
+	StorePlugin_StoreKey_FullMethodName     = "/cerberus.storeplugin.v1.StorePlugin/StoreKey"
+	StorePlugin_ListKeys_FullMethodName     = "/cerberus.storeplugin.v1.StorePlugin/ListKeys"
+	StorePlugin_DeleteKey_FullMethodName    = "/cerberus.storeplugin.v1.StorePlugin/DeleteKey"
+)
+
+// StorePluginClient is the client API for StorePlugin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// StorePlugin mirrors the store interface of cerberus. Errors are reported
+// with the gRPC status codes:
+//   - NOT_FOUND when the key isn't in the store
+//   - ALREADY_EXISTS when storing a key which is already in the store
+//   - PERMISSION_DENIED when the password doesn't decrypt the key
+//   - UNIMPLEMENTED for the methods of the capabilities the plugin doesn't have
+type StorePluginClient interface {
+	// Capabilities returns the protocol version and the capabilities of the
+	// plugin, cerberus calls it once when connecting
+	Capabilities(ctx context.Context, in *CapabilitiesRequest, opts ...grpc.CallOption) (*CapabilitiesResponse, error)
+	// Health reports whether the plugin can serve requests, cerberus waits for
+	// SERVING before using a plugin it launched
+	Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
+	RetrieveKey(ctx context.Context, in *RetrieveKeyRequest, opts ...grpc.CallOption) (*RetrieveKeyResponse, error)
+	StoreKey(ctx context.Context, in *StoreKeyRequest, opts ...grpc.CallOption) (*StoreKeyResponse, error)
+	ListKeys(ctx context.Context, in *ListKeysRequest, opts ...grpc.CallOption) (*ListKeysResponse, error)
+	// DeleteKey is only called on the plugins with the delete_key capability
+	DeleteKey(ctx context.Context, in *DeleteKeyRequest, opts ...grpc.CallOption) (*DeleteKeyResponse, error)
+}
+
+type storePluginClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStorePluginClient(cc grpc.ClientConnInterface) StorePluginClient {
+	return &storePluginClient{cc}
+}
+
+func (c *storePluginClient) Capabilities(ctx context.Context, in *CapabilitiesRequest, opts ...grpc.CallOption) (*CapabilitiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CapabilitiesResponse)
+	err := c.cc.Invoke(ctx, StorePlugin_Capabilities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storePluginClient) Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HealthResponse)
+	err := c.cc.Invoke(ctx, StorePlugin_Health_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storePluginClient) RetrieveKey(ctx context.Context, in *RetrieveKeyRequest, opts ...grpc.CallOption) (*RetrieveKeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RetrieveKeyResponse)
+	err := c.cc.Invoke(ctx, StorePlugin_RetrieveKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storePluginClient) StoreKey(ctx context.Context, in *StoreKeyRequest, opts ...grpc.CallOption) (*StoreKeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StoreKeyResponse)
+	err := c.cc.Invoke(ctx, StorePlugin_StoreKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storePluginClient) ListKeys(ctx context.Context, in *ListKeysRequest, opts ...grpc.CallOption) (*ListKeysResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListKeysResponse)
+	err := c.cc.Invoke(ctx, StorePlugin_ListKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storePluginClient) DeleteKey(ctx context.Context, in *DeleteKeyRequest, opts ...grpc.CallOption) (*DeleteKeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteKeyResponse)
+	err := c.cc.Invoke(ctx, StorePlugin_DeleteKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StorePluginServer is the server API for StorePlugin service.
+// All implementations should embed UnimplementedStorePluginServer
+// for forward compatibility.
+//
+// StorePlugin mirrors the store interface of cerberus. Errors are reported
+// with the gRPC status codes:
+//   - NOT_FOUND when the key isn't in the store
+//   - ALREADY_EXISTS when storing a key which is already in the store
+//   - PERMISSION_DENIED when the password doesn't decrypt the key
+//   - UNIMPLEMENTED for the methods of the capabilities the plugin doesn't have
+type StorePluginServer interface {
+	// Capabilities returns the protocol version and the capabilities of the
+	// plugin, cerberus calls it once when connecting
+	Capabilities(context.Context, *CapabilitiesRequest) (*CapabilitiesResponse, error)
+	// Health reports whether the plugin can serve requests, cerberus waits for
+	// SERVING before using a plugin it launched
+	Health(context.Context, *HealthRequest) (*HealthResponse, error)
+	RetrieveKey(context.Context, *RetrieveKeyRequest) (*RetrieveKeyResponse, error)
+	StoreKey(context.Context, *StoreKeyRequest) (*StoreKeyResponse, error)
+	ListKeys(context.Context, *ListKeysRequest) (*ListKeysResponse, error)
+	// DeleteKey is only called on the plugins with the delete_key capability
+	DeleteKey(context.Context, *DeleteKeyRequest) (*DeleteKeyResponse, error)
+}
+
+// UnimplementedStorePluginServer should be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedStorePluginServer struct{}
+
+func (UnimplementedStorePluginServer) Capabilities(context.Context, *CapabilitiesRequest) (*CapabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Capabilities not implemented")
+}
+func (UnimplementedStorePluginServer) Health(context.Context, *HealthRequest) (*HealthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Health not implemented")
+}
+func (UnimplementedStorePluginServer) RetrieveKey(context.Context, *RetrieveKeyRequest) (*RetrieveKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RetrieveKey not implemented")
+}
+func (UnimplementedStorePluginServer) StoreKey(context.Context, *StoreKeyRequest) (*StoreKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StoreKey not implemented")
+}
+func (UnimplementedStorePluginServer) ListKeys(context.Context, *ListKeysRequest) (*ListKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListKeys not implemented")
+}
+func (UnimplementedStorePluginServer) DeleteKey(context.Context, *DeleteKeyRequest) (*DeleteKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteKey not implemented")
+}
+func (UnimplementedStorePluginServer) testEmbeddedByValue() {}
+
+// UnsafeStorePluginServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to StorePluginServer will
+// result in compilation errors.
+type UnsafeStorePluginServer interface {
+	mustEmbedUnimplementedStorePluginServer()
+}
+
+func RegisterStorePluginServer(s grpc.ServiceRegistrar, srv StorePluginServer) {
+	// If the following call pancis, it indicates UnimplementedStorePluginServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&StorePlugin_ServiceDesc, srv)
+}
+
+func _StorePlugin_Capabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CapabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorePluginServer).Capabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorePlugin_Capabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorePluginServer).Capabilities(ctx, req.(*CapabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorePlugin_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorePluginServer).Health(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorePlugin_Health_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorePluginServer).Health(ctx, req.(*HealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorePlugin_RetrieveKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RetrieveKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorePluginServer).RetrieveKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorePlugin_RetrieveKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorePluginServer).RetrieveKey(ctx, req.(*RetrieveKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorePlugin_StoreKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StoreKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorePluginServer).StoreKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorePlugin_StoreKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorePluginServer).StoreKey(ctx, req.(*StoreKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorePlugin_ListKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorePluginServer).ListKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorePlugin_ListKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorePluginServer).ListKeys(ctx, req.(*ListKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorePlugin_DeleteKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorePluginServer).DeleteKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorePlugin_DeleteKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorePluginServer).DeleteKey(ctx, req.(*DeleteKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// StorePlugin_ServiceDesc is the grpc.ServiceDesc for StorePlugin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var StorePlugin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cerberus.storeplugin.v1.StorePlugin",
+	HandlerType: (*StorePluginServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Capabilities",
+			Handler:    _StorePlugin_Capabilities_Handler,
+		},
+		{
+			MethodName: "Health",
+			Handler:    _StorePlugin_Health_Handler,
+		},
+		{
+			MethodName: "RetrieveKey",
+			Handler:    _StorePlugin_RetrieveKey_Handler,
+		},
+		{
+			MethodName: "StoreKey",
+			Handler:    _StorePlugin_StoreKey_Handler,
+		},
+		{
+			MethodName: "ListKeys",
+			Handler:    _StorePlugin_ListKeys_Handler,
+		},
+		{
+			MethodName: "DeleteKey",
+			Handler:    _StorePlugin_DeleteKey_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "storeplugin.proto",
+}